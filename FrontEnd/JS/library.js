@@ -1,4 +1,8 @@
 // 图书馆管理系统前端逻辑
+
+// 借阅记录需要的GraphQL字段
+const LOAN_FIELDS = 'borrowDate dueDate isOverdue fineAmount book { bookId title author }';
+
 class LibraryManager {
     constructor() {
         this.currentUser = authManager.getUserInfo();
@@ -13,7 +17,40 @@ class LibraryManager {
             console.error('初始化数据加载失败:', err);
             document.getElementById('borrowRecords').innerHTML = '<p>加载失败，请刷新页面</p>';
         });
-        this.loadBorrowRecords();
+        // 没有打开图书列表时也接收本人的借阅和预约事件
+        this.subscribeUpdates([]);
+    }
+
+    // 订阅实时事件：列表中图书的可借数量变化，以及本人的借阅和预约事件
+    subscribeUpdates(bookIds) {
+        if (!window.EventSource) return;
+
+        const params = new URLSearchParams();
+        if (bookIds.length > 0) params.set('book_id', bookIds.join(','));
+        if (this.currentUser.token) params.set('token', this.currentUser.token);
+        if (![...params.keys()].length) return;
+
+        if (this.eventSource) this.eventSource.close();
+        this.eventSource = new EventSource(`http://localhost:8085/api/v1/stream?${params}`);
+
+        this.eventSource.addEventListener('availability', e => {
+            this.updateBookAvailability(JSON.parse(e.data).data);
+        });
+        ['loan.borrowed', 'loan.returned', 'hold.ready'].forEach(type => {
+            this.eventSource.addEventListener(type, () => this.loadBorrowRecords());
+        });
+    }
+
+    // 更新图书卡片上的库存和借阅状态
+    updateBookAvailability(change) {
+        const card = document.querySelector(`.book-card[data-book-id="${change.book_id}"]`);
+        if (!card) return;
+
+        const available = change.available_copies > 0 && change.can_borrow;
+        card.querySelector('.copies-info').textContent = `库存: ${change.available_copies}/${change.total_copies}`;
+        const status = card.querySelector('.status');
+        status.className = `status ${available ? 'available' : 'unavailable'}`;
+        status.textContent = available ? '可借阅' : '不可借阅';
     }
 
     // 绑定事件
@@ -88,21 +125,43 @@ class LibraryManager {
         }
     }
 
-    // 加载用户个人信息
+    // 执行GraphQL查询，返回data；令牌失效时退出登录，其他错误抛出第一个错误的提示
+    async graphql(query) {
+        const headers = authManager.getAuthHeaders();
+        if (this.currentUser.token) headers['Authorization'] = `Bearer ${this.currentUser.token}`;
+
+        const response = await fetch('http://localhost:8085/api/v1/graphql', {
+            method: 'POST',
+            headers,
+            body: JSON.stringify({ query }),
+        });
+        if (!authManager.checkApiResponse(response)) return null;
+
+        const result = await response.json();
+        if (result.errors && result.errors.length > 0) {
+            const error = result.errors[0];
+            if (error.extensions && error.extensions.code === 'UNAUTHORIZED') {
+                authManager.logout();
+                return null;
+            }
+            throw new Error(error.message || result.message);
+        }
+        return result.data;
+    }
+
+    // 加载用户个人信息，与借阅记录和借阅的图书一次查询取回
     async loadUserProfile() {
         try {
-            const response = await fetch(`http://localhost:8085/student/info?stu_id=${this.currentUser.stu_id}`, {
-                headers: authManager.getAuthHeaders(),
+            const data = await this.graphql(`{ me { stuId name trust canBorrow loans { ${LOAN_FIELDS} } } }`);
+            if (!data || !data.me) return;
+
+            this.displayUserInfo({
+                stu_id: data.me.stuId,
+                name: data.me.name,
+                trust: data.me.trust,
+                can_borrow: data.me.canBorrow,
             });
-
-            if (!authManager.checkApiResponse(response)) return;
-
-            const data = await response.json();
-
-            if (data.data) {
-                this.displayUserInfo(data.data);
-                this.loadBorrowRecords();
-            }
+            this.renderBorrowRecords(data.me.loans);
         } catch (error) {
             console.error('加载用户信息失败:', error);
             this.showMessage('profilePanel', '加载用户信息失败', 'error');
@@ -126,24 +185,32 @@ class LibraryManager {
             const recordsContainer = document.getElementById('borrowRecords');
             recordsContainer.innerHTML = '<p class="loading">加载借阅记录中...</p>';
 
-            const response = await fetch(`http://localhost:8085/borrow/records?stu_id=${this.currentUser.stu_id}`, {
-                headers: authManager.getAuthHeaders(),
-            });
-
-            if (!authManager.checkApiResponse(response)) return;
-
-            const data = await response.json();
-            if (data.data && data.data.length > 0) {
-                this.displayBorrowRecords(data.data);
-            } else {
-                recordsContainer.innerHTML = '<p class="result-message">暂无借阅记录</p>';
-            }
+            const data = await this.graphql(`{ me { loans { ${LOAN_FIELDS} } } }`);
+            if (!data || !data.me) return;
+            this.renderBorrowRecords(data.me.loans);
         } catch (error) {
             console.error('加载借阅记录失败:', error);
             document.getElementById('borrowRecords').innerHTML = '<p class="result-message error">加载借阅记录失败</p>';
         }
     }
 
+    // 把GraphQL返回的借阅转为借阅记录的格式后显示
+    renderBorrowRecords(loans) {
+        if (!loans || loans.length === 0) {
+            document.getElementById('borrowRecords').innerHTML = '<p class="result-message">暂无借阅记录</p>';
+            return;
+        }
+        this.displayBorrowRecords(loans.map(loan => ({
+            book_id: loan.book ? loan.book.bookId : '',
+            book_title: loan.book && loan.book.title,
+            book_author: loan.book && loan.book.author,
+            borrow_date: loan.borrowDate,
+            due_date: loan.dueDate,
+            is_overdue: loan.isOverdue,
+            fine_amount: loan.fineAmount,
+        })));
+    }
+
     // 显示借阅记录
     displayBorrowRecords(records) {
         const recordsContainer = document.getElementById('borrowRecords');
@@ -192,14 +259,14 @@ class LibraryManager {
         bookList.innerHTML = '<p class="loading">加载图书中...</p>';
 
         try {
-            const response = await fetch('http://localhost:8085/books/list', {
+            const response = await fetch('http://localhost:8085/api/v1/books', {
                 headers: authManager.getAuthHeaders(),
             });
 
             // 检查响应状态
             if (!response.ok) {
                 const errorData = await response.json();
-                const errorMessage = errorData.error || '加载失败';
+                const errorMessage = errorData.message || '加载失败';
                 bookList.innerHTML = `<p class="result-message error">加载图书失败：${errorMessage}</p>`;
                 return;
             }
@@ -230,14 +297,14 @@ class LibraryManager {
         bookList.innerHTML = '<p class="loading">搜索中...</p>';
 
         try {
-            const response = await fetch(`http://localhost:8085/books/search?keyword=${encodeURIComponent(keyword)}`, {
+            const response = await fetch(`http://localhost:8085/api/v1/books?keyword=${encodeURIComponent(keyword)}`, {
                 headers: authManager.getAuthHeaders(),
             });
 
             // 检查响应状态
             if (!response.ok) {
                 const errorData = await response.json();
-                const errorMessage = errorData.error || '搜索失败';
+                const errorMessage = errorData.message || '搜索失败';
                 bookList.innerHTML = `<p class="result-message error">搜索失败：${errorMessage}</p>`;
                 return;
             }
@@ -285,19 +352,20 @@ class LibraryManager {
         `).join('');
 
         bookList.innerHTML = booksHTML;
+        this.subscribeUpdates(books.map(book => book.book_id));
     }
 
     // 显示图书详情
     async showBookDetail(bookId) {
         try {
-            const response = await fetch(`http://localhost:8085/books/${bookId}`, {
+            const response = await fetch(`http://localhost:8085/api/v1/books/${encodeURIComponent(bookId)}`, {
                 headers: authManager.getAuthHeaders(),
             });
 
             // 检查响应状态
             if (!response.ok) {
                 const errorData = await response.json();
-                const errorMessage = errorData.error || '获取详情失败';
+                const errorMessage = errorData.message || '获取详情失败';
                 this.showMessage('bookPanel', `获取图书详情失败：${errorMessage}`, 'error');
                 return;
             }
@@ -388,7 +456,7 @@ class LibraryManager {
         borrowBtn.textContent = '借阅中...';
 
         try {
-            const response = await fetch('http://localhost:8085/borrow/borrow', {
+            const response = await fetch('http://localhost:8085/api/v1/loans', {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
@@ -403,20 +471,23 @@ class LibraryManager {
             // 检查响应状态
             if (!response.ok) {
                 const errorData = await response.json();
-                const errorMessage = errorData.error || '借书失败';
+                const errorMessage = errorData.message || '借书失败';
                 
                 // 先关闭借书窗口
                 this.closeModal();
                 
-                // 处理特定的错误情况
-                if (errorMessage.includes('学生借阅权限已被禁用')) {
-                    this.showMessage('bookPanel', '借阅失败：您的借阅权限已被禁用，可能由于逾期未还书或未支付罚款。请先归还逾期图书或支付罚款后重试。', 'error');
-                } else if (errorMessage.includes('书籍不可借阅或已全部借出')) {
-                    this.showMessage('bookPanel', '借阅失败：该图书暂不可借阅或已全部借出，请选择其他图书。', 'error');
-                } else if (errorMessage.includes('已达到最大借阅数量')) {
-                    this.showMessage('bookPanel', '借阅失败：您已达到最大借阅数量限制，请先归还部分图书。', 'error');
-                } else {
-                    this.showMessage('bookPanel', `借阅失败：${errorMessage}`, 'error');
+                // 按错误码处理特定的错误情况
+                switch (errorData.code) {
+                    case 'BORROW_DISABLED':
+                    case 'UNPAID_FINE':
+                        this.showMessage('bookPanel', '借阅失败：您的借阅权限已被禁用，可能由于逾期未还书或未支付罚款。请先归还逾期图书或支付罚款后重试。', 'error');
+                        break;
+                    case 'BOOK_NOT_BORROWABLE':
+                    case 'BOOK_UNAVAILABLE':
+                        this.showMessage('bookPanel', '借阅失败：该图书暂不可借阅或已全部借出，请选择其他图书。', 'error');
+                        break;
+                    default:
+                        this.showMessage('bookPanel', `借阅失败：${errorMessage}`, 'error');
                 }
                 return;
             }
@@ -427,9 +498,7 @@ class LibraryManager {
             this.showMessage('bookPanel', data.message || '借书成功', 'success');
             this.closeModal();
             
-            // 刷新图书列表
-            this.loadAllBooks();
-            
+            // 图书库存由实时事件更新，无需重新加载列表
             // 刷新用户信息（可能影响借阅状态）
             this.loadUserProfile();
         } catch (error) {
@@ -461,7 +530,7 @@ class LibraryManager {
         }
 
         try {
-            const response = await fetch('http://localhost:8085/borrow/return', {
+            const response = await fetch('http://localhost:8085/api/v1/returns', {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
@@ -476,15 +545,18 @@ class LibraryManager {
             // 检查响应状态
             if (!response.ok) {
                 const errorData = await response.json();
-                const errorMessage = errorData.error || '还书失败';
+                const errorMessage = errorData.message || '还书失败';
                 
-                // 处理特定的错误情况
-                if (errorMessage.includes('借阅记录不存在')) {
-                    this.showMessage('profilePanel', '还书失败：未找到该借阅记录，可能已经归还或记录有误', 'error');
-                } else if (errorMessage.includes('图书不存在')) {
-                    this.showMessage('profilePanel', '还书失败：图书信息不存在，请联系管理员', 'error');
-                } else {
-                    this.showMessage('profilePanel', `还书失败：${errorMessage}`, 'error');
+                // 按错误码处理特定的错误情况
+                switch (errorData.code) {
+                    case 'LOAN_NOT_FOUND':
+                        this.showMessage('profilePanel', '还书失败：未找到该借阅记录，可能已经归还或记录有误', 'error');
+                        break;
+                    case 'BOOK_NOT_FOUND':
+                        this.showMessage('profilePanel', '还书失败：图书信息不存在，请联系管理员', 'error');
+                        break;
+                    default:
+                        this.showMessage('profilePanel', `还书失败：${errorMessage}`, 'error');
                 }
                 return;
             }
@@ -494,8 +566,9 @@ class LibraryManager {
             const data = await response.json();
             
             // 显示还书结果
-            if (data.fine_amount && data.fine_amount > 0) {
-                this.showMessage('profilePanel', `${data.message}，罚款金额：¥${data.fine_amount.toFixed(2)}。请注意：逾期罚款可能影响您的借阅权限。`, 'warning');
+            const fineAmount = data.data ? data.data.fine_amount : 0;
+            if (fineAmount > 0) {
+                this.showMessage('profilePanel', `${data.message}，罚款金额：¥${fineAmount.toFixed(2)}。请注意：逾期罚款可能影响您的借阅权限。`, 'warning');
             } else {
                 this.showMessage('profilePanel', data.message || '还书成功', 'success');
             }
@@ -40,7 +40,7 @@ document.addEventListener('DOMContentLoaded', function() {
         loginBtn.disabled = true;
 
         // 发送登录请求
-        fetch('http://localhost:8085/student/login', {
+        fetch('http://localhost:8085/api/v1/sessions/student', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
@@ -53,7 +53,7 @@ document.addEventListener('DOMContentLoaded', function() {
         .then(response => {
             if (!response.ok) {
                 return response.json().then(errorData => {
-                    throw new Error(errorData.error || '登录失败');
+                    throw new Error(errorData.message || '登录失败');
                 });
             }
             return response.json();
@@ -0,0 +1,117 @@
+// Package apperr 定义带错误码的业务错误
+// 各层返回*Error表明失败原因，控制器的错误处理中间件按错误码统一决定HTTP状态码和响应内容，
+// 前端根据稳定的错误码分支处理，不再解析提示文字；提示按请求的语言从i18n目录中输出
+package apperr
+
+import (
+	"backend/i18n"
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Error 带错误码的错误
+type Error struct {
+	Code   Code
+	Params map[string]interface{} // 提示中的参数，替换提示模板中的 {name}
+	Data   interface{}            // 随错误返回给客户端的数据，如借出受阻原因
+	Err    error                  // 原始错误，只记录日志，不返回给客户端
+
+	key string // 自定义提示在i18n目录中的键，为空时使用错误码的默认提示
+}
+
+// 使用错误码的默认提示创建错误
+func New(code Code) *Error {
+	return &Error{Code: code}
+}
+
+// 使用i18n目录中的自定义提示创建错误
+func NewMessage(code Code, key string) *Error {
+	return &Error{Code: code, key: key}
+}
+
+// 参数校验失败，key为说明哪个参数有误的提示
+func Invalid(key string) *Error {
+	return NewMessage(CodeInvalidArgument, key)
+}
+
+// 包装原始错误，客户端只能看到错误码的默认提示
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Err: err}
+}
+
+// 设置提示参数
+func (e *Error) With(key string, value interface{}) *Error {
+	if e.Params == nil {
+		e.Params = make(map[string]interface{})
+	}
+	e.Params[key] = value
+	return e
+}
+
+// 设置随错误返回的数据
+func (e *Error) WithData(data interface{}) *Error {
+	e.Data = data
+	return e
+}
+
+// 面向用户的提示，使用默认语言
+func (e *Error) Message() string {
+	return e.Localize(i18n.Default)
+}
+
+// 按指定语言输出面向用户的提示；错误码的默认提示以错误码为键登记在i18n目录中
+func (e *Error) Localize(locale string) string {
+	key := e.key
+	if key == "" {
+		key = string(e.Code)
+	}
+	return i18n.Translate(locale, key, i18n.Params(e.Params))
+}
+
+// HTTP状态码
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message() + ": " + e.Err.Error()
+	}
+	return e.Message()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// 转换为*Error：已是*Error的原样返回，请求超时或取消转为对应错误码，其余视为内部错误
+func From(err error) *Error {
+	var appErr *Error
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(CodeTimeout, err)
+	case errors.Is(err, context.Canceled):
+		return Wrap(CodeCanceled, err)
+	}
+	return Wrap(CodeInternal, err)
+}
+
+// 获取错误码，不是*Error时返回空
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return ""
+}
+
+// 判断错误是否为指定错误码
+func Is(err error, code Code) bool {
+	return CodeOf(err) == code
+}
@@ -0,0 +1,69 @@
+package apperr
+
+import (
+	"backend/i18n"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	for _, tc := range []struct {
+		err  *Error
+		want string
+	}{
+		{New(CodeBookNotFound), "书籍不存在"},
+		{NewMessage(CodeLoanNotFound, "error.item_not_on_loan"), "该单册没有未归还的借阅记录"},
+		{New(CodeOverrideNotAllowed).With("code", "item_not_found"), "不可强制越过的受阻原因: item_not_found"},
+		{Wrap(CodeInternal, errors.New("connection refused")), "服务器内部错误"},
+		{New("UNKNOWN_CODE"), "UNKNOWN_CODE"},
+	} {
+		if got := tc.err.Message(); got != tc.want {
+			t.Errorf("%s 提示 = %q，期望 %q", tc.err.Code, got, tc.want)
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	err := New(CodeCoverTooLarge).With("max_mb", 5)
+	if got, want := err.Localize(i18n.EnUS), "Cover file must not exceed 5 MB"; got != want {
+		t.Errorf("英文提示 = %q，期望 %q", got, want)
+	}
+	if got, want := err.Localize(i18n.ZhCN), "封面文件不能超过5MB"; got != want {
+		t.Errorf("中文提示 = %q，期望 %q", got, want)
+	}
+}
+
+func TestFrom(t *testing.T) {
+	wrapped := fmt.Errorf("还书失败: %w", New(CodeItemNotFound))
+	for _, tc := range []struct {
+		err    error
+		code   Code
+		status int
+	}{
+		{wrapped, CodeItemNotFound, http.StatusNotFound},
+		{New(CodeBookUnavailable), CodeBookUnavailable, http.StatusConflict},
+		{New(CodeUnpaidFine), CodeUnpaidFine, http.StatusUnprocessableEntity},
+		{fmt.Errorf("查询失败: %w", context.DeadlineExceeded), CodeTimeout, http.StatusGatewayTimeout},
+		{context.Canceled, CodeCanceled, 499},
+		{errors.New("connection refused"), CodeInternal, http.StatusInternalServerError},
+	} {
+		got := From(tc.err)
+		if got.Code != tc.code || got.Status() != tc.status {
+			t.Errorf("From(%v) = %s %d，期望 %s %d", tc.err, got.Code, got.Status(), tc.code, tc.status)
+		}
+	}
+}
+
+// 每个错误码都要在各语言的目录中登记默认提示
+func TestCodesRegistered(t *testing.T) {
+	for code := range statuses {
+		for _, locale := range i18n.Locales() {
+			if !i18n.Has(locale, string(code)) {
+				t.Errorf("%s 缺少%s提示", code, locale)
+			}
+		}
+	}
+}
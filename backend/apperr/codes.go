@@ -0,0 +1,144 @@
+package apperr
+
+import "net/http"
+
+// Code 错误码，对外稳定，前端据此分支处理；新增错误码时同时登记HTTP状态码，并在i18n目录中以错误码为键登记各语言的默认提示
+type Code string
+
+// 成功响应的code
+const CodeOK Code = "OK"
+
+// 通用错误
+const (
+	CodeInvalidArgument Code = "INVALID_ARGUMENT"
+	CodeUnauthorized    Code = "UNAUTHORIZED"
+	CodeForbidden       Code = "FORBIDDEN"
+	CodeNotFound        Code = "NOT_FOUND"
+	CodeTooManyRequests Code = "TOO_MANY_REQUESTS"
+	CodeCanceled        Code = "CANCELED"
+	CodeInternal        Code = "INTERNAL"
+	CodeTimeout         Code = "TIMEOUT"
+)
+
+// 认证
+const (
+	CodeTokenInvalid       Code = "TOKEN_INVALID"
+	CodeTokenExpired       Code = "TOKEN_EXPIRED"
+	CodeInvalidCredentials Code = "INVALID_CREDENTIALS"
+	CodeInvalidPIN         Code = "INVALID_PIN"
+	CodePINLocked          Code = "PIN_LOCKED"
+	CodeDeviceKeyInvalid   Code = "DEVICE_KEY_INVALID"
+	CodeDeviceDisabled     Code = "DEVICE_DISABLED"
+)
+
+// 资源不存在
+const (
+	CodeBookNotFound         Code = "BOOK_NOT_FOUND"
+	CodeItemNotFound         Code = "ITEM_NOT_FOUND"
+	CodeLoanNotFound         Code = "LOAN_NOT_FOUND"
+	CodeStudentNotFound      Code = "STUDENT_NOT_FOUND"
+	CodeLibrarianNotFound    Code = "LIBRARIAN_NOT_FOUND"
+	CodeBranchNotFound       Code = "BRANCH_NOT_FOUND"
+	CodeHoldNotFound         Code = "HOLD_NOT_FOUND"
+	CodeTransferNotFound     Code = "TRANSFER_NOT_FOUND"
+	CodeClosureNotFound      Code = "CLOSURE_NOT_FOUND"
+	CodeDeviceNotFound       Code = "DEVICE_NOT_FOUND"
+	CodeWebhookNotFound      Code = "WEBHOOK_NOT_FOUND"
+	CodeEventNotFound        Code = "EVENT_NOT_FOUND"
+	CodeNotificationNotFound Code = "NOTIFICATION_NOT_FOUND"
+	CodeDeliveryNotFound     Code = "DELIVERY_NOT_FOUND"
+	CodeCoverNotFound        Code = "COVER_NOT_FOUND"
+)
+
+// 借阅、预约、调拨等业务规则
+const (
+	CodeBorrowDisabled         Code = "BORROW_DISABLED"
+	CodeUnpaidFine             Code = "UNPAID_FINE"
+	CodeBookNotBorrowable      Code = "BOOK_NOT_BORROWABLE"
+	CodeBookUnavailable        Code = "BOOK_UNAVAILABLE"
+	CodeNoCopyAtBranch         Code = "NO_COPY_AT_BRANCH"
+	CodeNoHoldings             Code = "NO_HOLDINGS"
+	CodeHoldExists             Code = "HOLD_EXISTS"
+	CodeHoldClosed             Code = "HOLD_CLOSED"
+	CodeNoFineDue              Code = "NO_FINE_DUE"
+	CodeCheckoutBlocked        Code = "CHECKOUT_BLOCKED"
+	CodeOverrideNotAllowed     Code = "OVERRIDE_NOT_ALLOWED"
+	CodeOverrideReasonRequired Code = "OVERRIDE_REASON_REQUIRED"
+	CodeItemNotOnShelf         Code = "ITEM_NOT_ON_SHELF"
+	CodeItemAtBranch           Code = "ITEM_AT_BRANCH"
+	CodeTransferStateInvalid   Code = "TRANSFER_STATE_INVALID"
+	CodeWebhookDisabled        Code = "WEBHOOK_DISABLED"
+	CodeMailNotConfigured      Code = "MAIL_NOT_CONFIGURED"
+	CodeStudentExists          Code = "STUDENT_EXISTS"
+	CodeBookExists             Code = "BOOK_EXISTS"
+	CodeBookHasItems           Code = "BOOK_HAS_ITEMS"
+)
+
+// 封面
+const (
+	CodeCoverTooLarge        Code = "COVER_TOO_LARGE"
+	CodeCoverUnsupportedType Code = "COVER_UNSUPPORTED_TYPE"
+	CodeCoverInvalid         Code = "COVER_INVALID"
+)
+
+// 错误码对应的HTTP状态码，未登记的按500处理
+var statuses = map[Code]int{
+	CodeInvalidArgument: http.StatusBadRequest,
+	CodeUnauthorized:    http.StatusUnauthorized,
+	CodeForbidden:       http.StatusForbidden,
+	CodeNotFound:        http.StatusNotFound,
+	CodeTooManyRequests: http.StatusTooManyRequests,
+	CodeCanceled:        499, // 客户端已断开连接，与Nginx的约定一致
+	CodeInternal:        http.StatusInternalServerError,
+	CodeTimeout:         http.StatusGatewayTimeout,
+
+	CodeTokenInvalid:       http.StatusUnauthorized,
+	CodeTokenExpired:       http.StatusUnauthorized,
+	CodeInvalidCredentials: http.StatusUnauthorized,
+	CodeInvalidPIN:         http.StatusUnauthorized,
+	CodePINLocked:          http.StatusTooManyRequests,
+	CodeDeviceKeyInvalid:   http.StatusUnauthorized,
+	CodeDeviceDisabled:     http.StatusForbidden,
+
+	CodeBookNotFound:         http.StatusNotFound,
+	CodeItemNotFound:         http.StatusNotFound,
+	CodeLoanNotFound:         http.StatusNotFound,
+	CodeStudentNotFound:      http.StatusNotFound,
+	CodeLibrarianNotFound:    http.StatusNotFound,
+	CodeBranchNotFound:       http.StatusNotFound,
+	CodeHoldNotFound:         http.StatusNotFound,
+	CodeTransferNotFound:     http.StatusNotFound,
+	CodeClosureNotFound:      http.StatusNotFound,
+	CodeDeviceNotFound:       http.StatusNotFound,
+	CodeWebhookNotFound:      http.StatusNotFound,
+	CodeEventNotFound:        http.StatusNotFound,
+	CodeNotificationNotFound: http.StatusNotFound,
+	CodeDeliveryNotFound:     http.StatusNotFound,
+	CodeCoverNotFound:        http.StatusNotFound,
+
+	// 资源当前状态不允许该操作时返回409，请求本身违反业务规则时返回422
+	CodeBorrowDisabled:         http.StatusUnprocessableEntity,
+	CodeUnpaidFine:             http.StatusUnprocessableEntity,
+	CodeBookNotBorrowable:      http.StatusUnprocessableEntity,
+	CodeBookUnavailable:        http.StatusConflict,
+	CodeNoCopyAtBranch:         http.StatusConflict,
+	CodeNoHoldings:             http.StatusUnprocessableEntity,
+	CodeHoldExists:             http.StatusConflict,
+	CodeHoldClosed:             http.StatusConflict,
+	CodeNoFineDue:              http.StatusUnprocessableEntity,
+	CodeCheckoutBlocked:        http.StatusConflict,
+	CodeOverrideNotAllowed:     http.StatusUnprocessableEntity,
+	CodeOverrideReasonRequired: http.StatusUnprocessableEntity,
+	CodeItemNotOnShelf:         http.StatusConflict,
+	CodeItemAtBranch:           http.StatusConflict,
+	CodeTransferStateInvalid:   http.StatusConflict,
+	CodeWebhookDisabled:        http.StatusConflict,
+	CodeMailNotConfigured:      http.StatusServiceUnavailable,
+	CodeStudentExists:          http.StatusConflict,
+	CodeBookExists:             http.StatusConflict,
+	CodeBookHasItems:           http.StatusConflict,
+
+	CodeCoverTooLarge:        http.StatusRequestEntityTooLarge,
+	CodeCoverUnsupportedType: http.StatusUnsupportedMediaType,
+	CodeCoverInvalid:         http.StatusUnprocessableEntity,
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"backend/dao"
+	"backend/do"
+	"backend/service"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"time"
+)
+
+// 新建学生；未指定密码时随机生成，只在本次输出中出现
+func studentCreate(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("student create")
+	stuID := fs.String("id", "", "学号")
+	name := fs.String("name", "", "姓名")
+	password := fs.String("password", "", "登录密码，为空时随机生成")
+	trust := fs.Float64("trust", 1, "信任度")
+	if _, err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	generated := *password == ""
+	if generated {
+		*password = rand.Text()
+	}
+	student := &do.Student{StuId: *stuID, Name: *name, Password: *password, Trust: *trust, CanBorrow: true}
+	if err := service.NewAdminService(dao.NewStore(c.db)).CreateStudent(ctx, student); err != nil {
+		return err
+	}
+
+	type createdStudent struct {
+		StuID    string `json:"stu_id"`
+		Name     string `json:"name"`
+		Password string `json:"password,omitempty"`
+	}
+	result := createdStudent{StuID: student.StuId, Name: student.Name}
+	if generated {
+		result.Password = student.Password
+	}
+	return c.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "已新建学生 %s（%s）\n", student.StuId, student.Name)
+		if generated {
+			fmt.Fprintf(w, "初始密码: %s\n", student.Password)
+		}
+	})
+}
+
+// 禁止学生借阅
+func studentDisable(ctx context.Context, c *cli, args []string) error {
+	return setBorrowPermission(ctx, c, "student disable", args, false)
+}
+
+// 恢复学生借阅
+func studentEnable(ctx context.Context, c *cli, args []string) error {
+	return setBorrowPermission(ctx, c, "student enable", args, true)
+}
+
+func setBorrowPermission(ctx context.Context, c *cli, name string, args []string, canBorrow bool) error {
+	positional, err := parse(c.flags(name), args, 1)
+	if err != nil {
+		return err
+	}
+	stuID := positional[0]
+
+	studentService := service.NewStudentService(dao.NewStore(c.db))
+	if canBorrow {
+		err = studentService.EnableBorrowPermission(ctx, stuID)
+	} else {
+		err = studentService.DisableBorrowPermission(ctx, stuID)
+	}
+	if err != nil {
+		return err
+	}
+
+	type permission struct {
+		StuID     string `json:"stu_id"`
+		CanBorrow bool   `json:"can_borrow"`
+	}
+	return c.print(permission{StuID: stuID, CanBorrow: canBorrow}, func(w io.Writer) {
+		if canBorrow {
+			fmt.Fprintf(w, "已恢复学生 %s 的借阅权限\n", stuID)
+		} else {
+			fmt.Fprintf(w, "已禁止学生 %s 借阅\n", stuID)
+		}
+	})
+}
+
+// 重置学生密码；未指定密码时随机生成，只在本次输出中出现
+func studentResetPassword(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("student reset-password")
+	password := fs.String("password", "", "新密码，为空时随机生成")
+	positional, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	stuID := positional[0]
+
+	generated := *password == ""
+	if generated {
+		*password = rand.Text()
+	}
+	if err := service.NewAdminService(dao.NewStore(c.db)).ResetPassword(ctx, stuID, *password); err != nil {
+		return err
+	}
+
+	type reset struct {
+		StuID    string `json:"stu_id"`
+		Password string `json:"password,omitempty"`
+	}
+	result := reset{StuID: stuID}
+	if generated {
+		result.Password = *password
+	}
+	return c.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "已重置学生 %s 的密码\n", stuID)
+		if generated {
+			fmt.Fprintf(w, "新密码: %s\n", *password)
+		}
+	})
+}
+
+// 调整书籍的总馆藏数量
+func bookCopies(ctx context.Context, c *cli, args []string) error {
+	fs := c.flags("book copies")
+	total := fs.Int("total", -1, "总馆藏数量")
+	positional, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if *total < 0 {
+		return usageError("book copies 需要 -total 参数，且不能为负数")
+	}
+
+	book, err := service.NewAdminService(dao.NewStore(c.db)).SetBookCopies(ctx, positional[0], *total)
+	if err != nil {
+		return err
+	}
+	return c.print(book, func(w io.Writer) {
+		fmt.Fprintf(w, "《%s》总馆藏 %d 册，可借 %d 册\n", book.Title, book.TotalCopies, book.AvailableCopies)
+	})
+}
+
+// 减免学生名下的全部罚款
+func fineWaive(ctx context.Context, c *cli, args []string) error {
+	positional, err := parse(c.flags("fine waive"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	waiver, err := service.NewAdminService(dao.NewStore(c.db)).WaiveFines(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	return c.print(waiver, func(w io.Writer) {
+		fmt.Fprintf(w, "已减免学生 %s 的 %d 条借阅罚款，共 %.2f 元，借阅权限已恢复\n", waiver.StuID, waiver.Records, waiver.Amount)
+	})
+}
+
+// 逾期扫描：更新逾期借阅的罚款，再生成到期和逾期提醒（由服务器的通知任务发送）
+func overdueSweep(ctx context.Context, c *cli, args []string) error {
+	if _, err := parse(c.flags("overdue sweep"), args, 0); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	sweep, err := service.NewAdminService(dao.NewStore(c.db)).SweepOverdue(ctx, now)
+	if err != nil {
+		return err
+	}
+	notices, err := service.NewNotificationService(c.db, nil).EnqueueNotices(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	type sweepResult struct {
+		*service.OverdueSweep
+		Notices int `json:"notices"`
+	}
+	return c.print(sweepResult{OverdueSweep: sweep, Notices: notices}, func(w io.Writer) {
+		fmt.Fprintf(w, "逾期借阅\t%d\n", sweep.OverdueLoans)
+		fmt.Fprintf(w, "罚款有变化\t%d\n", sweep.Updated)
+		fmt.Fprintf(w, "罚款合计\t%.2f\n", sweep.TotalFines)
+		fmt.Fprintf(w, "新生成提醒\t%d\n", notices)
+	})
+}
+
+// 流通概况，与Prometheus指标中的流通数据一致
+func reportCirculation(ctx context.Context, c *cli, args []string) error {
+	if _, err := parse(c.flags("report circulation"), args, 0); err != nil {
+		return err
+	}
+
+	stats, err := service.NewStatsService(c.db).CirculationStats(ctx)
+	if err != nil {
+		return err
+	}
+	return c.print(stats, func(w io.Writer) {
+		fmt.Fprintf(w, "逾期借阅\t%d\n", stats.OverdueLoans)
+		fmt.Fprintf(w, "未支付罚款\t%.2f\n", stats.OutstandingFines)
+		fmt.Fprintf(w, "有罚款的学生\t%d\n", stats.FinedStudents)
+		fmt.Fprintf(w, "无可借副本的图书\t%d\n", stats.BooksUnavailable)
+	})
+}
+
+// 逾期借阅清单，罚款按截至现在的逾期天数计算
+func reportOverdue(ctx context.Context, c *cli, args []string) error {
+	if _, err := parse(c.flags("report overdue"), args, 0); err != nil {
+		return err
+	}
+
+	loans, err := service.NewAdminService(dao.NewStore(c.db)).OverdueReport(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	return c.print(loans, func(w io.Writer) {
+		if len(loans) == 0 {
+			fmt.Fprintln(w, "没有逾期借阅")
+			return
+		}
+		fmt.Fprintln(w, "学号\t姓名\t书籍ID\t书名\t条码\t应还日期\t逾期天数\t罚款")
+		for _, loan := range loans {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%.2f\n",
+				loan.StuID, loan.StudentName, loan.BookID, loan.Title, loan.Barcode,
+				loan.DueDate.Format(time.DateOnly), loan.DaysOverdue, loan.FineAmount)
+		}
+	})
+}
@@ -0,0 +1,260 @@
+// librarian 命令行管理工具，运维人员不必再直接操作数据库：新建和停用学生、重置密码、调整馆藏、减免罚款、
+// 按需执行逾期扫描、导入导出数据和输出报表。与服务器使用相同的配置和业务层，修改数据的操作以cli角色写入审计日志
+package main
+
+import (
+	"backend/apperr"
+	"backend/config"
+	"backend/dao"
+	"backend/do"
+	"backend/logging"
+	"backend/migrations"
+	"backend/service"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"os/user"
+	"text/tabwriter"
+)
+
+const usage = `用法: librarian [配置参数] <命令> [参数]
+  student create -id 学号 -name 姓名 [-password 密码] [-trust 信用分]
+                                         新建学生，未指定密码时随机生成并输出一次
+  student disable <学号>                 禁止借阅
+  student enable <学号>                  恢复借阅
+  student reset-password [-password 密码] <学号>
+                                         重置密码，未指定密码时随机生成并输出一次
+  book copies -total 数量 <书籍ID>        调整总馆藏数量（未登记单册的书籍）
+  fine waive <学号>                      减免学生名下的全部罚款并恢复借阅
+  overdue sweep                          更新逾期借阅的罚款并生成到期提醒
+  import books|students [-format csv|json] <文件>
+                                         批量导入，任一条记录有误时全部不导入
+  export books|students [-format csv|json] [-o 文件]
+                                         导出到文件，默认输出到标准输出
+  report circulation|overdue             流通概况、逾期借阅清单
+
+配置参数与服务器相同（如 -config config.yaml -database.driver sqlite），见 library -h
+每个命令都可以加 -json，结果以JSON输出到标准输出；出错时输出 {"code": 错误码, "message": 提示} 并以状态码1退出`
+
+func main() {
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "加载配置失败:", err)
+		os.Exit(1)
+	}
+	if len(args) == 0 || args[0] == "help" {
+		fmt.Fprintln(os.Stderr, usage)
+		if len(args) == 0 {
+			os.Exit(2)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cli := &cli{stdout: os.Stdout}
+	if err := cli.run(ctx, cfg, args); err != nil {
+		cli.fail(err)
+		os.Exit(1)
+	}
+}
+
+// 命令行的运行环境
+type cli struct {
+	stdout   io.Writer
+	jsonMode bool // 命令带-json参数时以JSON输出
+	db       *sql.DB
+}
+
+// 连接数据库后执行命令；日志只输出警告以上的级别，写到标准错误，不混入命令的输出
+func (c *cli) run(ctx context.Context, cfg *config.Config, args []string) error {
+	command, ok := commands[args[0]]
+	if !ok {
+		return usageError("未知的命令: %s", args[0])
+	}
+
+	logger, err := logging.New(os.Stderr, "warn", cfg.Log.Format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	dao.SetSlowQueryThreshold(cfg.Log.SlowQuery.Std())
+	service.SetLoanPolicy(service.LoanPolicy{
+		PeriodMonths: cfg.Loan.PeriodMonths,
+		FinePerDay:   cfg.Loan.FinePerDay,
+		DueSoonDays:  cfg.Loan.DueSoonDays,
+	})
+
+	db, err := dao.Open(cfg.Database.Driver, cfg.Database.DSN())
+	if err != nil {
+		return fmt.Errorf("数据库连接失败: %w", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	c.db = db
+
+	// 表结构不是最新时拒绝执行，避免按旧表结构写入数据
+	migrator, err := migrations.NewMigrator(db, cfg.Database.Driver)
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("有%d个迁移尚未执行，请先运行 library migrate up", pending)
+	}
+
+	return command(service.WithActor(ctx, cliActor()), c, args[1:])
+}
+
+// 审计日志中的操作人为执行命令的系统用户
+func cliActor() service.Actor {
+	actor := service.Actor{Role: do.ActorCLI}
+	if u, err := user.Current(); err == nil {
+		actor.ID = u.Username
+	}
+	return actor
+}
+
+// 命令，args为命令名之后的参数
+type command func(ctx context.Context, c *cli, args []string) error
+
+var commands = map[string]command{
+	"student": group("student", map[string]command{
+		"create":         studentCreate,
+		"disable":        studentDisable,
+		"enable":         studentEnable,
+		"reset-password": studentResetPassword,
+	}),
+	"book": group("book", map[string]command{
+		"copies": bookCopies,
+	}),
+	"fine": group("fine", map[string]command{
+		"waive": fineWaive,
+	}),
+	"overdue": group("overdue", map[string]command{
+		"sweep": overdueSweep,
+	}),
+	"import": group("import", map[string]command{
+		"books":    importBooks,
+		"students": importStudents,
+	}),
+	"export": group("export", map[string]command{
+		"books":    exportBooks,
+		"students": exportStudents,
+	}),
+	"report": group("report", map[string]command{
+		"circulation": reportCirculation,
+		"overdue":     reportOverdue,
+	}),
+}
+
+// 按第一个参数分派到子命令
+func group(name string, subcommands map[string]command) command {
+	return func(ctx context.Context, c *cli, args []string) error {
+		if len(args) == 0 {
+			return usageError("缺少%s的子命令", name)
+		}
+		sub, ok := subcommands[args[0]]
+		if !ok {
+			return usageError("未知的命令: %s %s", name, args[0])
+		}
+		return sub(ctx, c, args[1:])
+	}
+}
+
+// 参数错误，输出时附带用法
+type usageErr struct {
+	msg string
+}
+
+func (e *usageErr) Error() string {
+	return e.msg
+}
+
+func usageError(format string, args ...interface{}) error {
+	return &usageErr{msg: fmt.Sprintf(format, args...)}
+}
+
+// 命令的参数，-json对所有命令有效；出错时只返回错误，由fail统一输出
+func (c *cli) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&c.jsonMode, "json", false, "以JSON输出")
+	return fs
+}
+
+// 解析参数，参数可以写在位置参数之后（如 student reset-password 20230001 -password x）；
+// 位置参数的数量必须为n
+func parse(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, usageError("")
+			}
+			return nil, usageError("%s: %v", fs.Name(), err)
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(positional) != n {
+		return nil, usageError("%s 需要%d个参数，实际为%d个", fs.Name(), n, len(positional))
+	}
+	return positional, nil
+}
+
+// 输出命令结果：JSON模式输出value，否则调用text输出给人看的文字
+func (c *cli) print(value interface{}, text func(w io.Writer)) error {
+	if c.jsonMode {
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	text(w)
+	return w.Flush()
+}
+
+// 输出错误：业务错误按错误码的提示输出，参数错误附带用法
+func (c *cli) fail(err error) {
+	code, message := apperr.CodeOf(err), err.Error()
+	var invalid *usageErr
+	switch {
+	case errors.As(err, &invalid):
+		code = apperr.CodeInvalidArgument
+	case code != "":
+		message = apperr.From(err).Message()
+	}
+
+	if c.jsonMode {
+		if code == "" {
+			code = apperr.CodeInternal
+		}
+		json.NewEncoder(c.stdout).Encode(map[string]string{"code": string(code), "message": message})
+		return
+	}
+	if message != "" {
+		fmt.Fprintln(os.Stderr, "错误:", message)
+	}
+	if invalid != nil {
+		fmt.Fprintln(os.Stderr, usage)
+	}
+}
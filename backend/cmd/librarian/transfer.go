@@ -0,0 +1,345 @@
+package main
+
+import (
+	"backend/dao"
+	"backend/do"
+	"backend/service"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 导入导出的文件格式，CSV的第一行为列名，列的顺序不限，未知的列忽略
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+)
+
+// 导入导出的学生，导出时不含密码
+type studentRecord struct {
+	StuID     string    `json:"stu_id"`
+	Name      string    `json:"name"`
+	Password  string    `json:"password,omitempty"`
+	Trust     *float64  `json:"trust,omitempty"`      // 导入时默认为1
+	CanBorrow *bool     `json:"can_borrow,omitempty"` // 导入时默认为true
+	CreatedAt time.Time `json:"created_at,omitzero"`  // 只在导出时使用
+}
+
+// 导入导出的书籍
+type bookRecord struct {
+	BookID          string    `json:"book_id"`
+	Title           string    `json:"title"`
+	Author          string    `json:"author"`
+	Description     string    `json:"description"`
+	TotalCopies     int       `json:"total_copies"`
+	AvailableCopies *int      `json:"available_copies,omitempty"` // 导入时默认等于总馆藏
+	CanBorrow       *bool     `json:"can_borrow,omitempty"`       // 导入时默认为true
+	CreatedAt       time.Time `json:"created_at,omitzero"`        // 只在导出时使用
+}
+
+var (
+	studentColumns = []string{"stu_id", "name", "password", "trust", "can_borrow", "created_at"}
+	bookColumns    = []string{"book_id", "title", "author", "description", "total_copies", "available_copies", "can_borrow", "created_at"}
+)
+
+func importStudents(ctx context.Context, c *cli, args []string) error {
+	records, err := readImport(c, "import students", args, func(row map[string]string) (studentRecord, error) {
+		record := studentRecord{StuID: row["stu_id"], Name: row["name"], Password: row["password"]}
+		var err error
+		if record.Trust, err = optionalFloat(row, "trust"); err != nil {
+			return record, err
+		}
+		record.CanBorrow, err = optionalBool(row, "can_borrow")
+		return record, err
+	})
+	if err != nil {
+		return err
+	}
+
+	students := make([]do.Student, len(records))
+	for i, record := range records {
+		students[i] = do.Student{StuId: record.StuID, Name: record.Name, Password: record.Password, Trust: 1, CanBorrow: true}
+		if record.Trust != nil {
+			students[i].Trust = *record.Trust
+		}
+		if record.CanBorrow != nil {
+			students[i].CanBorrow = *record.CanBorrow
+		}
+	}
+	imported, err := service.NewAdminService(dao.NewStore(c.db)).ImportStudents(ctx, students)
+	if err != nil {
+		return err
+	}
+	return printImported(c, imported, "名学生")
+}
+
+func importBooks(ctx context.Context, c *cli, args []string) error {
+	records, err := readImport(c, "import books", args, func(row map[string]string) (bookRecord, error) {
+		record := bookRecord{BookID: row["book_id"], Title: row["title"], Author: row["author"], Description: row["description"]}
+		var err error
+		if record.TotalCopies, err = strconv.Atoi(strings.TrimSpace(row["total_copies"])); err != nil {
+			return record, fmt.Errorf("total_copies不是整数: %q", row["total_copies"])
+		}
+		if record.AvailableCopies, err = optionalInt(row, "available_copies"); err != nil {
+			return record, err
+		}
+		record.CanBorrow, err = optionalBool(row, "can_borrow")
+		return record, err
+	})
+	if err != nil {
+		return err
+	}
+
+	books := make([]do.Book, len(records))
+	for i, record := range records {
+		books[i] = do.Book{
+			BookID:          record.BookID,
+			Title:           record.Title,
+			Author:          record.Author,
+			Description:     record.Description,
+			TotalCopies:     record.TotalCopies,
+			AvailableCopies: record.TotalCopies,
+			CanBorrow:       true,
+		}
+		if record.AvailableCopies != nil {
+			books[i].AvailableCopies = *record.AvailableCopies
+		}
+		if record.CanBorrow != nil {
+			books[i].CanBorrow = *record.CanBorrow
+		}
+	}
+	imported, err := service.NewAdminService(dao.NewStore(c.db)).ImportBooks(ctx, books)
+	if err != nil {
+		return err
+	}
+	return printImported(c, imported, "本书籍")
+}
+
+func printImported(c *cli, imported int, unit string) error {
+	type importResult struct {
+		Imported int `json:"imported"`
+	}
+	return c.print(importResult{Imported: imported}, func(w io.Writer) {
+		fmt.Fprintf(w, "已导入 %d %s\n", imported, unit)
+	})
+}
+
+// 读取导入文件；未指定-format时按扩展名判断，.json为JSON，其余为CSV。
+// CSV的每一行由fromRow转换，出错时注明行号
+func readImport[T any](c *cli, name string, args []string, fromRow func(map[string]string) (T, error)) ([]T, error) {
+	fs := c.flags(name)
+	format := fs.String("format", "", "文件格式 csv|json，默认按扩展名判断")
+	positional, err := parse(fs, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	path := positional[0]
+	if *format == "" {
+		*format = formatCSV
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			*format = formatJSON
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch *format {
+	case formatJSON:
+		var records []T
+		if err := json.NewDecoder(file).Decode(&records); err != nil {
+			return nil, fmt.Errorf("解析%s失败: %w", path, err)
+		}
+		return records, nil
+
+	case formatCSV:
+		reader := csv.NewReader(file)
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("读取%s的列名失败: %w", path, err)
+		}
+		for i := range header {
+			header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+		}
+		var records []T
+		for line := 2; ; line++ {
+			fields, err := reader.Read()
+			if err == io.EOF {
+				return records, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("解析%s失败: %w", path, err)
+			}
+			row := make(map[string]string, len(header))
+			for i, column := range header {
+				row[column] = fields[i]
+			}
+			record, err := fromRow(row)
+			if err != nil {
+				return nil, fmt.Errorf("%s第%d行: %w", path, line, err)
+			}
+			records = append(records, record)
+		}
+
+	default:
+		return nil, usageError("不支持的文件格式: %s", *format)
+	}
+}
+
+func optionalInt(row map[string]string, column string) (*int, error) {
+	value := strings.TrimSpace(row[column])
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s不是整数: %q", column, value)
+	}
+	return &n, nil
+}
+
+func optionalFloat(row map[string]string, column string) (*float64, error) {
+	value := strings.TrimSpace(row[column])
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s不是数字: %q", column, value)
+	}
+	return &f, nil
+}
+
+func optionalBool(row map[string]string, column string) (*bool, error) {
+	value := strings.TrimSpace(row[column])
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s不是true/false: %q", column, value)
+	}
+	return &b, nil
+}
+
+func exportStudents(ctx context.Context, c *cli, args []string) error {
+	return writeExport(c, "export students", args, studentColumns,
+		func() ([]studentRecord, error) {
+			students, err := service.NewAdminService(dao.NewStore(c.db)).ExportStudents(ctx)
+			if err != nil {
+				return nil, err
+			}
+			records := make([]studentRecord, len(students))
+			for i, student := range students {
+				records[i] = studentRecord{
+					StuID:     student.StuId,
+					Name:      student.Name,
+					Trust:     &student.Trust,
+					CanBorrow: &student.CanBorrow,
+					CreatedAt: student.CreatedAt,
+				}
+			}
+			return records, nil
+		},
+		func(record studentRecord) []string {
+			return []string{
+				record.StuID,
+				record.Name,
+				"",
+				strconv.FormatFloat(*record.Trust, 'f', -1, 64),
+				strconv.FormatBool(*record.CanBorrow),
+				record.CreatedAt.Format(time.RFC3339),
+			}
+		})
+}
+
+func exportBooks(ctx context.Context, c *cli, args []string) error {
+	return writeExport(c, "export books", args, bookColumns,
+		func() ([]bookRecord, error) {
+			books, err := service.NewAdminService(dao.NewStore(c.db)).ExportBooks(ctx)
+			if err != nil {
+				return nil, err
+			}
+			records := make([]bookRecord, len(books))
+			for i, book := range books {
+				records[i] = bookRecord{
+					BookID:          book.BookID,
+					Title:           book.Title,
+					Author:          book.Author,
+					Description:     book.Description,
+					TotalCopies:     book.TotalCopies,
+					AvailableCopies: &book.AvailableCopies,
+					CanBorrow:       &book.CanBorrow,
+					CreatedAt:       book.CreatedAt,
+				}
+			}
+			return records, nil
+		},
+		func(record bookRecord) []string {
+			return []string{
+				record.BookID,
+				record.Title,
+				record.Author,
+				record.Description,
+				strconv.Itoa(record.TotalCopies),
+				strconv.Itoa(*record.AvailableCopies),
+				strconv.FormatBool(*record.CanBorrow),
+				record.CreatedAt.Format(time.RFC3339),
+			}
+		})
+}
+
+// 导出到-o指定的文件或标准输出，格式与导入相同，导出的文件可以直接导入另一个库（学生需补上密码）；
+// 导出的内容本身就是数据，-json参数不改变格式
+func writeExport[T any](c *cli, name string, args []string, columns []string, load func() ([]T, error), toRow func(T) []string) error {
+	fs := c.flags(name)
+	format := fs.String("format", formatCSV, "文件格式 csv|json")
+	output := fs.String("o", "", "输出文件，默认为标准输出")
+	if _, err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *format != formatCSV && *format != formatJSON {
+		return usageError("不支持的文件格式: %s", *format)
+	}
+
+	records, err := load()
+	if err != nil {
+		return err
+	}
+
+	w := c.stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if *format == formatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if records == nil {
+			records = []T{}
+		}
+		return encoder.Encode(records)
+	}
+	writer := csv.NewWriter(w)
+	writer.Write(columns)
+	for _, record := range records {
+		writer.Write(toRow(record))
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,415 @@
+// Package config 负责加载和校验服务配置
+// 配置来源的优先级从低到高为：默认值、配置文件（YAML或TOML）、环境变量、命令行参数
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Config 服务的全部配置项
+// 每个配置项的键由yaml标签按层级拼接而成（如 server.tls.cert_file），
+// 对应环境变量 LIBRARY_SERVER_TLS_CERT_FILE 和命令行参数 -server.tls.cert_file；
+// env标签列出兼容的旧环境变量名，secret标签标记的配置项不会出现在日志中
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	GRPC      GRPCConfig      `yaml:"grpc" toml:"grpc"`
+	GraphQL   GraphQLConfig   `yaml:"graphql" toml:"graphql"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	CORS      CORSConfig      `yaml:"cors" toml:"cors"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	Loan      LoanConfig      `yaml:"loan" toml:"loan"`
+	Scheduler SchedulerConfig `yaml:"scheduler" toml:"scheduler"`
+	Storage   StorageConfig   `yaml:"storage" toml:"storage"`
+	SMTP      SMTPConfig      `yaml:"smtp" toml:"smtp"`
+	Events    EventsConfig    `yaml:"events" toml:"events"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	Metrics   MetricsConfig   `yaml:"metrics" toml:"metrics"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+}
+
+// ServerConfig HTTP监听配置，同时设置证书和私钥时启用HTTPS
+// RequestTimeout为请求的默认处理时限，上传封面使用UploadTimeout，实时推送的长连接不设时限
+// 收到退出信号后就绪检查立即返回不可用，等待ShutdownDelay后停止接受新连接，
+// 处理中的请求和后台任务最多再等待ShutdownTimeout
+type ServerConfig struct {
+	Listen          string    `yaml:"listen" toml:"listen"`
+	TLS             TLSConfig `yaml:"tls" toml:"tls"`
+	RequestTimeout  Duration  `yaml:"request_timeout" toml:"request_timeout"`
+	UploadTimeout   Duration  `yaml:"upload_timeout" toml:"upload_timeout"`
+	ShutdownDelay   Duration  `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	ShutdownTimeout Duration  `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string `yaml:"key_file" toml:"key_file"`
+}
+
+// Enabled 是否启用HTTPS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// GRPCConfig gRPC监听配置，Listen为空时不启动gRPC服务；设置了server.tls时使用同一证书
+// 处理时限和退出时限与HTTP服务相同
+type GRPCConfig struct {
+	Listen string `yaml:"listen" toml:"listen"`
+}
+
+// GraphQLConfig GraphQL查询的限制，超过限制的查询在执行前被拒绝
+// MaxDepth为字段的最大嵌套层数，MaxComplexity为估算的最大查询代价（每个字段计1，列表中的字段按10倍计）
+type GraphQLConfig struct {
+	MaxDepth      int `yaml:"max_depth" toml:"max_depth"`
+	MaxComplexity int `yaml:"max_complexity" toml:"max_complexity"`
+}
+
+// DatabaseConfig 数据库连接、连接池和迁移配置，连接池参数为0时使用database/sql的默认值
+// Driver为mysql或postgres时使用Host等连接参数，为sqlite时使用Path指定的数据库文件
+type DatabaseConfig struct {
+	Driver          string   `yaml:"driver" toml:"driver"`
+	Path            string   `yaml:"path" toml:"path"`
+	SSLMode         string   `yaml:"sslmode" toml:"sslmode"`
+	Host            string   `yaml:"host" toml:"host"`
+	Port            int      `yaml:"port" toml:"port"`
+	User            string   `yaml:"user" toml:"user"`
+	Password        string   `yaml:"password" toml:"password" secret:"true"`
+	Name            string   `yaml:"name" toml:"name"`
+	MaxOpenConns    int      `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int      `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+	// 启动时自动执行尚未执行的迁移，关闭时只提示
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate"`
+}
+
+// DSN 生成数据库连接串，含密码，不要写入日志
+func (c DatabaseConfig) DSN() string {
+	if c.Driver == "sqlite" {
+		// 开启外键约束和WAL；写事务开始时即加锁，避免并发事务在提交时才发现冲突；
+		// 时间按SQLite的格式（2006-01-02 15:04:05带时区）写入，与SQL中的日期函数和测试数据一致
+		params := url.Values{}
+		params.Add("_pragma", "foreign_keys(1)")
+		params.Add("_pragma", "journal_mode(WAL)")
+		params.Add("_pragma", "busy_timeout(5000)")
+		params.Set("_txlock", "immediate")
+		params.Set("_time_format", "sqlite")
+		return "file:" + c.Path + "?" + params.Encode()
+	}
+	if c.Driver == "postgres" {
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(c.User, c.Password),
+			Host:     net.JoinHostPort(c.Host, fmt.Sprint(c.Port)),
+			Path:     "/" + c.Name,
+			RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+		}
+		return dsn.String()
+	}
+
+	dsn := mysql.NewConfig()
+	dsn.User = c.User
+	dsn.Passwd = c.Password
+	dsn.Net = "tcp"
+	dsn.Addr = net.JoinHostPort(c.Host, fmt.Sprint(c.Port))
+	dsn.DBName = c.Name
+	dsn.ParseTime = true
+	dsn.Loc = time.Local
+	dsn.Params = map[string]string{"charset": "utf8mb4"}
+	return dsn.FormatDSN()
+}
+
+// CORSConfig 跨域配置，AllowOrigins为 * 时允许任意来源，此时不能携带凭据
+type CORSConfig struct {
+	AllowOrigins     []string `yaml:"allow_origins" toml:"allow_origins"`
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials"`
+}
+
+// AuthConfig 访问令牌配置，Secret为空时每次启动随机生成（重启后已签发的令牌失效）
+type AuthConfig struct {
+	Secret   string   `yaml:"secret" toml:"secret" env:"AUTH_SECRET" secret:"true"`
+	TokenTTL Duration `yaml:"token_ttl" toml:"token_ttl"`
+}
+
+// LoanConfig 借阅规则
+type LoanConfig struct {
+	PeriodMonths int     `yaml:"period_months" toml:"period_months"`
+	FinePerDay   float64 `yaml:"fine_per_day" toml:"fine_per_day"`
+	DueSoonDays  int     `yaml:"due_soon_days" toml:"due_soon_days"`
+}
+
+// SchedulerConfig 后台定时任务的执行间隔
+type SchedulerConfig struct {
+	NotifyInterval  Duration `yaml:"notify_interval" toml:"notify_interval" env:"NOTIFY_INTERVAL"`
+	WebhookInterval Duration `yaml:"webhook_interval" toml:"webhook_interval" env:"WEBHOOK_INTERVAL"`
+}
+
+// StorageConfig 封面存储，Type为local时存放在本地目录，为s3时使用S3兼容存储（如MinIO）
+type StorageConfig struct {
+	Type string   `yaml:"type" toml:"type" env:"COVER_STORAGE"`
+	Dir  string   `yaml:"dir" toml:"dir" env:"COVER_DIR"`
+	S3   S3Config `yaml:"s3" toml:"s3"`
+}
+
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint" toml:"endpoint" env:"S3_ENDPOINT"`
+	Region    string `yaml:"region" toml:"region" env:"S3_REGION"`
+	Bucket    string `yaml:"bucket" toml:"bucket" env:"S3_BUCKET"`
+	AccessKey string `yaml:"access_key" toml:"access_key" env:"S3_ACCESS_KEY" secret:"true"`
+	SecretKey string `yaml:"secret_key" toml:"secret_key" env:"S3_SECRET_KEY" secret:"true"`
+}
+
+// SMTPConfig 邮件通知的SMTP服务器，Host为空时不发送邮件通知
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" toml:"port" env:"SMTP_PORT"`
+	Username string `yaml:"username" toml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" toml:"password" env:"SMTP_PASSWORD" secret:"true"`
+	From     string `yaml:"from" toml:"from" env:"SMTP_FROM"`
+}
+
+// EventsConfig 实时事件转发，Broker为memory时只在本实例内转发，为db时经数据库在实例间转发
+type EventsConfig struct {
+	Broker       string   `yaml:"broker" toml:"broker" env:"EVENT_BROKER"`
+	PollInterval Duration `yaml:"poll_interval" toml:"poll_interval" env:"EVENT_POLL_INTERVAL"`
+}
+
+// LogConfig 日志配置，Format为json时每行输出一个JSON对象，为text时输出 key=value 形式
+// 执行时间超过SlowQuery的SQL记录为警告，为0时不记录
+type LogConfig struct {
+	Level     string   `yaml:"level" toml:"level"`
+	Format    string   `yaml:"format" toml:"format"`
+	SlowQuery Duration `yaml:"slow_query" toml:"slow_query"`
+}
+
+// MetricsConfig Prometheus指标，Enabled时在 /metrics 输出；设置Token后抓取时需携带 Authorization: Bearer <token>
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled"`
+	Token   string `yaml:"token" toml:"token" secret:"true"`
+}
+
+// TracingConfig OpenTelemetry链路追踪，Exporter为none时不导出，为stdout时输出到标准输出（本地调试），
+// 为otlp时经OTLP/HTTP发送到Endpoint（host:port，为空时使用OTEL_EXPORTER_OTLP_ENDPOINT或localhost:4318）
+// SampleRatio为新请求的采样比例，请求已携带traceparent时沿用上游的采样决定
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter"`
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	Insecure    bool    `yaml:"insecure" toml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+}
+
+// Default 默认配置，与本地开发环境一致
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Listen:          ":8085",
+			RequestTimeout:  Duration(10 * time.Second),
+			UploadTimeout:   Duration(time.Minute),
+			ShutdownTimeout: Duration(20 * time.Second),
+		},
+		GRPC: GRPCConfig{
+			Listen: ":8086",
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:      8,
+			MaxComplexity: 1000,
+		},
+		Database: DatabaseConfig{
+			Driver:          "mysql",
+			Path:            "library.db",
+			SSLMode:         "disable",
+			Host:            "localhost",
+			Port:            13306,
+			User:            "root",
+			Password:        "12345678",
+			Name:            "bookTest",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: Duration(30 * time.Minute),
+			ConnMaxIdleTime: Duration(5 * time.Minute),
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{"*"},
+		},
+		Auth: AuthConfig{
+			TokenTTL: Duration(24 * time.Hour),
+		},
+		Loan: LoanConfig{
+			PeriodMonths: 2,
+			FinePerDay:   0.5,
+			DueSoonDays:  3,
+		},
+		Scheduler: SchedulerConfig{
+			NotifyInterval:  Duration(10 * time.Minute),
+			WebhookInterval: Duration(30 * time.Second),
+		},
+		Storage: StorageConfig{
+			Type: "local",
+			Dir:  "uploads",
+		},
+		SMTP: SMTPConfig{
+			Port: 25,
+		},
+		Events: EventsConfig{
+			Broker:       "memory",
+			PollInterval: Duration(time.Second),
+		},
+		Log: LogConfig{
+			Level:     "info",
+			Format:    "json",
+			SlowQuery: Duration(500 * time.Millisecond),
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+			ServiceName: "library-backend",
+		},
+	}
+}
+
+// Validate 校验配置，一次返回所有不合法的配置项
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Listen); err != nil {
+		errs = append(errs, fmt.Errorf("server.listen格式错误: %v", err))
+	}
+	if c.Server.TLS.Enabled() {
+		check(c.Server.TLS.CertFile != "" && c.Server.TLS.KeyFile != "", "server.tls.cert_file和server.tls.key_file需要同时设置")
+		for key, path := range map[string]string{"server.tls.cert_file": c.Server.TLS.CertFile, "server.tls.key_file": c.Server.TLS.KeyFile} {
+			if path == "" {
+				continue
+			}
+			if _, err := os.Stat(path); err != nil {
+				errs = append(errs, fmt.Errorf("%s无法读取: %v", key, err))
+			}
+		}
+	}
+	check(c.Server.RequestTimeout > 0, "server.request_timeout必须大于0")
+	check(c.Server.UploadTimeout > 0, "server.upload_timeout必须大于0")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay不能为负数")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout必须大于0")
+	if c.GRPC.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.GRPC.Listen); err != nil {
+			errs = append(errs, fmt.Errorf("grpc.listen格式错误: %v", err))
+		}
+		check(c.GRPC.Listen != c.Server.Listen, "grpc.listen不能与server.listen相同")
+	}
+	check(c.GraphQL.MaxDepth > 0, "graphql.max_depth必须大于0")
+	check(c.GraphQL.MaxComplexity > 0, "graphql.max_complexity必须大于0")
+
+	db := c.Database
+	switch db.Driver {
+	case "mysql", "postgres":
+		check(db.Host != "", "database.host不能为空")
+		check(db.Port > 0 && db.Port <= 65535, "database.port必须在1到65535之间")
+		check(db.User != "", "database.user不能为空")
+		check(db.Name != "", "database.name不能为空")
+		if db.Driver == "postgres" {
+			check(db.SSLMode == "disable" || db.SSLMode == "require" || db.SSLMode == "verify-ca" || db.SSLMode == "verify-full",
+				"database.sslmode只能是disable、require、verify-ca或verify-full")
+		}
+	case "sqlite":
+		check(db.Path != "", "database.path不能为空")
+	default:
+		errs = append(errs, fmt.Errorf("database.driver只能是mysql、postgres或sqlite: %s", db.Driver))
+	}
+	check(db.MaxOpenConns >= 0, "database.max_open_conns不能为负数")
+	check(db.MaxIdleConns >= 0, "database.max_idle_conns不能为负数")
+	check(db.MaxOpenConns == 0 || db.MaxIdleConns <= db.MaxOpenConns, "database.max_idle_conns不能大于database.max_open_conns")
+	check(db.ConnMaxLifetime >= 0, "database.conn_max_lifetime不能为负数")
+	check(db.ConnMaxIdleTime >= 0, "database.conn_max_idle_time不能为负数")
+
+	check(len(c.CORS.AllowOrigins) > 0, "cors.allow_origins不能为空")
+	for _, origin := range c.CORS.AllowOrigins {
+		if origin == "*" {
+			check(len(c.CORS.AllowOrigins) == 1, "cors.allow_origins包含 * 时不能再列出其他来源")
+			check(!c.CORS.AllowCredentials, "cors.allow_origins为 * 时不能开启cors.allow_credentials")
+			continue
+		}
+		u, err := url.Parse(origin)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && (u.Path == "" || u.Path == "/"),
+			"cors.allow_origins中的来源格式错误: %s（应为 http(s)://主机[:端口]）", origin)
+	}
+
+	check(c.Auth.Secret == "" || len(c.Auth.Secret) >= 16, "auth.secret长度至少为16")
+	check(c.Auth.TokenTTL > 0, "auth.token_ttl必须大于0")
+
+	check(c.Loan.PeriodMonths >= 1 && c.Loan.PeriodMonths <= 24, "loan.period_months必须在1到24之间")
+	check(c.Loan.FinePerDay >= 0, "loan.fine_per_day不能为负数")
+	check(c.Loan.DueSoonDays >= 1, "loan.due_soon_days必须大于0")
+
+	check(c.Scheduler.NotifyInterval > 0, "scheduler.notify_interval必须大于0")
+	check(c.Scheduler.WebhookInterval > 0, "scheduler.webhook_interval必须大于0")
+
+	switch c.Storage.Type {
+	case "local":
+		check(c.Storage.Dir != "", "storage.dir不能为空")
+	case "s3":
+		check(c.Storage.S3.Endpoint != "", "storage.s3.endpoint不能为空")
+		check(c.Storage.S3.Bucket != "", "storage.s3.bucket不能为空")
+	default:
+		errs = append(errs, fmt.Errorf("storage.type只能是local或s3: %s", c.Storage.Type))
+	}
+
+	if c.SMTP.Host != "" {
+		check(c.SMTP.Port > 0 && c.SMTP.Port <= 65535, "smtp.port必须在1到65535之间")
+		check(c.SMTP.From != "", "设置smtp.host时smtp.from不能为空")
+	}
+
+	check(c.Events.Broker == "memory" || c.Events.Broker == "db", "events.broker只能是memory或db: %s", c.Events.Broker)
+	check(c.Events.PollInterval > 0, "events.poll_interval必须大于0")
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level只能是debug、info、warn或error: %s", c.Log.Level))
+	}
+	check(c.Log.Format == "json" || c.Log.Format == "text", "log.format只能是json或text: %s", c.Log.Format)
+	check(c.Log.SlowQuery >= 0, "log.slow_query不能为负数")
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter只能是none、stdout或otlp: %s", c.Tracing.Exporter))
+	}
+	if c.Tracing.Endpoint != "" {
+		_, _, err := net.SplitHostPort(c.Tracing.Endpoint)
+		check(err == nil, "tracing.endpoint格式错误（应为 主机:端口）: %s", c.Tracing.Endpoint)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio必须在0到1之间")
+	check(c.Tracing.ServiceName != "", "tracing.service_name不能为空")
+
+	return errors.Join(errs...)
+}
+
+// Redacted 以 键=值 的形式列出全部配置项，敏感配置项已设置时显示为******，可直接写入日志
+func (c *Config) Redacted() string {
+	var pairs []string
+	for _, f := range c.fields() {
+		value := f.String()
+		if f.secret && value != "" {
+			value = "******"
+		}
+		pairs = append(pairs, f.key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// 环境变量前缀
+const envPrefix = "LIBRARY_"
+
+// Duration 支持 30s、10m 这类写法的时间间隔
+type Duration time.Duration
+
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	value, err := time.ParseDuration(strings.TrimSpace(string(text)))
+	if err != nil {
+		return err
+	}
+	*d = Duration(value)
+	return nil
+}
+
+// Load 按 默认值 < 配置文件 < 环境变量 < 命令行参数 的优先级加载配置并校验
+// 配置文件由 -config 参数或 LIBRARY_CONFIG 环境变量指定，按扩展名识别YAML或TOML；
+// 返回命令行中参数之后剩余的部分（如子命令）
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+	fields := cfg.fields()
+
+	flags := flag.NewFlagSet("library", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv(envPrefix+"CONFIG"), "配置文件路径（.yaml/.yml/.toml）")
+	var flagValues []func() error
+	for _, f := range fields {
+		f := f
+		flags.Func(f.key, "覆盖配置项 "+f.key+"，环境变量 "+strings.Join(f.envs, "/"), func(value string) error {
+			flagValues = append(flagValues, func() error { return f.set(value) })
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, f := range fields {
+		for _, name := range f.envs {
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				continue
+			}
+			if err := f.set(value); err != nil {
+				return nil, nil, fmt.Errorf("环境变量%s格式错误: %v", name, err)
+			}
+			break
+		}
+	}
+
+	for _, apply := range flagValues {
+		if err := apply(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("配置校验失败:\n%v", err)
+	}
+	return cfg, flags.Args(), nil
+}
+
+// 读取配置文件，文件中出现未知的配置项时报错，避免拼写错误被静默忽略
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("解析配置文件%s失败: %v", path, err)
+		}
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(c); err != nil {
+			// go-toml的错误信息不含键名，逐个列出未知的配置项
+			var strict *toml.StrictMissingError
+			if errors.As(err, &strict) {
+				var unknown []string
+				for _, e := range strict.Errors {
+					row, _ := e.Position()
+					unknown = append(unknown, fmt.Sprintf("%s（第%d行）", strings.Join(e.Key(), "."), row))
+				}
+				return fmt.Errorf("解析配置文件%s失败: 未知的配置项 %s", path, strings.Join(unknown, "、"))
+			}
+			return fmt.Errorf("解析配置文件%s失败: %v", path, err)
+		}
+	default:
+		return fmt.Errorf("不支持的配置文件格式: %s", ext)
+	}
+	return nil
+}
+
+// field 一个可单独覆盖的配置项
+type field struct {
+	key    string
+	envs   []string
+	secret bool
+	value  reflect.Value
+}
+
+// 按yaml标签展开全部配置项
+func (c *Config) fields() []field {
+	var fields []field
+	collectFields(reflect.ValueOf(c).Elem(), "", &fields)
+	return fields
+}
+
+func collectFields(v reflect.Value, prefix string, fields *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		key := prefix + name
+		if sf.Type.Kind() == reflect.Struct {
+			collectFields(v.Field(i), key+".", fields)
+			continue
+		}
+
+		envs := []string{envPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))}
+		if legacy := sf.Tag.Get("env"); legacy != "" {
+			envs = append(envs, legacy)
+		}
+		*fields = append(*fields, field{
+			key:    key,
+			envs:   envs,
+			secret: sf.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+}
+
+// 从字符串设置配置项，列表以逗号分隔
+func (f field) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch ptr := f.value.Addr().Interface().(type) {
+	case *string:
+		*ptr = raw
+	case *int:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%s需要整数: %s", f.key, raw)
+		}
+		*ptr = value
+	case *float64:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%s需要数字: %s", f.key, raw)
+		}
+		*ptr = value
+	case *bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s需要true或false: %s", f.key, raw)
+		}
+		*ptr = value
+	case *Duration:
+		if err := ptr.UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("%s需要时间间隔（如 30s、10m）: %s", f.key, raw)
+		}
+	case *[]string:
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		*ptr = values
+	default:
+		return fmt.Errorf("不支持的配置项类型: %s", f.key)
+	}
+	return nil
+}
+
+func (f field) String() string {
+	if values, ok := f.value.Interface().([]string); ok {
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprint(f.value.Interface())
+}
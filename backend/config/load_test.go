@@ -0,0 +1,152 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	t.Setenv("LIBRARY_CONFIG", "")
+	path := writeConfigFile(t, "config.yaml", `
+server:
+  listen: ":9000"
+database:
+  name: libfile
+loan:
+  fine_per_day: 1
+scheduler:
+  notify_interval: 1m
+smtp:
+  host: smtp.example.com
+  from: library@example.com
+  port: 25
+`)
+	// 环境变量覆盖配置文件，命令行参数覆盖环境变量；新旧环境变量名同时存在时以新的为准
+	t.Setenv("LIBRARY_DATABASE_NAME", "libenv")
+	t.Setenv("LIBRARY_LOAN_FINE_PER_DAY", "2")
+	t.Setenv("NOTIFY_INTERVAL", "5m")
+	t.Setenv("LIBRARY_SMTP_PORT", "2525")
+	t.Setenv("SMTP_PORT", "26")
+
+	cfg, args, err := Load([]string{"-config", path, "-loan.fine_per_day", "3", "migrate", "up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		key       string
+		got, want interface{}
+	}{
+		{"server.listen", cfg.Server.Listen, ":9000"},
+		{"database.name", cfg.Database.Name, "libenv"},
+		{"loan.fine_per_day", cfg.Loan.FinePerDay, 3.0},
+		{"scheduler.notify_interval", cfg.Scheduler.NotifyInterval.Std(), 5 * time.Minute},
+		{"smtp.port", cfg.SMTP.Port, 2525},
+		{"smtp.host", cfg.SMTP.Host, "smtp.example.com"},
+		// 未设置的配置项保留默认值
+		{"loan.period_months", cfg.Loan.PeriodMonths, 2},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %v，期望 %v", tc.key, tc.got, tc.want)
+		}
+	}
+	if !slices.Equal(args, []string{"migrate", "up"}) {
+		t.Errorf("剩余参数 = %v", args)
+	}
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", "[storage]\ndir = \"/var/lib/library/covers\"\n")
+	t.Setenv("LIBRARY_CONFIG", path)
+	cfg, _, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Storage.Dir != "/var/lib/library/covers" {
+		t.Errorf("storage.dir = %s，期望 /var/lib/library/covers", cfg.Storage.Dir)
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	t.Setenv("LIBRARY_CONFIG", "")
+	for name, content := range map[string]string{
+		"config.yaml": "server:\n  listn: \":9000\"\n",
+		"config.toml": "[server]\nlistn = \":9000\"\n",
+	} {
+		path := writeConfigFile(t, name, content)
+		if _, _, err := Load([]string{"-config", path}); err == nil || !strings.Contains(err.Error(), "listn") {
+			t.Errorf("%s 中有未知的配置项 = %v，期望报错并指出 listn", name, err)
+		}
+	}
+}
+
+func TestLoadInvalidValue(t *testing.T) {
+	t.Setenv("LIBRARY_CONFIG", "")
+	t.Setenv("LIBRARY_DATABASE_PORT", "abc")
+	if _, _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "LIBRARY_DATABASE_PORT") {
+		t.Errorf("环境变量格式错误 = %v，期望指出变量名", err)
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("默认配置校验失败: %v", err)
+	}
+
+	cfg := Default()
+	cfg.Server.Listen = "bad"
+	cfg.Database.Host = ""
+	cfg.Auth.Secret = "short"
+	cfg.Storage.Type = "ftp"
+	cfg.Events.Broker = "kafka"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("不合法的配置通过了校验")
+	}
+	for _, key := range []string{"server.listen", "database.host", "auth.secret", "storage.type", "events.broker"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("校验错误中缺少 %s: %v", key, err)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "db-password"
+	cfg.Auth.Secret = "auth-secret-0123456789"
+	cfg.SMTP.Password = "smtp-password"
+	cfg.Storage.S3.AccessKey = "s3-access-key"
+	cfg.Storage.S3.SecretKey = "s3-secret-key"
+
+	redacted := cfg.Redacted()
+	pairs := strings.Fields(redacted)
+	for _, key := range []string{"database.password", "auth.secret", "smtp.password", "storage.s3.access_key", "storage.s3.secret_key"} {
+		if !slices.Contains(pairs, key+"=******") {
+			t.Errorf("Redacted() 中 %s 未隐藏", key)
+		}
+	}
+	for _, secret := range []string{"db-password", "auth-secret", "smtp-password", "s3-access-key", "s3-secret-key"} {
+		if strings.Contains(redacted, secret) {
+			t.Errorf("Redacted() 中出现了 %s", secret)
+		}
+	}
+	// 普通配置项原样输出，未设置的敏感配置项显示为空
+	if !slices.Contains(pairs, "server.listen=:8085") {
+		t.Errorf("Redacted() 中缺少 server.listen: %s", redacted)
+	}
+	if !slices.Contains(strings.Fields(Default().Redacted()), "smtp.password=") {
+		t.Errorf("未设置的 smtp.password 应显示为空")
+	}
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditController 审计日志查询，仅管理员可用
+type AuditController struct {
+	auditService *service.AuditService
+}
+
+func NewAuditController(auditService *service.AuditService) *AuditController {
+	return &AuditController{auditService: auditService}
+}
+
+// 查询审计日志，从新到旧排列；按action、entity_type、entity_id、actor_id、时间范围（since、until）筛选，
+// 翻页时把上一页最后一条的id作为before_id
+func (c *AuditController) List(ctx *gin.Context) {
+	filter := do.AuditFilter{
+		Action:     ctx.Query("action"),
+		EntityType: ctx.Query("entity_type"),
+		EntityID:   ctx.Query("entity_id"),
+		ActorID:    ctx.Query("actor_id"),
+	}
+
+	var err error
+	if filter.Limit, err = strconv.Atoi(ctx.DefaultQuery("limit", "100")); err != nil || filter.Limit <= 0 {
+		ctx.Error(apperr.Invalid("invalid.limit"))
+		return
+	}
+	if filter.BeforeID, err = strconv.ParseInt(ctx.DefaultQuery("before_id", "0"), 10, 64); err != nil {
+		ctx.Error(apperr.Invalid("invalid.before_id"))
+		return
+	}
+	if filter.Since, err = auditTime(ctx, "since", false); err != nil {
+		ctx.Error(err)
+		return
+	}
+	if filter.Until, err = auditTime(ctx, "until", true); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	entries, err := c.auditService.ListEntries(ctx.Request.Context(), filter)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", entries)
+}
+
+// 解析时间查询参数，可以是RFC3339时间或日期；日期作为until时包含当天
+func auditTime(ctx *gin.Context, field string, endOfDay bool) (*time.Time, error) {
+	value := ctx.Query(field)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation(do.DateLayout, value, time.Local)
+	if err != nil {
+		return nil, apperr.Invalid("invalid.audit_time").With("field", field)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
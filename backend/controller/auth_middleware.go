@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上下文中保存当前调用方的键
+const principalKey = "principal"
+
+// 识别请求的操作人并保存在请求的ctx中，供审计日志使用；不拒绝任何请求，权限仍由RequireRoles检查
+// 携带有效令牌时为令牌中的角色和用户，否则为匿名
+func Identify(authService *service.AuthService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		actor := service.Actor{Role: do.ActorAnonymous, IP: ctx.ClientIP()}
+		if token, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer "); ok && token != "" {
+			if principal, err := authService.ParseToken(token); err == nil {
+				actor.Role = principal.Role
+				actor.ID = principal.Subject
+			}
+		}
+		ctx.Request = ctx.Request.WithContext(service.WithActor(ctx.Request.Context(), actor))
+		ctx.Next()
+	}
+}
+
+// 要求请求携带指定角色之一的访问令牌（Authorization: Bearer <token>）
+func RequireRoles(authService *service.AuthService, roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			abortWithError(ctx, apperr.New(apperr.CodeUnauthorized))
+			return
+		}
+
+		principal, err := authService.ParseToken(token)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		for _, role := range roles {
+			if principal.Role == role {
+				ctx.Set(principalKey, principal)
+				ctx.Next()
+				return
+			}
+		}
+		abortWithError(ctx, apperr.New(apperr.CodeForbidden))
+	}
+}
+
+// 要求令牌中的用户与路径参数param一致，用于学生只能访问本人数据的接口，需在RequireRoles之后注册
+func RequireSelf(param string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal := CurrentPrincipal(ctx)
+		if principal == nil || principal.Subject != ctx.Param(param) {
+			abortWithError(ctx, apperr.New(apperr.CodeForbidden))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// 要求调用方能访问路径参数param指定的学生：学生只能访问本人的数据，馆员和管理员可以访问任何学生的，
+// 与gRPC、GraphQL接口的规则相同；需在RequireRoles之后注册
+func RequireStudentAccess(param string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal := CurrentPrincipal(ctx)
+		if principal == nil {
+			abortWithError(ctx, apperr.New(apperr.CodeUnauthorized))
+			return
+		}
+		if principal.Role == service.RoleStudent && principal.Subject != ctx.Param(param) {
+			abortWithError(ctx, apperr.New(apperr.CodeForbidden))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// 确定请求操作的学生：学生令牌时为令牌中的学号，请求中的stu_id可以省略，与令牌不一致时拒绝；
+// 馆员、管理员代学生操作以及旧版未认证的路由使用请求中的stu_id
+func requestStudentID(ctx *gin.Context, stuID string) (string, error) {
+	if principal := CurrentPrincipal(ctx); principal != nil && principal.Role == service.RoleStudent {
+		if stuID != "" && stuID != principal.Subject {
+			return "", apperr.New(apperr.CodeForbidden)
+		}
+		return principal.Subject, nil
+	}
+	if stuID == "" {
+		return "", apperr.Invalid("invalid.stu_id_required")
+	}
+	return stuID, nil
+}
+
+// 获取当前请求的调用方，未经过认证中间件时返回nil
+func CurrentPrincipal(ctx *gin.Context) *service.Principal {
+	if value, ok := ctx.Get(principalKey); ok {
+		return value.(*service.Principal)
+	}
+	return nil
+}
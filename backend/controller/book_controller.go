@@ -1,8 +1,8 @@
 package controller
 
 import (
+	"backend/apperr"
 	"backend/service"
-	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,49 +19,52 @@ func NewBookController(bookService *service.BookService) *BookController {
 func (c *BookController) SearchBooks(ctx *gin.Context) {
 	keyword := ctx.Query("keyword")
 	if keyword == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请输入搜索关键词"})
+		ctx.Error(apperr.Invalid("invalid.keyword_required"))
 		return
 	}
 
-	books, err := c.bookService.SearchBooks(keyword)
+	books, err := c.bookService.SearchBooks(ctx.Request.Context(), keyword)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"data": books,
-	})
+	respondOK(ctx, "", books)
 }
 
 // 获取书籍详情
 func (c *BookController) GetBookDetail(ctx *gin.Context) {
 	bookID := ctx.Param("id")
 	if bookID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "书籍ID不能为空"})
+		ctx.Error(apperr.Invalid("invalid.book_id_required"))
 		return
 	}
 
-	book, err := c.bookService.GetBookDetail(bookID)
+	book, err := c.bookService.GetBookDetail(ctx.Request.Context(), bookID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"data": book,
-	})
+	respondOK(ctx, "", book)
+}
+
+// 获取书籍列表（/api/v1），带keyword参数时按书名或作者查找
+func (c *BookController) ListBooks(ctx *gin.Context) {
+	if ctx.Query("keyword") != "" {
+		c.SearchBooks(ctx)
+		return
+	}
+	c.GetAllBooks(ctx)
 }
 
 // 获取所有书籍列表
 func (c *BookController) GetAllBooks(ctx *gin.Context) {
-	books, err := c.bookService.GetAllBooks()
+	books, err := c.bookService.GetAllBooks(ctx.Request.Context())
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"data": books,
-	})
+	respondOK(ctx, "", books)
 }
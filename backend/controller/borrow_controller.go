@@ -1,8 +1,9 @@
 package controller
 
 import (
+	"backend/apperr"
+	"backend/i18n"
 	"backend/service"
-	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,56 +16,62 @@ func NewBorrowController(borrowService *service.BorrowService) *BorrowController
 	return &BorrowController{borrowService: borrowService}
 }
 
-// 借书
+// 借书：学生令牌时学号取自令牌，馆员代借或旧版路由时为请求中的stu_id
 func (c *BorrowController) BorrowBook(ctx *gin.Context) {
 	var request struct {
-		StuID  string `json:"stu_id" binding:"required"`
-		BookID string `json:"book_id" binding:"required"`
+		StuID    string `json:"stu_id"`
+		BookID   string `json:"book_id" binding:"required"`
+		BranchID string `json:"branch_id"`
 	}
 
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误: " + err.Error()})
+		ctx.Error(invalidRequest(err))
+		return
+	}
+	stuID, err := requestStudentID(ctx, request.StuID)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	err := c.borrowService.BorrowBook(request.StuID, request.BookID)
+	record, err := c.borrowService.BorrowBook(ctx.Request.Context(), stuID, request.BookID, request.BranchID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"message": "借书成功",
-	})
+	respondOKWith(ctx, "ok.borrowed", i18n.Params{"due_date": record.DueDate}, gin.H{"due_date": record.DueDate})
 }
 
 // 还书
 func (c *BorrowController) ReturnBook(ctx *gin.Context) {
 	var request struct {
-		StuID  string `json:"stu_id" binding:"required"`
-		BookID string `json:"book_id" binding:"required"`
+		StuID    string `json:"stu_id"`
+		BookID   string `json:"book_id" binding:"required"`
+		BranchID string `json:"branch_id"`
 	}
 
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误: " + err.Error()})
+		ctx.Error(invalidRequest(err))
 		return
 	}
-
-	fineAmount, err := c.borrowService.ReturnBook(request.StuID, request.BookID)
+	stuID, err := requestStudentID(ctx, request.StuID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	response := gin.H{
-		"message": "还书成功",
+	fineAmount, err := c.borrowService.ReturnBook(ctx.Request.Context(), stuID, request.BookID, request.BranchID)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
+
 	if fineAmount > 0 {
-		response["fine_amount"] = fineAmount
-		response["message"] = "还书成功，产生逾期罚款"
+		respondOKWith(ctx, "ok.returned_with_fine", i18n.Params{"fine": i18n.Money(fineAmount)}, gin.H{"fine_amount": fineAmount})
+		return
 	}
-
-	ctx.JSON(http.StatusOK, response)
+	respondOK(ctx, "ok.returned", nil)
 }
 
 // 支付罚款
@@ -74,19 +81,25 @@ func (c *BorrowController) PayFine(ctx *gin.Context) {
 	}
 
 	if err := ctx.ShouldBindJSON(&request); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误: " + err.Error()})
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
-	err := c.borrowService.PayFine(request.StuID)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.payFine(ctx, request.StuID)
+}
+
+// 支付罚款（/api/v1），学号为路径参数
+func (c *BorrowController) PayStudentFine(ctx *gin.Context) {
+	c.payFine(ctx, ctx.Param("id"))
+}
+
+func (c *BorrowController) payFine(ctx *gin.Context, stuID string) {
+	if err := c.borrowService.PayFine(ctx.Request.Context(), stuID); err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"message": "罚款支付成功，借阅权限已恢复",
-	})
+	respondOK(ctx, "ok.fine_paid", nil)
 }
 
 // 获取借阅记录
@@ -95,19 +108,26 @@ func (c *BorrowController) GetBorrowRecord(ctx *gin.Context) {
 	bookID := ctx.Query("book_id")
 
 	if stuID == "" || bookID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "学号和书籍ID不能为空"})
+		ctx.Error(apperr.Invalid("invalid.stu_id_book_id"))
 		return
 	}
 
-	record, err := c.borrowService.GetBorrowRecord(stuID, bookID)
+	c.getBorrowRecord(ctx, stuID, bookID)
+}
+
+// 获取学生借阅某本书的记录（/api/v1），学号和书籍ID为路径参数
+func (c *BorrowController) GetStudentLoan(ctx *gin.Context) {
+	c.getBorrowRecord(ctx, ctx.Param("id"), ctx.Param("book_id"))
+}
+
+func (c *BorrowController) getBorrowRecord(ctx *gin.Context, stuID, bookID string) {
+	record, err := c.borrowService.GetBorrowRecord(ctx.Request.Context(), stuID, bookID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"data": record,
-	})
+	respondOK(ctx, "", record)
 }
 
 // 获取学生的所有借阅记录
@@ -115,17 +135,24 @@ func (c *BorrowController) GetStudentBorrowRecords(ctx *gin.Context) {
 	stuID := ctx.Query("stu_id")
 
 	if stuID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "学号不能为空"})
+		ctx.Error(apperr.Invalid("invalid.stu_id_required"))
 		return
 	}
 
-	records, err := c.borrowService.GetStudentBorrowRecordsWithBookInfo(stuID)
+	c.getStudentBorrowRecords(ctx, stuID)
+}
+
+// 获取学生的所有借阅记录（/api/v1），学号为路径参数
+func (c *BorrowController) GetStudentLoans(ctx *gin.Context) {
+	c.getStudentBorrowRecords(ctx, ctx.Param("id"))
+}
+
+func (c *BorrowController) getStudentBorrowRecords(ctx *gin.Context, stuID string) {
+	records, err := c.borrowService.GetStudentBorrowRecordsWithBookInfo(ctx.Request.Context(), stuID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"data": records,
-	})
+	respondOK(ctx, "", records)
 }
@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"backend/do"
+	"backend/repository/memory"
+	"backend/service"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 使用内存存储搭建图书、借阅和学生接口
+func newTestRouter(t *testing.T) (*gin.Engine, *memory.Store) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore()
+	store.AddStudent(do.Student{StuId: "S1", Name: "张三", Password: "pass1", Trust: 100, CanBorrow: true})
+	store.AddBook(do.Book{BookID: "B1", Title: "Go语言程序设计", Author: "张明", TotalCopies: 1, AvailableCopies: 1, CanBorrow: true})
+
+	authService := service.NewAuthService([]byte("test-secret"), time.Hour)
+	bookController := NewBookController(service.NewBookService(store))
+	borrowController := NewBorrowController(service.NewBorrowService(store, nil))
+	studentController := NewStudentController(service.NewStudentService(store), authService)
+
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.NoRoute(NotFound)
+	r.Use(Localize(authService, nil))
+	r.GET("/books/search", bookController.SearchBooks)
+	r.GET("/books/:id", bookController.GetBookDetail)
+	r.POST("/borrow/borrow", borrowController.BorrowBook)
+	r.POST("/borrow/return", borrowController.ReturnBook)
+	r.GET("/borrow/records", borrowController.GetStudentBorrowRecords)
+	r.POST("/student/login", studentController.Login)
+	return r, store
+}
+
+func doJSON(t *testing.T, r http.Handler, method, path string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s 响应不是JSON: %s", method, path, w.Body.String())
+	}
+	return w.Code, resp
+}
+
+func TestBorrowAPI(t *testing.T) {
+	r, store := newTestRouter(t)
+	loan := gin.H{"stu_id": "S1", "book_id": "B1"}
+
+	if code, resp := doJSON(t, r, http.MethodPost, "/borrow/borrow", gin.H{"stu_id": "S1"}); code != http.StatusBadRequest || resp["code"] != "INVALID_ARGUMENT" {
+		t.Errorf("缺少参数时 = %d %v，期望 400 INVALID_ARGUMENT", code, resp)
+	}
+
+	if code, resp := doJSON(t, r, http.MethodPost, "/borrow/borrow", loan); code != http.StatusOK || resp["code"] != "OK" {
+		t.Fatalf("借书 = %d: %v", code, resp)
+	}
+	code, resp := doJSON(t, r, http.MethodPost, "/borrow/borrow", loan)
+	if code != http.StatusConflict || resp["code"] != "BOOK_UNAVAILABLE" || resp["message"] != "书籍已全部借出" {
+		t.Errorf("全部借出后借书 = %d %v，期望 409 BOOK_UNAVAILABLE", code, resp)
+	}
+
+	code, resp = doJSON(t, r, http.MethodGet, "/borrow/records?stu_id=S1", nil)
+	if code != http.StatusOK {
+		t.Fatalf("借阅记录状态码 = %d: %v", code, resp)
+	}
+	records, _ := resp["data"].([]interface{})
+	if len(records) != 1 {
+		t.Fatalf("借阅记录 = %v，期望 1 条", resp["data"])
+	}
+	if title := records[0].(map[string]interface{})["book_title"]; title != "Go语言程序设计" {
+		t.Errorf("借阅记录书名 = %v", title)
+	}
+
+	code, resp = doJSON(t, r, http.MethodPost, "/borrow/return", loan)
+	if code != http.StatusOK || resp["data"] != nil {
+		t.Errorf("还书 = %d %v，期望成功且无罚款", code, resp)
+	}
+	if got := len(store.OutboxEvents()); got != 2 {
+		t.Errorf("发件箱事件数 = %d，期望 2", got)
+	}
+}
+
+func TestBookAPI(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	code, resp := doJSON(t, r, http.MethodGet, "/books/B1", nil)
+	if code != http.StatusOK {
+		t.Fatalf("书籍详情状态码 = %d: %v", code, resp)
+	}
+	if book, _ := resp["data"].(map[string]interface{}); book["title"] != "Go语言程序设计" {
+		t.Errorf("书籍详情 = %v", resp["data"])
+	}
+
+	code, resp = doJSON(t, r, http.MethodGet, "/books/NONE", nil)
+	if code != http.StatusNotFound || resp["code"] != "BOOK_NOT_FOUND" || resp["message"] != "书籍不存在" {
+		t.Errorf("不存在的书籍 = %d %v，期望 404 BOOK_NOT_FOUND", code, resp)
+	}
+
+	code, resp = doJSON(t, r, http.MethodGet, "/books/search?keyword="+url.QueryEscape("张明"), nil)
+	if code != http.StatusOK {
+		t.Fatalf("搜索状态码 = %d: %v", code, resp)
+	}
+	if books, _ := resp["data"].([]interface{}); len(books) != 1 {
+		t.Errorf("搜索结果 = %v，期望 1 本", resp["data"])
+	}
+}
+
+func TestStudentLoginAPI(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	// 学号不存在和密码错误返回相同的错误
+	for _, login := range []gin.H{{"stu_id": "S1", "password": "wrong"}, {"stu_id": "NOBODY", "password": "pass1"}} {
+		code, resp := doJSON(t, r, http.MethodPost, "/student/login", login)
+		if code != http.StatusUnauthorized || resp["code"] != "INVALID_CREDENTIALS" {
+			t.Errorf("登录 %v = %d %v，期望 401 INVALID_CREDENTIALS", login, code, resp)
+		}
+	}
+
+	code, resp := doJSON(t, r, http.MethodPost, "/student/login", gin.H{"stu_id": "S1", "password": "pass1"})
+	if code != http.StatusOK {
+		t.Fatalf("登录状态码 = %d: %v", code, resp)
+	}
+	if data, _ := resp["data"].(map[string]interface{}); data["token"] == "" || data["token"] == nil {
+		t.Errorf("登录响应缺少令牌: %v", resp)
+	}
+}
+
+func TestUnknownRoute(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	code, resp := doJSON(t, r, http.MethodGet, "/nothing", nil)
+	if code != http.StatusNotFound || resp["code"] != "NOT_FOUND" {
+		t.Errorf("未知接口 = %d %v，期望 404 NOT_FOUND", code, resp)
+	}
+}
+
+func TestLocalizedMessages(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	for _, tc := range []struct {
+		path           string
+		acceptLanguage string
+		locale         string
+		message        string
+	}{
+		{"/books/NONE", "", "zh-CN", "书籍不存在"},
+		{"/books/NONE", "en-US,en;q=0.9", "en-US", "Book not found"},
+		{"/books/NONE?lang=zh-CN", "en-US", "zh-CN", "书籍不存在"},
+		{"/nothing?lang=en", "", "en-US", "No such endpoint: GET /nothing"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.acceptLanguage != "" {
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var resp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s 响应不是JSON: %s", tc.path, w.Body.String())
+		}
+		if resp["message"] != tc.message || w.Header().Get("Content-Language") != tc.locale {
+			t.Errorf("%s (%s) = %s %v，期望 %s %q", tc.path, tc.acceptLanguage, w.Header().Get("Content-Language"), resp["message"], tc.locale, tc.message)
+		}
+	}
+}
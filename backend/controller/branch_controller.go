@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"backend/do"
+	"backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BranchController struct {
+	branchService *service.BranchService
+}
+
+func NewBranchController(branchService *service.BranchService) *BranchController {
+	return &BranchController{branchService: branchService}
+}
+
+// 获取所有分馆
+func (c *BranchController) GetAllBranches(ctx *gin.Context) {
+	branches, err := c.branchService.GetAllBranches(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", branches)
+}
+
+// 创建分馆（馆员）
+func (c *BranchController) CreateBranch(ctx *gin.Context) {
+	var request struct {
+		BranchID string `json:"branch_id" binding:"required"`
+		Name     string `json:"name" binding:"required"`
+		Address  string `json:"address"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	branch := &do.Branch{BranchID: request.BranchID, Name: request.Name, Address: request.Address}
+	if err := c.branchService.CreateBranch(ctx.Request.Context(), branch); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.branch_created", branch)
+}
+
+// 为书籍登记单册（馆员）
+func (c *BranchController) AddBookItem(ctx *gin.Context) {
+	var request struct {
+		Barcode      string `json:"barcode" binding:"required"`
+		HomeBranchID string `json:"home_branch_id" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	item, err := c.branchService.AddBookItem(ctx.Request.Context(), ctx.Param("id"), request.Barcode, request.HomeBranchID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.item_registered", item)
+}
+
+// 获取书籍的所有单册（馆员）
+func (c *BranchController) GetBookItems(ctx *gin.Context) {
+	items, err := c.branchService.GetBookItems(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", items)
+}
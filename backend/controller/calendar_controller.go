@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalendarController 开馆日历接口，查询公开，修改仅管理员可用
+type CalendarController struct {
+	calendarService *service.CalendarService
+}
+
+func NewCalendarController(calendarService *service.CalendarService) *CalendarController {
+	return &CalendarController{calendarService: calendarService}
+}
+
+// 获取每周开放时间和闭馆日期，默认查询今天起一年内
+func (c *CalendarController) GetCalendar(ctx *gin.Context) {
+	today := time.Now()
+	from := ctx.DefaultQuery("from", today.Format(do.DateLayout))
+	to := ctx.DefaultQuery("to", today.AddDate(1, 0, 0).Format(do.DateLayout))
+
+	calendar, err := c.calendarService.GetCalendar(ctx.Request.Context(), from, to)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", calendar)
+}
+
+// 查询某天是否开馆
+func (c *CalendarController) GetDay(ctx *gin.Context) {
+	day, err := c.calendarService.GetDay(ctx.Request.Context(), ctx.Param("date"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", day)
+}
+
+// 设置某个星期的开放时间（管理员）
+func (c *CalendarController) SetOpeningHours(ctx *gin.Context) {
+	weekday, err := strconv.Atoi(ctx.Param("weekday"))
+	if err != nil {
+		ctx.Error(apperr.Invalid("invalid.weekday"))
+		return
+	}
+
+	var request struct {
+		OpenTime  string `json:"open_time"`
+		CloseTime string `json:"close_time"`
+		Closed    bool   `json:"closed"`
+	}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	hours := &do.OpeningHours{
+		Weekday:   weekday,
+		OpenTime:  request.OpenTime,
+		CloseTime: request.CloseTime,
+		Closed:    request.Closed,
+	}
+	if err := c.calendarService.SetOpeningHours(ctx.Request.Context(), hours); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.hours_updated", hours)
+}
+
+// 添加闭馆日期（管理员）
+func (c *CalendarController) AddClosure(ctx *gin.Context) {
+	var request struct {
+		Name      string `json:"name" binding:"required"`
+		Kind      string `json:"kind"`
+		StartDate string `json:"start_date" binding:"required"`
+		EndDate   string `json:"end_date"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	closure := &do.LibraryClosure{
+		Name:      request.Name,
+		Kind:      request.Kind,
+		StartDate: request.StartDate,
+		EndDate:   request.EndDate,
+	}
+	if err := c.calendarService.AddClosure(ctx.Request.Context(), closure); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.closure_added", closure)
+}
+
+// 删除闭馆日期（管理员）
+func (c *CalendarController) DeleteClosure(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperr.Invalid("invalid.closure_id"))
+		return
+	}
+
+	if err := c.calendarService.DeleteClosure(ctx.Request.Context(), id); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.closure_deleted", nil)
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/i18n"
+	"backend/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CirculationController 流通台接口，仅馆员可用
+type CirculationController struct {
+	circulationService *service.CirculationService
+}
+
+func NewCirculationController(circulationService *service.CirculationService) *CirculationController {
+	return &CirculationController{circulationService: circulationService}
+}
+
+// 扫描借书证，查看学生概况和受阻原因
+func (c *CirculationController) GetPatron(ctx *gin.Context) {
+	patron, err := c.circulationService.GetPatron(ctx.Request.Context(), ctx.Param("stu_id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", patron)
+}
+
+// 批量借出；受阻时返回409和受阻原因，馆员可带上overrides和override_reason重新提交
+func (c *CirculationController) Checkout(ctx *gin.Context) {
+	var request struct {
+		StuID          string   `json:"stu_id" binding:"required"`
+		Barcodes       []string `json:"barcodes" binding:"required"`
+		BranchID       string   `json:"branch_id"`
+		Overrides      []string `json:"overrides"`
+		OverrideReason string   `json:"override_reason"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+	if err := service.ValidateOverrideCodes(request.Overrides); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	result, err := c.circulationService.Checkout(ctx.Request.Context(), &service.CheckoutRequest{
+		LibrarianID:    CurrentPrincipal(ctx).Subject,
+		StuID:          request.StuID,
+		BranchID:       request.BranchID,
+		Barcodes:       request.Barcodes,
+		Overrides:      request.Overrides,
+		OverrideReason: request.OverrideReason,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if result.Blocked {
+		ctx.Error(apperr.New(apperr.CodeCheckoutBlocked).WithData(result))
+		return
+	}
+
+	respondOKWith(ctx, "ok.checked_out", i18n.Params{"count": len(result.Loans)}, result)
+}
+
+// 批量还书，逐册返回罚款和触发的预约/调拨
+func (c *CirculationController) Checkin(ctx *gin.Context) {
+	var request struct {
+		Barcodes []string `json:"barcodes" binding:"required"`
+		BranchID string   `json:"branch_id"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	items := c.circulationService.Checkin(ctx.Request.Context(), request.Barcodes, request.BranchID)
+	returned := 0
+	for _, item := range items {
+		if item.Success {
+			returned++
+		}
+	}
+	respondOKWith(ctx, "ok.checked_in", i18n.Params{"returned": returned, "count": len(items)}, items)
+}
+
+// 查询强制借出记录
+func (c *CirculationController) ListOverrides(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		ctx.Error(apperr.Invalid("invalid.limit"))
+		return
+	}
+
+	overrides, err := c.circulationService.ListOverrides(ctx.Request.Context(), ctx.Query("stu_id"), limit)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", overrides)
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/service"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CoverController struct {
+	coverService *service.CoverService
+}
+
+func NewCoverController(coverService *service.CoverService) *CoverController {
+	return &CoverController{coverService: coverService}
+}
+
+// 上传封面（馆员），表单字段名为cover
+func (c *CoverController) UploadCover(ctx *gin.Context) {
+	bookID := ctx.Param("id")
+
+	fileHeader, err := ctx.FormFile("cover")
+	if err != nil {
+		ctx.Error(apperr.Invalid("invalid.cover_file_required"))
+		return
+	}
+	if fileHeader.Size > service.MaxCoverBytes {
+		ctx.Error(apperr.New(apperr.CodeCoverTooLarge).With("max_mb", service.MaxCoverBytes>>20))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, service.MaxCoverBytes+1))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	book, err := c.coverService.UploadCover(ctx.Request.Context(), bookID, data)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.cover_uploaded", book)
+}
+
+// 删除封面（馆员）
+func (c *CoverController) DeleteCover(ctx *gin.Context) {
+	if err := c.coverService.DeleteCover(ctx.Request.Context(), ctx.Param("id")); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.cover_deleted", nil)
+}
+
+// 获取封面图片，size可选thumb或detail
+// 请求带有与当前版本一致的v参数时允许客户端长期缓存
+func (c *CoverController) GetCover(ctx *gin.Context) {
+	size := ctx.DefaultQuery("size", service.CoverSizeDetail.Name)
+
+	data, contentType, version, err := c.coverService.GetCover(ctx.Request.Context(), ctx.Param("id"), size)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	etag := `"` + version + "-" + size + `"`
+	ctx.Header("ETag", etag)
+	if ctx.Query("v") == version {
+		ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		ctx.Header("Cache-Control", "public, max-age=300")
+	}
+
+	if ctx.GetHeader("If-None-Match") == etag {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.Data(http.StatusOK, contentType, data)
+}
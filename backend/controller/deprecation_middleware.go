@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation 已弃用接口的弃用日期和停用日期
+type Deprecation struct {
+	Since  time.Time
+	Sunset time.Time
+}
+
+// 替代接口路径中的参数，如 /api/v1/students/:stu_id/loans
+var successorParam = regexp.MustCompile(`:([a-z_]+)`)
+
+// 标记已弃用的接口：返回Deprecation（RFC 9745）和Sunset（RFC 8594）响应头，
+// 并以 Link: <...>; rel="successor-version" 指向替代接口；successor中的 :name 依次用同名的路径参数或查询参数替换，
+// 请求中没有对应参数时（如参数在请求体中）不返回Link
+func Deprecated(deprecation Deprecation, successor string) gin.HandlerFunc {
+	since := "@" + strconv.FormatInt(deprecation.Since.Unix(), 10)
+	sunset := deprecation.Sunset.UTC().Format(http.TimeFormat)
+	return func(ctx *gin.Context) {
+		ctx.Header("Deprecation", since)
+		ctx.Header("Sunset", sunset)
+
+		resolved := true
+		link := successorParam.ReplaceAllStringFunc(successor, func(param string) string {
+			name := param[1:]
+			value := ctx.Param(name)
+			if value == "" {
+				value = ctx.Query(name)
+			}
+			if value == "" {
+				resolved = false
+			}
+			return url.PathEscape(value)
+		})
+		if resolved {
+			ctx.Header("Link", "<"+link+`>; rel="successor-version"`)
+		}
+		ctx.Next()
+	}
+}
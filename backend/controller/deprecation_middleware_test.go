@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	deprecation := Deprecation{
+		Since:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2026, 7, 1, 8, 0, 0, 0, time.FixedZone("CST", 8*3600)),
+	}
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	r.GET("/books/:id", Deprecated(deprecation, "/api/v1/books/:id"), ok)
+	r.GET("/records", Deprecated(deprecation, "/api/v1/students/:stu_id/loans/:book_id"), ok)
+	r.GET("/search", Deprecated(deprecation, "/api/v1/books?keyword=:keyword"), ok)
+
+	for _, tc := range []struct {
+		path, link string
+	}{
+		{"/books/B1", `</api/v1/books/B1>; rel="successor-version"`},
+		{"/records?stu_id=2023&book_id=B1", `</api/v1/students/2023/loans/B1>; rel="successor-version"`},
+		// 参数需要转义
+		{"/search?keyword=a%2Fb%20c", `</api/v1/books?keyword=a%2Fb%20c>; rel="successor-version"`},
+		// 缺少参数时不返回Link
+		{"/records?stu_id=2023", ""},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s = %d", tc.path, w.Code)
+		}
+		if got := w.Header().Get("Deprecation"); got != "@1767225600" {
+			t.Errorf("GET %s 的Deprecation = %q", tc.path, got)
+		}
+		if got := w.Header().Get("Sunset"); got != "Wed, 01 Jul 2026 00:00:00 GMT" {
+			t.Errorf("GET %s 的Sunset = %q", tc.path, got)
+		}
+		if got := w.Header().Get("Link"); got != tc.link {
+			t.Errorf("GET %s 的Link = %q，期望 %q", tc.path, got, tc.link)
+		}
+	}
+}
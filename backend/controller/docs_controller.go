@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"backend/openapi"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// 文档页面，Swagger UI的脚本和样式由/docs/assets提供
+const docsPage = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+  <meta charset="UTF-8">
+  <title>图书馆管理系统 API</title>
+  <link rel="stylesheet" href="/docs/assets/swagger-ui.css">
+  <link rel="icon" type="image/png" href="/docs/assets/favicon-32x32.png" sizes="32x32">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/assets/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({
+      url: "/openapi.json",
+      dom_id: "#swagger-ui",
+      deepLinking: true,
+      presets: [SwaggerUIBundle.presets.apis],
+    });
+  </script>
+</body>
+</html>
+`
+
+// DocsController 接口文档和交互式文档页面，不需要登录
+type DocsController struct {
+	specJSON []byte
+	assets   http.FileSystem
+}
+
+func NewDocsController() (*DocsController, error) {
+	specJSON, err := openapi.JSON()
+	if err != nil {
+		return nil, err
+	}
+	return &DocsController{specJSON: specJSON, assets: http.FS(swaggerFiles.FS)}, nil
+}
+
+// OpenAPI文档（YAML）
+func (c *DocsController) SpecYAML(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "application/yaml; charset=utf-8", openapi.YAML)
+}
+
+// OpenAPI文档（JSON），文档页面读取这个地址
+func (c *DocsController) SpecJSON(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", c.specJSON)
+}
+
+// 交互式文档页面（Swagger UI）
+func (c *DocsController) Page(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
+
+// Swagger UI的静态文件
+func (c *DocsController) Assets(ctx *gin.Context) {
+	ctx.FileFromFS(ctx.Param("filepath"), c.assets)
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/i18n"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 统一的响应格式：
+//
+//	{"code": "OK", "message": "借书成功", "data": {...}}
+//	{"code": "BOOK_NOT_FOUND", "message": "书籍不存在"}
+//
+// code为OK或apperr中的错误码，前端按code分支处理；message为给用户看的提示，按Localize协商的语言输出；data按需返回
+
+// 统一输出错误响应，需要注册在RequestTimeout之后、认证等中间件之前
+// 处理函数和中间件出错时调用ctx.Error记录错误后直接返回，由这里按错误码设置HTTP状态码
+func ErrorHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		// 已经开始输出响应（如实时推送）时不能再改写
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+
+		err := ctx.Errors.Last().Err
+		appErr := apperr.From(err)
+		// 数据库驱动返回的错误不一定包装了ctx的错误，请求已超时或取消时按超时或取消处理
+		if appErr.Code == apperr.CodeInternal {
+			if ctxErr := ctx.Request.Context().Err(); ctxErr != nil {
+				appErr = apperr.From(ctxErr)
+			}
+		}
+
+		status := appErr.Status()
+		if status >= http.StatusInternalServerError {
+			slog.ErrorContext(ctx.Request.Context(), "请求处理失败", "method", ctx.Request.Method, "path", ctx.Request.URL.Path, "status", status, "error", err)
+		}
+
+		locale := requestLocale(ctx)
+		body := gin.H{"code": appErr.Code, "message": appErr.Localize(locale)}
+		if appErr.Data != nil {
+			body["data"] = appErr.Data
+		}
+		ctx.JSON(status, body)
+	}
+}
+
+// 未匹配到路由时返回404
+func NotFound(ctx *gin.Context) {
+	ctx.Error(apperr.NewMessage(apperr.CodeNotFound, "error.route_not_found").With("method", ctx.Request.Method).With("path", ctx.Request.URL.Path))
+}
+
+// 成功响应，key为i18n目录中的提示；key或data为空时不返回该字段
+func respondOK(ctx *gin.Context, key string, data interface{}) {
+	respondOKWith(ctx, key, nil, data)
+}
+
+// 带参数提示的成功响应
+func respondOKWith(ctx *gin.Context, key string, params i18n.Params, data interface{}) {
+	body := gin.H{"code": apperr.CodeOK}
+	if key != "" {
+		body["message"] = i18n.Translate(requestLocale(ctx), key, params)
+	}
+	if data != nil {
+		body["data"] = data
+	}
+	ctx.JSON(http.StatusOK, body)
+}
+
+// 请求协商的语言，同时在响应头中注明
+func requestLocale(ctx *gin.Context) string {
+	locale := i18n.Locale(ctx.Request.Context())
+	ctx.Header("Content-Language", locale)
+	return locale
+}
+
+// 中间件中出错时中止后续处理
+func abortWithError(ctx *gin.Context, err error) {
+	ctx.Error(err)
+	ctx.Abort()
+}
+
+// 请求参数绑定失败
+func invalidRequest(err error) error {
+	return apperr.Invalid("error.invalid_request").With("detail", err.Error())
+}
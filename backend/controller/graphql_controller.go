@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"backend/graph"
+	"backend/service"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GraphQLController GraphQL接口，不需要登录的字段（如图书）匿名可查，其余字段按令牌中的角色授权
+type GraphQLController struct {
+	schema      *graph.Schema
+	authService *service.AuthService
+}
+
+func NewGraphQLController(schema *graph.Schema, authService *service.AuthService) *GraphQLController {
+	return &GraphQLController{schema: schema, authService: authService}
+}
+
+// 执行GraphQL查询，响应为标准的 {"data": ..., "errors": [...]}，字段的错误码在errors的extensions.code中
+// 携带的令牌无效时直接拒绝，避免按匿名执行后只得到一堆null
+func (c *GraphQLController) Query(ctx *gin.Context) {
+	if token, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer "); ok && token != "" {
+		if _, err := c.authService.ParseToken(token); err != nil {
+			ctx.Error(err)
+			return
+		}
+	}
+
+	type QueryRequest struct {
+		Query         string                 `json:"query" binding:"required"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+
+	var req QueryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	requestLocale(ctx)
+	result := c.schema.Execute(ctx.Request.Context(), graph.Request{
+		Query:         req.Query,
+		OperationName: req.OperationName,
+		Variables:     req.Variables,
+	})
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"backend/i18n"
+	"backend/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthController 存活和就绪检查，供负载均衡和容器编排使用，不需要登录
+type HealthController struct {
+	healthService *service.HealthService
+}
+
+func NewHealthController(healthService *service.HealthService) *HealthController {
+	return &HealthController{healthService: healthService}
+}
+
+// 存活检查：进程能处理请求即返回200，不检查数据库，避免数据库故障时进程被反复重启
+func (c *HealthController) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": i18n.Translate(requestLocale(ctx), "ok.health", nil),
+	})
+}
+
+// 就绪检查：数据库、迁移和后台任务均正常时返回200，否则返回503并列出各项检查的结果
+func (c *HealthController) Ready(ctx *gin.Context) {
+	report := c.healthService.Ready(ctx.Request.Context())
+	status, code, key := "OK", http.StatusOK, "ok.health"
+	if !report.Ready {
+		status, code, key = "UNAVAILABLE", http.StatusServiceUnavailable, "error.not_ready"
+	}
+	ctx.JSON(code, gin.H{
+		"status":  status,
+		"message": i18n.Translate(requestLocale(ctx), key, nil),
+		"checks":  report.Checks,
+	})
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/service"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HoldController struct {
+	holdService *service.HoldService
+}
+
+func NewHoldController(holdService *service.HoldService) *HoldController {
+	return &HoldController{holdService: holdService}
+}
+
+// 预约图书：学生令牌时学号取自令牌，馆员代约或旧版路由时为请求中的stu_id
+func (c *HoldController) PlaceHold(ctx *gin.Context) {
+	var request struct {
+		StuID          string `json:"stu_id"`
+		BookID         string `json:"book_id" binding:"required"`
+		PickupBranchID string `json:"pickup_branch_id" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+	stuID, err := requestStudentID(ctx, request.StuID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	hold, err := c.holdService.PlaceHold(ctx.Request.Context(), stuID, request.BookID, request.PickupBranchID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.hold_placed", hold)
+}
+
+// 取消预约：学生使用令牌时可以不带请求体
+func (c *HoldController) CancelHold(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperr.Invalid("invalid.hold_id"))
+		return
+	}
+
+	var request struct {
+		StuID string `json:"stu_id"`
+	}
+	if err := ctx.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+	stuID, err := requestStudentID(ctx, request.StuID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := c.holdService.CancelHold(ctx.Request.Context(), id, stuID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.hold_canceled", nil)
+}
+
+// 获取学生的预约列表
+func (c *HoldController) GetStudentHolds(ctx *gin.Context) {
+	stuID := ctx.Query("stu_id")
+	if stuID == "" {
+		ctx.Error(apperr.Invalid("invalid.stu_id_required"))
+		return
+	}
+
+	c.getStudentHolds(ctx, stuID)
+}
+
+// 获取学生的预约列表（/api/v1），学号为路径参数
+func (c *HoldController) ListStudentHolds(ctx *gin.Context) {
+	c.getStudentHolds(ctx, ctx.Param("id"))
+}
+
+func (c *HoldController) getStudentHolds(ctx *gin.Context, stuID string) {
+	holds, err := c.holdService.GetStudentHolds(ctx.Request.Context(), stuID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", holds)
+}
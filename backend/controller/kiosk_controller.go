@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/i18n"
+	"backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KioskController 自助借还机接口与设备管理接口
+type KioskController struct {
+	kioskService *service.KioskService
+}
+
+func NewKioskController(kioskService *service.KioskService) *KioskController {
+	return &KioskController{kioskService: kioskService}
+}
+
+// 自助借书：学生刷借书证并输入PIN，借出的书记在设备所在分馆
+func (c *KioskController) Checkout(ctx *gin.Context) {
+	var request struct {
+		StuID    string   `json:"stu_id" binding:"required"`
+		PIN      string   `json:"pin" binding:"required"`
+		Barcodes []string `json:"barcodes" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	slip, result, err := c.kioskService.Checkout(ctx.Request.Context(), CurrentKioskDevice(ctx), request.StuID, request.PIN, request.Barcodes)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// 自助借还机不能强制借出，受阻时提示学生到服务台办理
+	if result.Blocked {
+		ctx.Error(apperr.NewMessage(apperr.CodeCheckoutBlocked, "error.kiosk_checkout_blocked").WithData(result))
+		return
+	}
+
+	respondOKWith(ctx, "ok.checked_out", i18n.Params{"count": len(slip.Items)}, slip)
+}
+
+// 自助还书，凭条中逐册列出归还结果和罚款
+func (c *KioskController) Return(ctx *gin.Context) {
+	var request struct {
+		Barcodes []string `json:"barcodes" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	respondOK(ctx, "", c.kioskService.Return(ctx.Request.Context(), CurrentKioskDevice(ctx), request.Barcodes))
+}
+
+// 获取所有设备
+func (c *KioskController) GetAllDevices(ctx *gin.Context) {
+	devices, err := c.kioskService.GetAllDevices(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", devices)
+}
+
+// 登记设备，返回的api_key只显示这一次
+func (c *KioskController) RegisterDevice(ctx *gin.Context) {
+	var request struct {
+		DeviceID        string `json:"device_id" binding:"required"`
+		Name            string `json:"name" binding:"required"`
+		BranchID        string `json:"branch_id" binding:"required"`
+		RateLimitPerMin int    `json:"rate_limit_per_min"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	device, apiKey, err := c.kioskService.RegisterDevice(ctx.Request.Context(), request.DeviceID, request.Name, request.BranchID, request.RateLimitPerMin)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.device_registered", gin.H{
+		"device":  device,
+		"api_key": apiKey,
+	})
+}
+
+// 重新生成设备密钥
+func (c *KioskController) RotateDeviceKey(ctx *gin.Context) {
+	apiKey, err := c.kioskService.RotateDeviceKey(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.device_key_rotated", gin.H{
+		"api_key": apiKey,
+	})
+}
+
+// 启用设备
+func (c *KioskController) EnableDevice(ctx *gin.Context) {
+	c.setDeviceEnabled(ctx, true)
+}
+
+// 停用设备，停用后设备密钥立即不可用
+func (c *KioskController) DisableDevice(ctx *gin.Context) {
+	c.setDeviceEnabled(ctx, false)
+}
+
+func (c *KioskController) setDeviceEnabled(ctx *gin.Context, enabled bool) {
+	if err := c.kioskService.SetDeviceEnabled(ctx.Request.Context(), ctx.Param("id"), enabled); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.device_status_updated", nil)
+}
+
+// 修改设备每分钟请求上限
+func (c *KioskController) SetDeviceRateLimit(ctx *gin.Context) {
+	var request struct {
+		RateLimitPerMin int `json:"rate_limit_per_min" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	if err := c.kioskService.SetDeviceRateLimit(ctx.Request.Context(), ctx.Param("id"), request.RateLimitPerMin); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.rate_limit_updated", nil)
+}
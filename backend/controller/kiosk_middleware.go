@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/service"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上下文中保存当前自助借还机的键
+const kioskDeviceKey = "kiosk_device"
+
+// 自助借还机以X-Device-Key请求头认证，并按设备限制每分钟请求数
+func RequireKiosk(kioskService *service.KioskService) gin.HandlerFunc {
+	limiter := newDeviceRateLimiter()
+
+	return func(ctx *gin.Context) {
+		apiKey := ctx.GetHeader("X-Device-Key")
+		if apiKey == "" {
+			abortWithError(ctx, apperr.NewMessage(apperr.CodeUnauthorized, "error.device_key_missing"))
+			return
+		}
+
+		device, err := kioskService.AuthenticateDevice(ctx.Request.Context(), apiKey)
+		if err != nil {
+			// 不向调用方区分密钥无效和设备已停用
+			if apperr.Is(err, apperr.CodeDeviceDisabled) {
+				err = apperr.New(apperr.CodeDeviceKeyInvalid)
+			}
+			abortWithError(ctx, err)
+			return
+		}
+
+		if !limiter.allow(device.DeviceID, device.RateLimitPerMin, time.Now()) {
+			ctx.Header("Retry-After", "60")
+			abortWithError(ctx, apperr.New(apperr.CodeTooManyRequests))
+			return
+		}
+
+		ctx.Set(principalKey, &service.Principal{Role: do.RoleKiosk, Subject: device.DeviceID})
+		ctx.Set(kioskDeviceKey, device)
+		actor := service.Actor{Role: do.RoleKiosk, ID: device.DeviceID, IP: ctx.ClientIP()}
+		ctx.Request = ctx.Request.WithContext(service.WithActor(ctx.Request.Context(), actor))
+		ctx.Next()
+	}
+}
+
+// 获取当前请求的自助借还机，未经过RequireKiosk时返回nil
+func CurrentKioskDevice(ctx *gin.Context) *do.KioskDevice {
+	if value, ok := ctx.Get(kioskDeviceKey); ok {
+		return value.(*do.KioskDevice)
+	}
+	return nil
+}
+
+// 按设备的令牌桶限流，桶容量为每分钟上限，令牌按上限匀速补充
+type deviceRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newDeviceRateLimiter() *deviceRateLimiter {
+	return &deviceRateLimiter{buckets: make(map[string]*tokenBucket)}
+}
+
+func (l *deviceRateLimiter) allow(deviceID string, perMin int, now time.Time) bool {
+	capacity := float64(perMin)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[deviceID]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, last: now}
+		l.buckets[deviceID] = bucket
+	}
+
+	// 补充令牌，上限调小后桶中多余的令牌一并丢弃
+	bucket.tokens += now.Sub(bucket.last).Minutes() * capacity
+	if bucket.tokens > capacity {
+		bucket.tokens = capacity
+	}
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
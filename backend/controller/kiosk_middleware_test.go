@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeviceRateLimiter(t *testing.T) {
+	// 每一步在start之后at时刻以perMin的上限连续请求n次，期望结果均为want
+	type step struct {
+		device string
+		at     time.Duration
+		perMin int
+		n      int
+		want   bool
+	}
+	start := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name  string
+		steps []step
+	}{
+		{"突发请求不超过桶容量", []step{
+			{"K1", 0, 3, 3, true},
+			{"K1", 0, 3, 1, false},
+		}},
+		{"令牌按上限匀速补充", []step{
+			{"K1", 0, 60, 60, true},
+			{"K1", 0, 60, 1, false},
+			{"K1", time.Second, 60, 1, true},
+			{"K1", time.Second, 60, 1, false},
+			{"K1", 31 * time.Second, 60, 30, true},
+			{"K1", 31 * time.Second, 60, 1, false},
+		}},
+		{"空闲再久也不超过桶容量", []step{
+			{"K1", 0, 2, 1, true},
+			{"K1", time.Hour, 2, 2, true},
+			{"K1", time.Hour, 2, 1, false},
+		}},
+		{"上限调小后丢弃多余的令牌", []step{
+			{"K1", 0, 10, 1, true},
+			{"K1", 0, 2, 2, true},
+			{"K1", 0, 2, 1, false},
+		}},
+		{"上限调大后按新上限补充", []step{
+			{"K1", 0, 1, 1, true},
+			{"K1", 0, 1, 1, false},
+			{"K1", time.Minute, 5, 5, true},
+		}},
+		{"设备之间互不影响", []step{
+			{"K1", 0, 1, 1, true},
+			{"K1", 0, 1, 1, false},
+			{"K2", 0, 1, 1, true},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := newDeviceRateLimiter()
+			for i, s := range tc.steps {
+				for j := 0; j < s.n; j++ {
+					if got := limiter.allow(s.device, s.perMin, start.Add(s.at)); got != s.want {
+						t.Fatalf("第%d步第%d次请求 allow(%s, %d, +%s) = %v，期望 %v", i+1, j+1, s.device, s.perMin, s.at, got, s.want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LibrarianController struct {
+	librarianService *service.LibrarianService
+	authService      *service.AuthService
+}
+
+func NewLibrarianController(librarianService *service.LibrarianService, authService *service.AuthService) *LibrarianController {
+	return &LibrarianController{librarianService: librarianService, authService: authService}
+}
+
+// 馆员登录
+func (c *LibrarianController) Login(ctx *gin.Context) {
+	var request struct {
+		LibrarianID string `json:"librarian_id" binding:"required"`
+		Password    string `json:"password" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	librarian, err := c.librarianService.GetLibrarianInfo(ctx.Request.Context(), request.LibrarianID)
+	if err != nil && !apperr.Is(err, apperr.CodeLibrarianNotFound) {
+		ctx.Error(err)
+		return
+	}
+	if err != nil || librarian.Password != request.Password {
+		ctx.Error(apperr.NewMessage(apperr.CodeInvalidCredentials, "error.librarian_credentials"))
+		return
+	}
+
+	token, err := c.authService.IssueToken(librarian.Role, librarian.LibrarianID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.login", gin.H{
+		"librarian_id": librarian.LibrarianID,
+		"name":         librarian.Name,
+		"role":         librarian.Role,
+		"token":        token,
+	})
+}
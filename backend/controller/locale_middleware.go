@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"backend/i18n"
+	"backend/service"
+	"log/slog"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 协商请求的语言并保存在请求的ctx中，提示按该语言输出
+// 优先级：lang查询参数 > 学生通知设置中的语言（携带学生令牌时）> Accept-Language > 默认中文
+// 学生的语言设置需要查询数据库，只在请求第一次输出提示时才协商；notificationService为nil时不查询学生的设置
+func Localize(authService *service.AuthService, notificationService *service.NotificationService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resolve := func() string {
+			if locale := i18n.Match(ctx.Query("lang")); locale != "" {
+				return locale
+			}
+			if locale := preferredLocale(ctx, authService, notificationService); locale != "" {
+				return locale
+			}
+			return i18n.Negotiate(ctx.GetHeader("Accept-Language"))
+		}
+		ctx.Request = ctx.Request.WithContext(i18n.WithLocaleFunc(ctx.Request.Context(), resolve))
+		ctx.Next()
+	}
+}
+
+// 学生令牌对应的学生保存的语言，令牌无效或没有设置时返回空
+func preferredLocale(ctx *gin.Context, authService *service.AuthService, notificationService *service.NotificationService) string {
+	token, _ := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		token = ctx.Query("token")
+	}
+	if token == "" || notificationService == nil {
+		return ""
+	}
+	principal, err := authService.ParseToken(token)
+	if err != nil || principal.Role != service.RoleStudent {
+		return ""
+	}
+
+	language, err := notificationService.PreferredLanguage(ctx.Request.Context(), principal.Subject)
+	if err != nil {
+		// 查询失败时按请求头协商，不影响请求本身；请求已超时或取消时不必记录
+		if ctx.Request.Context().Err() == nil {
+			slog.WarnContext(ctx.Request.Context(), "查询学生的语言设置失败", "stu_id", principal.Subject, "error", err)
+		}
+		return ""
+	}
+	return i18n.Match(language)
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/logging"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求ID的请求头和响应头
+const requestIDHeader = "X-Request-ID"
+
+// 调用方传入的请求ID只接受较短的字母、数字和 - _ .，避免日志被注入任意内容
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// 为每个请求分配请求ID：沿用调用方（如网关）传入的X-Request-ID，没有或格式不合法时生成新的
+// 请求ID写入响应头并保存在请求的ctx中，之后带ctx记录的日志和审计日志都会带上它，需要最先注册
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(requestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = logging.NewRequestID()
+		}
+		ctx.Header(requestIDHeader, id)
+		ctx.Request = ctx.Request.WithContext(logging.WithRequestID(ctx.Request.Context(), id))
+		ctx.Next()
+	}
+}
+
+// 每个请求结束后记录一条访问日志，5xx记为错误，4xx记为警告
+func AccessLog() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		slog.LogAttrs(ctx.Request.Context(), level, "请求",
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.String("route", ctx.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", ctx.ClientIP()),
+			slog.Int("bytes", ctx.Writer.Size()),
+		)
+	}
+}
+
+// 处理函数panic时记录调用栈并返回500，替代gin默认的Recovery以便使用结构化日志和统一的响应格式
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(ctx *gin.Context, recovered interface{}) {
+		slog.ErrorContext(ctx.Request.Context(), "请求处理panic",
+			"method", ctx.Request.Method, "path", ctx.Request.URL.Path, "panic", recovered, "stack", string(debug.Stack()))
+		appErr := apperr.New(apperr.CodeInternal)
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"code": appErr.Code, "message": appErr.Localize(requestLocale(ctx))})
+	})
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"backend/logging"
+	"backend/service"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestIDAndActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authService := service.NewAuthService([]byte("test-secret"), time.Hour)
+	r := gin.New()
+	r.Use(Recovery(), RequestID(), ErrorHandler(), Identify(authService))
+	r.GET("/whoami", func(ctx *gin.Context) {
+		actor := service.ActorFrom(ctx.Request.Context())
+		ctx.JSON(http.StatusOK, gin.H{
+			"request_id": logging.RequestID(ctx.Request.Context()),
+			"role":       actor.Role,
+			"id":         actor.ID,
+		})
+	})
+	r.GET("/panic", func(ctx *gin.Context) {
+		panic("boom")
+	})
+
+	token, err := authService.IssueToken(service.RoleStudent, "S1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name          string
+		requestID     string
+		authorization string
+		keepID        bool
+		role, id      string
+	}{
+		{"生成请求ID", "", "", false, "anonymous", ""},
+		{"沿用请求ID", "gateway-42.a_b", "", true, "anonymous", ""},
+		{"不合法的请求ID", "bad id\nx", "", false, "anonymous", ""},
+		{"携带令牌", "", "Bearer " + token, false, service.RoleStudent, "S1"},
+		{"无效令牌", "", "Bearer nope", false, "anonymous", ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		if tc.requestID != "" {
+			req.Header.Set("X-Request-ID", tc.requestID)
+		}
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var resp map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: 响应不是JSON: %s", tc.name, w.Body.String())
+		}
+		header := w.Header().Get("X-Request-ID")
+		if header == "" || header != resp["request_id"] {
+			t.Errorf("%s: 响应头 %q，ctx中 %q，期望一致且不为空", tc.name, header, resp["request_id"])
+		}
+		if tc.keepID != (header == tc.requestID) {
+			t.Errorf("%s: 请求ID = %q，传入 %q", tc.name, header, tc.requestID)
+		}
+		if resp["role"] != tc.role || resp["id"] != tc.id {
+			t.Errorf("%s: 操作人 = %s/%s，期望 %s/%s", tc.name, resp["role"], resp["id"], tc.role, tc.id)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusInternalServerError || resp["code"] != "INTERNAL" {
+		t.Errorf("panic = %d %s，期望 500 INTERNAL", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/metrics"
+	"crypto/subtle"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 按路由模板记录请求处理时间和状态码，路由参数不计入标签，避免标签数量随ID增长
+func Metrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+		metrics.ObserveRequest(ctx.Request.Method, ctx.FullPath(), ctx.Writer.Status(), time.Since(start))
+	}
+}
+
+// 输出Prometheus指标；token不为空时要求抓取请求携带 Authorization: Bearer <token>
+func MetricsHandler(token string) gin.HandlerFunc {
+	handler := metrics.Handler()
+	return func(ctx *gin.Context) {
+		if token != "" {
+			provided := ctx.GetHeader("Authorization")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte("Bearer "+token)) != 1 {
+				ctx.Error(apperr.New(apperr.CodeUnauthorized))
+				return
+			}
+		}
+		handler.ServeHTTP(ctx.Writer, ctx.Request)
+	}
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Metrics(), ErrorHandler())
+	r.NoRoute(NotFound)
+	r.GET("/books/:id", func(ctx *gin.Context) {
+		respondOK(ctx, "", ctx.Param("id"))
+	})
+	r.GET("/metrics", MetricsHandler("scrape-token"))
+
+	for _, path := range []string{"/books/B1", "/books/B2", "/nothing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("未携带令牌抓取 = %d，期望 401", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape-token")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("抓取 = %d", w.Code)
+	}
+	body := w.Body.String()
+	// 路由参数不作为标签，两本书的请求计入同一路由
+	for _, want := range []string{
+		`library_http_request_duration_seconds_count{method="GET",route="/books/:id",status="200"} 2`,
+		`library_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+		`library_http_request_duration_seconds_count{method="GET",route="/metrics",status="401"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("指标中缺少 %s", want)
+		}
+	}
+	if strings.Contains(body, "/books/B1") {
+		t.Error("路由标签不应包含具体的ID")
+	}
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationController struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationController(notificationService *service.NotificationService) *NotificationController {
+	return &NotificationController{notificationService: notificationService}
+}
+
+// 获取当前学生的通知设置（学生令牌）
+func (c *NotificationController) GetPreference(ctx *gin.Context) {
+	pref, err := c.notificationService.GetPreference(ctx.Request.Context(), CurrentPrincipal(ctx).Subject)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", pref)
+}
+
+// 修改当前学生的通知设置（学生令牌）
+func (c *NotificationController) SetPreference(ctx *gin.Context) {
+	var request struct {
+		Email     string `json:"email"`
+		Language  string `json:"language"`
+		DueSoon   bool   `json:"due_soon"`
+		Overdue   bool   `json:"overdue"`
+		HoldReady bool   `json:"hold_ready"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	pref := &do.NotificationPreference{
+		StuID:     CurrentPrincipal(ctx).Subject,
+		Email:     request.Email,
+		Language:  request.Language,
+		DueSoon:   request.DueSoon,
+		Overdue:   request.Overdue,
+		HoldReady: request.HoldReady,
+	}
+	if err := c.notificationService.SetPreference(ctx.Request.Context(), pref); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.preference_saved", pref)
+}
+
+// 查询通知投递记录（馆员）
+func (c *NotificationController) ListDeliveries(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		ctx.Error(apperr.Invalid("invalid.limit"))
+		return
+	}
+
+	notifications, err := c.notificationService.ListDeliveries(ctx.Request.Context(), ctx.Query("stu_id"), ctx.Query("status"), limit)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", notifications)
+}
+
+// 立即重新发送一条未成功的通知（馆员）
+func (c *NotificationController) RetryDelivery(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperr.Invalid("invalid.notification_id"))
+		return
+	}
+
+	if err := c.notificationService.RetryDelivery(ctx.Request.Context(), id); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.notification_retry", nil)
+}
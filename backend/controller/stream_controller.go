@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/events"
+	"backend/service"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 单个连接最多订阅的图书数
+	maxStreamBooks = 200
+	// 心跳间隔，防止代理因连接空闲断开
+	streamHeartbeat = 25 * time.Second
+)
+
+// StreamController 以Server-Sent Events推送实时事件
+type StreamController struct {
+	bus         *events.Bus
+	authService *service.AuthService
+	closing     chan struct{}
+	closeOnce   sync.Once
+}
+
+func NewStreamController(bus *events.Bus, authService *service.AuthService) *StreamController {
+	return &StreamController{bus: bus, authService: authService, closing: make(chan struct{})}
+}
+
+// 结束全部推送连接，退出时调用；推送是长连接，不主动结束时服务器会一直等到退出时限
+func (c *StreamController) Close() {
+	c.closeOnce.Do(func() { close(c.closing) })
+}
+
+// 订阅图书可借数量变化（book_id可重复或逗号分隔）；携带学生令牌时同时推送本人的借阅和预约事件。
+// 浏览器EventSource不能设置请求头，令牌也可以放在token查询参数中
+func (c *StreamController) Stream(ctx *gin.Context) {
+	var bookIDs []string
+	for _, value := range ctx.QueryArray("book_id") {
+		for _, bookID := range strings.Split(value, ",") {
+			if bookID = strings.TrimSpace(bookID); bookID != "" {
+				bookIDs = append(bookIDs, bookID)
+			}
+		}
+	}
+	if len(bookIDs) > maxStreamBooks {
+		ctx.Error(apperr.Invalid("invalid.too_many_stream_books").With("max", maxStreamBooks))
+		return
+	}
+
+	var stuID string
+	token, _ := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		token = ctx.Query("token")
+	}
+	if token != "" {
+		principal, err := c.authService.ParseToken(token)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		if principal.Role == service.RoleStudent {
+			stuID = principal.Subject
+		}
+	}
+
+	if len(bookIDs) == 0 && stuID == "" {
+		ctx.Error(apperr.Invalid("invalid.stream_target"))
+		return
+	}
+
+	sub := c.bus.Subscribe(bookIDs, stuID)
+	defer sub.Close()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.SSEvent("ready", gin.H{"book_ids": bookIDs, "personal": stuID != ""})
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-sub.C:
+			ctx.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			io.WriteString(w, ": ping\n\n")
+			return true
+		case <-ctx.Request.Context().Done():
+			return false
+		case <-c.closing:
+			return false
+		}
+	})
+}
@@ -1,18 +1,19 @@
 package controller
 
 import (
+	"backend/apperr"
 	"backend/service"
-	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
 type StudentController struct {
 	studentService *service.StudentService
+	authService    *service.AuthService
 }
 
-func NewStudentController(studentService *service.StudentService) *StudentController {
-	return &StudentController{studentService: studentService}
+func NewStudentController(studentService *service.StudentService, authService *service.AuthService) *StudentController {
+	return &StudentController{studentService: studentService, authService: authService}
 }
 
 // 学生登录
@@ -24,40 +25,38 @@ func (c *StudentController) Login(ctx *gin.Context) {
 
 	var req LoginRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
-	// 获取学生信息
-	student, err := c.studentService.GetStudentInfo(req.StuID)
+	// 验证学号和密码
+	student, err := c.studentService.Authenticate(ctx.Request.Context(), req.StuID, req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "学号或密码错误"})
+		ctx.Error(err)
 		return
 	}
 
-	// 验证密码
-	if student.Password != req.Password {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "学号或密码错误"})
+	// 检查借阅权限
+	canBorrow, message, err := c.studentService.CanStudentBorrow(ctx.Request.Context(), req.StuID)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	// 检查借阅权限
-	canBorrow, message, err := c.studentService.CanStudentBorrow(req.StuID)
+	token, err := c.authService.IssueToken(service.RoleStudent, student.StuId)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "系统错误: " + err.Error()})
+		ctx.Error(err)
 		return
 	}
 
 	// 返回登录成功信息
-	ctx.JSON(http.StatusOK, gin.H{
-		"message": "登录成功",
-		"data": gin.H{
-			"stu_id":      student.StuId,
-			"name":        student.Name,
-			"trust":       student.Trust,
-			"can_borrow":  canBorrow,
-			"borrow_info": message,
-		},
+	respondOK(ctx, "ok.login", gin.H{
+		"stu_id":      student.StuId,
+		"name":        student.Name,
+		"trust":       student.Trust,
+		"can_borrow":  canBorrow,
+		"borrow_info": message,
+		"token":       token,
 	})
 }
 
@@ -65,20 +64,67 @@ func (c *StudentController) Login(ctx *gin.Context) {
 func (c *StudentController) GetStudentInfo(ctx *gin.Context) {
 	stuID := ctx.Query("stu_id")
 	if stuID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "学号不能为空"})
+		ctx.Error(apperr.Invalid("invalid.stu_id_required"))
 		return
 	}
 
-	student, err := c.studentService.GetStudentInfo(stuID)
+	c.getStudentInfo(ctx, stuID)
+}
+
+// 获取学生信息（/api/v1），学号为路径参数
+func (c *StudentController) GetStudent(ctx *gin.Context) {
+	c.getStudentInfo(ctx, ctx.Param("id"))
+}
+
+func (c *StudentController) getStudentInfo(ctx *gin.Context, stuID string) {
+	student, err := c.studentService.GetStudentInfo(ctx.Request.Context(), stuID)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "学生不存在"})
+		ctx.Error(err)
 		return
 	}
 
 	// 隐藏密码信息
 	student.Password = ""
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"data": student,
-	})
+	respondOK(ctx, "", student)
+}
+
+// 设置自助借还机PIN，需要验证登录密码
+func (c *StudentController) SetPIN(ctx *gin.Context) {
+	var request struct {
+		StuID    string `json:"stu_id" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		PIN      string `json:"pin" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	c.setPIN(ctx, request.StuID, request.Password, request.PIN)
+}
+
+// 设置自助借还机PIN（/api/v1），学号为路径参数
+func (c *StudentController) SetStudentPIN(ctx *gin.Context) {
+	var request struct {
+		Password string `json:"password" binding:"required"`
+		PIN      string `json:"pin" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	c.setPIN(ctx, ctx.Param("id"), request.Password, request.PIN)
+}
+
+func (c *StudentController) setPIN(ctx *gin.Context, stuID, password, pin string) {
+	if err := c.studentService.SetPIN(ctx.Request.Context(), stuID, password, pin); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.pin_set", nil)
 }
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 为请求的ctx设置处理时限，超时或客户端断开连接时正在执行的数据库操作随之中止
+// routes按“方法 路由模板”（如 POST /books/:id/cover）覆盖默认时限，时限为0表示不设时限
+func RequestTimeout(defaultTimeout time.Duration, routes map[string]time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		timeout, ok := routes[ctx.Request.Method+" "+ctx.FullPath()]
+		if !ok {
+			timeout = defaultTimeout
+		}
+		if timeout <= 0 {
+			ctx.Next()
+			return
+		}
+
+		requestCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(requestCtx)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestTimeout(20*time.Millisecond, map[string]time.Duration{
+		"POST /books/:id/cover": time.Minute,
+		"GET /stream":           0,
+	}))
+	r.Use(ErrorHandler())
+
+	// 返回请求ctx的剩余时限，没有时限时返回-1
+	remaining := func(ctx *gin.Context) {
+		deadline, ok := ctx.Request.Context().Deadline()
+		if !ok {
+			ctx.JSON(http.StatusOK, gin.H{"data": -1})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"data": time.Until(deadline).Seconds()})
+	}
+	r.GET("/books/:id", remaining)
+	r.POST("/books/:id/cover", remaining)
+	r.GET("/stream", remaining)
+	// 驱动返回的错误不一定包装了ctx的错误，按请求ctx的状态判断为超时
+	r.GET("/slow", func(ctx *gin.Context) {
+		<-ctx.Request.Context().Done()
+		ctx.Error(errors.New("driver: bad connection"))
+	})
+
+	for _, tc := range []struct {
+		method, path string
+		min, max     float64
+	}{
+		{http.MethodGet, "/books/B1", 0, 0.02},
+		{http.MethodPost, "/books/B1/cover", 59, 60},
+		{http.MethodGet, "/stream", -1, -1},
+	} {
+		code, resp := doJSON(t, r, tc.method, tc.path, nil)
+		got, _ := resp["data"].(float64)
+		if code != http.StatusOK || got < tc.min || got > tc.max {
+			t.Errorf("%s %s 剩余时限 = %v，期望在 [%v, %v] 之间", tc.method, tc.path, got, tc.min, tc.max)
+		}
+	}
+
+	code, resp := doJSON(t, r, http.MethodGet, "/slow", nil)
+	if code != http.StatusGatewayTimeout || resp["code"] != "TIMEOUT" {
+		t.Errorf("超时请求 = %d %v，期望 504 TIMEOUT", code, resp)
+	}
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"backend/logging"
+	"backend/tracing"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 为每个请求创建服务端span，沿用请求头中W3C traceparent/tracestate传入的链路；
+// span名为方法和路由模板（如 POST /borrow），业务层和DAO的span都是它的子span，需在RequestID之后注册
+func Tracing() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		parent := otel.GetTextMapPropagator().Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+
+		route := ctx.FullPath()
+		name := ctx.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+		spanCtx, span := tracing.Tracer().Start(parent, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", ctx.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", ctx.Request.URL.Path),
+				attribute.String("client.address", ctx.ClientIP()),
+				attribute.String("library.request_id", logging.RequestID(ctx.Request.Context())),
+			))
+		// 处理函数panic时记录后交给Recovery处理
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				span.SetStatus(codes.Error, fmt.Sprint(recovered))
+				span.End()
+				panic(recovered)
+			}
+		}()
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		for _, err := range ctx.Errors {
+			span.RecordError(err.Err)
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/tracing"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	if _, err := tracing.Setup(context.Background(), tracing.Options{Exporter: "none"}); err != nil {
+		t.Fatal(err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID(), Tracing(), ErrorHandler())
+	r.GET("/books/:id", func(ctx *gin.Context) {
+		_, span := tracing.Start(ctx.Request.Context(), "BookService.GetBookDetail")
+		span.End()
+		respondOK(ctx, "", ctx.Param("id"))
+	})
+	r.GET("/broken", func(ctx *gin.Context) {
+		ctx.Error(apperr.New(apperr.CodeInternal))
+	})
+
+	// 沿用上游传入的链路
+	req := httptest.NewRequest(http.MethodGet, "/books/B1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("span数 = %d，期望 2", len(spans))
+	}
+	child, server := spans[0], spans[1]
+	if server.Name() != "GET /books/:id" {
+		t.Errorf("span名 = %s", server.Name())
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s，期望沿用traceparent", got)
+	}
+	if got := server.Parent().SpanID().String(); got != "00f067aa0ba902b7" || !server.Parent().IsRemote() {
+		t.Errorf("父span = %s，期望为上游的span", got)
+	}
+	if child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("业务层的span应为请求span的子span")
+	}
+	attrs := attribute.NewSet(server.Attributes()...)
+	if v, _ := attrs.Value("http.response.status_code"); v.AsInt64() != http.StatusOK {
+		t.Errorf("状态码属性 = %v", v)
+	}
+	if v, _ := attrs.Value("library.request_id"); v.AsString() == "" {
+		t.Error("缺少请求ID属性")
+	}
+
+	// 没有traceparent时开始新链路，5xx标记为错误
+	recorder.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+	spans = recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("span数 = %d，期望 1", len(spans))
+	}
+	if spans[0].Parent().IsValid() {
+		t.Error("没有traceparent时不应有父span")
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("5xx的span状态 = %v，期望 Error", spans[0].Status().Code)
+	}
+}
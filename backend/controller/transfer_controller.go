@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransferController struct {
+	transferService *service.TransferService
+}
+
+func NewTransferController(transferService *service.TransferService) *TransferController {
+	return &TransferController{transferService: transferService}
+}
+
+// 申请调拨
+func (c *TransferController) RequestTransfer(ctx *gin.Context) {
+	var request struct {
+		Barcode    string `json:"barcode" binding:"required"`
+		ToBranchID string `json:"to_branch_id" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	transfer, err := c.transferService.RequestTransfer(ctx.Request.Context(), request.Barcode, request.ToBranchID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.transfer_requested", transfer)
+}
+
+// 发出调拨
+func (c *TransferController) ShipTransfer(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperr.Invalid("invalid.transfer_id"))
+		return
+	}
+
+	if err := c.transferService.ShipTransfer(ctx.Request.Context(), id); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.transfer_shipped", nil)
+}
+
+// 签收调拨
+func (c *TransferController) ReceiveTransfer(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperr.Invalid("invalid.transfer_id"))
+		return
+	}
+
+	if err := c.transferService.ReceiveTransfer(ctx.Request.Context(), id); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.transfer_received", nil)
+}
+
+// 查询调拨单，可按status和branch_id过滤
+func (c *TransferController) ListTransfers(ctx *gin.Context) {
+	transfers, err := c.transferService.ListTransfers(ctx.Request.Context(), ctx.Query("status"), ctx.Query("branch_id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", transfers)
+}
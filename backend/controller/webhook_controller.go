@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"backend/apperr"
+	"backend/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookController 推送地址与投递记录管理，仅管理员可用
+type WebhookController struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookController(webhookService *service.WebhookService) *WebhookController {
+	return &WebhookController{webhookService: webhookService}
+}
+
+// 获取所有推送地址
+func (c *WebhookController) GetEndpoints(ctx *gin.Context) {
+	endpoints, err := c.webhookService.GetEndpoints(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", endpoints)
+}
+
+// 登记推送地址，返回的secret只显示这一次
+func (c *WebhookController) RegisterEndpoint(ctx *gin.Context) {
+	var request struct {
+		URL    string   `json:"url" binding:"required"`
+		Events []string `json:"events" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	endpoint, secret, err := c.webhookService.RegisterEndpoint(ctx.Request.Context(), request.URL, request.Events)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.webhook_registered", gin.H{
+		"endpoint": endpoint,
+		"secret":   secret,
+	})
+}
+
+// 启用推送地址
+func (c *WebhookController) EnableEndpoint(ctx *gin.Context) {
+	c.setEndpointEnabled(ctx, true)
+}
+
+// 停用推送地址
+func (c *WebhookController) DisableEndpoint(ctx *gin.Context) {
+	c.setEndpointEnabled(ctx, false)
+}
+
+func (c *WebhookController) setEndpointEnabled(ctx *gin.Context, enabled bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperr.Invalid("invalid.webhook_id"))
+		return
+	}
+
+	if err := c.webhookService.SetEndpointEnabled(ctx.Request.Context(), id, enabled); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.webhook_updated", nil)
+}
+
+// 查询投递记录，status=dead查看死信
+func (c *WebhookController) ListDeliveries(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		ctx.Error(apperr.Invalid("invalid.limit"))
+		return
+	}
+	endpointID, err := strconv.Atoi(ctx.DefaultQuery("endpoint_id", "0"))
+	if err != nil {
+		ctx.Error(apperr.Invalid("invalid.endpoint_id"))
+		return
+	}
+
+	deliveries, err := c.webhookService.ListDeliveries(ctx.Request.Context(), ctx.Query("status"), endpointID, limit)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "", deliveries)
+}
+
+// 重新推送一条未成功的投递
+func (c *WebhookController) RetryDelivery(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(apperr.Invalid("invalid.delivery_id"))
+		return
+	}
+
+	if err := c.webhookService.RetryDelivery(ctx.Request.Context(), id); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondOK(ctx, "ok.webhook_retry", nil)
+}
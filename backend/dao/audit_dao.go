@@ -0,0 +1,112 @@
+package dao
+
+import (
+	"backend/do"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+)
+
+// AuditDAO 审计日志，只提供写入和查询，数据库中的触发器拒绝修改和删除
+type AuditDAO struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewAuditDAO(db *sql.DB) *AuditDAO {
+	return &AuditDAO{db: db}
+}
+
+func NewAuditDAOTx(tx *sql.Tx) *AuditDAO {
+	return &AuditDAO{tx: tx}
+}
+
+func (dao *AuditDAO) getExecutor() executor {
+	return newExecutor(dao.db, dao.tx)
+}
+
+// 写入审计日志，应与被记录的业务操作在同一事务中调用
+func (dao *AuditDAO) RecordAudit(ctx context.Context, entry *do.AuditEntry) error {
+	query := `INSERT INTO audit_log
+		(occurred_at, actor_role, actor_id, client_ip, request_id, action, entity_type, entity_id, before_value, after_value)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	executor := dao.getExecutor()
+	id, err := executor.Insert(ctx, query, entry.OccurredAt, entry.ActorRole, entry.ActorID, entry.ClientIP, entry.RequestID,
+		entry.Action, entry.EntityType, entry.EntityID, nullJSON(entry.Before), nullJSON(entry.After))
+	if err != nil {
+		return err
+	}
+	entry.ID = id
+	return nil
+}
+
+// 按条件查询审计日志，按ID从新到旧排列
+func (dao *AuditDAO) ListAuditEntries(ctx context.Context, filter do.AuditFilter) ([]do.AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+	for _, c := range []struct {
+		column string
+		value  string
+	}{
+		{"action", filter.Action},
+		{"entity_type", filter.EntityType},
+		{"entity_id", filter.EntityID},
+		{"actor_id", filter.ActorID},
+	} {
+		if c.value != "" {
+			conditions = append(conditions, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "occurred_at >= ?")
+		args = append(args, *filter.Since)
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "occurred_at < ?")
+		args = append(args, *filter.Until)
+	}
+	if filter.BeforeID > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, filter.BeforeID)
+	}
+
+	query := `SELECT id, occurred_at, actor_role, actor_id, client_ip, request_id, action, entity_type, entity_id, before_value, after_value
+		FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []do.AuditEntry{}
+	for rows.Next() {
+		var entry do.AuditEntry
+		var before, after sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.OccurredAt, &entry.ActorRole, &entry.ActorID, &entry.ClientIP, &entry.RequestID,
+			&entry.Action, &entry.EntityType, &entry.EntityID, &before, &after); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			entry.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			entry.After = json.RawMessage(after.String)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// 空值保存为NULL
+func nullJSON(value json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(value), Valid: len(value) > 0}
+}
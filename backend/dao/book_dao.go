@@ -1,9 +1,10 @@
 package dao
 
 import (
-	"database/sql"
-	"fmt"
+	"backend/apperr"
 	"backend/do"
+	"context"
+	"database/sql"
 )
 
 type BookDAO struct {
@@ -19,128 +20,192 @@ func NewBookDAOTx(tx *sql.Tx) *BookDAO {
 	return &BookDAO{tx: tx}
 }
 
-func (dao *BookDAO) getExecutor() interface {
-	Query(query string, args ...interface{}) (*sql.Rows, error)
-	QueryRow(query string, args ...interface{}) *sql.Row
-	Exec(query string, args ...interface{}) (sql.Result, error)
-} {
-	if dao.tx != nil {
-		return dao.tx
-	}
-	return dao.db
+func (dao *BookDAO) getExecutor() executor {
+	return newExecutor(dao.db, dao.tx)
 }
 
-// 根据书名或作者查找书籍
-func (dao *BookDAO) FindBooksByTitleOrAuthor(keyword string) ([]do.Book, error) {
-	query := `
-		SELECT book_id, title, author, description, total_copies, available_copies, can_borrow, created_at
-		FROM books 
-		WHERE (title LIKE ? OR author LIKE ?) AND can_borrow = true
-	`
-	
-	executor := dao.getExecutor()
-	rows, err := executor.Query(query, "%"+keyword+"%", "%"+keyword+"%")
+// books表查询列，与scanBook的扫描顺序一致
+const bookColumns = "book_id, title, author, description, total_copies, available_copies, can_borrow, cover_key, created_at"
+
+// 扫描一行书籍数据
+func scanBook(row rowScanner) (*do.Book, error) {
+	var book do.Book
+	var coverKey sql.NullString
+	err := row.Scan(
+		&book.BookID,
+		&book.Title,
+		&book.Author,
+		&book.Description,
+		&book.TotalCopies,
+		&book.AvailableCopies,
+		&book.CanBorrow,
+		&coverKey,
+		&book.CreatedAt,
+	)
 	if err != nil {
 		return nil, err
 	}
+	book.CoverKey = coverKey.String
+	return &book, nil
+}
+
+// 扫描多行书籍数据
+func scanBooks(rows *sql.Rows) ([]do.Book, error) {
 	defer rows.Close()
 
 	var books []do.Book
 	for rows.Next() {
-		var book do.Book
-		err := rows.Scan(
-			&book.BookID,
-			&book.Title,
-			&book.Author,
-			&book.Description,
-			&book.TotalCopies,
-			&book.AvailableCopies,
-			&book.CanBorrow,
-			&book.CreatedAt,
-		)
+		book, err := scanBook(rows)
 		if err != nil {
 			return nil, err
 		}
-		books = append(books, book)
+		books = append(books, *book)
 	}
 
-	return books, nil
+	return books, rows.Err()
 }
 
-// 根据图书ID获取书籍信息
-func (dao *BookDAO) GetBookByID(bookID string) (*do.Book, error) {
+// 根据书名或作者查找书籍
+func (dao *BookDAO) FindBooksByTitleOrAuthor(ctx context.Context, keyword string) ([]do.Book, error) {
 	query := `
-		SELECT book_id, title, author, description, total_copies, available_copies, can_borrow, created_at
-		FROM books 
+		SELECT ` + bookColumns + `
+		FROM books
+		WHERE (title ` + current.like() + ` ? OR author ` + current.like() + ` ?) AND can_borrow = true
+	`
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, "%"+keyword+"%", "%"+keyword+"%")
+	if err != nil {
+		return nil, err
+	}
+
+	return scanBooks(rows)
+}
+
+// 根据图书ID获取书籍信息，在事务中加行锁
+func (dao *BookDAO) GetBookByID(ctx context.Context, bookID string) (*do.Book, error) {
+	query := `
+		SELECT ` + bookColumns + `
+		FROM books
 		WHERE book_id = ?
 	`
-	
+	if dao.tx != nil {
+		query += forUpdate()
+	}
+
 	executor := dao.getExecutor()
-	row := executor.QueryRow(query, bookID)
-	
-	var book do.Book
-	err := row.Scan(
-		&book.BookID,
-		&book.Title,
-		&book.Author,
-		&book.Description,
-		&book.TotalCopies,
-		&book.AvailableCopies,
-		&book.CanBorrow,
-		&book.CreatedAt,
-	)
-	
+	book, err := scanBook(executor.QueryRow(ctx, query, bookID))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("书籍不存在")
+			return nil, apperr.New(apperr.CodeBookNotFound)
 		}
 		return nil, err
 	}
-	
-	return &book, nil
+
+	return book, nil
+}
+
+// 按图书ID批量获取书籍，不存在的ID不返回
+func (dao *BookDAO) GetBooksByIDs(ctx context.Context, bookIDs []string) ([]do.Book, error) {
+	if len(bookIDs) == 0 {
+		return nil, nil
+	}
+	placeholders, args := inList(bookIDs)
+	query := "SELECT " + bookColumns + " FROM books WHERE book_id IN " + placeholders
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanBooks(rows)
 }
 
 // 获取所有书籍列表
-func (dao *BookDAO) GetAllBooks() ([]do.Book, error) {
+func (dao *BookDAO) GetAllBooks(ctx context.Context) ([]do.Book, error) {
 	query := `
-		SELECT book_id, title, author, description, total_copies, available_copies, can_borrow, created_at
-		FROM books 
+		SELECT ` + bookColumns + `
+		FROM books
 		ORDER BY created_at DESC
 	`
-	
+
 	executor := dao.getExecutor()
-	rows, err := executor.Query(query)
+	rows, err := executor.Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var books []do.Book
-	for rows.Next() {
-		var book do.Book
-		err := rows.Scan(
-			&book.BookID,
-			&book.Title,
-			&book.Author,
-			&book.Description,
-			&book.TotalCopies,
-			&book.AvailableCopies,
-			&book.CanBorrow,
-			&book.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		books = append(books, book)
-	}
-
-	return books, nil
+	return scanBooks(rows)
 }
 
 // 更新书籍可借阅数量
-func (dao *BookDAO) UpdateBookAvailableCopies(bookID string, availableCopies int) error {
+func (dao *BookDAO) UpdateBookAvailableCopies(ctx context.Context, bookID string, availableCopies int) error {
 	query := "UPDATE books SET available_copies = ? WHERE book_id = ?"
 	executor := dao.getExecutor()
-	_, err := executor.Exec(query, availableCopies, bookID)
+	_, err := executor.Exec(ctx, query, availableCopies, bookID)
+	return err
+}
+
+// 可借阅数量减1，没有可借副本时返回BOOK_UNAVAILABLE；在原值上修改，并发借出不会覆盖彼此的结果
+func (dao *BookDAO) DecrementAvailableCopies(ctx context.Context, bookID string) error {
+	query := "UPDATE books SET available_copies = available_copies - 1 WHERE book_id = ? AND available_copies > 0"
+	executor := dao.getExecutor()
+	result, err := executor.Exec(ctx, query, bookID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apperr.New(apperr.CodeBookUnavailable)
+	}
+	return nil
+}
+
+// 可借阅数量加1，在原值上修改
+func (dao *BookDAO) IncrementAvailableCopies(ctx context.Context, bookID string) error {
+	query := "UPDATE books SET available_copies = available_copies + 1 WHERE book_id = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, bookID)
+	return err
+}
+
+// 新增书籍，由调用方设置created_at
+func (dao *BookDAO) CreateBook(ctx context.Context, book *do.Book) error {
+	query := `
+		INSERT INTO books (book_id, title, author, description, total_copies, available_copies, can_borrow, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, book.BookID, book.Title, book.Author, book.Description,
+		book.TotalCopies, book.AvailableCopies, book.CanBorrow, book.CreatedAt)
+	return err
+}
+
+// 更新书籍总馆藏和可借阅数量
+func (dao *BookDAO) UpdateBookCopies(ctx context.Context, bookID string, totalCopies, availableCopies int) error {
+	query := "UPDATE books SET total_copies = ?, available_copies = ? WHERE book_id = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, totalCopies, availableCopies, bookID)
+	return err
+}
+
+// 更新书籍封面存储键，传空字符串表示移除封面
+func (dao *BookDAO) UpdateBookCoverKey(ctx context.Context, bookID, coverKey string) error {
+	query := "UPDATE books SET cover_key = ? WHERE book_id = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, sql.NullString{String: coverKey, Valid: coverKey != ""}, bookID)
+	return err
+}
+
+// 根据单册重新计算总馆藏和可借数量；没有单册记录的书籍保持原有计数不变
+func (dao *BookDAO) SyncCopiesFromItems(ctx context.Context, bookID string) error {
+	query := `
+		UPDATE books
+		SET total_copies = (SELECT COUNT(*) FROM book_items WHERE book_id = ?),
+		    available_copies = (SELECT COUNT(*) FROM book_items WHERE book_id = ? AND status = 'available')
+		WHERE book_id = ? AND EXISTS (SELECT 1 FROM book_items WHERE book_id = ?)
+	`
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, bookID, bookID, bookID, bookID)
 	return err
 }
@@ -0,0 +1,172 @@
+package dao
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"context"
+	"database/sql"
+)
+
+type BookItemDAO struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewBookItemDAO(db *sql.DB) *BookItemDAO {
+	return &BookItemDAO{db: db}
+}
+
+func NewBookItemDAOTx(tx *sql.Tx) *BookItemDAO {
+	return &BookItemDAO{tx: tx}
+}
+
+func (dao *BookItemDAO) getExecutor() executor {
+	return newExecutor(dao.db, dao.tx)
+}
+
+// book_items表查询列，与scanBookItem的扫描顺序一致
+const bookItemColumns = "barcode, book_id, home_branch_id, current_branch_id, status, created_at"
+
+func scanBookItem(row rowScanner) (*do.BookItem, error) {
+	var item do.BookItem
+	err := row.Scan(
+		&item.Barcode,
+		&item.BookID,
+		&item.HomeBranchID,
+		&item.CurrentBranchID,
+		&item.Status,
+		&item.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// 新增单册
+func (dao *BookItemDAO) CreateItem(ctx context.Context, item *do.BookItem) error {
+	query := `
+		INSERT INTO book_items (barcode, book_id, home_branch_id, current_branch_id, status)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, item.Barcode, item.BookID, item.HomeBranchID, item.CurrentBranchID, item.Status)
+	return err
+}
+
+// 根据条码获取单册，在事务中加行锁
+func (dao *BookItemDAO) GetItemByBarcode(ctx context.Context, barcode string) (*do.BookItem, error) {
+	query := "SELECT " + bookItemColumns + " FROM book_items WHERE barcode = ?"
+	if dao.tx != nil {
+		query += forUpdate()
+	}
+
+	executor := dao.getExecutor()
+	item, err := scanBookItem(executor.QueryRow(ctx, query, barcode))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.New(apperr.CodeItemNotFound)
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
+// 获取某本书的所有单册
+func (dao *BookItemDAO) GetItemsByBookID(ctx context.Context, bookID string) ([]do.BookItem, error) {
+	query := "SELECT " + bookItemColumns + " FROM book_items WHERE book_id = ? ORDER BY barcode"
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []do.BookItem
+	for rows.Next() {
+		item, err := scanBookItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+// 统计某本书的单册数量
+func (dao *BookItemDAO) CountItems(ctx context.Context, bookID string) (int, error) {
+	executor := dao.getExecutor()
+	var count int
+	err := executor.QueryRow(ctx, "SELECT COUNT(*) FROM book_items WHERE book_id = ?", bookID).Scan(&count)
+	return count, err
+}
+
+// 查找一册在架可借的单册，branchID为空时不限分馆，优先返回位于所属分馆的单册
+// 没有可借单册时返回nil；在事务中加行锁，避免并发借出同一册
+func (dao *BookItemDAO) FindAvailableItem(ctx context.Context, bookID, branchID string) (*do.BookItem, error) {
+	query := "SELECT " + bookItemColumns + " FROM book_items WHERE book_id = ? AND status = ?"
+	args := []interface{}{bookID, do.ItemStatusAvailable}
+	if branchID != "" {
+		query += " AND current_branch_id = ?"
+		args = append(args, branchID)
+	}
+	query += " ORDER BY (home_branch_id = current_branch_id) DESC, barcode LIMIT 1"
+	if dao.tx != nil {
+		query += forUpdate()
+	}
+
+	executor := dao.getExecutor()
+	item, err := scanBookItem(executor.QueryRow(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
+// 更新单册所在分馆和状态
+func (dao *BookItemDAO) UpdateItemLocation(ctx context.Context, barcode, currentBranchID, status string) error {
+	query := "UPDATE book_items SET current_branch_id = ?, status = ? WHERE barcode = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, currentBranchID, status, barcode)
+	return err
+}
+
+// 按分馆统计某本书的馆藏：Total为归属该分馆的册数，Available为当前在该分馆架上可借的册数
+func (dao *BookItemDAO) GetBranchAvailability(ctx context.Context, bookID string) ([]do.BranchAvailability, error) {
+	query := `
+		SELECT br.branch_id, br.name,
+		       (SELECT COUNT(*) FROM book_items bi
+		        WHERE bi.book_id = ? AND bi.home_branch_id = br.branch_id),
+		       (SELECT COUNT(*) FROM book_items bi
+		        WHERE bi.book_id = ? AND bi.current_branch_id = br.branch_id AND bi.status = 'available')
+		FROM branches br
+		ORDER BY br.branch_id
+	`
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, bookID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []do.BranchAvailability
+	for rows.Next() {
+		var availability do.BranchAvailability
+		err := rows.Scan(
+			&availability.BranchID,
+			&availability.BranchName,
+			&availability.Total,
+			&availability.Available,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, availability)
+	}
+	return result, rows.Err()
+}
@@ -1,9 +1,10 @@
 package dao
 
 import (
+	"backend/apperr"
 	"backend/do"
+	"context"
 	"database/sql"
-	"fmt"
 	"time"
 )
 
@@ -20,121 +21,212 @@ func NewBorrowDAOTx(tx *sql.Tx) *BorrowDAO {
 	return &BorrowDAO{tx: tx}
 }
 
-func (dao *BorrowDAO) getExecutor() interface {
-	Query(query string, args ...interface{}) (*sql.Rows, error)
-	QueryRow(query string, args ...interface{}) *sql.Row
-	Exec(query string, args ...interface{}) (sql.Result, error)
-} {
-	if dao.tx != nil {
-		return dao.tx
+func (dao *BorrowDAO) getExecutor() executor {
+	return newExecutor(dao.db, dao.tx)
+}
+
+// borrow_records表查询列，与scanBorrowRecord的扫描顺序一致
+const borrowRecordColumns = "id, stu_id, book_id, barcode, borrow_date, due_date, return_date, is_overdue, fine_amount, created_at"
+
+// 扫描一行借阅记录
+func scanBorrowRecord(row rowScanner) (*do.BorrowRecord, error) {
+	var record do.BorrowRecord
+	var barcode sql.NullString
+	err := row.Scan(
+		&record.ID,
+		&record.StuID,
+		&record.BookID,
+		&barcode,
+		&record.BorrowDate,
+		&record.DueDate,
+		&record.ReturnDate,
+		&record.IsOverdue,
+		&record.FineAmount,
+		&record.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
 	}
-	return dao.db
+	record.Barcode = barcode.String
+	return &record, nil
 }
 
-// 创建借阅记录
-func (dao *BorrowDAO) CreateBorrowRecord(record *do.BorrowRecord) error {
+// 创建借阅记录，成功后回填自增ID
+func (dao *BorrowDAO) CreateBorrowRecord(ctx context.Context, record *do.BorrowRecord) error {
 	query := `
-		INSERT INTO borrow_records (stu_id, book_id, borrow_date, due_date, return_date, is_overdue, fine_amount)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO borrow_records (stu_id, book_id, barcode, borrow_date, due_date, return_date, is_overdue, fine_amount)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	executor := dao.getExecutor()
-	_, err := executor.Exec(
+	id, err := executor.Insert(ctx,
 		query,
 		record.StuID,
 		record.BookID,
+		sql.NullString{String: record.Barcode, Valid: record.Barcode != ""},
 		record.BorrowDate,
 		record.DueDate,
 		record.ReturnDate,
 		record.IsOverdue,
 		record.FineAmount,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	record.ID = int(id)
+	return nil
 }
 
-// 根据学号和图书ID获取借阅记录
-func (dao *BorrowDAO) GetBorrowRecord(stuID, bookID string) (*do.BorrowRecord, error) {
+// 根据学号和图书ID获取未归还的借阅记录，在事务中加行锁
+func (dao *BorrowDAO) GetBorrowRecord(ctx context.Context, stuID, bookID string) (*do.BorrowRecord, error) {
 	query := `
-		SELECT id, stu_id, book_id, borrow_date, due_date, return_date, is_overdue, fine_amount, created_at
-		FROM borrow_records 
+		SELECT ` + borrowRecordColumns + `
+		FROM borrow_records
 		WHERE stu_id = ? AND book_id = ? AND return_date IS NULL
 	`
+	if dao.tx != nil {
+		query += forUpdate()
+	}
 
 	executor := dao.getExecutor()
-	row := executor.QueryRow(query, stuID, bookID)
+	record, err := scanBorrowRecord(executor.QueryRow(ctx, query, stuID, bookID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.New(apperr.CodeLoanNotFound)
+		}
+		return nil, err
+	}
 
-	var record do.BorrowRecord
-	err := row.Scan(
-		&record.ID,
-		&record.StuID,
-		&record.BookID,
-		&record.BorrowDate,
-		&record.DueDate,
-		&record.ReturnDate,
-		&record.IsOverdue,
-		&record.FineAmount,
-		&record.CreatedAt,
-	)
+	return record, nil
+}
+
+// 根据单册条码获取未归还的借阅记录，在事务中加行锁
+func (dao *BorrowDAO) GetActiveBorrowRecordByBarcode(ctx context.Context, barcode string) (*do.BorrowRecord, error) {
+	query := `
+		SELECT ` + borrowRecordColumns + `
+		FROM borrow_records
+		WHERE barcode = ? AND return_date IS NULL
+	`
+	if dao.tx != nil {
+		query += forUpdate()
+	}
 
+	executor := dao.getExecutor()
+	record, err := scanBorrowRecord(executor.QueryRow(ctx, query, barcode))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("借阅记录不存在")
+			return nil, apperr.NewMessage(apperr.CodeLoanNotFound, "error.item_not_on_loan")
 		}
 		return nil, err
 	}
 
-	return &record, nil
+	return record, nil
 }
 
-// 还书操作
-func (dao *BorrowDAO) ReturnBook(stuID, bookID string, returnDate time.Time) error {
+// 还书操作：记录归还时间、是否逾期和罚款金额；借阅已归还时返回LOAN_NOT_FOUND
+func (dao *BorrowDAO) ReturnBorrowRecord(ctx context.Context, id int, returnDate time.Time, isOverdue bool, fineAmount float64) error {
 	query := `
-		UPDATE borrow_records 
-		SET return_date = ?, is_overdue = (due_date < ?)
-		WHERE stu_id = ? AND book_id = ? AND return_date IS NULL
+		UPDATE borrow_records
+		SET return_date = ?, is_overdue = ?, fine_amount = ?
+		WHERE id = ? AND return_date IS NULL
 	`
 
 	executor := dao.getExecutor()
-	_, err := executor.Exec(query, returnDate, returnDate, stuID, bookID)
+	result, err := executor.Exec(ctx, query, returnDate, isOverdue, fineAmount, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apperr.New(apperr.CodeLoanNotFound)
+	}
+	return nil
+}
+
+// 获取now之前到期仍未归还的借阅记录，按应还日期排序
+func (dao *BorrowDAO) GetOverdueBorrowRecords(ctx context.Context, now time.Time) ([]do.BorrowRecord, error) {
+	query := `
+		SELECT ` + borrowRecordColumns + `
+		FROM borrow_records
+		WHERE return_date IS NULL AND due_date < ?
+		ORDER BY due_date, id
+	`
+	return dao.queryBorrowRecords(ctx, query, now)
+}
+
+// 获取学生名下有罚款的借阅记录，包括已归还的
+func (dao *BorrowDAO) GetStudentFinedRecords(ctx context.Context, stuID string) ([]do.BorrowRecord, error) {
+	query := `
+		SELECT ` + borrowRecordColumns + `
+		FROM borrow_records
+		WHERE stu_id = ? AND fine_amount > 0
+		ORDER BY id
+	`
+	return dao.queryBorrowRecords(ctx, query, stuID)
+}
+
+// 更新借阅记录的逾期状态和罚款金额，不改变归还时间
+func (dao *BorrowDAO) UpdateFine(ctx context.Context, id int, isOverdue bool, fineAmount float64) error {
+	query := "UPDATE borrow_records SET is_overdue = ?, fine_amount = ? WHERE id = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, isOverdue, fineAmount, id)
 	return err
 }
 
-// 检查是否逾期并计算罚款
-func (dao *BorrowDAO) CheckOverdueAndCalculateFine(stuID, bookID string, currentDate time.Time) (bool, float64, error) {
-	record, err := dao.GetBorrowRecord(stuID, bookID)
+// 获取学生的所有借阅记录
+func (dao *BorrowDAO) GetStudentBorrowRecords(ctx context.Context, stuID string) ([]do.BorrowRecord, error) {
+	query := `
+		SELECT ` + borrowRecordColumns + `
+		FROM borrow_records
+		WHERE stu_id = ? AND return_date IS NULL
+	`
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, stuID)
 	if err != nil {
-		return false, 0, err
+		return nil, err
 	}
+	defer rows.Close()
 
-	if currentDate.After(record.DueDate) {
-		// 计算逾期天数
-		daysOverdue := int(currentDate.Sub(record.DueDate).Hours() / 24)
-		fineAmount := float64(daysOverdue) * 0.5 // 每天0.5元罚款
-
-		// 更新罚款金额
-		updateQuery := "UPDATE borrow_records SET is_overdue = true, fine_amount = ? WHERE id = ?"
-		executor := dao.getExecutor()
-		_, err := executor.Exec(updateQuery, fineAmount, record.ID)
+	var records []do.BorrowRecord
+	for rows.Next() {
+		record, err := scanBorrowRecord(rows)
 		if err != nil {
-			return false, 0, err
+			return nil, err
 		}
-
-		return true, fineAmount, nil
+		records = append(records, *record)
 	}
 
-	return false, 0, nil
+	return records, rows.Err()
 }
 
-// 获取学生的所有借阅记录
-func (dao *BorrowDAO) GetStudentBorrowRecords(stuID string) ([]do.BorrowRecord, error) {
+// 批量获取若干学生未归还的借阅记录，按借出时间从新到旧
+func (dao *BorrowDAO) GetActiveBorrowRecordsByStudentIDs(ctx context.Context, stuIDs []string) ([]do.BorrowRecord, error) {
+	return dao.getActiveBorrowRecordsIn(ctx, "stu_id", stuIDs)
+}
+
+// 批量获取若干图书未归还的借阅记录，按借出时间从新到旧
+func (dao *BorrowDAO) GetActiveBorrowRecordsByBookIDs(ctx context.Context, bookIDs []string) ([]do.BorrowRecord, error) {
+	return dao.getActiveBorrowRecordsIn(ctx, "book_id", bookIDs)
+}
+
+func (dao *BorrowDAO) getActiveBorrowRecordsIn(ctx context.Context, column string, values []string) ([]do.BorrowRecord, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	placeholders, args := inList(values)
 	query := `
-		SELECT id, stu_id, book_id, borrow_date, due_date, return_date, is_overdue, fine_amount, created_at
-		FROM borrow_records 
-		WHERE stu_id = ? AND return_date IS NULL
+		SELECT ` + borrowRecordColumns + `
+		FROM borrow_records
+		WHERE ` + column + ` IN ` + placeholders + ` AND return_date IS NULL
+		ORDER BY borrow_date DESC, id DESC
 	`
+	return dao.queryBorrowRecords(ctx, query, args...)
+}
 
+// 执行查询并扫描多行借阅记录，查询列为borrowRecordColumns
+func (dao *BorrowDAO) queryBorrowRecords(ctx context.Context, query string, args ...interface{}) ([]do.BorrowRecord, error) {
 	executor := dao.getExecutor()
-	rows, err := executor.Query(query, stuID)
+	rows, err := executor.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -142,31 +234,20 @@ func (dao *BorrowDAO) GetStudentBorrowRecords(stuID string) ([]do.BorrowRecord,
 
 	var records []do.BorrowRecord
 	for rows.Next() {
-		var record do.BorrowRecord
-		err := rows.Scan(
-			&record.ID,
-			&record.StuID,
-			&record.BookID,
-			&record.BorrowDate,
-			&record.DueDate,
-			&record.ReturnDate,
-			&record.IsOverdue,
-			&record.FineAmount,
-			&record.CreatedAt,
-		)
+		record, err := scanBorrowRecord(rows)
 		if err != nil {
 			return nil, err
 		}
-		records = append(records, record)
+		records = append(records, *record)
 	}
 
-	return records, nil
+	return records, rows.Err()
 }
 
 // 获取学生的借阅记录（包含图书信息）
-func (dao *BorrowDAO) GetStudentBorrowRecordsWithBookInfo(stuID string) ([]map[string]interface{}, error) {
+func (dao *BorrowDAO) GetStudentBorrowRecordsWithBookInfo(ctx context.Context, stuID string) ([]map[string]interface{}, error) {
 	query := `
-		SELECT br.id, br.stu_id, br.book_id, br.borrow_date, br.due_date, br.return_date, 
+		SELECT br.id, br.stu_id, br.book_id, br.barcode, br.borrow_date, br.due_date, br.return_date,
 		       br.is_overdue, br.fine_amount, br.created_at,
 		       b.title, b.author
 		FROM borrow_records br
@@ -176,7 +257,7 @@ func (dao *BorrowDAO) GetStudentBorrowRecordsWithBookInfo(stuID string) ([]map[s
 	`
 
 	executor := dao.getExecutor()
-	rows, err := executor.Query(query, stuID)
+	rows, err := executor.Query(ctx, query, stuID)
 	if err != nil {
 		return nil, err
 	}
@@ -185,12 +266,14 @@ func (dao *BorrowDAO) GetStudentBorrowRecordsWithBookInfo(stuID string) ([]map[s
 	var records []map[string]interface{}
 	for rows.Next() {
 		var record do.BorrowRecord
+		var barcode sql.NullString
 		var title, author string
 
 		err := rows.Scan(
 			&record.ID,
 			&record.StuID,
 			&record.BookID,
+			&barcode,
 			&record.BorrowDate,
 			&record.DueDate,
 			&record.ReturnDate,
@@ -209,6 +292,7 @@ func (dao *BorrowDAO) GetStudentBorrowRecordsWithBookInfo(stuID string) ([]map[s
 			"id":          record.ID,
 			"stu_id":      record.StuID,
 			"book_id":     record.BookID,
+			"barcode":     barcode.String,
 			"borrow_date": record.BorrowDate,
 			"due_date":    record.DueDate,
 			"return_date": record.ReturnDate,
@@ -0,0 +1,86 @@
+package dao
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"context"
+	"database/sql"
+)
+
+type BranchDAO struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewBranchDAO(db *sql.DB) *BranchDAO {
+	return &BranchDAO{db: db}
+}
+
+func NewBranchDAOTx(tx *sql.Tx) *BranchDAO {
+	return &BranchDAO{tx: tx}
+}
+
+func (dao *BranchDAO) getExecutor() executor {
+	return newExecutor(dao.db, dao.tx)
+}
+
+// 创建分馆
+func (dao *BranchDAO) CreateBranch(ctx context.Context, branch *do.Branch) error {
+	query := "INSERT INTO branches (branch_id, name, address) VALUES (?, ?, ?)"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, branch.BranchID, branch.Name, branch.Address)
+	return err
+}
+
+// 根据分馆ID获取分馆信息
+func (dao *BranchDAO) GetBranchByID(ctx context.Context, branchID string) (*do.Branch, error) {
+	query := `
+		SELECT branch_id, name, address, created_at
+		FROM branches
+		WHERE branch_id = ?
+	`
+
+	executor := dao.getExecutor()
+	var branch do.Branch
+	err := executor.QueryRow(ctx, query, branchID).Scan(
+		&branch.BranchID,
+		&branch.Name,
+		&branch.Address,
+		&branch.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.New(apperr.CodeBranchNotFound)
+		}
+		return nil, err
+	}
+
+	return &branch, nil
+}
+
+// 获取所有分馆
+func (dao *BranchDAO) GetAllBranches(ctx context.Context) ([]do.Branch, error) {
+	query := `
+		SELECT branch_id, name, address, created_at
+		FROM branches
+		ORDER BY branch_id
+	`
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var branches []do.Branch
+	for rows.Next() {
+		var branch do.Branch
+		if err := rows.Scan(&branch.BranchID, &branch.Name, &branch.Address, &branch.CreatedAt); err != nil {
+			return nil, err
+		}
+		branches = append(branches, branch)
+	}
+
+	return branches, rows.Err()
+}
@@ -0,0 +1,115 @@
+package dao
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"context"
+	"database/sql"
+)
+
+type CalendarDAO struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewCalendarDAO(db *sql.DB) *CalendarDAO {
+	return &CalendarDAO{db: db}
+}
+
+func NewCalendarDAOTx(tx *sql.Tx) *CalendarDAO {
+	return &CalendarDAO{tx: tx}
+}
+
+func (dao *CalendarDAO) getExecutor() executor {
+	return newExecutor(dao.db, dao.tx)
+}
+
+// 获取每周开放时间，未设置的星期视为正常开放
+func (dao *CalendarDAO) GetOpeningHours(ctx context.Context) ([]do.OpeningHours, error) {
+	query := `
+		SELECT weekday, open_time, close_time, closed
+		FROM library_hours
+		ORDER BY weekday
+	`
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hours []do.OpeningHours
+	for rows.Next() {
+		var h do.OpeningHours
+		if err := rows.Scan(&h.Weekday, &h.OpenTime, &h.CloseTime, &h.Closed); err != nil {
+			return nil, err
+		}
+		hours = append(hours, h)
+	}
+
+	return hours, rows.Err()
+}
+
+// 设置某个星期的开放时间
+func (dao *CalendarDAO) SetOpeningHours(ctx context.Context, hours *do.OpeningHours) error {
+	query := current.upsert("library_hours",
+		[]string{"weekday", "open_time", "close_time", "closed"},
+		[]string{"weekday"},
+		[]string{"open_time", "close_time", "closed"})
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, hours.Weekday, hours.OpenTime, hours.CloseTime, hours.Closed)
+	return err
+}
+
+// 创建闭馆日期段
+func (dao *CalendarDAO) CreateClosure(ctx context.Context, closure *do.LibraryClosure) (int, error) {
+	query := "INSERT INTO library_closures (name, kind, start_date, end_date) VALUES (?, ?, ?, ?)"
+	executor := dao.getExecutor()
+	id, err := executor.Insert(ctx, query, closure.Name, closure.Kind, closure.StartDate, closure.EndDate)
+	return int(id), err
+}
+
+// 删除闭馆日期段
+func (dao *CalendarDAO) DeleteClosure(ctx context.Context, id int) error {
+	executor := dao.getExecutor()
+	result, err := executor.Exec(ctx, "DELETE FROM library_closures WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apperr.New(apperr.CodeClosureNotFound)
+	}
+	return nil
+}
+
+// 获取与[from, to]有交集的闭馆日期段，日期格式为 2006-01-02
+func (dao *CalendarDAO) GetClosures(ctx context.Context, from, to string) ([]do.LibraryClosure, error) {
+	query := `
+		SELECT id, name, kind, start_date, end_date, created_at
+		FROM library_closures
+		WHERE end_date >= ? AND start_date <= ?
+		ORDER BY start_date, id
+	`
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var closures []do.LibraryClosure
+	for rows.Next() {
+		var closure do.LibraryClosure
+		var startDate, endDate dateColumn
+		if err := rows.Scan(&closure.ID, &closure.Name, &closure.Kind, &startDate, &endDate, &closure.CreatedAt); err != nil {
+			return nil, err
+		}
+		closure.StartDate = string(startDate)
+		closure.EndDate = string(endDate)
+		closures = append(closures, closure)
+	}
+
+	return closures, rows.Err()
+}
@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"backend/do"
+	"context"
+	"database/sql"
+)
+
+type CirculationOverrideDAO struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewCirculationOverrideDAO(db *sql.DB) *CirculationOverrideDAO {
+	return &CirculationOverrideDAO{db: db}
+}
+
+func NewCirculationOverrideDAOTx(tx *sql.Tx) *CirculationOverrideDAO {
+	return &CirculationOverrideDAO{tx: tx}
+}
+
+func (dao *CirculationOverrideDAO) getExecutor() executor {
+	return newExecutor(dao.db, dao.tx)
+}
+
+// 记录一次强制借出
+func (dao *CirculationOverrideDAO) CreateOverride(ctx context.Context, override *do.CirculationOverride) error {
+	query := `
+		INSERT INTO circulation_overrides (librarian_id, stu_id, barcode, block_code, reason)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx,
+		query,
+		override.LibrarianID,
+		override.StuID,
+		sql.NullString{String: override.Barcode, Valid: override.Barcode != ""},
+		override.BlockCode,
+		override.Reason,
+	)
+	return err
+}
+
+// 查询强制借出记录，stuID为空时返回全部，按时间倒序
+func (dao *CirculationOverrideDAO) ListOverrides(ctx context.Context, stuID string, limit int) ([]do.CirculationOverride, error) {
+	query := "SELECT id, librarian_id, stu_id, barcode, block_code, reason, created_at FROM circulation_overrides"
+	var args []interface{}
+	if stuID != "" {
+		query += " WHERE stu_id = ?"
+		args = append(args, stuID)
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []do.CirculationOverride
+	for rows.Next() {
+		var override do.CirculationOverride
+		var barcode sql.NullString
+		err := rows.Scan(
+			&override.ID,
+			&override.LibrarianID,
+			&override.StuID,
+			&barcode,
+			&override.BlockCode,
+			&override.Reason,
+			&override.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		override.Barcode = barcode.String
+		overrides = append(overrides, override)
+	}
+	return overrides, rows.Err()
+}
@@ -4,22 +4,25 @@ import (
 	"database/sql"
 	"fmt"
 
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
-// 使用GORM创建数据库连接（用于表迁移）
-func NewGormDB(addr string) (*gorm.DB, error) {
-	db, err := gorm.Open(mysql.Open(addr), &gorm.Config{})
-	if err != nil {
-		return nil, err
+// 按数据库类型创建连接并设置DAO使用的SQL方言，表结构由migrations包维护
+func Open(driver, dsn string) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
+	switch driver {
+	case DriverMySQL:
+		db, err = sql.Open("mysql", dsn)
+	case DriverPostgres:
+		db, err = sql.Open("pgx", dsn)
+	case DriverSQLite:
+		// 纯Go实现的驱动（modernc.org/sqlite），不需要cgo
+		db = sql.OpenDB(newSQLiteConnector(dsn))
+	default:
+		return nil, fmt.Errorf("不支持的数据库类型: %s", driver)
 	}
-	return db, nil
-}
-
-// 使用标准SQL创建数据库连接（用于业务操作）
-func NewSQLDB(addr string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", addr)
 	if err != nil {
 		return nil, err
 	}
@@ -27,23 +30,17 @@ func NewSQLDB(addr string) (*sql.DB, error) {
 	// 测试连接
 	err = db.Ping()
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
+	current = dialect{name: driver}
 	return db, nil
 }
 
-type Table interface {
-	TableName() string
-}
-
-func CreateTables(db *gorm.DB, tables []Table) error {
-	for _, table := range tables {
-		if err := db.AutoMigrate(table); err != nil {
-			return err
-		}
-	}
-	return nil
+// rowScanner 兼容*sql.Row和*sql.Rows的扫描接口
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
 // 执行SQL脚本文件
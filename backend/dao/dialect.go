@@ -0,0 +1,277 @@
+package dao
+
+import (
+	"backend/do"
+	"backend/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 支持的数据库类型
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// sqliteTimeLayout SQLite中时间以UTC文本保存，与CURRENT_TIMESTAMP的格式一致，可以直接按字符串比较
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+// dialect 各数据库在SQL写法上的差异，DAO中的SQL以MySQL写法为准，其余数据库按方言改写
+type dialect struct {
+	name string
+}
+
+// 当前使用的方言，由Open设置；一个进程只连接一种数据库
+var current = dialect{name: DriverMySQL}
+
+// 在事务中锁定查询到的行；SQLite的写事务本身是串行的，不需要也不支持行锁
+func forUpdate() string {
+	if current.name == DriverSQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// 插入一行，keys上的主键或唯一键冲突时用新值更新updates中的列
+func (d dialect) upsert(table string, columns, keys, updates []string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders)
+
+	sets := make([]string, len(updates))
+	if d.name != DriverMySQL {
+		for i, column := range updates {
+			sets[i] = fmt.Sprintf("%s = excluded.%s", column, column)
+		}
+		return query + fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(keys, ", "), strings.Join(sets, ", "))
+	}
+	for i, column := range updates {
+		sets[i] = fmt.Sprintf("%s = VALUES(%s)", column, column)
+	}
+	return query + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+// 插入一行，主键或唯一键冲突时忽略；into为 INSERT INTO 之后的部分
+func (d dialect) insertIgnore(into string) string {
+	switch d.name {
+	case DriverSQLite:
+		return "INSERT OR IGNORE INTO " + into
+	case DriverPostgres:
+		return "INSERT INTO " + into + " ON CONFLICT DO NOTHING"
+	}
+	return "INSERT IGNORE INTO " + into
+}
+
+// IN 条件的占位符和参数，如 (?, ?, ?)；调用方保证values不为空
+func inList(values []string) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")", args
+}
+
+// 不区分大小写的模糊匹配；MySQL和SQLite的LIKE默认不区分大小写，PostgreSQL需要使用ILIKE
+func (d dialect) like() string {
+	if d.name == DriverPostgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// 把 ? 占位符改写为PostgreSQL的 $1、$2……，单引号字符串中的问号保持不变
+func (d dialect) rebind(query string) string {
+	if d.name != DriverPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case c == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// 转换查询参数：SQLite没有时间类型，时间统一转为UTC文本
+func (d dialect) args(args []interface{}) []interface{} {
+	if d.name != DriverSQLite {
+		return args
+	}
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			converted[i] = v.UTC().Format(sqliteTimeLayout)
+		case *time.Time:
+			if v != nil {
+				converted[i] = v.UTC().Format(sqliteTimeLayout)
+			}
+		case sql.NullTime:
+			if v.Valid {
+				converted[i] = v.Time.UTC().Format(sqliteTimeLayout)
+			}
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
+
+// queryer *sql.DB和*sql.Tx共有的执行方法
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// executor 按当前方言改写SQL和参数后执行；ctx取消或超时时中止查询并释放连接
+type executor struct {
+	q queryer
+}
+
+func (e executor) Query(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	ctx, done := observeQuery(ctx, query)
+	defer func() { done(err) }()
+	return e.q.QueryContext(ctx, current.rebind(query), current.args(args)...)
+}
+
+func (e executor) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := observeQuery(ctx, query)
+	row := e.q.QueryRowContext(ctx, current.rebind(query), current.args(args)...)
+	done(row.Err())
+	return row
+}
+
+func (e executor) Exec(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
+	ctx, done := observeQuery(ctx, query)
+	defer func() { done(err) }()
+	return e.q.ExecContext(ctx, current.rebind(query), current.args(args)...)
+}
+
+// 为一条SQL创建span并在执行结束时记录日志；span名为操作和表名（如 SELECT books），
+// 等待行锁的时间计入SELECT ... FOR UPDATE的span
+func observeQuery(ctx context.Context, query string) (context.Context, func(err error)) {
+	start := time.Now()
+	statement := strings.Join(strings.Fields(query), " ")
+	operation, table := summarizeQuery(statement)
+	name := operation
+	if table != "" {
+		name += " " + table
+	}
+	ctx, span := tracing.Start(ctx, name,
+		attribute.String("db.system.name", current.name),
+		attribute.String("db.operation.name", operation),
+		attribute.String("db.collection.name", table),
+		attribute.String("db.query.text", statement),
+	)
+	return ctx, func(err error) {
+		// 没有结果行不算查询失败
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+		tracing.End(span, &err)
+		logQuery(ctx, statement, start)
+	}
+}
+
+var tablePattern = regexp.MustCompile(`(?i)\b(?:from|into|update|join)\s+([a-z_][a-z0-9_]*)`)
+
+// 取出SQL的操作（SELECT、INSERT等）和第一个表名，取不到表名时为空
+func summarizeQuery(statement string) (operation, table string) {
+	operation, _, _ = strings.Cut(statement, " ")
+	operation = strings.ToUpper(operation)
+	if match := tablePattern.FindStringSubmatch(statement); match != nil {
+		table = match[1]
+	}
+	return operation, table
+}
+
+// 慢查询阈值，由启动时的配置设置，0表示不记录慢查询
+var slowQueryThreshold time.Duration
+
+func SetSlowQueryThreshold(d time.Duration) {
+	slowQueryThreshold = d
+}
+
+// 记录SQL执行时间：超过慢查询阈值时记录警告，日志级别为debug时记录每条SQL；
+// Query只计到返回第一批结果为止，不含逐行读取的时间
+func logQuery(ctx context.Context, statement string, start time.Time) {
+	elapsed := time.Since(start)
+	level := slog.LevelDebug
+	if slowQueryThreshold > 0 && elapsed >= slowQueryThreshold {
+		level = slog.LevelWarn
+	}
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+	message := "执行SQL"
+	if level == slog.LevelWarn {
+		message = "慢查询"
+	}
+	slog.Log(ctx, level, message, "sql", statement, "elapsed", elapsed)
+}
+
+// 执行INSERT并返回自增主键；PostgreSQL驱动不支持LastInsertId，改用 RETURNING id
+func (e executor) Insert(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	if current.name == DriverPostgres {
+		var id int64
+		err := e.QueryRow(ctx, query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	result, err := e.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// 事务中的DAO使用事务执行，否则使用连接池
+func newExecutor(db *sql.DB, tx *sql.Tx) executor {
+	if tx != nil {
+		return executor{q: tx}
+	}
+	return executor{q: db}
+}
+
+// dateColumn 扫描DATE列：MySQL和PostgreSQL返回time.Time，SQLite以 2006-01-02 格式的文本保存
+type dateColumn string
+
+func (d *dateColumn) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case time.Time:
+		*d = dateColumn(v.Format(do.DateLayout))
+	case string:
+		*d = dateColumn(firstN(v, len(do.DateLayout)))
+	case []byte:
+		*d = dateColumn(firstN(string(v), len(do.DateLayout)))
+	default:
+		return fmt.Errorf("无法将%T转换为日期", src)
+	}
+	return nil
+}
+
+func firstN(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
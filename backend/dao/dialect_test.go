@@ -0,0 +1,74 @@
+package dao
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	postgres := dialect{name: DriverPostgres}
+	for query, want := range map[string]string{
+		"SELECT * FROM books WHERE book_id = ?":                   "SELECT * FROM books WHERE book_id = $1",
+		"UPDATE holds SET status = ? WHERE id = ? AND status = ?": "UPDATE holds SET status = $1 WHERE id = $2 AND status = $3",
+		"SELECT * FROM books WHERE title = '?' AND author = ?":    "SELECT * FROM books WHERE title = '?' AND author = $1",
+		"SELECT COUNT(*) FROM books":                              "SELECT COUNT(*) FROM books",
+	} {
+		if got := postgres.rebind(query); got != want {
+			t.Errorf("rebind(%q) = %q，期望 %q", query, got, want)
+		}
+	}
+
+	query := "SELECT * FROM books WHERE book_id = ?"
+	for _, name := range []string{DriverMySQL, DriverSQLite} {
+		if got := (dialect{name: name}).rebind(query); got != query {
+			t.Errorf("%s 不应改写占位符: %q", name, got)
+		}
+	}
+}
+
+func TestUpsertAndInsertIgnore(t *testing.T) {
+	columns, keys, updates := []string{"weekday", "open_time"}, []string{"weekday"}, []string{"open_time"}
+	for name, want := range map[string]string{
+		DriverMySQL:    "INSERT INTO library_hours (weekday, open_time) VALUES (?, ?) ON DUPLICATE KEY UPDATE open_time = VALUES(open_time)",
+		DriverPostgres: "INSERT INTO library_hours (weekday, open_time) VALUES (?, ?) ON CONFLICT (weekday) DO UPDATE SET open_time = excluded.open_time",
+		DriverSQLite:   "INSERT INTO library_hours (weekday, open_time) VALUES (?, ?) ON CONFLICT (weekday) DO UPDATE SET open_time = excluded.open_time",
+	} {
+		if got := (dialect{name: name}).upsert("library_hours", columns, keys, updates); got != want {
+			t.Errorf("%s upsert = %q，期望 %q", name, got, want)
+		}
+	}
+
+	into := "notifications (kind, ref_id) VALUES (?, ?)"
+	for name, want := range map[string]string{
+		DriverMySQL:    "INSERT IGNORE INTO notifications (kind, ref_id) VALUES (?, ?)",
+		DriverPostgres: "INSERT INTO notifications (kind, ref_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+		DriverSQLite:   "INSERT OR IGNORE INTO notifications (kind, ref_id) VALUES (?, ?)",
+	} {
+		if got := (dialect{name: name}).insertIgnore(into); got != want {
+			t.Errorf("%s insertIgnore = %q，期望 %q", name, got, want)
+		}
+	}
+}
+
+func TestInList(t *testing.T) {
+	placeholders, args := inList([]string{"B001", "B002", "B003"})
+	if placeholders != "(?, ?, ?)" {
+		t.Errorf("占位符 = %q", placeholders)
+	}
+	if len(args) != 3 || args[0] != "B001" || args[2] != "B003" {
+		t.Errorf("参数 = %v", args)
+	}
+}
+
+func TestSummarizeQuery(t *testing.T) {
+	for statement, want := range map[string][2]string{
+		"SELECT * FROM books WHERE book_id = ?":                                      {"SELECT", "books"},
+		"select br.id from borrow_records br JOIN books b ON b.book_id = br.book_id": {"SELECT", "borrow_records"},
+		"INSERT INTO audit_log (action) VALUES (?)":                                  {"INSERT", "audit_log"},
+		"UPDATE students SET can_borrow = ? WHERE stu_id = ?":                        {"UPDATE", "students"},
+		"DELETE FROM holds WHERE id = ?":                                             {"DELETE", "holds"},
+		"SELECT 1":                                                                   {"SELECT", ""},
+	} {
+		operation, table := summarizeQuery(statement)
+		if operation != want[0] || table != want[1] {
+			t.Errorf("summarizeQuery(%q) = %s %s，期望 %s %s", statement, operation, table, want[0], want[1])
+		}
+	}
+}
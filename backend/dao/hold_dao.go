@@ -0,0 +1,192 @@
+package dao
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type HoldDAO struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewHoldDAO(db *sql.DB) *HoldDAO {
+	return &HoldDAO{db: db}
+}
+
+func NewHoldDAOTx(tx *sql.Tx) *HoldDAO {
+	return &HoldDAO{tx: tx}
+}
+
+func (dao *HoldDAO) getExecutor() executor {
+	return newExecutor(dao.db, dao.tx)
+}
+
+// holds表查询列，与scanHold的扫描顺序一致
+const holdColumns = "id, stu_id, book_id, pickup_branch_id, barcode, status, ready_at, created_at"
+
+func scanHold(row rowScanner) (*do.Hold, error) {
+	var hold do.Hold
+	var barcode sql.NullString
+	err := row.Scan(
+		&hold.ID,
+		&hold.StuID,
+		&hold.BookID,
+		&hold.PickupBranchID,
+		&barcode,
+		&hold.Status,
+		&hold.ReadyAt,
+		&hold.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	hold.Barcode = barcode.String
+	return &hold, nil
+}
+
+func scanHolds(rows *sql.Rows) ([]do.Hold, error) {
+	defer rows.Close()
+
+	var holds []do.Hold
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, *hold)
+	}
+	return holds, rows.Err()
+}
+
+// 创建预约，返回自增ID
+func (dao *HoldDAO) CreateHold(ctx context.Context, hold *do.Hold) (int, error) {
+	query := `
+		INSERT INTO holds (stu_id, book_id, pickup_branch_id, status)
+		VALUES (?, ?, ?, ?)
+	`
+	executor := dao.getExecutor()
+	id, err := executor.Insert(ctx, query, hold.StuID, hold.BookID, hold.PickupBranchID, hold.Status)
+	return int(id), err
+}
+
+// 根据ID获取预约，在事务中加行锁
+func (dao *HoldDAO) GetHoldByID(ctx context.Context, id int) (*do.Hold, error) {
+	query := "SELECT " + holdColumns + " FROM holds WHERE id = ?"
+	if dao.tx != nil {
+		query += forUpdate()
+	}
+
+	executor := dao.getExecutor()
+	hold, err := scanHold(executor.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.New(apperr.CodeHoldNotFound)
+		}
+		return nil, err
+	}
+	return hold, nil
+}
+
+// 获取学生对某本书尚未结束的预约，没有时返回nil
+func (dao *HoldDAO) GetActiveHold(ctx context.Context, stuID, bookID string) (*do.Hold, error) {
+	query := "SELECT " + holdColumns + ` FROM holds
+		WHERE stu_id = ? AND book_id = ? AND status IN ('waiting', 'in_transit', 'ready')
+		LIMIT 1`
+
+	executor := dao.getExecutor()
+	hold, err := scanHold(executor.QueryRow(ctx, query, stuID, bookID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return hold, nil
+}
+
+// 获取某本书最早的排队预约，没有时返回nil
+func (dao *HoldDAO) GetNextWaitingHold(ctx context.Context, bookID string) (*do.Hold, error) {
+	query := "SELECT " + holdColumns + ` FROM holds
+		WHERE book_id = ? AND status = 'waiting'
+		ORDER BY created_at, id
+		LIMIT 1`
+	if dao.tx != nil {
+		query += forUpdate()
+	}
+
+	executor := dao.getExecutor()
+	hold, err := scanHold(executor.QueryRow(ctx, query, bookID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return hold, nil
+}
+
+// 获取学生的预约列表
+func (dao *HoldDAO) GetStudentHolds(ctx context.Context, stuID string) ([]do.Hold, error) {
+	query := "SELECT " + holdColumns + " FROM holds WHERE stu_id = ? ORDER BY created_at DESC"
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, stuID)
+	if err != nil {
+		return nil, err
+	}
+	return scanHolds(rows)
+}
+
+// 批量获取若干学生的预约，按创建时间从新到旧
+func (dao *HoldDAO) GetHoldsByStudentIDs(ctx context.Context, stuIDs []string) ([]do.Hold, error) {
+	if len(stuIDs) == 0 {
+		return nil, nil
+	}
+	placeholders, args := inList(stuIDs)
+	query := "SELECT " + holdColumns + " FROM holds WHERE stu_id IN " + placeholders + " ORDER BY created_at DESC, id DESC"
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanHolds(rows)
+}
+
+// 更新预约状态和分配的单册
+func (dao *HoldDAO) UpdateHoldStatus(ctx context.Context, id int, status, barcode string) error {
+	query := "UPDATE holds SET status = ?, barcode = ? WHERE id = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, status, sql.NullString{String: barcode, Valid: barcode != ""}, id)
+	return err
+}
+
+// 标记预约已到取书分馆
+func (dao *HoldDAO) MarkHoldReady(ctx context.Context, id int, barcode string, readyAt time.Time) error {
+	query := "UPDATE holds SET status = 'ready', barcode = ?, ready_at = ? WHERE id = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, barcode, readyAt, id)
+	return err
+}
+
+// 获取某册所在预约架对应的预约，没有时返回nil
+func (dao *HoldDAO) GetReadyHoldByBarcode(ctx context.Context, barcode string) (*do.Hold, error) {
+	query := "SELECT " + holdColumns + " FROM holds WHERE barcode = ? AND status = 'ready' LIMIT 1"
+	if dao.tx != nil {
+		query += forUpdate()
+	}
+
+	executor := dao.getExecutor()
+	hold, err := scanHold(executor.QueryRow(ctx, query, barcode))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return hold, nil
+}
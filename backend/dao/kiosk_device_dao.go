@@ -0,0 +1,129 @@
+package dao
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type KioskDeviceDAO struct {
+	db *sql.DB
+}
+
+func NewKioskDeviceDAO(db *sql.DB) *KioskDeviceDAO {
+	return &KioskDeviceDAO{db: db}
+}
+
+func (dao *KioskDeviceDAO) getExecutor() executor {
+	return newExecutor(dao.db, nil)
+}
+
+// kiosk_devices表查询列，与scanKioskDevice的扫描顺序一致
+const kioskDeviceColumns = "device_id, name, branch_id, api_key_hash, enabled, rate_limit_per_min, last_seen_at, created_at"
+
+func scanKioskDevice(row rowScanner) (*do.KioskDevice, error) {
+	var device do.KioskDevice
+	err := row.Scan(
+		&device.DeviceID,
+		&device.Name,
+		&device.BranchID,
+		&device.APIKeyHash,
+		&device.Enabled,
+		&device.RateLimitPerMin,
+		&device.LastSeenAt,
+		&device.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// 登记设备
+func (dao *KioskDeviceDAO) CreateDevice(ctx context.Context, device *do.KioskDevice) error {
+	query := `
+		INSERT INTO kiosk_devices (device_id, name, branch_id, api_key_hash, enabled, rate_limit_per_min)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	_, err := dao.getExecutor().Exec(ctx,
+		query,
+		device.DeviceID,
+		device.Name,
+		device.BranchID,
+		device.APIKeyHash,
+		device.Enabled,
+		device.RateLimitPerMin,
+	)
+	return err
+}
+
+// 根据设备ID获取设备
+func (dao *KioskDeviceDAO) GetDeviceByID(ctx context.Context, deviceID string) (*do.KioskDevice, error) {
+	query := "SELECT " + kioskDeviceColumns + " FROM kiosk_devices WHERE device_id = ?"
+	device, err := scanKioskDevice(dao.getExecutor().QueryRow(ctx, query, deviceID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.New(apperr.CodeDeviceNotFound)
+		}
+		return nil, err
+	}
+	return device, nil
+}
+
+// 根据API密钥哈希获取设备
+func (dao *KioskDeviceDAO) GetDeviceByKeyHash(ctx context.Context, keyHash string) (*do.KioskDevice, error) {
+	query := "SELECT " + kioskDeviceColumns + " FROM kiosk_devices WHERE api_key_hash = ?"
+	device, err := scanKioskDevice(dao.getExecutor().QueryRow(ctx, query, keyHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.New(apperr.CodeDeviceKeyInvalid)
+		}
+		return nil, err
+	}
+	return device, nil
+}
+
+// 获取所有设备
+func (dao *KioskDeviceDAO) GetAllDevices(ctx context.Context) ([]do.KioskDevice, error) {
+	rows, err := dao.getExecutor().Query(ctx, "SELECT "+kioskDeviceColumns+" FROM kiosk_devices ORDER BY device_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []do.KioskDevice
+	for rows.Next() {
+		device, err := scanKioskDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, *device)
+	}
+	return devices, rows.Err()
+}
+
+// 启用或停用设备
+func (dao *KioskDeviceDAO) UpdateDeviceEnabled(ctx context.Context, deviceID string, enabled bool) error {
+	_, err := dao.getExecutor().Exec(ctx, "UPDATE kiosk_devices SET enabled = ? WHERE device_id = ?", enabled, deviceID)
+	return err
+}
+
+// 更新设备API密钥哈希
+func (dao *KioskDeviceDAO) UpdateDeviceKeyHash(ctx context.Context, deviceID, keyHash string) error {
+	_, err := dao.getExecutor().Exec(ctx, "UPDATE kiosk_devices SET api_key_hash = ? WHERE device_id = ?", keyHash, deviceID)
+	return err
+}
+
+// 更新设备每分钟请求上限
+func (dao *KioskDeviceDAO) UpdateDeviceRateLimit(ctx context.Context, deviceID string, rateLimitPerMin int) error {
+	_, err := dao.getExecutor().Exec(ctx, "UPDATE kiosk_devices SET rate_limit_per_min = ? WHERE device_id = ?", rateLimitPerMin, deviceID)
+	return err
+}
+
+// 记录设备最近一次请求时间
+func (dao *KioskDeviceDAO) TouchDevice(ctx context.Context, deviceID string, seenAt time.Time) error {
+	_, err := dao.getExecutor().Exec(ctx, "UPDATE kiosk_devices SET last_seen_at = ? WHERE device_id = ?", seenAt, deviceID)
+	return err
+}
@@ -0,0 +1,46 @@
+package dao
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"context"
+	"database/sql"
+)
+
+type LibrarianDAO struct {
+	db *sql.DB
+}
+
+func NewLibrarianDAO(db *sql.DB) *LibrarianDAO {
+	return &LibrarianDAO{db: db}
+}
+
+func (dao *LibrarianDAO) getExecutor() executor {
+	return newExecutor(dao.db, nil)
+}
+
+// 根据工号获取馆员信息
+func (dao *LibrarianDAO) GetLibrarianByID(ctx context.Context, librarianID string) (*do.Librarian, error) {
+	query := `
+		SELECT librarian_id, name, password, role, created_at
+		FROM librarians
+		WHERE librarian_id = ?
+	`
+
+	var librarian do.Librarian
+	err := dao.getExecutor().QueryRow(ctx, query, librarianID).Scan(
+		&librarian.LibrarianID,
+		&librarian.Name,
+		&librarian.Password,
+		&librarian.Role,
+		&librarian.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.New(apperr.CodeLibrarianNotFound)
+		}
+		return nil, err
+	}
+
+	return &librarian, nil
+}
@@ -0,0 +1,272 @@
+package dao
+
+import (
+	"backend/do"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type NotificationDAO struct {
+	db *sql.DB
+}
+
+func NewNotificationDAO(db *sql.DB) *NotificationDAO {
+	return &NotificationDAO{db: db}
+}
+
+func (dao *NotificationDAO) getExecutor() executor {
+	return newExecutor(dao.db, nil)
+}
+
+// 获取学生的通知设置，没有设置时返回nil
+func (dao *NotificationDAO) GetPreference(ctx context.Context, stuID string) (*do.NotificationPreference, error) {
+	query := `
+		SELECT stu_id, email, language, due_soon, overdue, hold_ready, updated_at
+		FROM notification_preferences
+		WHERE stu_id = ?
+	`
+
+	var pref do.NotificationPreference
+	err := dao.getExecutor().QueryRow(ctx, query, stuID).Scan(
+		&pref.StuID,
+		&pref.Email,
+		&pref.Language,
+		&pref.DueSoon,
+		&pref.Overdue,
+		&pref.HoldReady,
+		&pref.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pref, nil
+}
+
+// 保存学生的通知设置
+func (dao *NotificationDAO) SavePreference(ctx context.Context, pref *do.NotificationPreference) error {
+	// 显式写入更新时间，SQLite不支持 on update current_timestamp
+	query := current.upsert("notification_preferences",
+		[]string{"stu_id", "email", "language", "due_soon", "overdue", "hold_ready", "updated_at"},
+		[]string{"stu_id"},
+		[]string{"email", "language", "due_soon", "overdue", "hold_ready", "updated_at"})
+	_, err := dao.getExecutor().Exec(ctx, query, pref.StuID, pref.Email, pref.Language, pref.DueSoon, pref.Overdue, pref.HoldReady, time.Now())
+	return err
+}
+
+// 查找在[from, to]内到期、学生订阅了到期提醒且尚未通知过的借阅记录
+func (dao *NotificationDAO) FindDueSoonCandidates(ctx context.Context, from, to time.Time) ([]do.NotificationCandidate, error) {
+	query := `
+		SELECT br.id, br.stu_id, s.name, p.email, p.language, b.title, COALESCE(br.barcode, ''), br.due_date
+		FROM borrow_records br
+		JOIN students s ON s.stu_id = br.stu_id
+		JOIN books b ON b.book_id = br.book_id
+		JOIN notification_preferences p ON p.stu_id = br.stu_id AND p.due_soon = true
+		WHERE br.return_date IS NULL AND br.due_date >= ? AND br.due_date <= ?
+		  AND NOT EXISTS (SELECT 1 FROM notifications n WHERE n.kind = 'due_soon' AND n.ref_id = br.id)
+		ORDER BY br.id
+	`
+	return dao.findLoanCandidates(ctx, do.NotificationKindDueSoon, query, from, to)
+}
+
+// 查找已逾期、学生订阅了逾期提醒且尚未通知过的借阅记录
+func (dao *NotificationDAO) FindOverdueCandidates(ctx context.Context, now time.Time) ([]do.NotificationCandidate, error) {
+	query := `
+		SELECT br.id, br.stu_id, s.name, p.email, p.language, b.title, COALESCE(br.barcode, ''), br.due_date
+		FROM borrow_records br
+		JOIN students s ON s.stu_id = br.stu_id
+		JOIN books b ON b.book_id = br.book_id
+		JOIN notification_preferences p ON p.stu_id = br.stu_id AND p.overdue = true
+		WHERE br.return_date IS NULL AND br.due_date < ?
+		  AND NOT EXISTS (SELECT 1 FROM notifications n WHERE n.kind = 'overdue' AND n.ref_id = br.id)
+		ORDER BY br.id
+	`
+	return dao.findLoanCandidates(ctx, do.NotificationKindOverdue, query, now)
+}
+
+func (dao *NotificationDAO) findLoanCandidates(ctx context.Context, kind, query string, args ...interface{}) ([]do.NotificationCandidate, error) {
+	rows, err := dao.getExecutor().Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []do.NotificationCandidate
+	for rows.Next() {
+		c := do.NotificationCandidate{Kind: kind}
+		if err := rows.Scan(&c.RefID, &c.StuID, &c.StudentName, &c.Email, &c.Language, &c.BookTitle, &c.Barcode, &c.DueDate); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// 查找已上预约架、学生订阅了到馆提醒且尚未通知过的预约
+func (dao *NotificationDAO) FindHoldReadyCandidates(ctx context.Context) ([]do.NotificationCandidate, error) {
+	query := `
+		SELECT h.id, h.stu_id, s.name, p.email, p.language, b.title, COALESCE(h.barcode, ''), br.name
+		FROM holds h
+		JOIN students s ON s.stu_id = h.stu_id
+		JOIN books b ON b.book_id = h.book_id
+		JOIN branches br ON br.branch_id = h.pickup_branch_id
+		JOIN notification_preferences p ON p.stu_id = h.stu_id AND p.hold_ready = true
+		WHERE h.status = 'ready'
+		  AND NOT EXISTS (SELECT 1 FROM notifications n WHERE n.kind = 'hold_ready' AND n.ref_id = h.id)
+		ORDER BY h.id
+	`
+
+	rows, err := dao.getExecutor().Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []do.NotificationCandidate
+	for rows.Next() {
+		c := do.NotificationCandidate{Kind: do.NotificationKindHoldReady}
+		if err := rows.Scan(&c.RefID, &c.StuID, &c.StudentName, &c.Email, &c.Language, &c.BookTitle, &c.Barcode, &c.PickupBranch); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// 创建通知，同一类型和关联记录已有通知时忽略
+func (dao *NotificationDAO) CreateNotification(ctx context.Context, n *do.Notification) error {
+	query := current.insertIgnore(`notifications (stu_id, kind, ref_id, email, subject, body, status, attempts, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?)`)
+	_, err := dao.getExecutor().Exec(ctx, query, n.StuID, n.Kind, n.RefID, n.Email, n.Subject, n.Body, n.Status, n.NextAttemptAt)
+	return err
+}
+
+// notifications表查询列，与scanNotification的扫描顺序一致
+const notificationColumns = "id, stu_id, kind, ref_id, email, subject, body, status, attempts, last_error, next_attempt_at, sent_at, created_at"
+
+func scanNotification(row rowScanner) (*do.Notification, error) {
+	var n do.Notification
+	var lastError sql.NullString
+	err := row.Scan(
+		&n.ID,
+		&n.StuID,
+		&n.Kind,
+		&n.RefID,
+		&n.Email,
+		&n.Subject,
+		&n.Body,
+		&n.Status,
+		&n.Attempts,
+		&lastError,
+		&n.NextAttemptAt,
+		&n.SentAt,
+		&n.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	n.LastError = lastError.String
+	return &n, nil
+}
+
+func scanNotifications(rows *sql.Rows) ([]do.Notification, error) {
+	defer rows.Close()
+
+	var notifications []do.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, *n)
+	}
+	return notifications, rows.Err()
+}
+
+// 获取到了发送时间的待发送通知
+func (dao *NotificationDAO) GetDueNotifications(ctx context.Context, now time.Time, limit int) ([]do.Notification, error) {
+	query := "SELECT " + notificationColumns + ` FROM notifications
+		WHERE status = 'pending' AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?`
+
+	rows, err := dao.getExecutor().Query(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanNotifications(rows)
+}
+
+// 认领一条待发送通知：把下次发送时间推迟到leaseUntil，成功认领返回true，
+// 避免多个实例同时发送同一条通知
+func (dao *NotificationDAO) ClaimNotification(ctx context.Context, id int, now, leaseUntil time.Time) (bool, error) {
+	query := `
+		UPDATE notifications SET next_attempt_at = ?
+		WHERE id = ? AND status = 'pending' AND next_attempt_at <= ?
+	`
+	result, err := dao.getExecutor().Exec(ctx, query, leaseUntil, id, now)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// 标记通知已发送
+func (dao *NotificationDAO) MarkSent(ctx context.Context, id int, sentAt time.Time) error {
+	query := `
+		UPDATE notifications SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = ?
+		WHERE id = ?
+	`
+	_, err := dao.getExecutor().Exec(ctx, query, sentAt, id)
+	return err
+}
+
+// 记录一次发送失败，status为pending时在nextAttemptAt重试，为failed时不再重试
+func (dao *NotificationDAO) MarkFailed(ctx context.Context, id int, status, lastError string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE notifications SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ?
+		WHERE id = ?
+	`
+	_, err := dao.getExecutor().Exec(ctx, query, status, lastError, nextAttemptAt, id)
+	return err
+}
+
+// 重新发送一条通知，清零重试次数
+func (dao *NotificationDAO) ResetNotification(ctx context.Context, id int, now time.Time) (bool, error) {
+	query := `
+		UPDATE notifications SET status = 'pending', attempts = 0, next_attempt_at = ?
+		WHERE id = ? AND status <> 'sent'
+	`
+	result, err := dao.getExecutor().Exec(ctx, query, now, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// 查询投递记录，stuID和status为空时不过滤
+func (dao *NotificationDAO) ListNotifications(ctx context.Context, stuID, status string, limit int) ([]do.Notification, error) {
+	query := "SELECT " + notificationColumns + " FROM notifications WHERE 1 = 1"
+	var args []interface{}
+	if stuID != "" {
+		query += " AND stu_id = ?"
+		args = append(args, stuID)
+	}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := dao.getExecutor().Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanNotifications(rows)
+}
@@ -0,0 +1,121 @@
+package dao
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// SQLite中时间以UTC文本保存，驱动读出的时间为UTC。
+// sqliteConnector包装驱动，把读出的时间转为本地时区，与MySQL（loc=Local）、PostgreSQL读出的时间一致
+type sqliteConnector struct {
+	dsn    string
+	driver *sqlite.Driver
+}
+
+func newSQLiteConnector(dsn string) driver.Connector {
+	return sqliteConnector{dsn: dsn, driver: &sqlite.Driver{}}
+}
+
+// sqliteConn 驱动连接实现的接口，database/sql按这些接口使用ctx、检查和重置连接
+type sqliteConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+type sqliteStmt interface {
+	driver.Stmt
+	driver.StmtExecContext
+	driver.StmtQueryContext
+}
+
+func (c sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	sc, ok := conn.(sqliteConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("SQLite驱动连接类型不支持: %T", conn)
+	}
+	return localTimeConn{sc}, nil
+}
+
+func (c sqliteConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type localTimeConn struct {
+	sqliteConn
+}
+
+func (c localTimeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.sqliteConn.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return localTimeRows{rows}, nil
+}
+
+func (c localTimeConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c localTimeConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.sqliteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	ss, ok := stmt.(sqliteStmt)
+	if !ok {
+		stmt.Close()
+		return nil, fmt.Errorf("SQLite驱动语句类型不支持: %T", stmt)
+	}
+	return localTimeStmt{ss}, nil
+}
+
+type localTimeStmt struct {
+	sqliteStmt
+}
+
+func (s localTimeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.sqliteStmt.Query(args)
+	if err != nil {
+		return nil, err
+	}
+	return localTimeRows{rows}, nil
+}
+
+func (s localTimeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := s.sqliteStmt.QueryContext(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return localTimeRows{rows}, nil
+}
+
+type localTimeRows struct {
+	driver.Rows
+}
+
+func (r localTimeRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return err
+	}
+	for i, v := range dest {
+		if t, ok := v.(time.Time); ok {
+			dest[i] = t.Local()
+		}
+	}
+	return nil
+}
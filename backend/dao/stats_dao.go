@@ -0,0 +1,47 @@
+package dao
+
+import (
+	"backend/do"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// StatsDAO 汇总统计
+type StatsDAO struct {
+	db *sql.DB
+}
+
+func NewStatsDAO(db *sql.DB) *StatsDAO {
+	return &StatsDAO{db: db}
+}
+
+// 统计流通概况，now之前到期仍未归还的借阅记为逾期；
+// 罚款没有单独的支付记录，支付罚款后恢复借阅权限，因此被禁止借阅的学生名下的逾期罚款视为未支付
+func (dao *StatsDAO) GetCirculationStats(ctx context.Context, now time.Time) (*do.CirculationStats, error) {
+	executor := newExecutor(dao.db, nil)
+	var stats do.CirculationStats
+
+	err := executor.QueryRow(ctx, "SELECT COUNT(*) FROM borrow_records WHERE return_date IS NULL AND due_date < ?", now).
+		Scan(&stats.OverdueLoans)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT COALESCE(SUM(r.fine_amount), 0), COUNT(DISTINCT r.stu_id)
+		FROM borrow_records r
+		JOIN students s ON s.stu_id = r.stu_id
+		WHERE s.can_borrow = false AND r.is_overdue = true AND r.fine_amount > 0
+	`
+	if err := executor.QueryRow(ctx, query).Scan(&stats.OutstandingFines, &stats.FinedStudents); err != nil {
+		return nil, err
+	}
+
+	err = executor.QueryRow(ctx, "SELECT COUNT(*) FROM books WHERE total_copies > 0 AND available_copies <= 0").
+		Scan(&stats.BooksUnavailable)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
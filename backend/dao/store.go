@@ -0,0 +1,61 @@
+package dao
+
+import (
+	"backend/repository"
+	"context"
+	"database/sql"
+)
+
+// Store 基于SQL数据库（MySQL、PostgreSQL、SQLite）的repository.Store实现
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) Books() repository.BookRepository         { return NewBookDAO(s.db) }
+func (s *Store) BookItems() repository.BookItemRepository { return NewBookItemDAO(s.db) }
+func (s *Store) Borrows() repository.BorrowRepository     { return NewBorrowDAO(s.db) }
+func (s *Store) Students() repository.StudentRepository   { return NewStudentDAO(s.db) }
+func (s *Store) Holds() repository.HoldRepository         { return NewHoldDAO(s.db) }
+func (s *Store) Transfers() repository.TransferRepository { return NewTransferDAO(s.db) }
+func (s *Store) Calendar() repository.CalendarRepository  { return NewCalendarDAO(s.db) }
+func (s *Store) Outbox() repository.OutboxRepository      { return NewWebhookDAO(s.db) }
+func (s *Store) Audit() repository.AuditRepository        { return NewAuditDAO(s.db) }
+
+func (s *Store) Transaction(ctx context.Context, fn func(tx repository.Repositories) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(NewRepositoriesTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// txRepositories 绑定到同一个事务的仓储
+type txRepositories struct {
+	tx *sql.Tx
+}
+
+// NewRepositoriesTx 把已开启的事务包装为仓储，供仍直接管理事务的服务调用共用的业务逻辑
+func NewRepositoriesTx(tx *sql.Tx) repository.Repositories {
+	return &txRepositories{tx: tx}
+}
+
+func (r *txRepositories) Books() repository.BookRepository         { return NewBookDAOTx(r.tx) }
+func (r *txRepositories) BookItems() repository.BookItemRepository { return NewBookItemDAOTx(r.tx) }
+func (r *txRepositories) Borrows() repository.BorrowRepository     { return NewBorrowDAOTx(r.tx) }
+func (r *txRepositories) Students() repository.StudentRepository   { return NewStudentDAOTx(r.tx) }
+func (r *txRepositories) Holds() repository.HoldRepository         { return NewHoldDAOTx(r.tx) }
+func (r *txRepositories) Transfers() repository.TransferRepository { return NewTransferDAOTx(r.tx) }
+func (r *txRepositories) Calendar() repository.CalendarRepository  { return NewCalendarDAOTx(r.tx) }
+func (r *txRepositories) Outbox() repository.OutboxRepository      { return NewWebhookDAOTx(r.tx) }
+func (r *txRepositories) Audit() repository.AuditRepository        { return NewAuditDAOTx(r.tx) }
+
+var _ repository.Store = (*Store)(nil)
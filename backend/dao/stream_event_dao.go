@@ -0,0 +1,66 @@
+package dao
+
+import (
+	"backend/do"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type StreamEventDAO struct {
+	db *sql.DB
+}
+
+func NewStreamEventDAO(db *sql.DB) *StreamEventDAO {
+	return &StreamEventDAO{db: db}
+}
+
+func (dao *StreamEventDAO) getExecutor() executor {
+	return newExecutor(dao.db, nil)
+}
+
+// 写入一条实时事件
+func (dao *StreamEventDAO) CreateStreamEvent(ctx context.Context, payload string) error {
+	_, err := dao.getExecutor().Exec(ctx, "INSERT INTO stream_events (payload) VALUES (?)", payload)
+	return err
+}
+
+// 获取ID大于afterID的实时事件
+func (dao *StreamEventDAO) GetStreamEventsAfter(ctx context.Context, afterID, limit int) ([]do.StreamEvent, error) {
+	query := `
+		SELECT id, payload, created_at
+		FROM stream_events
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`
+
+	rows, err := dao.getExecutor().Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []do.StreamEvent
+	for rows.Next() {
+		var event do.StreamEvent
+		if err := rows.Scan(&event.ID, &event.Payload, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// 获取当前最大的实时事件ID，没有事件时返回0
+func (dao *StreamEventDAO) GetMaxStreamEventID(ctx context.Context) (int, error) {
+	var id int
+	err := dao.getExecutor().QueryRow(ctx, "SELECT COALESCE(MAX(id), 0) FROM stream_events").Scan(&id)
+	return id, err
+}
+
+// 删除早于before的实时事件
+func (dao *StreamEventDAO) DeleteStreamEventsBefore(ctx context.Context, before time.Time) error {
+	_, err := dao.getExecutor().Exec(ctx, "DELETE FROM stream_events WHERE created_at < ?", before)
+	return err
+}
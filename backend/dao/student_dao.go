@@ -1,9 +1,11 @@
 package dao
 
 import (
-	"database/sql"
-	"fmt"
+	"backend/apperr"
 	"backend/do"
+	"context"
+	"database/sql"
+	"time"
 )
 
 type StudentDAO struct {
@@ -19,28 +21,21 @@ func NewStudentDAOTx(tx *sql.Tx) *StudentDAO {
 	return &StudentDAO{tx: tx}
 }
 
-func (dao *StudentDAO) getExecutor() interface {
-	Query(query string, args ...interface{}) (*sql.Rows, error)
-	QueryRow(query string, args ...interface{}) *sql.Row
-	Exec(query string, args ...interface{}) (sql.Result, error)
-} {
-	if dao.tx != nil {
-		return dao.tx
-	}
-	return dao.db
+func (dao *StudentDAO) getExecutor() executor {
+	return newExecutor(dao.db, dao.tx)
 }
 
 // 根据学号获取学生信息
-func (dao *StudentDAO) GetStudentByID(stuID string) (*do.Student, error) {
+func (dao *StudentDAO) GetStudentByID(ctx context.Context, stuID string) (*do.Student, error) {
 	query := `
 		SELECT stu_id, name, password, trust, can_borrow, created_at
 		FROM students 
 		WHERE stu_id = ?
 	`
-	
+
 	executor := dao.getExecutor()
-	row := executor.QueryRow(query, stuID)
-	
+	row := executor.QueryRow(ctx, query, stuID)
+
 	var student do.Student
 	err := row.Scan(
 		&student.StuId,
@@ -50,39 +45,173 @@ func (dao *StudentDAO) GetStudentByID(stuID string) (*do.Student, error) {
 		&student.CanBorrow,
 		&student.CreatedAt,
 	)
-	
+
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("学生不存在")
+			return nil, apperr.New(apperr.CodeStudentNotFound)
 		}
 		return nil, err
 	}
-	
+
 	return &student, nil
 }
 
+// 按学号批量获取学生信息，不存在的学号不返回；不读取密码
+func (dao *StudentDAO) GetStudentsByIDs(ctx context.Context, stuIDs []string) ([]do.Student, error) {
+	if len(stuIDs) == 0 {
+		return nil, nil
+	}
+	placeholders, args := inList(stuIDs)
+	query := "SELECT stu_id, name, trust, can_borrow, created_at FROM students WHERE stu_id IN " + placeholders
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var students []do.Student
+	for rows.Next() {
+		var student do.Student
+		err := rows.Scan(
+			&student.StuId,
+			&student.Name,
+			&student.Trust,
+			&student.CanBorrow,
+			&student.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, student)
+	}
+
+	return students, rows.Err()
+}
+
+// 获取所有学生，按学号排序；不读取密码
+func (dao *StudentDAO) GetAllStudents(ctx context.Context) ([]do.Student, error) {
+	query := "SELECT stu_id, name, trust, can_borrow, created_at FROM students ORDER BY stu_id"
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var students []do.Student
+	for rows.Next() {
+		var student do.Student
+		err := rows.Scan(
+			&student.StuId,
+			&student.Name,
+			&student.Trust,
+			&student.CanBorrow,
+			&student.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, student)
+	}
+
+	return students, rows.Err()
+}
+
+// 新增学生，由调用方设置created_at
+func (dao *StudentDAO) CreateStudent(ctx context.Context, student *do.Student) error {
+	query := `
+		INSERT INTO students (stu_id, name, password, trust, can_borrow, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, student.StuId, student.Name, student.Password, student.Trust, student.CanBorrow, student.CreatedAt)
+	return err
+}
+
+// 更新学生登录密码
+func (dao *StudentDAO) UpdateStudentPassword(ctx context.Context, stuID, password string) error {
+	query := "UPDATE students SET password = ? WHERE stu_id = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, password, stuID)
+	return err
+}
+
 // 更新学生借阅状态
-func (dao *StudentDAO) UpdateStudentBorrowStatus(stuID string, canBorrow bool) error {
+func (dao *StudentDAO) UpdateStudentBorrowStatus(ctx context.Context, stuID string, canBorrow bool) error {
 	query := "UPDATE students SET can_borrow = ? WHERE stu_id = ?"
 	executor := dao.getExecutor()
-	_, err := executor.Exec(query, canBorrow, stuID)
+	_, err := executor.Exec(ctx, query, canBorrow, stuID)
 	return err
 }
 
 // 检查学生是否有未支付的罚款
-func (dao *StudentDAO) HasUnpaidFine(stuID string) (bool, error) {
+func (dao *StudentDAO) HasUnpaidFine(ctx context.Context, stuID string) (bool, error) {
 	query := `
 		SELECT COUNT(*) 
 		FROM borrow_records 
 		WHERE stu_id = ? AND is_overdue = true AND fine_amount > 0 AND return_date IS NULL
 	`
-	
+
 	executor := dao.getExecutor()
 	var count int
-	err := executor.QueryRow(query, stuID).Scan(&count)
+	err := executor.QueryRow(ctx, query, stuID).Scan(&count)
 	if err != nil {
 		return false, err
 	}
-	
+
 	return count > 0, nil
 }
+
+// 获取学生自助借还PIN的哈希值和输错次数，未设置PIN时Hash为空
+func (dao *StudentDAO) GetStudentPIN(ctx context.Context, stuID string) (*do.StudentPIN, error) {
+	query := "SELECT pin_hash, pin_failed_attempts, pin_locked_until FROM students WHERE stu_id = ?"
+	executor := dao.getExecutor()
+	var pinHash sql.NullString
+	var lockedUntil sql.NullTime
+	pin := &do.StudentPIN{}
+	err := executor.QueryRow(ctx, query, stuID).Scan(&pinHash, &pin.FailedAttempts, &lockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.New(apperr.CodeStudentNotFound)
+		}
+		return nil, err
+	}
+	pin.Hash = pinHash.String
+	if lockedUntil.Valid {
+		pin.LockedUntil = &lockedUntil.Time
+	}
+	return pin, nil
+}
+
+// 更新学生自助借还PIN的哈希值，同时解除锁定
+func (dao *StudentDAO) UpdateStudentPINHash(ctx context.Context, stuID, pinHash string) error {
+	query := "UPDATE students SET pin_hash = ?, pin_failed_attempts = 0, pin_locked_until = NULL WHERE stu_id = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, pinHash, stuID)
+	return err
+}
+
+// 记录一次PIN输错，连续输错达到maxAttempts次时锁定到lockedUntil并清零计数；
+// 在原值上计数，并发输错不会漏记。MySQL按顺序赋值，锁定时间须写在计数之前，使用的是旧的计数
+func (dao *StudentDAO) RecordPINFailure(ctx context.Context, stuID string, maxAttempts int, lockedUntil time.Time) error {
+	query := `
+		UPDATE students
+		SET pin_locked_until = CASE WHEN pin_failed_attempts + 1 >= ? THEN ? ELSE pin_locked_until END,
+			pin_failed_attempts = CASE WHEN pin_failed_attempts + 1 >= ? THEN 0 ELSE pin_failed_attempts + 1 END
+		WHERE stu_id = ?
+	`
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, maxAttempts, lockedUntil, maxAttempts, stuID)
+	return err
+}
+
+// PIN校验通过后清零输错次数
+func (dao *StudentDAO) ResetPINFailures(ctx context.Context, stuID string) error {
+	query := "UPDATE students SET pin_failed_attempts = 0, pin_locked_until = NULL WHERE stu_id = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, stuID)
+	return err
+}
@@ -0,0 +1,147 @@
+package dao
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type TransferDAO struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewTransferDAO(db *sql.DB) *TransferDAO {
+	return &TransferDAO{db: db}
+}
+
+func NewTransferDAOTx(tx *sql.Tx) *TransferDAO {
+	return &TransferDAO{tx: tx}
+}
+
+func (dao *TransferDAO) getExecutor() executor {
+	return newExecutor(dao.db, dao.tx)
+}
+
+// transfers表查询列，与scanTransfer的扫描顺序一致
+const transferColumns = "id, barcode, from_branch_id, to_branch_id, reason, hold_id, status, shipped_at, received_at, created_at"
+
+func scanTransfer(row rowScanner) (*do.Transfer, error) {
+	var transfer do.Transfer
+	var holdID sql.NullInt64
+	err := row.Scan(
+		&transfer.ID,
+		&transfer.Barcode,
+		&transfer.FromBranchID,
+		&transfer.ToBranchID,
+		&transfer.Reason,
+		&holdID,
+		&transfer.Status,
+		&transfer.ShippedAt,
+		&transfer.ReceivedAt,
+		&transfer.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if holdID.Valid {
+		id := int(holdID.Int64)
+		transfer.HoldID = &id
+	}
+	return &transfer, nil
+}
+
+// 创建调拨单，返回自增ID
+func (dao *TransferDAO) CreateTransfer(ctx context.Context, transfer *do.Transfer) (int, error) {
+	query := `
+		INSERT INTO transfers (barcode, from_branch_id, to_branch_id, reason, hold_id, status)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	executor := dao.getExecutor()
+	id, err := executor.Insert(ctx,
+		query,
+		transfer.Barcode,
+		transfer.FromBranchID,
+		transfer.ToBranchID,
+		transfer.Reason,
+		transfer.HoldID,
+		transfer.Status,
+	)
+	return int(id), err
+}
+
+// 根据ID获取调拨单，在事务中加行锁
+func (dao *TransferDAO) GetTransferByID(ctx context.Context, id int) (*do.Transfer, error) {
+	query := "SELECT " + transferColumns + " FROM transfers WHERE id = ?"
+	if dao.tx != nil {
+		query += forUpdate()
+	}
+
+	executor := dao.getExecutor()
+	transfer, err := scanTransfer(executor.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.New(apperr.CodeTransferNotFound)
+		}
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// 查询调拨单，status和branchID为空时不过滤；branchID同时匹配调出和调入分馆
+func (dao *TransferDAO) ListTransfers(ctx context.Context, status, branchID string) ([]do.Transfer, error) {
+	query := "SELECT " + transferColumns + " FROM transfers WHERE 1 = 1"
+	var args []interface{}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	if branchID != "" {
+		query += " AND (from_branch_id = ? OR to_branch_id = ?)"
+		args = append(args, branchID, branchID)
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []do.Transfer
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, *transfer)
+	}
+	return transfers, rows.Err()
+}
+
+// 标记调拨单已发出
+func (dao *TransferDAO) MarkShipped(ctx context.Context, id int, shippedAt time.Time) error {
+	query := "UPDATE transfers SET status = 'in_transit', shipped_at = ? WHERE id = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, shippedAt, id)
+	return err
+}
+
+// 标记调拨单已签收
+func (dao *TransferDAO) MarkReceived(ctx context.Context, id int, receivedAt time.Time) error {
+	query := "UPDATE transfers SET status = 'received', received_at = ? WHERE id = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, receivedAt, id)
+	return err
+}
+
+// 更新调拨单状态
+func (dao *TransferDAO) UpdateTransferStatus(ctx context.Context, id int, status string) error {
+	query := "UPDATE transfers SET status = ? WHERE id = ?"
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, status, id)
+	return err
+}
@@ -0,0 +1,307 @@
+package dao
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type WebhookDAO struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewWebhookDAO(db *sql.DB) *WebhookDAO {
+	return &WebhookDAO{db: db}
+}
+
+func NewWebhookDAOTx(tx *sql.Tx) *WebhookDAO {
+	return &WebhookDAO{tx: tx}
+}
+
+func (dao *WebhookDAO) getExecutor() executor {
+	return newExecutor(dao.db, dao.tx)
+}
+
+// webhook_endpoints表查询列，与scanWebhookEndpoint的扫描顺序一致
+const webhookEndpointColumns = "id, url, secret, events, enabled, created_at"
+
+func scanWebhookEndpoint(row rowScanner) (*do.WebhookEndpoint, error) {
+	var endpoint do.WebhookEndpoint
+	var events string
+	if err := row.Scan(&endpoint.ID, &endpoint.URL, &endpoint.Secret, &events, &endpoint.Enabled, &endpoint.CreatedAt); err != nil {
+		return nil, err
+	}
+	endpoint.Events = strings.Split(events, ",")
+	return &endpoint, nil
+}
+
+// 登记推送地址，订阅的事件以逗号分隔保存
+func (dao *WebhookDAO) CreateEndpoint(ctx context.Context, endpoint *do.WebhookEndpoint) (int, error) {
+	query := "INSERT INTO webhook_endpoints (url, secret, events, enabled) VALUES (?, ?, ?, ?)"
+	executor := dao.getExecutor()
+	id, err := executor.Insert(ctx, query, endpoint.URL, endpoint.Secret, strings.Join(endpoint.Events, ","), endpoint.Enabled)
+	return int(id), err
+}
+
+// 根据ID获取推送地址
+func (dao *WebhookDAO) GetEndpointByID(ctx context.Context, id int) (*do.WebhookEndpoint, error) {
+	query := "SELECT " + webhookEndpointColumns + " FROM webhook_endpoints WHERE id = ?"
+	executor := dao.getExecutor()
+	endpoint, err := scanWebhookEndpoint(executor.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.New(apperr.CodeWebhookNotFound)
+		}
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// 获取推送地址，onlyEnabled为true时只返回启用的
+func (dao *WebhookDAO) GetEndpoints(ctx context.Context, onlyEnabled bool) ([]do.WebhookEndpoint, error) {
+	query := "SELECT " + webhookEndpointColumns + " FROM webhook_endpoints"
+	if onlyEnabled {
+		query += " WHERE enabled = true"
+	}
+	query += " ORDER BY id"
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []do.WebhookEndpoint
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, *endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+// 启用或停用推送地址
+func (dao *WebhookDAO) UpdateEndpointEnabled(ctx context.Context, id int, enabled bool) error {
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, "UPDATE webhook_endpoints SET enabled = ? WHERE id = ?", enabled, id)
+	return err
+}
+
+// 写入发件箱，应与产生事件的业务操作在同一事务中调用
+func (dao *WebhookDAO) CreateEvent(ctx context.Context, eventType, payload string) error {
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, "INSERT INTO webhook_outbox (event_type, payload) VALUES (?, ?)", eventType, payload)
+	return err
+}
+
+// 根据ID获取发件箱事件
+func (dao *WebhookDAO) GetEventByID(ctx context.Context, id int) (*do.WebhookEvent, error) {
+	query := "SELECT id, event_type, payload, dispatched_at, created_at FROM webhook_outbox WHERE id = ?"
+	executor := dao.getExecutor()
+
+	var event do.WebhookEvent
+	err := executor.QueryRow(ctx, query, id).Scan(&event.ID, &event.EventType, &event.Payload, &event.DispatchedAt, &event.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.New(apperr.CodeEventNotFound)
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// 获取尚未分发的事件，在事务中加行锁
+func (dao *WebhookDAO) GetUndispatchedEvents(ctx context.Context, limit int) ([]do.WebhookEvent, error) {
+	query := `
+		SELECT id, event_type, payload, dispatched_at, created_at
+		FROM webhook_outbox
+		WHERE dispatched_at IS NULL
+		ORDER BY id
+		LIMIT ?
+	`
+	if dao.tx != nil {
+		query += forUpdate()
+	}
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []do.WebhookEvent
+	for rows.Next() {
+		var event do.WebhookEvent
+		if err := rows.Scan(&event.ID, &event.EventType, &event.Payload, &event.DispatchedAt, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// 标记事件已分发到各推送地址
+func (dao *WebhookDAO) MarkEventDispatched(ctx context.Context, id int, dispatchedAt time.Time) error {
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, "UPDATE webhook_outbox SET dispatched_at = ? WHERE id = ?", dispatchedAt, id)
+	return err
+}
+
+// 为一个推送地址创建投递
+func (dao *WebhookDAO) CreateDelivery(ctx context.Context, eventID, endpointID int, nextAttemptAt time.Time) error {
+	query := `
+		INSERT INTO webhook_deliveries (event_id, endpoint_id, status, attempts, next_attempt_at)
+		VALUES (?, ?, 'pending', 0, ?)
+	`
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, eventID, endpointID, nextAttemptAt)
+	return err
+}
+
+// webhook_deliveries查询列（连同事件类型和推送地址），与scanWebhookDelivery的扫描顺序一致
+const webhookDeliveryColumns = `d.id, d.event_id, d.endpoint_id, o.event_type, e.url, d.status, d.attempts,
+	d.last_status_code, d.last_error, d.next_attempt_at, d.delivered_at, d.created_at`
+
+const webhookDeliveryFrom = ` FROM webhook_deliveries d
+	JOIN webhook_outbox o ON o.id = d.event_id
+	JOIN webhook_endpoints e ON e.id = d.endpoint_id`
+
+func scanWebhookDelivery(row rowScanner) (*do.WebhookDelivery, error) {
+	var delivery do.WebhookDelivery
+	var statusCode sql.NullInt64
+	var lastError sql.NullString
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.EventID,
+		&delivery.EndpointID,
+		&delivery.EventType,
+		&delivery.URL,
+		&delivery.Status,
+		&delivery.Attempts,
+		&statusCode,
+		&lastError,
+		&delivery.NextAttemptAt,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	delivery.LastStatusCode = int(statusCode.Int64)
+	delivery.LastError = lastError.String
+	return &delivery, nil
+}
+
+func scanWebhookDeliveries(rows *sql.Rows) ([]do.WebhookDelivery, error) {
+	defer rows.Close()
+
+	var deliveries []do.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// 获取到了推送时间的投递
+func (dao *WebhookDAO) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]do.WebhookDelivery, error) {
+	query := "SELECT " + webhookDeliveryColumns + webhookDeliveryFrom + `
+		WHERE d.status = 'pending' AND d.next_attempt_at <= ?
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT ?`
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanWebhookDeliveries(rows)
+}
+
+// 认领一条投递：把下次推送时间推迟到leaseUntil，成功认领返回true
+func (dao *WebhookDAO) ClaimDelivery(ctx context.Context, id int, now, leaseUntil time.Time) (bool, error) {
+	query := `
+		UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id = ? AND status = 'pending' AND next_attempt_at <= ?
+	`
+	executor := dao.getExecutor()
+	result, err := executor.Exec(ctx, query, leaseUntil, id, now)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// 标记投递成功
+func (dao *WebhookDAO) MarkDelivered(ctx context.Context, id, statusCode int, deliveredAt time.Time) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, last_status_code = ?, last_error = NULL, delivered_at = ?
+		WHERE id = ?
+	`
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, statusCode, deliveredAt, id)
+	return err
+}
+
+// 记录一次投递失败，status为pending时在nextAttemptAt重试，为dead时进入死信
+func (dao *WebhookDAO) MarkDeliveryFailed(ctx context.Context, id int, status string, statusCode int, lastError string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, last_status_code = ?, last_error = ?, next_attempt_at = ?
+		WHERE id = ?
+	`
+	executor := dao.getExecutor()
+	_, err := executor.Exec(ctx, query, status, sql.NullInt64{Int64: int64(statusCode), Valid: statusCode != 0}, lastError, nextAttemptAt, id)
+	return err
+}
+
+// 重新投递一条未成功的投递，清零重试次数
+func (dao *WebhookDAO) ResetDelivery(ctx context.Context, id int, now time.Time) (bool, error) {
+	query := `
+		UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = ?
+		WHERE id = ? AND status <> 'delivered'
+	`
+	executor := dao.getExecutor()
+	result, err := executor.Exec(ctx, query, now, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// 查询投递记录，status为空、endpointID为0时不过滤
+func (dao *WebhookDAO) ListDeliveries(ctx context.Context, status string, endpointID, limit int) ([]do.WebhookDelivery, error) {
+	query := "SELECT " + webhookDeliveryColumns + webhookDeliveryFrom + " WHERE 1 = 1"
+	var args []interface{}
+	if status != "" {
+		query += " AND d.status = ?"
+		args = append(args, status)
+	}
+	if endpointID != 0 {
+		query += " AND d.endpoint_id = ?"
+		args = append(args, endpointID)
+	}
+	query += " ORDER BY d.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	executor := dao.getExecutor()
+	rows, err := executor.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanWebhookDeliveries(rows)
+}
@@ -0,0 +1,70 @@
+package do
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 审计日志记录的操作
+const (
+	AuditLoanBorrow       = "loan.borrow"               // 借出
+	AuditLoanReturn       = "loan.return"               // 归还
+	AuditFinePay          = "fine.pay"                  // 支付罚款
+	AuditBorrowPermission = "student.borrow_permission" // 学生借阅权限变化
+	AuditBranchCreate     = "branch.create"             // 新建分馆
+	AuditItemCreate       = "item.create"               // 登记单册
+	AuditCoverUpdate      = "book.cover_update"         // 上传封面
+	AuditCoverDelete      = "book.cover_delete"         // 删除封面
+	AuditStudentCreate    = "student.create"            // 新建学生
+	AuditPasswordReset    = "student.password_reset"    // 重置学生密码
+	AuditBookCreate       = "book.create"               // 新增书籍
+	AuditBookCopies       = "book.copies"               // 调整馆藏数量
+	AuditFineWaive        = "fine.waive"                // 减免罚款
+)
+
+// 审计日志记录的对象类型
+const (
+	AuditEntityLoan    = "loan"
+	AuditEntityStudent = "student"
+	AuditEntityBook    = "book"
+	AuditEntityItem    = "item"
+	AuditEntityBranch  = "branch"
+)
+
+// 审计日志中的操作人角色，除学生、馆员和自助借还机（RoleKiosk）外还有以下三种
+const (
+	ActorAnonymous = "anonymous" // 未携带有效令牌的请求
+	ActorSystem    = "system"    // 后台任务
+	ActorCLI       = "cli"       // 命令行管理工具，操作人ID为执行命令的系统用户
+)
+
+// AuditEntry 审计日志，只追加不修改；Before/After为操作前后的值（JSON），新建时Before为空，删除时After为空
+type AuditEntry struct {
+	ID         int64           `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	OccurredAt time.Time       `json:"occurred_at" gorm:"column:occurred_at"`
+	ActorRole  string          `json:"actor_role" gorm:"column:actor_role"`
+	ActorID    string          `json:"actor_id,omitempty" gorm:"column:actor_id"`
+	ClientIP   string          `json:"client_ip,omitempty" gorm:"column:client_ip"`
+	RequestID  string          `json:"request_id,omitempty" gorm:"column:request_id"`
+	Action     string          `json:"action" gorm:"column:action"`
+	EntityType string          `json:"entity_type" gorm:"column:entity_type"`
+	EntityID   string          `json:"entity_id" gorm:"column:entity_id"`
+	Before     json.RawMessage `json:"before,omitempty" gorm:"column:before_value"`
+	After      json.RawMessage `json:"after,omitempty" gorm:"column:after_value"`
+}
+
+func (a *AuditEntry) TableName() string {
+	return "audit_log"
+}
+
+// AuditFilter 审计日志查询条件，字段为空时不限；BeforeID大于0时只返回ID更小的记录，用于翻页
+type AuditFilter struct {
+	Action     string
+	EntityType string
+	EntityID   string
+	ActorID    string
+	Since      *time.Time
+	Until      *time.Time
+	BeforeID   int64
+	Limit      int
+}
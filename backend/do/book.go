@@ -3,14 +3,20 @@ package do
 import "time"
 
 type Book struct {
-	BookID         string    `json:"book_id" gorm:"column:book_id;primaryKey"`
-	Title          string    `json:"title" gorm:"column:title"`
-	Author         string    `json:"author" gorm:"column:author"`
-	Description    string    `json:"description" gorm:"column:description"`
-	TotalCopies    int       `json:"total_copies" gorm:"column:total_copies"`
-	AvailableCopies int      `json:"available_copies" gorm:"column:available_copies"`
-	CanBorrow      bool      `json:"can_borrow" gorm:"column:can_borrow"`
-	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at"`
+	BookID          string    `json:"book_id" gorm:"column:book_id;primaryKey"`
+	Title           string    `json:"title" gorm:"column:title"`
+	Author          string    `json:"author" gorm:"column:author"`
+	Description     string    `json:"description" gorm:"column:description"`
+	TotalCopies     int       `json:"total_copies" gorm:"column:total_copies"`
+	AvailableCopies int       `json:"available_copies" gorm:"column:available_copies"`
+	CanBorrow       bool      `json:"can_borrow" gorm:"column:can_borrow"`
+	CoverKey        string    `json:"-" gorm:"column:cover_key"`
+	CoverURL        string    `json:"cover_url,omitempty" gorm:"-"`
+	CoverThumbURL   string    `json:"cover_thumb_url,omitempty" gorm:"-"`
+	CreatedAt       time.Time `json:"created_at" gorm:"column:created_at"`
+
+	// 按分馆统计的馆藏，仅在书籍详情中返回
+	Branches []BranchAvailability `json:"branches,omitempty" gorm:"-"`
 }
 
 func (b *Book) TableName() string {
@@ -0,0 +1,25 @@
+package do
+
+import "time"
+
+// 单册状态
+const (
+	ItemStatusAvailable = "available"     // 在架可借
+	ItemStatusOnLoan    = "on_loan"       // 已借出
+	ItemStatusInTransit = "in_transit"    // 分馆间调拨中
+	ItemStatusOnHold    = "on_hold_shelf" // 在预约架上等待取书
+)
+
+// BookItem 一本书的实体馆藏（单册），以条码区分
+type BookItem struct {
+	Barcode         string    `json:"barcode" gorm:"column:barcode;primaryKey"`
+	BookID          string    `json:"book_id" gorm:"column:book_id"`
+	HomeBranchID    string    `json:"home_branch_id" gorm:"column:home_branch_id"`
+	CurrentBranchID string    `json:"current_branch_id" gorm:"column:current_branch_id"`
+	Status          string    `json:"status" gorm:"column:status"`
+	CreatedAt       time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (b *BookItem) TableName() string {
+	return "book_items"
+}
@@ -0,0 +1,22 @@
+package do
+
+import "time"
+
+type Branch struct {
+	BranchID  string    `json:"branch_id" gorm:"column:branch_id;primaryKey"`
+	Name      string    `json:"name" gorm:"column:name"`
+	Address   string    `json:"address" gorm:"column:address"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (b *Branch) TableName() string {
+	return "branches"
+}
+
+// BranchAvailability 某分馆内一种书的馆藏情况
+type BranchAvailability struct {
+	BranchID   string `json:"branch_id"`
+	BranchName string `json:"branch_name"`
+	Total      int    `json:"total"`
+	Available  int    `json:"available"`
+}
@@ -0,0 +1,39 @@
+package do
+
+import "time"
+
+// 闭馆类型
+const (
+	ClosureKindHoliday = "holiday" // 节假日
+	ClosureKindBreak   = "break"   // 寒暑假
+	ClosureKindOther   = "other"   // 临时闭馆等
+)
+
+// 日期格式，闭馆日期按本地日期比较
+const DateLayout = "2006-01-02"
+
+// OpeningHours 每周某一天的开放时间，Weekday与time.Weekday一致（0为周日）
+type OpeningHours struct {
+	Weekday   int    `json:"weekday" gorm:"column:weekday;primaryKey"`
+	OpenTime  string `json:"open_time" gorm:"column:open_time"`   // 如 08:00
+	CloseTime string `json:"close_time" gorm:"column:close_time"` // 如 22:00
+	Closed    bool   `json:"closed" gorm:"column:closed"`         // 当天固定闭馆
+}
+
+func (h *OpeningHours) TableName() string {
+	return "library_hours"
+}
+
+// LibraryClosure 一段闭馆日期（节假日、寒暑假），起止日期均包含在内
+type LibraryClosure struct {
+	ID        int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Name      string    `json:"name" gorm:"column:name"`
+	Kind      string    `json:"kind" gorm:"column:kind"`
+	StartDate string    `json:"start_date" gorm:"column:start_date"`
+	EndDate   string    `json:"end_date" gorm:"column:end_date"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (c *LibraryClosure) TableName() string {
+	return "library_closures"
+}
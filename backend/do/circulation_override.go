@@ -0,0 +1,18 @@
+package do
+
+import "time"
+
+// CirculationOverride 馆员强制借出的记录，每个被越过的受阻原因一条
+type CirculationOverride struct {
+	ID          int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	LibrarianID string    `json:"librarian_id" gorm:"column:librarian_id"`
+	StuID       string    `json:"stu_id" gorm:"column:stu_id"`
+	Barcode     string    `json:"barcode,omitempty" gorm:"column:barcode"`
+	BlockCode   string    `json:"block_code" gorm:"column:block_code"`
+	Reason      string    `json:"reason" gorm:"column:reason"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (c *CirculationOverride) TableName() string {
+	return "circulation_overrides"
+}
@@ -0,0 +1,27 @@
+package do
+
+import "time"
+
+// 预约状态
+const (
+	HoldStatusWaiting   = "waiting"    // 排队等待可用副本
+	HoldStatusInTransit = "in_transit" // 已分配副本，正调拨到取书分馆
+	HoldStatusReady     = "ready"      // 副本已在取书分馆预约架上
+	HoldStatusFulfilled = "fulfilled"  // 已借出
+	HoldStatusCancelled = "cancelled"
+)
+
+type Hold struct {
+	ID             int        `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	StuID          string     `json:"stu_id" gorm:"column:stu_id"`
+	BookID         string     `json:"book_id" gorm:"column:book_id"`
+	PickupBranchID string     `json:"pickup_branch_id" gorm:"column:pickup_branch_id"`
+	Barcode        string     `json:"barcode,omitempty" gorm:"column:barcode"`
+	Status         string     `json:"status" gorm:"column:status"`
+	ReadyAt        *time.Time `json:"ready_at" gorm:"column:ready_at"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+func (h *Hold) TableName() string {
+	return "holds"
+}
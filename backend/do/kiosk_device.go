@@ -0,0 +1,22 @@
+package do
+
+import "time"
+
+// 自助借还机登录主体角色
+const RoleKiosk = "kiosk"
+
+// KioskDevice 自助借还机，以设备API密钥认证
+type KioskDevice struct {
+	DeviceID        string     `json:"device_id" gorm:"column:device_id;primaryKey"`
+	Name            string     `json:"name" gorm:"column:name"`
+	BranchID        string     `json:"branch_id" gorm:"column:branch_id"`
+	APIKeyHash      string     `json:"-" gorm:"column:api_key_hash"`
+	Enabled         bool       `json:"enabled" gorm:"column:enabled"`
+	RateLimitPerMin int        `json:"rate_limit_per_min" gorm:"column:rate_limit_per_min"`
+	LastSeenAt      *time.Time `json:"last_seen_at" gorm:"column:last_seen_at"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+func (k *KioskDevice) TableName() string {
+	return "kiosk_devices"
+}
@@ -0,0 +1,21 @@
+package do
+
+import "time"
+
+// 馆员角色
+const (
+	RoleLibrarian = "librarian"
+	RoleAdmin     = "admin"
+)
+
+type Librarian struct {
+	LibrarianID string    `json:"librarian_id" gorm:"column:librarian_id;primaryKey"`
+	Name        string    `json:"name" gorm:"column:name"`
+	Password    string    `json:"password" gorm:"column:password"`
+	Role        string    `json:"role" gorm:"column:role"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (l *Librarian) TableName() string {
+	return "librarians"
+}
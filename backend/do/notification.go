@@ -0,0 +1,73 @@
+package do
+
+import "time"
+
+// 通知类型
+const (
+	NotificationKindDueSoon   = "due_soon"   // 3天内到期
+	NotificationKindOverdue   = "overdue"    // 已逾期
+	NotificationKindHoldReady = "hold_ready" // 预约的书已到取书分馆
+)
+
+// 通知投递状态
+const (
+	NotificationStatusPending = "pending" // 等待发送（含失败后等待重试）
+	NotificationStatusSent    = "sent"
+	NotificationStatusFailed  = "failed" // 重试次数用尽
+)
+
+// 通知语言
+const (
+	LanguageZhCN = "zh-CN"
+	LanguageEnUS = "en-US"
+)
+
+// NotificationPreference 学生的通知设置，没有设置的学生不接收任何通知
+type NotificationPreference struct {
+	StuID     string    `json:"stu_id" gorm:"column:stu_id;primaryKey"`
+	Email     string    `json:"email" gorm:"column:email"`
+	Language  string    `json:"language" gorm:"column:language"`
+	DueSoon   bool      `json:"due_soon" gorm:"column:due_soon"`
+	Overdue   bool      `json:"overdue" gorm:"column:overdue"`
+	HoldReady bool      `json:"hold_ready" gorm:"column:hold_ready"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (p *NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
+
+// Notification 一条通知及其投递记录，同一类型对同一借阅记录或预约只发一次
+type Notification struct {
+	ID            int        `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	StuID         string     `json:"stu_id" gorm:"column:stu_id"`
+	Kind          string     `json:"kind" gorm:"column:kind"`
+	RefID         int        `json:"ref_id" gorm:"column:ref_id"` // 借阅记录ID或预约ID
+	Email         string     `json:"email" gorm:"column:email"`
+	Subject       string     `json:"subject" gorm:"column:subject"`
+	Body          string     `json:"body" gorm:"column:body"`
+	Status        string     `json:"status" gorm:"column:status"`
+	Attempts      int        `json:"attempts" gorm:"column:attempts"`
+	LastError     string     `json:"last_error,omitempty" gorm:"column:last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"column:next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at" gorm:"column:sent_at"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+func (n *Notification) TableName() string {
+	return "notifications"
+}
+
+// NotificationCandidate 需要发送通知的借阅记录或预约，连同收件人信息
+type NotificationCandidate struct {
+	Kind         string
+	RefID        int
+	StuID        string
+	StudentName  string
+	Email        string
+	Language     string
+	BookTitle    string
+	Barcode      string
+	DueDate      time.Time // 到期和逾期通知
+	PickupBranch string    // 预约到馆通知
+}
@@ -0,0 +1,9 @@
+package do
+
+// CirculationStats 流通概况，用于监控指标
+type CirculationStats struct {
+	OverdueLoans     int     `json:"overdue_loans"`     // 已过应还日期仍未归还的借阅
+	OutstandingFines float64 `json:"outstanding_fines"` // 被禁止借阅的学生名下的罚款合计（支付罚款后恢复借阅）
+	FinedStudents    int     `json:"fined_students"`    // 有未支付罚款的学生数
+	BooksUnavailable int     `json:"books_unavailable"` // 有馆藏但没有可借副本的图书
+}
@@ -0,0 +1,14 @@
+package do
+
+import "time"
+
+// StreamEvent 多实例部署时经数据库转发的实时事件
+type StreamEvent struct {
+	ID        int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Payload   string    `json:"payload" gorm:"column:payload"` // 事件（JSON）
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (e *StreamEvent) TableName() string {
+	return "stream_events"
+}
@@ -14,3 +14,10 @@ type Student struct {
 func (s *Student) TableName() string {
 	return "students"
 }
+
+// StudentPIN 自助借还PIN的哈希及连续输错的次数，LockedUntil之前不再校验PIN
+type StudentPIN struct {
+	Hash           string
+	FailedAttempts int
+	LockedUntil    *time.Time
+}
@@ -0,0 +1,35 @@
+package do
+
+import "time"
+
+// 调拨状态
+const (
+	TransferStatusRequested = "requested"  // 已申请，等待发出
+	TransferStatusInTransit = "in_transit" // 运送中
+	TransferStatusReceived  = "received"   // 目标分馆已签收
+	TransferStatusCancelled = "cancelled"
+)
+
+// 调拨原因
+const (
+	TransferReasonManual     = "manual"      // 馆员手动发起
+	TransferReasonReturnHome = "return_home" // 在非所属分馆归还，送回所属分馆
+	TransferReasonHold       = "hold"        // 送往预约的取书分馆
+)
+
+type Transfer struct {
+	ID           int        `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Barcode      string     `json:"barcode" gorm:"column:barcode"`
+	FromBranchID string     `json:"from_branch_id" gorm:"column:from_branch_id"`
+	ToBranchID   string     `json:"to_branch_id" gorm:"column:to_branch_id"`
+	Reason       string     `json:"reason" gorm:"column:reason"`
+	HoldID       *int       `json:"hold_id" gorm:"column:hold_id"`
+	Status       string     `json:"status" gorm:"column:status"`
+	ShippedAt    *time.Time `json:"shipped_at" gorm:"column:shipped_at"`
+	ReceivedAt   *time.Time `json:"received_at" gorm:"column:received_at"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+func (t *Transfer) TableName() string {
+	return "transfers"
+}
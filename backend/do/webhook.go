@@ -0,0 +1,75 @@
+package do
+
+import "time"
+
+// 对外推送的流通事件
+const (
+	EventBookBorrowed   = "book.borrowed"
+	EventBookReturned   = "book.returned"
+	EventFineCharged    = "fine.charged"
+	EventFinePaid       = "fine.paid"
+	EventStudentBlocked = "student.blocked"
+)
+
+// 所有可订阅的事件
+var WebhookEvents = []string{
+	EventBookBorrowed,
+	EventBookReturned,
+	EventFineCharged,
+	EventFinePaid,
+	EventStudentBlocked,
+}
+
+// 推送状态
+const (
+	WebhookDeliveryPending   = "pending"   // 等待推送（含失败后等待重试）
+	WebhookDeliveryDelivered = "delivered" // 对方返回2xx
+	WebhookDeliveryDead      = "dead"      // 重试次数用尽，进入死信
+)
+
+// WebhookEndpoint 管理员登记的推送地址，Events为订阅的事件
+type WebhookEndpoint struct {
+	ID        int       `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	URL       string    `json:"url" gorm:"column:url"`
+	Secret    string    `json:"-" gorm:"column:secret"` // HMAC签名密钥，只在登记时返回一次
+	Events    []string  `json:"events" gorm:"-"`
+	Enabled   bool      `json:"enabled" gorm:"column:enabled"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (w *WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+// WebhookEvent 发件箱中的一条事件，与产生它的业务操作在同一事务中写入
+type WebhookEvent struct {
+	ID           int        `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	EventType    string     `json:"event_type" gorm:"column:event_type"`
+	Payload      string     `json:"payload" gorm:"column:payload"` // 事件数据（JSON）
+	DispatchedAt *time.Time `json:"dispatched_at" gorm:"column:dispatched_at"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+func (e *WebhookEvent) TableName() string {
+	return "webhook_outbox"
+}
+
+// WebhookDelivery 一条事件向一个推送地址的投递
+type WebhookDelivery struct {
+	ID             int        `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	EventID        int        `json:"event_id" gorm:"column:event_id"`
+	EndpointID     int        `json:"endpoint_id" gorm:"column:endpoint_id"`
+	EventType      string     `json:"event_type" gorm:"-"`
+	URL            string     `json:"url" gorm:"-"`
+	Status         string     `json:"status" gorm:"column:status"`
+	Attempts       int        `json:"attempts" gorm:"column:attempts"`
+	LastStatusCode int        `json:"last_status_code,omitempty" gorm:"column:last_status_code"`
+	LastError      string     `json:"last_error,omitempty" gorm:"column:last_error"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"column:next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at" gorm:"column:delivered_at"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+func (d *WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -0,0 +1,146 @@
+// Package events 进程内事件总线，借还书等操作发布图书可借数量变化和学生个人的借阅、预约事件，
+// 由实时推送接口转发给订阅的客户端。多实例部署时通过Broker在实例间转发事件。
+package events
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	TypeAvailability  = "availability"   // 图书可借数量变化
+	TypeLoanBorrowed  = "loan.borrowed"  // 学生借出图书
+	TypeLoanReturned  = "loan.returned"  // 学生归还图书
+	TypeHoldPlaced    = "hold.placed"    // 学生预约图书
+	TypeHoldReady     = "hold.ready"     // 预约的书已上取书分馆预约架
+	TypeHoldCancelled = "hold.cancelled" // 学生取消预约
+)
+
+// 每个订阅者缓冲的事件数，客户端处理不过来时丢弃新事件
+const subscriptionBuffer = 64
+
+// Event 一条实时事件；StuID不为空的是个人事件，只推送给该学生
+type Event struct {
+	Type   string          `json:"type"`
+	BookID string          `json:"book_id,omitempty"`
+	StuID  string          `json:"stu_id,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	At     time.Time       `json:"at"`
+}
+
+// 创建事件，data序列化为JSON
+func NewEvent(eventType, bookID, stuID string, data interface{}) (*Event, error) {
+	event := &Event{Type: eventType, BookID: bookID, StuID: stuID, At: time.Now()}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		event.Data = raw
+	}
+	return event, nil
+}
+
+// Broker 在实例间转发事件。Publish发布的事件（包括本实例发布的）都应交给Start传入的deliver
+type Broker interface {
+	Publish(event *Event) error
+	Start(deliver func(*Event)) error
+	Close() error
+}
+
+// Bus 事件总线，nil总线的所有方法都不做任何事，便于未配置推送时直接使用
+type Bus struct {
+	broker Broker
+
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func NewBus(broker Broker) (*Bus, error) {
+	bus := &Bus{broker: broker, subs: make(map[*Subscription]struct{})}
+	if err := broker.Start(bus.dispatch); err != nil {
+		return nil, err
+	}
+	return bus, nil
+}
+
+// 发布事件，失败只记录日志，不影响已经完成的业务操作
+func (b *Bus) Publish(event *Event) {
+	if b == nil || event == nil {
+		return
+	}
+	if err := b.broker.Publish(event); err != nil {
+		slog.Error("发布实时事件失败", "type", event.Type, "error", err)
+	}
+}
+
+// 订阅若干图书的可借数量变化，stuID不为空时同时订阅该学生的个人事件
+func (b *Bus) Subscribe(bookIDs []string, stuID string) *Subscription {
+	ch := make(chan *Event, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, bus: b, bookIDs: make(map[string]bool), stuID: stuID}
+	for _, bookID := range bookIDs {
+		sub.bookIDs[bookID] = true
+	}
+	if b == nil {
+		return sub
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// 把事件分发给本实例中匹配的订阅者
+func (b *Bus) dispatch(event *Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
+
+// 关闭总线和Broker
+func (b *Bus) Close() error {
+	if b == nil {
+		return nil
+	}
+	return b.broker.Close()
+}
+
+// Subscription 一个客户端的订阅，从C读取事件，结束时调用Close
+type Subscription struct {
+	C <-chan *Event
+
+	ch      chan *Event
+	bus     *Bus
+	bookIDs map[string]bool
+	stuID   string
+}
+
+func (s *Subscription) matches(event *Event) bool {
+	if event.StuID != "" {
+		return event.StuID == s.stuID
+	}
+	return s.bookIDs[event.BookID]
+}
+
+// 取消订阅
+func (s *Subscription) Close() {
+	if s.bus == nil {
+		return
+	}
+	s.bus.mu.Lock()
+	delete(s.bus.subs, s)
+	s.bus.mu.Unlock()
+}
@@ -0,0 +1,141 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func newTestBus(t *testing.T) *Bus {
+	t.Helper()
+	bus, err := NewBus(NewMemoryBroker())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bus.Close() })
+	return bus
+}
+
+func publish(t *testing.T, bus *Bus, eventType, bookID, stuID string) {
+	t.Helper()
+	event, err := NewEvent(eventType, bookID, stuID, map[string]int{"available_copies": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(event)
+}
+
+// 读出订阅中已有的全部事件，返回类型和图书
+func drain(sub *Subscription) []string {
+	var got []string
+	for {
+		select {
+		case event := <-sub.C:
+			got = append(got, event.Type+":"+event.BookID)
+		default:
+			return got
+		}
+	}
+}
+
+func assertEvents(t *testing.T, name string, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s 收到 %v，期望 %v", name, got, want)
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s 收到 %v，期望 %v", name, got, want)
+			return
+		}
+	}
+}
+
+func TestBusFiltering(t *testing.T) {
+	bus := newTestBus(t)
+	books := bus.Subscribe([]string{"B001", "B002"}, "")
+	defer books.Close()
+	student := bus.Subscribe(nil, "20230001")
+	defer student.Close()
+	both := bus.Subscribe([]string{"B003"}, "20230002")
+	defer both.Close()
+
+	publish(t, bus, TypeAvailability, "B001", "")
+	publish(t, bus, TypeAvailability, "B003", "")
+	publish(t, bus, TypeAvailability, "B002", "")
+	// 个人事件只推送给本人，即使订阅了对应的图书
+	publish(t, bus, TypeLoanBorrowed, "B001", "20230001")
+	publish(t, bus, TypeHoldReady, "B003", "20230002")
+	publish(t, bus, TypeHoldPlaced, "B004", "20230003")
+
+	assertEvents(t, "订阅图书", drain(books), "availability:B001", "availability:B002")
+	assertEvents(t, "学生20230001", drain(student), "loan.borrowed:B001")
+	assertEvents(t, "图书和学生20230002", drain(both), "availability:B003", "hold.ready:B003")
+}
+
+func TestBusSubscriptionClose(t *testing.T) {
+	bus := newTestBus(t)
+	sub := bus.Subscribe([]string{"B001"}, "")
+	sub.Close()
+	publish(t, bus, TypeAvailability, "B001", "")
+	assertEvents(t, "取消订阅后", drain(sub))
+}
+
+// 客户端处理不过来时丢弃新事件，不阻塞发布
+func TestBusDropsWhenBufferFull(t *testing.T) {
+	bus := newTestBus(t)
+	sub := bus.Subscribe([]string{"B001"}, "")
+	defer sub.Close()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriptionBuffer+10; i++ {
+			publish(t, bus, TypeAvailability, "B001", "")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("发布被阻塞")
+	}
+	if got := len(drain(sub)); got != subscriptionBuffer {
+		t.Errorf("缓冲的事件数 = %d，期望 %d", got, subscriptionBuffer)
+	}
+}
+
+// 未配置推送时总线为nil，所有方法都不做任何事
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	publish(t, bus, TypeAvailability, "B001", "")
+	sub := bus.Subscribe([]string{"B001"}, "")
+	sub.Close()
+	if err := bus.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	event, err := NewEvent(TypeLoanReturned, "B001", "20230001", map[string]string{"barcode": "B001-0001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Event
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Type != TypeLoanReturned || decoded.BookID != "B001" || decoded.StuID != "20230001" ||
+		string(decoded.Data) != `{"barcode":"B001-0001"}` || !decoded.At.Equal(event.At) {
+		t.Errorf("序列化往返 = %+v，原事件 %+v", decoded, event)
+	}
+
+	event, err = NewEvent(TypeAvailability, "B001", "", nil)
+	if err != nil || event.Data != nil {
+		t.Errorf("没有数据时 Data = %s, %v", event.Data, err)
+	}
+}
@@ -0,0 +1,169 @@
+package events
+
+import (
+	"backend/dao"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+const (
+	// 每次轮询最多读取的事件数
+	dbBrokerBatchSize = 500
+	// 实时事件保留时间，只用于实例间转发，过期后删除
+	dbBrokerRetention = time.Hour
+	// 自增ID出现空缺时等待的时间。并发写入的事务可能按与ID不同的顺序提交，
+	// 较小的ID稍后才可见；超过该时间仍未出现的ID视为回滚或被数据库跳过
+	dbBrokerGapTimeout = 30 * time.Second
+)
+
+// DBBroker 多实例部署使用：事件写入stream_events表，各实例轮询读取新事件
+// Close时取消ctx，中止正在执行的查询
+type DBBroker struct {
+	streamEventDAO *dao.StreamEventDAO
+	interval       time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewDBBroker(db *sql.DB, interval time.Duration) *DBBroker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DBBroker{
+		streamEventDAO: dao.NewStreamEventDAO(db),
+		interval:       interval,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+func (b *DBBroker) Publish(event *Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.streamEventDAO.CreateStreamEvent(b.ctx, string(payload))
+}
+
+// 从当前最新的事件之后开始轮询，启动前的事件不再推送
+func (b *DBBroker) Start(deliver func(*Event)) error {
+	lastID, err := b.streamEventDAO.GetMaxStreamEventID(b.ctx)
+	if err != nil {
+		return err
+	}
+
+	go b.poll(newStreamCursor(lastID, dbBrokerGapTimeout), deliver)
+	return nil
+}
+
+func (b *DBBroker) poll(cursor *streamCursor, deliver func(*Event)) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.ctx.Done():
+			return
+		}
+
+		// 从最早的空缺处重新读取，跳过已经推送过的事件；空缺之后已推送的事件可能超过一批，读到不满一批为止
+		afterID := cursor.lastID
+		for {
+			rows, err := b.streamEventDAO.GetStreamEventsAfter(b.ctx, afterID, dbBrokerBatchSize)
+			if err != nil {
+				slog.Error("读取实时事件失败", "error", err)
+				break
+			}
+			for _, row := range rows {
+				afterID = row.ID
+				if !cursor.accept(row.ID) {
+					continue
+				}
+				var event Event
+				if err := json.Unmarshal([]byte(row.Payload), &event); err != nil {
+					slog.Error("解析实时事件失败", "id", row.ID, "error", err)
+					continue
+				}
+				deliver(&event)
+			}
+			if len(rows) < dbBrokerBatchSize {
+				break
+			}
+		}
+		for _, id := range cursor.advance(time.Now()) {
+			slog.Warn("实时事件ID空缺超时，不再等待", "id", id)
+		}
+
+		if time.Since(lastCleanup) > dbBrokerRetention {
+			lastCleanup = time.Now()
+			if err := b.streamEventDAO.DeleteStreamEventsBefore(b.ctx, lastCleanup.Add(-dbBrokerRetention)); err != nil {
+				slog.Error("清理实时事件失败", "error", err)
+			}
+		}
+	}
+}
+
+func (b *DBBroker) Close() error {
+	b.cancel()
+	return nil
+}
+
+// streamCursor 记录轮询进度。lastID及之前的事件都已推送或放弃；
+// 之后已推送的ID记在seen中，尚未出现的ID记在gaps中，等到补齐或超时再推进lastID
+type streamCursor struct {
+	lastID     int
+	gapTimeout time.Duration
+	seen       map[int]bool
+	gaps       map[int]time.Time
+}
+
+func newStreamCursor(lastID int, gapTimeout time.Duration) *streamCursor {
+	return &streamCursor{
+		lastID:     lastID,
+		gapTimeout: gapTimeout,
+		seen:       make(map[int]bool),
+		gaps:       make(map[int]time.Time),
+	}
+}
+
+// 读到一条事件，返回是否需要推送（之前没有推送过）
+func (c *streamCursor) accept(id int) bool {
+	if id <= c.lastID || c.seen[id] {
+		return false
+	}
+	c.seen[id] = true
+	delete(c.gaps, id)
+	return true
+}
+
+// 记录新出现的空缺，并把lastID推进到第一个仍在等待的空缺之前，返回因超时放弃的ID
+func (c *streamCursor) advance(now time.Time) []int {
+	maxSeen := c.lastID
+	for id := range c.seen {
+		maxSeen = max(maxSeen, id)
+	}
+	for id := c.lastID + 1; id < maxSeen; id++ {
+		if _, ok := c.gaps[id]; !ok && !c.seen[id] {
+			c.gaps[id] = now
+		}
+	}
+
+	var expired []int
+	for c.lastID < maxSeen {
+		id := c.lastID + 1
+		if since, ok := c.gaps[id]; ok {
+			if now.Sub(since) < c.gapTimeout {
+				break
+			}
+			delete(c.gaps, id)
+			expired = append(expired, id)
+		}
+		delete(c.seen, id)
+		c.lastID = id
+	}
+	return expired
+}
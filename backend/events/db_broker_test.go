@@ -0,0 +1,88 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamCursorInOrder(t *testing.T) {
+	now := time.Now()
+	cursor := newStreamCursor(10, time.Minute)
+	for _, id := range []int{11, 12, 13} {
+		if !cursor.accept(id) {
+			t.Errorf("accept(%d) = false", id)
+		}
+	}
+	if expired := cursor.advance(now); len(expired) != 0 || cursor.lastID != 13 || len(cursor.seen) != 0 {
+		t.Errorf("advance = %v, lastID=%d, seen=%v", expired, cursor.lastID, cursor.seen)
+	}
+	if cursor.accept(12) || cursor.accept(10) {
+		t.Error("已推送的事件再次推送")
+	}
+}
+
+// 事务按与ID不同的顺序提交：较小的ID稍后可见时仍然推送，且每条只推送一次
+func TestStreamCursorOutOfOrder(t *testing.T) {
+	now := time.Now()
+	cursor := newStreamCursor(0, time.Minute)
+
+	cursor.accept(1)
+	cursor.accept(3)
+	cursor.accept(5)
+	cursor.advance(now)
+	if cursor.lastID != 1 {
+		t.Fatalf("存在空缺时 lastID = %d，期望停在 1", cursor.lastID)
+	}
+
+	// 下一次轮询从lastID之后重新读取
+	for _, id := range []int{3, 5} {
+		if cursor.accept(id) {
+			t.Errorf("accept(%d) 重复推送", id)
+		}
+	}
+	if !cursor.accept(2) {
+		t.Error("迟到的事件2没有推送")
+	}
+	cursor.advance(now.Add(time.Second))
+	if cursor.lastID != 3 {
+		t.Errorf("补齐2后 lastID = %d，期望 3", cursor.lastID)
+	}
+	if !cursor.accept(4) {
+		t.Error("迟到的事件4没有推送")
+	}
+	cursor.advance(now.Add(2 * time.Second))
+	if cursor.lastID != 5 || len(cursor.seen) != 0 || len(cursor.gaps) != 0 {
+		t.Errorf("全部补齐后 lastID=%d, seen=%v, gaps=%v", cursor.lastID, cursor.seen, cursor.gaps)
+	}
+}
+
+// 回滚的事务留下的空缺超时后放弃，不再阻塞后续事件
+func TestStreamCursorGapTimeout(t *testing.T) {
+	now := time.Now()
+	cursor := newStreamCursor(0, 30*time.Second)
+	cursor.accept(1)
+	cursor.accept(4)
+	cursor.advance(now)
+	if cursor.lastID != 1 {
+		t.Fatalf("lastID = %d", cursor.lastID)
+	}
+
+	// 新事件不受尚未超时的空缺影响
+	if !cursor.accept(5) {
+		t.Error("空缺之后的新事件没有推送")
+	}
+	if expired := cursor.advance(now.Add(29 * time.Second)); len(expired) != 0 || cursor.lastID != 1 {
+		t.Errorf("超时前 advance = %v, lastID = %d", expired, cursor.lastID)
+	}
+
+	expired := cursor.advance(now.Add(30 * time.Second))
+	if len(expired) != 2 || expired[0] != 2 || expired[1] != 3 {
+		t.Errorf("超时放弃的ID = %v，期望 [2 3]", expired)
+	}
+	if cursor.lastID != 5 || len(cursor.gaps) != 0 {
+		t.Errorf("超时后 lastID=%d, gaps=%v", cursor.lastID, cursor.gaps)
+	}
+	if cursor.accept(3) {
+		t.Error("超时放弃后的事件仍然推送")
+	}
+}
@@ -0,0 +1,35 @@
+package events
+
+import "sync"
+
+// MemoryBroker 单实例部署使用，发布的事件直接交给本实例
+type MemoryBroker struct {
+	mu      sync.RWMutex
+	deliver func(*Event)
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(event *Event) error {
+	b.mu.RLock()
+	deliver := b.deliver
+	b.mu.RUnlock()
+
+	if deliver != nil {
+		deliver(event)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Start(deliver func(*Event)) error {
+	b.mu.Lock()
+	b.deliver = deliver
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
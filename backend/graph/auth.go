@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/service"
+	"context"
+
+	"github.com/graphql-go/graphql"
+)
+
+// 字段的访问规则，返回nil时允许访问；被拒绝的字段为null，errors中带错误码
+type rule func(ctx context.Context, source interface{}) error
+
+// 馆员和管理员可以查看任何学生的数据
+func isStaff(actor service.Actor) bool {
+	return actor.Role == do.RoleLibrarian || actor.Role == do.RoleAdmin
+}
+
+// 检查调用方能否访问学生stuID的数据：学生本人、馆员或管理员
+func authorizeStudent(ctx context.Context, stuID string) error {
+	actor := service.ActorFrom(ctx)
+	switch {
+	case isStaff(actor):
+		return nil
+	case actor.Role == service.RoleStudent && actor.ID == stuID:
+		return nil
+	case actor.Role == service.RoleStudent || actor.Role == do.RoleKiosk:
+		return apperr.New(apperr.CodeForbidden)
+	}
+	return apperr.New(apperr.CodeUnauthorized)
+}
+
+// 只有馆员和管理员可以访问
+func staffOnly(ctx context.Context, _ interface{}) error {
+	actor := service.ActorFrom(ctx)
+	switch {
+	case isStaff(actor):
+		return nil
+	case actor.Role == service.RoleStudent || actor.Role == do.RoleKiosk:
+		return apperr.New(apperr.CodeForbidden)
+	}
+	return apperr.New(apperr.CodeUnauthorized)
+}
+
+// 学生本人、馆员或管理员可以访问，owner取出字段所属对象的学号
+func ownerOrStaff(owner func(source interface{}) string) rule {
+	return func(ctx context.Context, source interface{}) error {
+		return authorizeStudent(ctx, owner(source))
+	}
+}
+
+// 给一组字段加上同一访问规则
+func guard(r rule, fields graphql.Fields) graphql.Fields {
+	for _, field := range fields {
+		resolve := field.Resolve
+		field.Resolve = func(p graphql.ResolveParams) (interface{}, error) {
+			if err := r(p.Context, p.Source); err != nil {
+				return nil, err
+			}
+			return resolve(p)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,121 @@
+// Package graph GraphQL接口，一次请求取回页面需要的学生、借阅、图书、预约和罚款，与HTTP接口共用业务层和令牌
+// 嵌套字段通过loader按层合并查询，列表中每项的关联数据不会各查一次数据库；
+// 字段按调用方的角色授权，被拒绝的字段为null，errors中的extensions.code为apperr的错误码，提示按协商的语言输出
+package graph
+
+import (
+	"backend/apperr"
+	"backend/i18n"
+	"backend/service"
+	"backend/tracing"
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Deps GraphQL接口使用的业务层
+type Deps struct {
+	Books  *service.BookService
+	Lookup *service.LookupService
+	Limits Limits
+}
+
+// Schema 可执行的GraphQL schema
+type Schema struct {
+	schema graphql.Schema
+	lookup *service.LookupService
+	limits Limits
+}
+
+func New(deps Deps) (*Schema, error) {
+	schema, err := buildSchema(deps.Books)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{schema: schema, lookup: deps.Lookup, limits: deps.Limits}, nil
+}
+
+// Request GraphQL请求
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// 执行查询：解析、校验、检查限制后执行，调用方由ctx中的Actor确定；出错时结果中只有errors
+func (s *Schema) Execute(ctx context.Context, req Request) *graphql.Result {
+	ctx, span := tracing.Start(ctx, "GraphQL.Execute", attribute.String("graphql.operation.name", req.OperationName))
+	defer span.End()
+
+	result := s.execute(ctx, req)
+	result.Errors = formatErrors(ctx, result.Errors)
+	return result
+}
+
+func (s *Schema) execute(ctx context.Context, req Request) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if validation := graphql.ValidateDocument(&s.schema, doc, nil); !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+	if err := checkLimits(&s.schema, doc, req.OperationName, s.limits); err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	// loader只在本次请求内合并查询和缓存
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withLoaders(ctx, newLoaders(s.lookup)),
+	})
+}
+
+// 按错误码输出错误：字段返回的错误转为apperr，提示按协商的语言输出，内部错误记录日志；
+// 查询语句本身的错误（语法、校验、变量）没有错误码，作为参数错误原样输出
+func formatErrors(ctx context.Context, errs []gqlerrors.FormattedError) []gqlerrors.FormattedError {
+	locale := i18n.Locale(ctx)
+	for i, e := range errs {
+		err := cause(e)
+		if _, ok := err.(*gqlerrors.Error); ok {
+			errs[i].Extensions = map[string]interface{}{"code": apperr.CodeInvalidArgument}
+			continue
+		}
+
+		appErr := apperr.From(err)
+		if appErr.Status() >= http.StatusInternalServerError {
+			slog.ErrorContext(ctx, "GraphQL字段解析失败", "path", e.Path, "error", err)
+		}
+		errs[i].Message = appErr.Localize(locale)
+		errs[i].Extensions = map[string]interface{}{"code": appErr.Code}
+	}
+	return errs
+}
+
+// 取出字段返回的原始错误；执行器会把错误层层包装为gqlerrors的类型，查询语句本身的错误没有原始错误
+func cause(err error) error {
+	for {
+		switch e := err.(type) {
+		case gqlerrors.FormattedError:
+			if e.OriginalError() == nil {
+				return err
+			}
+			err = e.OriginalError()
+		case *gqlerrors.Error:
+			if e.OriginalError == nil {
+				return err
+			}
+			err = e.OriginalError
+		default:
+			return err
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package graph
+
+import (
+	"backend/apperr"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// 列表中的字段按列表长度估算代价，执行前不知道长度，按固定倍数计
+const listCost = 10
+
+// Limits 查询的限制，在执行前按查询语句估算，不访问数据库
+type Limits struct {
+	MaxDepth      int // 字段的最大嵌套层数，根字段为第1层
+	MaxComplexity int // 最大代价：每个字段计1，子字段的代价在列表中按listCost倍计
+}
+
+// 估算查询代价时的上下文
+type measurer struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+}
+
+// 检查要执行的操作是否超过限制；查询已通过校验，字段和片段都存在
+func checkLimits(schema *graphql.Schema, doc *ast.Document, operationName string, limits Limits) error {
+	m := &measurer{schema: schema, fragments: make(map[string]*ast.FragmentDefinition)}
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			if operation == nil && (operationName == "" || definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		case *ast.FragmentDefinition:
+			m.fragments[definition.Name.Value] = definition
+		}
+	}
+	// 找不到操作时由执行器报错
+	if operation == nil {
+		return nil
+	}
+
+	// 只有查询操作，其他操作由执行器拒绝
+	complexity, depth := m.measure(operation.SelectionSet, schema.QueryType(), 1)
+	if depth > limits.MaxDepth {
+		return apperr.Invalid("invalid.graphql_depth").With("depth", depth).With("max", limits.MaxDepth)
+	}
+	if complexity > limits.MaxComplexity {
+		return apperr.Invalid("invalid.graphql_complexity").With("complexity", complexity).With("max", limits.MaxComplexity)
+	}
+	return nil
+}
+
+// 返回选择集的代价和其中字段的最大层数，选择集中的字段位于第depth层
+func (m *measurer) measure(set *ast.SelectionSet, parent graphql.Type, depth int) (complexity, maxDepth int) {
+	if set == nil {
+		return 0, depth - 1
+	}
+	maxDepth = depth - 1
+	for _, selection := range set.Selections {
+		var cost, d int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			// 内省字段不计入
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			cost, d = m.measureField(selection, parent, depth)
+		case *ast.InlineFragment:
+			typ := parent
+			if selection.TypeCondition != nil {
+				typ = m.schema.Type(selection.TypeCondition.Name.Value)
+			}
+			cost, d = m.measure(selection.SelectionSet, typ, depth)
+		case *ast.FragmentSpread:
+			fragment, ok := m.fragments[selection.Name.Value]
+			if !ok {
+				continue
+			}
+			cost, d = m.measure(fragment.SelectionSet, m.schema.Type(fragment.TypeCondition.Name.Value), depth)
+		}
+		complexity += cost
+		maxDepth = max(maxDepth, d)
+	}
+	return complexity, maxDepth
+}
+
+func (m *measurer) measureField(field *ast.Field, parent graphql.Type, depth int) (int, int) {
+	object, ok := parent.(*graphql.Object)
+	if !ok {
+		return 1, depth
+	}
+	definition, ok := object.Fields()[field.Name.Value]
+	if !ok {
+		return 1, depth
+	}
+
+	typ, multiplier := definition.Type, 1
+	if nonNull, ok := typ.(*graphql.NonNull); ok {
+		typ = nonNull.OfType
+	}
+	if _, ok := typ.(*graphql.List); ok {
+		multiplier = listCost
+	}
+	children, d := m.measure(field.SelectionSet, graphql.GetNamed(definition.Type).(graphql.Type), depth+1)
+	return 1 + multiplier*children, max(depth, d)
+}
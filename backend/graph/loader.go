@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"backend/do"
+	"backend/service"
+	"context"
+	"sync"
+)
+
+// loader 合并同一层字段的读取：load只登记ID并返回thunk，执行器解析完这一层的所有字段后才调用thunk，
+// 第一个被调用的thunk用一次查询取回所有已登记的ID；同一请求内已取回的ID不再查询
+type loader[V any] struct {
+	fetch func(ctx context.Context, ids []string) (map[string]V, error)
+
+	mu      sync.Mutex
+	pending []string
+	queued  map[string]bool
+	values  map[string]V
+	errs    map[string]error
+}
+
+func newLoader[V any](fetch func(ctx context.Context, ids []string) (map[string]V, error)) *loader[V] {
+	return &loader[V]{
+		fetch:  fetch,
+		queued: make(map[string]bool),
+		values: make(map[string]V),
+		errs:   make(map[string]error),
+	}
+}
+
+// 登记id，返回的thunk取回该id的值；查不到时为V的零值
+func (l *loader[V]) load(ctx context.Context, id string) func() (V, error) {
+	l.mu.Lock()
+	if !l.queued[id] {
+		l.queued[id] = true
+		l.pending = append(l.pending, id)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if len(l.pending) > 0 {
+			ids := l.pending
+			l.pending = nil
+			values, err := l.fetch(ctx, ids)
+			for _, pendingID := range ids {
+				if err != nil {
+					l.errs[pendingID] = err
+					continue
+				}
+				l.values[pendingID] = values[pendingID]
+			}
+		}
+		return l.values[id], l.errs[id]
+	}
+}
+
+// loaders 一次请求使用的全部loader，请求结束后丢弃，不跨请求缓存
+type loaders struct {
+	books          *loader[*do.Book]
+	students       *loader[*do.Student]
+	loansByStudent *loader[[]do.BorrowRecord]
+	loansByBook    *loader[[]do.BorrowRecord]
+	holdsByStudent *loader[[]do.Hold]
+}
+
+func newLoaders(lookup *service.LookupService) *loaders {
+	return &loaders{
+		books:          newLoader(lookup.BooksByID),
+		students:       newLoader(lookup.StudentsByID),
+		loansByStudent: newLoader(lookup.LoansByStudent),
+		loansByBook:    newLoader(lookup.LoansByBook),
+		holdsByStudent: newLoader(lookup.HoldsByStudent),
+	}
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
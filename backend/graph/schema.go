@@ -0,0 +1,339 @@
+package graph
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/service"
+	"context"
+
+	"github.com/graphql-go/graphql"
+)
+
+// fines 学生未缴的罚款：逾期未还且已产生罚款的借阅，与借书前的罚款检查一致
+type fines struct {
+	stuID string
+	loans []*do.BorrowRecord
+}
+
+// 构建GraphQL类型。对象之间互相引用（借阅的图书、图书当前的借阅），字段用thunk延迟定义
+func buildSchema(books *service.BookService) (graphql.Schema, error) {
+	var bookType, studentType, loanType, holdType, finesType *graphql.Object
+
+	bookType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Book",
+		Description: "图书，除当前借阅外所有人可见",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			fields := graphql.Fields{
+				"bookId":          bookField(graphql.NewNonNull(graphql.ID), func(b *do.Book) interface{} { return b.BookID }),
+				"title":           bookField(graphql.NewNonNull(graphql.String), func(b *do.Book) interface{} { return b.Title }),
+				"author":          bookField(graphql.NewNonNull(graphql.String), func(b *do.Book) interface{} { return b.Author }),
+				"description":     bookField(graphql.NewNonNull(graphql.String), func(b *do.Book) interface{} { return b.Description }),
+				"totalCopies":     bookField(graphql.NewNonNull(graphql.Int), func(b *do.Book) interface{} { return b.TotalCopies }),
+				"availableCopies": bookField(graphql.NewNonNull(graphql.Int), func(b *do.Book) interface{} { return b.AvailableCopies }),
+				"canBorrow":       bookField(graphql.NewNonNull(graphql.Boolean), func(b *do.Book) interface{} { return b.CanBorrow }),
+				"coverUrl":        bookField(graphql.String, func(b *do.Book) interface{} { return optional(b.CoverURL) }),
+				"coverThumbUrl":   bookField(graphql.String, func(b *do.Book) interface{} { return optional(b.CoverThumbURL) }),
+				"createdAt":       bookField(graphql.NewNonNull(graphql.DateTime), func(b *do.Book) interface{} { return b.CreatedAt }),
+			}
+			fields["loans"] = guard(staffOnly, graphql.Fields{"loans": {
+				Type:        graphql.NewList(graphql.NewNonNull(loanType)),
+				Description: "当前未归还的借阅，仅馆员和管理员可见",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					load := loadersFrom(p.Context).loansByBook.load(p.Context, p.Source.(*do.Book).BookID)
+					return then(load, loanList), nil
+				},
+			}})["loans"]
+			return fields
+		}),
+	})
+
+	studentOwner := func(source interface{}) string { return source.(*do.Student).StuId }
+	studentType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Student",
+		Description: "学生，仅本人、馆员和管理员可见",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return guard(ownerOrStaff(studentOwner), graphql.Fields{
+				"stuId":     studentField(graphql.NewNonNull(graphql.ID), func(s *do.Student) interface{} { return s.StuId }),
+				"name":      studentField(graphql.NewNonNull(graphql.String), func(s *do.Student) interface{} { return s.Name }),
+				"trust":     studentField(graphql.NewNonNull(graphql.Float), func(s *do.Student) interface{} { return s.Trust }),
+				"canBorrow": studentField(graphql.NewNonNull(graphql.Boolean), func(s *do.Student) interface{} { return s.CanBorrow }),
+				"createdAt": studentField(graphql.NewNonNull(graphql.DateTime), func(s *do.Student) interface{} { return s.CreatedAt }),
+				"loans": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(loanType))),
+					Description: "当前未归还的借阅，按借出时间从新到旧",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						load := loadersFrom(p.Context).loansByStudent.load(p.Context, studentOwner(p.Source))
+						return then(load, loanList), nil
+					},
+				},
+				"holds": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(holdType))),
+					Description: "全部预约，按预约时间从新到旧",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						load := loadersFrom(p.Context).holdsByStudent.load(p.Context, studentOwner(p.Source))
+						return then(load, holdList), nil
+					},
+				},
+				"fines": {
+					Type:        graphql.NewNonNull(finesType),
+					Description: "未缴的罚款",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						stuID := studentOwner(p.Source)
+						load := loadersFrom(p.Context).loansByStudent.load(p.Context, stuID)
+						return then(load, func(records []do.BorrowRecord) interface{} {
+							unpaid := &fines{stuID: stuID, loans: []*do.BorrowRecord{}}
+							for i := range records {
+								if records[i].IsOverdue && records[i].FineAmount > 0 {
+									unpaid.loans = append(unpaid.loans, &records[i])
+								}
+							}
+							return unpaid
+						}), nil
+					},
+				},
+			})
+		}),
+	})
+
+	loanOwner := func(source interface{}) string { return source.(*do.BorrowRecord).StuID }
+	loanType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Loan",
+		Description: "借阅记录，仅借阅的学生本人、馆员和管理员可见",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return guard(ownerOrStaff(loanOwner), graphql.Fields{
+				"id":         loanField(graphql.NewNonNull(graphql.Int), func(r *do.BorrowRecord) interface{} { return r.ID }),
+				"barcode":    loanField(graphql.String, func(r *do.BorrowRecord) interface{} { return optional(r.Barcode) }),
+				"borrowDate": loanField(graphql.NewNonNull(graphql.DateTime), func(r *do.BorrowRecord) interface{} { return r.BorrowDate }),
+				"dueDate":    loanField(graphql.NewNonNull(graphql.DateTime), func(r *do.BorrowRecord) interface{} { return r.DueDate }),
+				"returnDate": loanField(graphql.DateTime, func(r *do.BorrowRecord) interface{} { return r.ReturnDate }),
+				"isOverdue":  loanField(graphql.NewNonNull(graphql.Boolean), func(r *do.BorrowRecord) interface{} { return r.IsOverdue }),
+				"fineAmount": loanField(graphql.NewNonNull(graphql.Float), func(r *do.BorrowRecord) interface{} { return r.FineAmount }),
+				"book": {
+					Type: bookType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						load := loadersFrom(p.Context).books.load(p.Context, p.Source.(*do.BorrowRecord).BookID)
+						return then(load, func(b *do.Book) interface{} { return b }), nil
+					},
+				},
+				"student": {
+					Type: studentType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						load := loadersFrom(p.Context).students.load(p.Context, loanOwner(p.Source))
+						return then(load, func(s *do.Student) interface{} { return s }), nil
+					},
+				},
+			})
+		}),
+	})
+
+	holdOwner := func(source interface{}) string { return source.(*do.Hold).StuID }
+	holdType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Hold",
+		Description: "预约，仅预约的学生本人、馆员和管理员可见",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return guard(ownerOrStaff(holdOwner), graphql.Fields{
+				"id":             holdField(graphql.NewNonNull(graphql.Int), func(h *do.Hold) interface{} { return h.ID }),
+				"status":         holdField(graphql.NewNonNull(graphql.String), func(h *do.Hold) interface{} { return h.Status }),
+				"pickupBranchId": holdField(graphql.NewNonNull(graphql.ID), func(h *do.Hold) interface{} { return h.PickupBranchID }),
+				"barcode":        holdField(graphql.String, func(h *do.Hold) interface{} { return optional(h.Barcode) }),
+				"readyAt":        holdField(graphql.DateTime, func(h *do.Hold) interface{} { return h.ReadyAt }),
+				"createdAt":      holdField(graphql.NewNonNull(graphql.DateTime), func(h *do.Hold) interface{} { return h.CreatedAt }),
+				"book": {
+					Type: bookType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						load := loadersFrom(p.Context).books.load(p.Context, p.Source.(*do.Hold).BookID)
+						return then(load, func(b *do.Book) interface{} { return b }), nil
+					},
+				},
+				"student": {
+					Type: studentType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						load := loadersFrom(p.Context).students.load(p.Context, holdOwner(p.Source))
+						return then(load, func(s *do.Student) interface{} { return s }), nil
+					},
+				},
+			})
+		}),
+	})
+
+	finesOwner := func(source interface{}) string { return source.(*fines).stuID }
+	finesType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Fines",
+		Description: "学生未缴的罚款，缴清后才能继续借书",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return guard(ownerOrStaff(finesOwner), graphql.Fields{
+				"total": {
+					Type: graphql.NewNonNull(graphql.Float),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						var total float64
+						for _, record := range p.Source.(*fines).loans {
+							total += record.FineAmount
+						}
+						return total, nil
+					},
+				},
+				"loans": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(loanType))),
+					Description: "产生罚款的借阅",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(*fines).loans, nil
+					},
+				},
+			})
+		}),
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": {
+				Type:        studentType,
+				Description: "当前登录的学生，需要学生令牌",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					actor := service.ActorFrom(p.Context)
+					switch actor.Role {
+					case service.RoleStudent:
+						return loadStudent(p.Context, actor.ID), nil
+					case do.ActorAnonymous, do.ActorSystem:
+						return nil, apperr.New(apperr.CodeUnauthorized)
+					}
+					return nil, apperr.New(apperr.CodeForbidden)
+				},
+			},
+			"student": {
+				Type:        studentType,
+				Description: "学生，学生只能查询本人",
+				Args: graphql.FieldConfigArgument{
+					"stuId": {Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					stuID, _ := p.Args["stuId"].(string)
+					if err := authorizeStudent(p.Context, stuID); err != nil {
+						return nil, err
+					}
+					return loadStudent(p.Context, stuID), nil
+				},
+			},
+			"book": {
+				Type: bookType,
+				Args: graphql.FieldConfigArgument{
+					"bookId": {Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					bookID, _ := p.Args["bookId"].(string)
+					load := loadersFrom(p.Context).books.load(p.Context, bookID)
+					return func() (interface{}, error) {
+						book, err := load()
+						if err != nil {
+							return nil, err
+						}
+						if book == nil {
+							return nil, apperr.New(apperr.CodeBookNotFound)
+						}
+						return book, nil
+					}, nil
+				},
+			},
+			"books": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bookType))),
+				Description: "图书列表；keyword不为空时按书名或作者搜索可借阅的图书",
+				Args: graphql.FieldConfigArgument{
+					"keyword": {Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var list []do.Book
+					var err error
+					if keyword, _ := p.Args["keyword"].(string); keyword != "" {
+						list, err = books.SearchBooks(p.Context, keyword)
+					} else {
+						list, err = books.GetAllBooks(p.Context)
+					}
+					if err != nil {
+						return nil, err
+					}
+					result := make([]*do.Book, len(list))
+					for i := range list {
+						result[i] = &list[i]
+					}
+					return result, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// 按学号读取学生，学生不存在时返回STUDENT_NOT_FOUND
+func loadStudent(ctx context.Context, stuID string) func() (interface{}, error) {
+	load := loadersFrom(ctx).students.load(ctx, stuID)
+	return func() (interface{}, error) {
+		student, err := load()
+		if err != nil {
+			return nil, err
+		}
+		if student == nil {
+			return nil, apperr.New(apperr.CodeStudentNotFound)
+		}
+		return student, nil
+	}
+}
+
+// 把loader的thunk转为执行器使用的thunk
+func then[V any](load func() (V, error), convert func(V) interface{}) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return convert(value), nil
+	}
+}
+
+func loanList(records []do.BorrowRecord) interface{} {
+	result := make([]*do.BorrowRecord, len(records))
+	for i := range records {
+		result[i] = &records[i]
+	}
+	return result
+}
+
+func holdList(holds []do.Hold) interface{} {
+	result := make([]*do.Hold, len(holds))
+	for i := range holds {
+		result[i] = &holds[i]
+	}
+	return result
+}
+
+// 空字符串输出为null
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func bookField(typ graphql.Output, value func(*do.Book) interface{}) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		return value(p.Source.(*do.Book)), nil
+	}}
+}
+
+func studentField(typ graphql.Output, value func(*do.Student) interface{}) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		return value(p.Source.(*do.Student)), nil
+	}}
+}
+
+func loanField(typ graphql.Output, value func(*do.BorrowRecord) interface{}) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		return value(p.Source.(*do.BorrowRecord)), nil
+	}}
+}
+
+func holdField(typ graphql.Output, value func(*do.Hold) interface{}) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		return value(p.Source.(*do.Hold)), nil
+	}}
+}
@@ -0,0 +1,170 @@
+package i18n
+
+// 英文提示
+var enUS = map[string]string{
+	// 错误码的默认提示，以错误码为键
+	"INVALID_ARGUMENT":  "Invalid request",
+	"UNAUTHORIZED":      "Not logged in or missing access token",
+	"FORBIDDEN":         "You do not have permission to perform this action",
+	"NOT_FOUND":         "The requested resource does not exist",
+	"TOO_MANY_REQUESTS": "Too many requests, please try again later",
+	"CANCELED":          "Request canceled",
+	"INTERNAL":          "Internal server error",
+	"TIMEOUT":           "The request timed out, please try again later",
+
+	"TOKEN_INVALID":       "Invalid token",
+	"TOKEN_EXPIRED":       "Token expired",
+	"INVALID_CREDENTIALS": "Incorrect account or password",
+	"INVALID_PIN":         "Incorrect library card or PIN",
+	"PIN_LOCKED":          "Too many wrong PIN attempts, please try again later",
+	"DEVICE_KEY_INVALID":  "Invalid device key or the device is disabled",
+	"DEVICE_DISABLED":     "Device disabled",
+
+	"BOOK_NOT_FOUND":         "Book not found",
+	"ITEM_NOT_FOUND":         "No item found for this barcode",
+	"LOAN_NOT_FOUND":         "Loan not found",
+	"STUDENT_NOT_FOUND":      "Student not found",
+	"LIBRARIAN_NOT_FOUND":    "Librarian not found",
+	"BRANCH_NOT_FOUND":       "Branch not found",
+	"HOLD_NOT_FOUND":         "Hold not found",
+	"TRANSFER_NOT_FOUND":     "Transfer not found",
+	"CLOSURE_NOT_FOUND":      "Closure not found",
+	"DEVICE_NOT_FOUND":       "Device not found",
+	"WEBHOOK_NOT_FOUND":      "Webhook endpoint not found",
+	"EVENT_NOT_FOUND":        "Event not found",
+	"NOTIFICATION_NOT_FOUND": "Notification not found or already sent",
+	"DELIVERY_NOT_FOUND":     "Delivery not found or already succeeded",
+	"COVER_NOT_FOUND":        "Cover not found",
+
+	"BORROW_DISABLED":          "Borrowing privileges are suspended",
+	"UNPAID_FINE":              "You have unpaid fines, please pay them first",
+	"BOOK_NOT_BORROWABLE":      "This book cannot be borrowed",
+	"BOOK_UNAVAILABLE":         "All copies are checked out",
+	"NO_COPY_AT_BRANCH":        "No copy is available at this branch",
+	"NO_HOLDINGS":              "This book has no holdings at any branch",
+	"HOLD_EXISTS":              "You already have an active hold on this book",
+	"HOLD_CLOSED":              "The hold has already been fulfilled or canceled",
+	"NO_FINE_DUE":              "No fines are due",
+	"CHECKOUT_BLOCKED":         "Checkout blocked",
+	"OVERRIDE_NOT_ALLOWED":     "This block cannot be overridden: {code}",
+	"OVERRIDE_REASON_REQUIRED": "A reason is required when overriding blocks",
+	"ITEM_NOT_ON_SHELF":        "Only items on the shelf can be transferred",
+	"ITEM_AT_BRANCH":           "The item is already at the destination branch",
+	"TRANSFER_STATE_INVALID":   "The transfer's status does not allow this operation",
+	"WEBHOOK_DISABLED":         "Webhook endpoint disabled",
+	"MAIL_NOT_CONFIGURED":      "Email delivery is not configured",
+	"STUDENT_EXISTS":           "Student ID already exists",
+	"BOOK_EXISTS":              "Book ID already exists",
+	"BOOK_HAS_ITEMS":           "This book has registered items; copies are counted from the items",
+
+	"COVER_TOO_LARGE":        "Cover file must not exceed {max_mb} MB",
+	"COVER_UNSUPPORTED_TYPE": "Unsupported cover format: {content_type}",
+	"COVER_INVALID":          "The cover image could not be decoded",
+
+	// 自定义错误提示
+	"error.route_not_found":        "No such endpoint: {method} {path}",
+	"error.invalid_request":        "Invalid request: {detail}",
+	"error.student_credentials":    "Incorrect student ID or password",
+	"error.librarian_credentials":  "Incorrect staff ID or password",
+	"error.device_key_missing":     "Missing device key",
+	"error.kiosk_checkout_blocked": "Checkout blocked, please go to the service desk",
+	"error.item_not_on_loan":       "This item has no open loan",
+	"error.cover_dimension":        "Cover dimensions must not exceed {max}x{max}",
+	"error.book_has_no_cover":      "This book has no cover",
+	"error.transfer_not_requested": "The transfer is not awaiting dispatch",
+	"error.transfer_closed":        "The transfer has already been completed or canceled",
+	"error.not_ready":              "Service is not ready",
+	"error.import_record":          "Record {index}: {detail}",
+
+	// 参数校验
+	"invalid.keyword_required":      "Please enter a search keyword",
+	"invalid.book_id_required":      "Book ID is required",
+	"invalid.stu_id_required":       "Student ID is required",
+	"invalid.stu_id_book_id":        "Student ID and book ID are required",
+	"invalid.limit":                 "Invalid limit",
+	"invalid.weekday":               "Invalid weekday",
+	"invalid.weekday_range":         "Weekday must be between 0 (Sunday) and 6 (Saturday)",
+	"invalid.date":                  "Invalid date, expected YYYY-MM-DD",
+	"invalid.start_date":            "Invalid start date, expected YYYY-MM-DD",
+	"invalid.end_date":              "Invalid end date, expected YYYY-MM-DD",
+	"invalid.end_before_start":      "End date must not be before start date",
+	"invalid.open_time":             "Invalid opening time, expected HH:MM",
+	"invalid.close_time":            "Invalid closing time, expected HH:MM",
+	"invalid.close_before_open":     "Closing time must be later than opening time",
+	"invalid.closure_kind":          "Closure kind must be holiday, break or other",
+	"invalid.closure_id":            "Invalid closure ID",
+	"invalid.hold_id":               "Invalid hold ID",
+	"invalid.transfer_id":           "Invalid transfer ID",
+	"invalid.notification_id":       "Invalid notification ID",
+	"invalid.webhook_id":            "Invalid webhook endpoint ID",
+	"invalid.endpoint_id":           "Invalid endpoint_id",
+	"invalid.delivery_id":           "Invalid delivery ID",
+	"invalid.cover_file_required":   "Please choose a cover file to upload",
+	"invalid.cover_size":            "Unsupported cover size: {size}",
+	"invalid.too_many_stream_books": "Too many books, at most {max, plural, one {# book} other {# books}} can be subscribed",
+	"invalid.stream_target":         "Specify book_id or provide a student token",
+	"invalid.barcodes_required":     "Please scan the barcodes of the items to check out",
+	"invalid.rate_limit":            "Rate limit must be greater than 0",
+	"invalid.language":              "Unsupported language: {language}",
+	"invalid.email":                 "Invalid email address",
+	"invalid.email_required":        "An email address is required to subscribe to notifications",
+	"invalid.pin":                   "PIN must be 4 to 6 digits",
+	"invalid.webhook_url":           "Webhook URL must be an http or https address",
+	"invalid.events_required":       "Subscribe to at least one event",
+	"invalid.unknown_event":         "Unknown event: {event}",
+	"invalid.audit_time":            "Invalid {field}, expected an RFC3339 time or YYYY-MM-DD",
+	"invalid.before_id":             "Invalid before_id",
+	"invalid.graphql_depth":         "Query is nested too deeply, at most {max} levels are allowed",
+	"invalid.graphql_complexity":    "Query is too complex (cost {complexity}), the limit is {max}",
+	"invalid.student_name_required": "Name is required",
+	"invalid.password_required":     "Password is required",
+	"invalid.book_title_required":   "Title and author are required",
+	"invalid.total_copies":          "Total copies cannot be less than the {on_loan} copies on loan",
+	"invalid.available_copies":      "Available copies must be between 0 and the total copies",
+
+	// 借出受阻原因
+	"block.duplicate_barcode":   "Barcode scanned more than once",
+	"block.item_not_found":      "No item found for this barcode",
+	"block.book_not_borrowable": "\"{title}\" cannot be borrowed",
+	"block.item_on_hold":        "This item is on hold for student {stu_id}",
+	"block.item_unavailable":    "This item is {status} and cannot be checked out",
+	"block.student_disabled":    "Borrowing privileges are suspended",
+	"block.unpaid_fine":         "Has unpaid fines",
+
+	// 自助借还凭条
+	"kiosk.returned":      "Returned",
+	"kiosk.returned_late": "Returned late with a fine of {fine}; borrowing is suspended until the fine is paid",
+	"kiosk.return_failed": "Could not be returned, please contact the service desk: {error}",
+
+	// 操作成功
+	"ok.health":                "Library system is running",
+	"ok.login":                 "Logged in",
+	"ok.borrowed":              "Borrowed successfully, please return by {due_date}",
+	"ok.returned":              "Returned successfully",
+	"ok.returned_with_fine":    "Returned successfully, an overdue fine of {fine} was charged",
+	"ok.fine_paid":             "Fine paid, borrowing privileges restored",
+	"ok.checked_out":           "{count, plural, one {# item} other {# items}} checked out",
+	"ok.checked_in":            "{returned} of {count, plural, one {# item} other {# items}} returned",
+	"ok.hold_placed":           "Hold placed",
+	"ok.hold_canceled":         "Hold canceled",
+	"ok.branch_created":        "Branch created",
+	"ok.item_registered":       "Item registered",
+	"ok.transfer_requested":    "Transfer requested",
+	"ok.transfer_shipped":      "Transfer dispatched",
+	"ok.transfer_received":     "Transfer received",
+	"ok.hours_updated":         "Opening hours updated",
+	"ok.closure_added":         "Closure added",
+	"ok.closure_deleted":       "Closure deleted",
+	"ok.cover_uploaded":        "Cover uploaded",
+	"ok.cover_deleted":         "Cover deleted",
+	"ok.device_registered":     "Device registered",
+	"ok.device_key_rotated":    "Device key rotated",
+	"ok.device_status_updated": "Device status updated",
+	"ok.rate_limit_updated":    "Rate limit updated",
+	"ok.webhook_registered":    "Webhook endpoint registered",
+	"ok.webhook_updated":       "Webhook endpoint status updated",
+	"ok.webhook_retry":         "The delivery will be retried",
+	"ok.preference_saved":      "Notification settings saved",
+	"ok.notification_retry":    "The notification will be resent",
+	"ok.pin_set":               "PIN set",
+}
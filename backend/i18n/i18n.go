@@ -0,0 +1,243 @@
+// Package i18n 接口提示的多语言目录
+// 提示按键索引，支持 {name} 参数替换和 {count, plural, one {...} other {...}} 形式的复数；
+// 请求的语言在控制器中协商后保存在ctx中，业务层和控制器通过T按请求的语言输出提示
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 支持的语言
+const (
+	ZhCN = "zh-CN"
+	EnUS = "en-US"
+
+	// 协商不出语言时使用的默认语言
+	Default = ZhCN
+)
+
+// Params 提示中的参数
+type Params map[string]interface{}
+
+// Money 金额参数，按语言格式化（如 3.50元 / ¥3.50）
+type Money float64
+
+// 各语言的提示目录，某个语言缺少的键使用默认语言的提示
+var catalogs = map[string]map[string]string{
+	ZhCN: zhCN,
+	EnUS: enUS,
+}
+
+// 是否支持该语言
+func IsSupported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// 匹配支持的语言，忽略大小写，只给出语种（如 en、zh-TW）时匹配同语种的语言；不支持时返回空
+func Match(tag string) string {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return ""
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	var sameBase string
+	for _, locale := range Locales() {
+		if strings.EqualFold(locale, tag) {
+			return locale
+		}
+		if localeBase, _, _ := strings.Cut(locale, "-"); sameBase == "" && strings.EqualFold(localeBase, base) {
+			sameBase = locale
+		}
+	}
+	return sameBase
+}
+
+// 按Accept-Language请求头协商语言，按q值从高到低取第一个支持的语言；都不支持时返回空
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if locale := Match(c.tag); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
+
+type localeKey struct{}
+
+// 请求的语言，首次使用时才协商，避免不输出提示的请求查询用户设置
+type lazyLocale struct {
+	once    sync.Once
+	resolve func() string
+	locale  string
+}
+
+// 在ctx中保存语言
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return WithLocaleFunc(ctx, func() string { return locale })
+}
+
+// 在ctx中保存协商语言的函数，首次调用Locale时执行一次
+func WithLocaleFunc(ctx context.Context, resolve func() string) context.Context {
+	return context.WithValue(ctx, localeKey{}, &lazyLocale{resolve: resolve})
+}
+
+// 获取ctx中的语言，没有或不支持时返回默认语言
+func Locale(ctx context.Context) string {
+	lazy, ok := ctx.Value(localeKey{}).(*lazyLocale)
+	if !ok {
+		return Default
+	}
+	lazy.once.Do(func() {
+		lazy.locale = lazy.resolve()
+		if !IsSupported(lazy.locale) {
+			lazy.locale = Default
+		}
+	})
+	return lazy.locale
+}
+
+// 按ctx中的语言输出提示
+func T(ctx context.Context, key string, params Params) string {
+	return Translate(Locale(ctx), key, params)
+}
+
+// 按指定语言输出提示；目录中没有该键时原样返回键
+func Translate(locale, key string, params Params) string {
+	message, ok := catalogs[locale][key]
+	if !ok {
+		if message, ok = catalogs[Default][key]; !ok {
+			return key
+		}
+		locale = Default
+	}
+	if len(params) == 0 || !strings.Contains(message, "{") {
+		return message
+	}
+
+	message = pluralPattern.ReplaceAllStringFunc(message, func(match string) string {
+		groups := pluralPattern.FindStringSubmatch(match)
+		return plural(locale, groups[2], params[groups[1]])
+	})
+	for name, value := range params {
+		message = strings.ReplaceAll(message, "{"+name+"}", format(locale, value))
+	}
+	return message
+}
+
+// 支持的语言列表
+func Locales() []string {
+	return []string{ZhCN, EnUS}
+}
+
+// 指定语言的目录中是否有该键的提示
+func Has(locale, key string) bool {
+	_, ok := catalogs[locale][key]
+	return ok
+}
+
+// {count, plural, one {# day} other {# days}}
+var (
+	pluralPattern = regexp.MustCompile(`\{(\w+), plural,((?:\s*=?\w+ \{[^{}]*\})+)\s*\}`)
+	pluralForm    = regexp.MustCompile(`(=?\w+) \{([^{}]*)\}`)
+)
+
+// 按数量选择复数形式，# 替换为数量；=n 形式优先于语言的复数规则
+func plural(locale, forms string, value interface{}) string {
+	n, ok := toInt(value)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	category := pluralCategory(locale, n)
+	var exact, byCategory, other string
+	var hasExact, hasCategory bool
+	for _, form := range pluralForm.FindAllStringSubmatch(forms, -1) {
+		switch form[1] {
+		case "=" + strconv.Itoa(n):
+			exact, hasExact = form[2], true
+		case category:
+			byCategory, hasCategory = form[2], true
+		}
+		if form[1] == "other" {
+			other = form[2]
+		}
+	}
+	text := other
+	switch {
+	case hasExact:
+		text = exact
+	case hasCategory:
+		text = byCategory
+	}
+	return strings.ReplaceAll(text, "#", strconv.Itoa(n))
+}
+
+// 语言的复数规则：中文不区分单复数，英文1为one
+func pluralCategory(locale string, n int) string {
+	if locale == EnUS && n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+// 按语言格式化参数：金额带货币符号，时间只显示日期
+func format(locale string, value interface{}) string {
+	switch v := value.(type) {
+	case Money:
+		if locale == ZhCN {
+			return fmt.Sprintf("%.2f元", float64(v))
+		}
+		return fmt.Sprintf("¥%.2f", float64(v))
+	case time.Time:
+		if locale == ZhCN {
+			return v.Format("2006年1月2日")
+		}
+		return v.Format("Jan 2, 2006")
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return format(locale, *v)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,94 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNegotiate(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"en-US", EnUS},
+		{"en-GB,en;q=0.9", EnUS},
+		{"zh-TW", ZhCN},
+		{"fr-FR,en;q=0.5,zh-CN;q=0.8", ZhCN},
+		{"zh-CN;q=0,en", EnUS},
+		{"fr, de;q=0.8", ""},
+		{"EN-us", EnUS},
+	} {
+		if got := Negotiate(tc.header); got != tc.want {
+			t.Errorf("Negotiate(%q) = %q，期望 %q", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	due := time.Date(2026, 3, 5, 0, 0, 0, 0, time.Local)
+	for _, tc := range []struct {
+		locale string
+		key    string
+		params Params
+		want   string
+	}{
+		{ZhCN, "ok.borrowed", Params{"due_date": due}, "借书成功，请于2026年3月5日前归还"},
+		{EnUS, "ok.borrowed", Params{"due_date": due}, "Borrowed successfully, please return by Mar 5, 2026"},
+		{ZhCN, "ok.returned_with_fine", Params{"fine": Money(3.5)}, "还书成功，产生逾期罚款3.50元"},
+		{EnUS, "ok.returned_with_fine", Params{"fine": Money(3.5)}, "Returned successfully, an overdue fine of ¥3.50 was charged"},
+		{ZhCN, "ok.checked_out", Params{"count": 1}, "借出成功，共1册"},
+		{EnUS, "ok.checked_out", Params{"count": 1}, "1 item checked out"},
+		{EnUS, "ok.checked_out", Params{"count": 3}, "3 items checked out"},
+		{EnUS, "ok.checked_in", Params{"returned": 1, "count": 2}, "1 of 2 items returned"},
+		{EnUS, "BOOK_NOT_FOUND", nil, "Book not found"},
+		// 不支持的语言使用默认语言，没有的键原样返回
+		{"fr-FR", "BOOK_NOT_FOUND", nil, "书籍不存在"},
+		{EnUS, "no.such.key", nil, "no.such.key"},
+	} {
+		if got := Translate(tc.locale, tc.key, tc.params); got != tc.want {
+			t.Errorf("Translate(%s, %s) = %q，期望 %q", tc.locale, tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestLocale(t *testing.T) {
+	if got := Locale(context.Background()); got != Default {
+		t.Errorf("未设置语言时 = %q，期望 %q", got, Default)
+	}
+
+	calls := 0
+	ctx := WithLocaleFunc(context.Background(), func() string {
+		calls++
+		return EnUS
+	})
+	for i := 0; i < 3; i++ {
+		if got := Locale(ctx); got != EnUS {
+			t.Fatalf("Locale = %q，期望 %q", got, EnUS)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("协商了%d次，期望只协商一次", calls)
+	}
+
+	if got := Locale(WithLocale(context.Background(), "fr-FR")); got != Default {
+		t.Errorf("不支持的语言 = %q，期望 %q", got, Default)
+	}
+}
+
+// 各语言的目录登记的键应一致
+func TestCatalogsComplete(t *testing.T) {
+	for _, locale := range Locales() {
+		for key := range catalogs[Default] {
+			if !Has(locale, key) {
+				t.Errorf("%s 缺少 %s", locale, key)
+			}
+		}
+		for key := range catalogs[locale] {
+			if !Has(Default, key) {
+				t.Errorf("%s 多出 %s", locale, key)
+			}
+		}
+	}
+}
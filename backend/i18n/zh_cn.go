@@ -0,0 +1,170 @@
+package i18n
+
+// 简体中文提示，同时是其他语言缺少某个键时使用的默认提示
+var zhCN = map[string]string{
+	// 错误码的默认提示，以错误码为键
+	"INVALID_ARGUMENT":  "参数错误",
+	"UNAUTHORIZED":      "未登录或缺少访问令牌",
+	"FORBIDDEN":         "没有权限执行该操作",
+	"NOT_FOUND":         "请求的资源不存在",
+	"TOO_MANY_REQUESTS": "请求过于频繁，请稍后再试",
+	"CANCELED":          "请求已取消",
+	"INTERNAL":          "服务器内部错误",
+	"TIMEOUT":           "请求处理超时，请稍后重试",
+
+	"TOKEN_INVALID":       "令牌无效",
+	"TOKEN_EXPIRED":       "令牌已过期",
+	"INVALID_CREDENTIALS": "账号或密码错误",
+	"INVALID_PIN":         "借书证或PIN错误",
+	"PIN_LOCKED":          "PIN连续输错次数过多，请稍后再试",
+	"DEVICE_KEY_INVALID":  "设备密钥无效或设备已停用",
+	"DEVICE_DISABLED":     "设备已停用",
+
+	"BOOK_NOT_FOUND":         "书籍不存在",
+	"ITEM_NOT_FOUND":         "条码对应的馆藏不存在",
+	"LOAN_NOT_FOUND":         "借阅记录不存在",
+	"STUDENT_NOT_FOUND":      "学生不存在",
+	"LIBRARIAN_NOT_FOUND":    "馆员不存在",
+	"BRANCH_NOT_FOUND":       "分馆不存在",
+	"HOLD_NOT_FOUND":         "预约不存在",
+	"TRANSFER_NOT_FOUND":     "调拨单不存在",
+	"CLOSURE_NOT_FOUND":      "闭馆日期不存在",
+	"DEVICE_NOT_FOUND":       "设备不存在",
+	"WEBHOOK_NOT_FOUND":      "推送地址不存在",
+	"EVENT_NOT_FOUND":        "事件不存在",
+	"NOTIFICATION_NOT_FOUND": "通知不存在或已发送",
+	"DELIVERY_NOT_FOUND":     "投递不存在或已成功",
+	"COVER_NOT_FOUND":        "封面不存在",
+
+	"BORROW_DISABLED":          "学生借阅权限已被禁用",
+	"UNPAID_FINE":              "有未支付的罚款，请先支付罚款",
+	"BOOK_NOT_BORROWABLE":      "书籍不可借阅",
+	"BOOK_UNAVAILABLE":         "书籍已全部借出",
+	"NO_COPY_AT_BRANCH":        "该分馆暂无可借副本",
+	"NO_HOLDINGS":              "该书未登记分馆馆藏",
+	"HOLD_EXISTS":              "已有该书的有效预约",
+	"HOLD_CLOSED":              "预约已完成或已取消",
+	"NO_FINE_DUE":              "没有需要支付的罚款",
+	"CHECKOUT_BLOCKED":         "借出受阻",
+	"OVERRIDE_NOT_ALLOWED":     "不可强制越过的受阻原因: {code}",
+	"OVERRIDE_REASON_REQUIRED": "强制借出时必须填写原因",
+	"ITEM_NOT_ON_SHELF":        "只有在架的馆藏才能调拨",
+	"ITEM_AT_BRANCH":           "馆藏已在目标分馆",
+	"TRANSFER_STATE_INVALID":   "调拨单状态不允许该操作",
+	"WEBHOOK_DISABLED":         "推送地址已停用",
+	"MAIL_NOT_CONFIGURED":      "未配置邮件发送",
+	"STUDENT_EXISTS":           "学号已存在",
+	"BOOK_EXISTS":              "书籍ID已存在",
+	"BOOK_HAS_ITEMS":           "该书已登记单册，馆藏数量按单册统计",
+
+	"COVER_TOO_LARGE":        "封面文件不能超过{max_mb}MB",
+	"COVER_UNSUPPORTED_TYPE": "不支持的封面格式: {content_type}",
+	"COVER_INVALID":          "封面图片无法解析",
+
+	// 自定义错误提示
+	"error.route_not_found":        "接口不存在: {method} {path}",
+	"error.invalid_request":        "参数错误: {detail}",
+	"error.student_credentials":    "学号或密码错误",
+	"error.librarian_credentials":  "工号或密码错误",
+	"error.device_key_missing":     "缺少设备密钥",
+	"error.kiosk_checkout_blocked": "借出受阻，请到服务台办理",
+	"error.item_not_on_loan":       "该单册没有未归还的借阅记录",
+	"error.cover_dimension":        "封面尺寸不能超过{max}x{max}",
+	"error.book_has_no_cover":      "该书籍没有封面",
+	"error.transfer_not_requested": "调拨单不是待发出状态",
+	"error.transfer_closed":        "调拨单已完成或已取消",
+	"error.not_ready":              "服务暂不可用",
+	"error.import_record":          "第{index}条记录: {detail}",
+
+	// 参数校验
+	"invalid.keyword_required":      "请输入搜索关键词",
+	"invalid.book_id_required":      "书籍ID不能为空",
+	"invalid.stu_id_required":       "学号不能为空",
+	"invalid.stu_id_book_id":        "学号和书籍ID不能为空",
+	"invalid.limit":                 "limit格式错误",
+	"invalid.weekday":               "星期格式错误",
+	"invalid.weekday_range":         "星期必须为0（周日）到6（周六）",
+	"invalid.date":                  "日期格式错误，应为 YYYY-MM-DD",
+	"invalid.start_date":            "开始日期格式错误，应为 YYYY-MM-DD",
+	"invalid.end_date":              "结束日期格式错误，应为 YYYY-MM-DD",
+	"invalid.end_before_start":      "结束日期不能早于开始日期",
+	"invalid.open_time":             "开馆时间格式错误，应为 HH:MM",
+	"invalid.close_time":            "闭馆时间格式错误，应为 HH:MM",
+	"invalid.close_before_open":     "闭馆时间必须晚于开馆时间",
+	"invalid.closure_kind":          "闭馆类型必须为 holiday、break 或 other",
+	"invalid.closure_id":            "闭馆日期ID格式错误",
+	"invalid.hold_id":               "预约ID格式错误",
+	"invalid.transfer_id":           "调拨单ID格式错误",
+	"invalid.notification_id":       "通知ID格式错误",
+	"invalid.webhook_id":            "推送地址ID格式错误",
+	"invalid.endpoint_id":           "endpoint_id格式错误",
+	"invalid.delivery_id":           "投递ID格式错误",
+	"invalid.cover_file_required":   "请选择要上传的封面文件",
+	"invalid.cover_size":            "不支持的封面尺寸: {size}",
+	"invalid.too_many_stream_books": "订阅的图书过多，最多{max, plural, other {#本}}",
+	"invalid.stream_target":         "请指定book_id或携带学生令牌",
+	"invalid.barcodes_required":     "请扫描要借出的单册条码",
+	"invalid.rate_limit":            "请求上限必须大于0",
+	"invalid.language":              "不支持的语言: {language}",
+	"invalid.email":                 "邮箱格式错误",
+	"invalid.email_required":        "订阅通知需要填写邮箱",
+	"invalid.pin":                   "PIN必须为4到6位数字",
+	"invalid.webhook_url":           "推送地址必须是http或https地址",
+	"invalid.events_required":       "至少需要订阅一个事件",
+	"invalid.unknown_event":         "未知的事件: {event}",
+	"invalid.audit_time":            "{field}格式错误，应为 RFC3339 时间或 YYYY-MM-DD",
+	"invalid.before_id":             "before_id格式错误",
+	"invalid.graphql_depth":         "查询嵌套过深，最多{max}层",
+	"invalid.graphql_complexity":    "查询过于复杂（代价{complexity}），最大为{max}",
+	"invalid.student_name_required": "姓名不能为空",
+	"invalid.password_required":     "密码不能为空",
+	"invalid.book_title_required":   "书名和作者不能为空",
+	"invalid.total_copies":          "总馆藏数量不能少于已借出的{on_loan}册",
+	"invalid.available_copies":      "可借数量必须在0到总馆藏数量之间",
+
+	// 借出受阻原因
+	"block.duplicate_barcode":   "重复扫描的条码",
+	"block.item_not_found":      "条码对应的馆藏不存在",
+	"block.book_not_borrowable": "《{title}》不可外借",
+	"block.item_on_hold":        "该册为学生{stu_id}预约保留",
+	"block.item_unavailable":    "该册当前状态为{status}，不能借出",
+	"block.student_disabled":    "学生借阅权限已被禁用",
+	"block.unpaid_fine":         "有未支付的罚款",
+
+	// 自助借还凭条
+	"kiosk.returned":      "已归还",
+	"kiosk.returned_late": "逾期归还，罚款{fine}，借阅权限已暂停，请支付罚款",
+	"kiosk.return_failed": "未能归还，请联系服务台: {error}",
+
+	// 操作成功
+	"ok.health":                "图书管理系统运行正常",
+	"ok.login":                 "登录成功",
+	"ok.borrowed":              "借书成功，请于{due_date}前归还",
+	"ok.returned":              "还书成功",
+	"ok.returned_with_fine":    "还书成功，产生逾期罚款{fine}",
+	"ok.fine_paid":             "罚款支付成功，借阅权限已恢复",
+	"ok.checked_out":           "借出成功，共{count, plural, other {#册}}",
+	"ok.checked_in":            "已归还{returned}册，共{count, plural, other {#册}}",
+	"ok.hold_placed":           "预约成功",
+	"ok.hold_canceled":         "预约已取消",
+	"ok.branch_created":        "分馆创建成功",
+	"ok.item_registered":       "馆藏登记成功",
+	"ok.transfer_requested":    "调拨申请成功",
+	"ok.transfer_shipped":      "调拨已发出",
+	"ok.transfer_received":     "调拨已签收",
+	"ok.hours_updated":         "开放时间已更新",
+	"ok.closure_added":         "闭馆日期已添加",
+	"ok.closure_deleted":       "闭馆日期已删除",
+	"ok.cover_uploaded":        "封面上传成功",
+	"ok.cover_deleted":         "封面已删除",
+	"ok.device_registered":     "设备登记成功",
+	"ok.device_key_rotated":    "设备密钥已更新",
+	"ok.device_status_updated": "设备状态已更新",
+	"ok.rate_limit_updated":    "请求上限已更新",
+	"ok.webhook_registered":    "推送地址登记成功",
+	"ok.webhook_updated":       "推送地址状态已更新",
+	"ok.webhook_retry":         "将重新推送",
+	"ok.preference_saved":      "通知设置已保存",
+	"ok.notification_retry":    "通知将重新发送",
+	"ok.pin_set":               "PIN设置成功",
+}
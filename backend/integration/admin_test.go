@@ -0,0 +1,168 @@
+package integration
+
+import (
+	"backend/apperr"
+	"backend/dao"
+	"backend/do"
+	"backend/service"
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminStudentsAndBooks(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		ctx := service.WithActor(context.Background(), service.Actor{Role: do.ActorCLI, ID: "operator"})
+		store := dao.NewStore(db)
+		adminService := service.NewAdminService(store)
+		studentService := service.NewStudentService(store)
+
+		student := &do.Student{StuId: "20240001", Name: "孙七", Password: "init", Trust: 1, CanBorrow: true}
+		if err := adminService.CreateStudent(ctx, student); err != nil {
+			t.Fatalf("新建学生失败: %v", err)
+		}
+		if err := adminService.CreateStudent(ctx, &do.Student{StuId: "20240001", Name: "重复", Password: "x"}); !apperr.Is(err, apperr.CodeStudentExists) {
+			t.Errorf("学号重复 = %v，期望 STUDENT_EXISTS", err)
+		}
+		if err := adminService.ResetPassword(ctx, "20240001", "changed"); err != nil {
+			t.Fatalf("重置密码失败: %v", err)
+		}
+		if _, err := studentService.Authenticate(ctx, "20240001", "changed"); err != nil {
+			t.Errorf("用新密码登录失败: %v", err)
+		}
+		if err := adminService.ResetPassword(ctx, "NOPE", "x"); !apperr.Is(err, apperr.CodeStudentNotFound) {
+			t.Errorf("重置不存在的学生 = %v，期望 STUDENT_NOT_FOUND", err)
+		}
+
+		// 审计日志记录命令行的操作人，不记录密码
+		entries, err := service.NewAuditService(db).ListEntries(ctx, do.AuditFilter{EntityType: do.AuditEntityStudent, EntityID: "20240001"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 || entries[0].Action != do.AuditPasswordReset || entries[1].Action != do.AuditStudentCreate {
+			t.Fatalf("审计日志 = %+v，期望从新到旧为重置密码、新建学生", entries)
+		}
+		if entries[1].ActorRole != do.ActorCLI || entries[1].ActorID != "operator" {
+			t.Errorf("操作人 = %s/%s", entries[1].ActorRole, entries[1].ActorID)
+		}
+		for _, entry := range entries {
+			if strings.Contains(string(entry.After), "init") || strings.Contains(string(entry.After), "changed") {
+				t.Errorf("审计日志中有密码: %s", entry.After)
+			}
+		}
+
+		// 借出一册后调整馆藏，借出的数量不变
+		if _, err := service.NewBorrowService(store, nil).BorrowBook(ctx, "20230003", "B004", ""); err != nil {
+			t.Fatalf("借书失败: %v", err)
+		}
+		book, err := adminService.SetBookCopies(ctx, "B004", 2)
+		if err != nil {
+			t.Fatalf("调整馆藏失败: %v", err)
+		}
+		if book.TotalCopies != 2 || book.AvailableCopies != 1 {
+			t.Errorf("馆藏 = %d/%d，期望 1/2", book.AvailableCopies, book.TotalCopies)
+		}
+		if _, err := adminService.SetBookCopies(ctx, "B004", 0); !apperr.Is(err, apperr.CodeInvalidArgument) {
+			t.Errorf("总馆藏少于借出数量 = %v，期望 INVALID_ARGUMENT", err)
+		}
+		if _, err := adminService.SetBookCopies(ctx, "B003", 5); !apperr.Is(err, apperr.CodeBookHasItems) {
+			t.Errorf("调整按单册管理的书籍 = %v，期望 BOOK_HAS_ITEMS", err)
+		}
+
+		// 批量导入中任一条有误时全部不导入，错误中注明第几条
+		books := []do.Book{
+			{BookID: "B101", Title: "编译原理", Author: "周八", TotalCopies: 2, AvailableCopies: 2, CanBorrow: true},
+			{BookID: "B001", Title: "重复", Author: "周八", TotalCopies: 1, AvailableCopies: 1, CanBorrow: true},
+		}
+		_, err = adminService.ImportBooks(ctx, books)
+		if !apperr.Is(err, apperr.CodeBookExists) || apperr.From(err).Params["index"] != 2 {
+			t.Errorf("导入重复的书籍 = %v，期望第2条 BOOK_EXISTS", err)
+		}
+		if _, err := dao.NewBookDAO(db).GetBookByID(ctx, "B101"); !apperr.Is(err, apperr.CodeBookNotFound) {
+			t.Errorf("导入失败后 B101 = %v，期望不存在", err)
+		}
+		imported, err := adminService.ImportBooks(ctx, books[:1])
+		if err != nil || imported != 1 {
+			t.Fatalf("导入书籍 = %d, %v", imported, err)
+		}
+		exported, err := adminService.ExportBooks(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(exported) != 5 {
+			t.Errorf("导出书籍 %d 本，期望 5 本", len(exported))
+		}
+
+		if _, err := adminService.ImportStudents(ctx, []do.Student{{StuId: "20240002", Name: "吴九"}}); !apperr.Is(err, apperr.CodeInvalidArgument) {
+			t.Errorf("导入没有密码的学生 = %v，期望 INVALID_ARGUMENT", err)
+		}
+		students, err := adminService.ExportStudents(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range students {
+			if s.Password != "" {
+				t.Errorf("导出的学生 %s 含密码", s.StuId)
+			}
+		}
+	})
+}
+
+func TestAdminOverdueSweepAndWaiver(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		ctx := service.WithActor(context.Background(), service.Actor{Role: do.ActorCLI, ID: "operator"})
+		store := dao.NewStore(db)
+		adminService := service.NewAdminService(store)
+		studentService := service.NewStudentService(store)
+
+		// 测试数据中20230001的B001应于2024-03-01到期，20230002的B002应于2024-03-15到期；
+		// 按读出的应还时间取逾期10天多两小时，结果与数据库和本地时区无关
+		loan, err := store.Borrows().GetBorrowRecord(ctx, "20230001", "B001")
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := loan.DueDate.AddDate(0, 0, 10).Add(2 * time.Hour)
+		report, err := adminService.OverdueReport(ctx, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report) != 1 || report[0].StuID != "20230001" || report[0].StudentName == "" || report[0].Title == "" ||
+			report[0].DaysOverdue != 10 || report[0].FineAmount != 5 {
+			t.Fatalf("逾期报表 = %+v，期望 20230001 逾期10天罚款5元", report)
+		}
+
+		sweep, err := adminService.SweepOverdue(ctx, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *sweep != (service.OverdueSweep{OverdueLoans: 1, Updated: 1, TotalFines: 5}) {
+			t.Errorf("逾期扫描 = %+v", *sweep)
+		}
+		// 罚款没有变化时不重复更新
+		if sweep, err = adminService.SweepOverdue(ctx, now); err != nil || sweep.Updated != 0 {
+			t.Errorf("再次扫描 = %+v, %v，期望没有更新", sweep, err)
+		}
+		if canBorrow, _, err := studentService.CanStudentBorrow(ctx, "20230001"); err != nil || canBorrow {
+			t.Errorf("有罚款时可借 = %v, %v，期望不可借", canBorrow, err)
+		}
+
+		waiver, err := adminService.WaiveFines(ctx, "20230001")
+		if err != nil {
+			t.Fatalf("减免罚款失败: %v", err)
+		}
+		if waiver.Records != 1 || waiver.Amount != 5 {
+			t.Errorf("减免 = %+v", waiver)
+		}
+		if canBorrow, _, err := studentService.CanStudentBorrow(ctx, "20230001"); err != nil || !canBorrow {
+			t.Errorf("减免后可借 = %v, %v，期望可借", canBorrow, err)
+		}
+		if _, err := adminService.WaiveFines(ctx, "20230001"); !apperr.Is(err, apperr.CodeNoFineDue) {
+			t.Errorf("再次减免 = %v，期望 NO_FINE_DUE", err)
+		}
+		if _, err := adminService.WaiveFines(ctx, "20230003"); !apperr.Is(err, apperr.CodeNoFineDue) {
+			t.Errorf("减免没有罚款的学生 = %v，期望 NO_FINE_DUE", err)
+		}
+	})
+}
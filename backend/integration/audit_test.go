@@ -0,0 +1,63 @@
+package integration
+
+import (
+	"backend/dao"
+	"backend/do"
+	"backend/service"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		ctx := service.WithActor(context.Background(), service.Actor{Role: do.RoleLibrarian, ID: "L001", IP: "127.0.0.1"})
+		borrowService := service.NewBorrowService(dao.NewStore(db), nil)
+
+		since := time.Now().Add(-time.Minute)
+		if _, err := borrowService.BorrowBook(ctx, "20230003", "B003", "MAIN"); err != nil {
+			t.Fatalf("借书失败: %v", err)
+		}
+		if _, err := borrowService.ReturnBook(ctx, "20230003", "B003", "MAIN"); err != nil {
+			t.Fatalf("还书失败: %v", err)
+		}
+
+		auditService := service.NewAuditService(db)
+		entries, err := auditService.ListEntries(ctx, do.AuditFilter{ActorID: "L001", Since: &since})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 || entries[0].Action != do.AuditLoanReturn || entries[1].Action != do.AuditLoanBorrow {
+			t.Fatalf("审计日志 = %+v，期望从新到旧为归还、借出", entries)
+		}
+		returned := entries[0]
+		if returned.ActorRole != do.RoleLibrarian || returned.ClientIP != "127.0.0.1" || returned.EntityType != do.AuditEntityLoan ||
+			len(returned.Before) == 0 || len(returned.After) == 0 {
+			t.Errorf("归还记录 = %+v", returned)
+		}
+		if entries[1].Before != nil {
+			t.Errorf("借出记录的操作前的值 = %s，期望为空", entries[1].Before)
+		}
+
+		// 按对象和翻页查询
+		page, err := auditService.ListEntries(ctx, do.AuditFilter{EntityType: do.AuditEntityLoan, EntityID: returned.EntityID, BeforeID: returned.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 1 || page[0].ID != entries[1].ID {
+			t.Errorf("翻页 = %+v，期望只有借出记录", page)
+		}
+
+		// 审计日志只能追加
+		if _, err := db.Exec("UPDATE audit_log SET actor_id = 'X'"); err == nil {
+			t.Error("修改审计日志应失败")
+		}
+		if _, err := db.Exec("DELETE FROM audit_log"); err == nil {
+			t.Error("删除审计日志应失败")
+		}
+		if entries, _ := auditService.ListEntries(ctx, do.AuditFilter{ActorID: "L001"}); len(entries) != 2 {
+			t.Errorf("审计日志数 = %d，期望 2", len(entries))
+		}
+	})
+}
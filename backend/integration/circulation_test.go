@@ -0,0 +1,480 @@
+package integration
+
+import (
+	"backend/apperr"
+	"backend/dao"
+	"backend/do"
+	"backend/service"
+	"context"
+	"database/sql"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBorrowAndReturnItem(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		store := dao.NewStore(db)
+		borrowService := service.NewBorrowService(store, nil)
+
+		before := time.Now()
+		if _, err := borrowService.BorrowBook(ctx, "20230003", "B003", "MAIN"); err != nil {
+			t.Fatalf("借书失败: %v", err)
+		}
+
+		record, err := borrowService.GetBorrowRecord(ctx, "20230003", "B003")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Barcode != "B003-0001" {
+			t.Errorf("借出单册 = %s，期望总馆的 B003-0001", record.Barcode)
+		}
+		// 时间按秒保存，读出后为本地时区
+		if record.BorrowDate.Before(before.Add(-time.Second)) || record.BorrowDate.After(time.Now().Add(time.Second)) {
+			t.Errorf("借书时间 = %v，期望约为 %v", record.BorrowDate, before)
+		}
+		if record.BorrowDate.Location() != time.Local {
+			t.Errorf("借书时间时区 = %v，期望本地时区", record.BorrowDate.Location())
+		}
+		if record.ReturnDate != nil || record.IsOverdue {
+			t.Errorf("新借阅记录 = %+v", record)
+		}
+
+		book, err := store.Books().GetBookByID(ctx, "B003")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if book.TotalCopies != 2 || book.AvailableCopies != 1 {
+			t.Errorf("馆藏 = %d/%d，期望 1/2", book.AvailableCopies, book.TotalCopies)
+		}
+
+		// 在东区归还，调回总馆
+		fine, err := borrowService.ReturnBook(ctx, "20230003", "B003", "EAST")
+		if err != nil {
+			t.Fatalf("还书失败: %v", err)
+		}
+		if fine != 0 {
+			t.Errorf("罚款 = %v，期望 0", fine)
+		}
+		item, err := store.BookItems().GetItemByBarcode(ctx, "B003-0001")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.Status != do.ItemStatusInTransit || item.CurrentBranchID != "EAST" {
+			t.Errorf("单册 = %+v，期望在东区等待调回", item)
+		}
+		transfers, err := dao.NewTransferDAO(db).ListTransfers(ctx, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(transfers) != 1 || transfers[0].ToBranchID != "MAIN" || transfers[0].Reason != do.TransferReasonReturnHome {
+			t.Errorf("调拨单 = %+v，期望一张调回总馆的调拨单", transfers)
+		}
+	})
+}
+
+func TestOverdueFineSkipsClosures(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		store := dao.NewStore(db)
+		borrowService := service.NewBorrowService(store, nil)
+
+		dueDate := time.Now().AddDate(0, 0, -10).Add(-time.Hour)
+		record := &do.BorrowRecord{StuID: "20230003", BookID: "B004", BorrowDate: dueDate.AddDate(0, -2, 0), DueDate: dueDate}
+		if err := dao.NewBorrowDAO(db).CreateBorrowRecord(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+		closure := &do.LibraryClosure{
+			Name:      "校庆",
+			Kind:      do.ClosureKindHoliday,
+			StartDate: dueDate.AddDate(0, 0, 2).Format(do.DateLayout),
+			EndDate:   dueDate.AddDate(0, 0, 4).Format(do.DateLayout),
+		}
+		if err := service.NewCalendarService(db).AddClosure(ctx, closure); err != nil {
+			t.Fatal(err)
+		}
+
+		fine, err := borrowService.ReturnBook(ctx, "20230003", "B004", "")
+		if err != nil {
+			t.Fatalf("还书失败: %v", err)
+		}
+		if fine != 3.5 {
+			t.Errorf("罚款 = %v，期望 3.5（逾期10天，其中3天闭馆）", fine)
+		}
+
+		canBorrow, _, err := service.NewStudentService(store).CanStudentBorrow(ctx, "20230003")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if canBorrow {
+			t.Error("产生罚款后学生应被禁止借阅")
+		}
+	})
+}
+
+func TestCalendarAndPreferencesUpsert(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		calendarService := service.NewCalendarService(db)
+		for _, hours := range []do.OpeningHours{
+			{Weekday: 0, OpenTime: "09:00", CloseTime: "17:00"},
+			{Weekday: 0, OpenTime: "10:00", CloseTime: "16:00", Closed: true},
+		} {
+			hours := hours
+			if err := calendarService.SetOpeningHours(ctx, &hours); err != nil {
+				t.Fatalf("设置开放时间失败: %v", err)
+			}
+		}
+		closure := &do.LibraryClosure{Name: "国庆节", Kind: do.ClosureKindHoliday, StartDate: "2030-10-01", EndDate: "2030-10-07"}
+		if err := calendarService.AddClosure(ctx, closure); err != nil {
+			t.Fatal(err)
+		}
+
+		view, err := calendarService.GetCalendar(ctx, "2030-10-05", "2030-10-20")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(view.Hours) != 1 || view.Hours[0].OpenTime != "10:00" || !view.Hours[0].Closed {
+			t.Errorf("开放时间 = %+v，期望覆盖为第二次设置的值", view.Hours)
+		}
+		if len(view.Closures) != 1 || view.Closures[0].StartDate != "2030-10-01" || view.Closures[0].EndDate != "2030-10-07" {
+			t.Errorf("闭馆日期 = %+v", view.Closures)
+		}
+
+		notificationDAO := dao.NewNotificationDAO(db)
+		for _, email := range []string{"old@example.com", "new@example.com"} {
+			pref := &do.NotificationPreference{StuID: "20230001", Email: email, Language: "zh-CN", DueSoon: true}
+			if err := notificationDAO.SavePreference(ctx, pref); err != nil {
+				t.Fatalf("保存通知设置失败: %v", err)
+			}
+		}
+		pref, err := notificationDAO.GetPreference(ctx, "20230001")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pref.Email != "new@example.com" || !pref.DueSoon || pref.Overdue {
+			t.Errorf("通知设置 = %+v", pref)
+		}
+
+		// 同一类型和关联记录的通知只保存一条
+		for i := 0; i < 2; i++ {
+			n := &do.Notification{StuID: "20230001", Kind: do.NotificationKindOverdue, RefID: 1, Email: pref.Email,
+				Subject: "逾期提醒", Body: "请尽快归还", Status: do.NotificationStatusPending, NextAttemptAt: time.Now()}
+			if err := notificationDAO.CreateNotification(ctx, n); err != nil {
+				t.Fatalf("创建通知失败: %v", err)
+			}
+		}
+		notifications, err := notificationDAO.ListNotifications(ctx, "20230001", "", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notifications) != 1 {
+			t.Errorf("通知数 = %d，期望 1", len(notifications))
+		}
+		due, err := notificationDAO.GetDueNotifications(ctx, time.Now().Add(time.Second), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 1 {
+			t.Errorf("待发送通知数 = %d，期望 1", len(due))
+		}
+	})
+}
+
+// 请求已取消时不开启事务，数据不变
+func TestCanceledRequest(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		store := dao.NewStore(db)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := service.NewBorrowService(store, nil).BorrowBook(ctx, "20230003", "B001", ""); err == nil {
+			t.Fatal("请求取消后借书应失败")
+		}
+		book, err := store.Books().GetBookByID(context.Background(), "B001")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if book.AvailableCopies != 5 {
+			t.Errorf("可借数量 = %d，期望 5", book.AvailableCopies)
+		}
+	})
+}
+
+// 并发借最后几册时不能超借：B003按单册借出，B004按数量借出
+func TestConcurrentBorrow(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		borrowService := service.NewBorrowService(dao.NewStore(db), nil)
+		bookDAO := dao.NewBookDAO(db)
+
+		// B004只剩2册可借
+		if err := bookDAO.UpdateBookAvailableCopies(ctx, "B004", 2); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, bookID := range []string{"B003", "B004"} {
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			succeeded := 0
+			for _, stuID := range []string{"20230001", "20230002", "20230003", "20230001", "20230002", "20230003"} {
+				wg.Add(1)
+				go func(stuID string) {
+					defer wg.Done()
+					if _, err := borrowService.BorrowBook(ctx, stuID, bookID, ""); err == nil {
+						mu.Lock()
+						succeeded++
+						mu.Unlock()
+					}
+				}(stuID)
+			}
+			wg.Wait()
+
+			if succeeded != 2 {
+				t.Errorf("%s 借书成功 %d 次，期望只有 2 次（共2册可借）", bookID, succeeded)
+			}
+			book, err := bookDAO.GetBookByID(ctx, bookID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if book.AvailableCopies != 0 {
+				t.Errorf("%s 可借数量 = %d，期望 0", bookID, book.AvailableCopies)
+			}
+		}
+	})
+}
+
+// 同一借阅并发归还时只有一次成功，可借数量只增加一次
+func TestConcurrentReturn(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		borrowService := service.NewBorrowService(dao.NewStore(db), nil)
+		if _, err := borrowService.BorrowBook(ctx, "20230003", "B004", ""); err != nil {
+			t.Fatalf("借书失败: %v", err)
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, 4)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = borrowService.ReturnBook(ctx, "20230003", "B004", "")
+			}(i)
+		}
+		wg.Wait()
+
+		succeeded := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				succeeded++
+			case !apperr.Is(err, apperr.CodeLoanNotFound):
+				t.Errorf("重复归还 = %v，期望 LOAN_NOT_FOUND", err)
+			}
+		}
+		if succeeded != 1 {
+			t.Errorf("还书成功 %d 次，期望 1 次", succeeded)
+		}
+		book, err := dao.NewBookDAO(db).GetBookByID(ctx, "B004")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if book.AvailableCopies != 4 {
+			t.Errorf("可借数量 = %d，期望 4", book.AvailableCopies)
+		}
+	})
+}
+
+func blockCodes(blocks []service.CheckoutBlock) []string {
+	codes := make([]string, len(blocks))
+	for i, block := range blocks {
+		codes[i] = block.Code
+	}
+	return codes
+}
+
+func TestCirculationCheckout(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		circulationService := service.NewCirculationService(db, nil)
+		borrowDAO := dao.NewBorrowDAO(db)
+
+		// 20230001被禁止借阅且有未支付的罚款
+		if _, err := db.Exec("UPDATE borrow_records SET is_overdue = true, fine_amount = 5 WHERE stu_id = '20230001' AND book_id = 'B001'"); err != nil {
+			t.Fatal(err)
+		}
+		if err := service.NewStudentService(dao.NewStore(db)).DisableBorrowPermission(ctx, "20230001"); err != nil {
+			t.Fatal(err)
+		}
+
+		// 受阻时整批不借出，学生的受阻原因全部列出
+		result, err := circulationService.Checkout(ctx, &service.CheckoutRequest{
+			StuID:    "20230001",
+			Barcodes: []string{"B003-0001", "B003-0001", "NOPE"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{service.BlockStudentDisabled, service.BlockUnpaidFine, service.BlockDuplicateBarcode, service.BlockItemNotFound}
+		if !result.Blocked || !slices.Equal(blockCodes(result.Blocks), want) || len(result.Loans) != 0 {
+			t.Fatalf("受阻的借出 = %+v，期望受阻原因 %v", result, want)
+		}
+		if !result.Blocks[0].Overridable || !result.Blocks[1].Overridable || result.Blocks[2].Overridable || result.Blocks[3].Overridable {
+			t.Errorf("可越过的受阻原因 = %+v", result.Blocks)
+		}
+		if _, err := borrowDAO.GetActiveBorrowRecordByBarcode(ctx, "B003-0001"); !apperr.Is(err, apperr.CodeLoanNotFound) {
+			t.Errorf("受阻后 B003-0001 的借阅 = %v，期望没有借出", err)
+		}
+
+		// 重复扫描不能越过，越过其他原因后整批仍不借出
+		result, err = circulationService.Checkout(ctx, &service.CheckoutRequest{
+			StuID:          "20230001",
+			Barcodes:       []string{"B003-0001", "B003-0001"},
+			Overrides:      []string{service.BlockStudentDisabled, service.BlockUnpaidFine, service.BlockDuplicateBarcode},
+			OverrideReason: "校长批准",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Blocked || !slices.Equal(blockCodes(result.Blocks), []string{service.BlockDuplicateBarcode}) || len(result.Loans) != 0 {
+			t.Errorf("越过后重复扫描 = %+v，期望仍因 duplicate_barcode 受阻", result)
+		}
+
+		// 越过时必须填写原因
+		overridden := &service.CheckoutRequest{
+			LibrarianID:    "L001",
+			StuID:          "20230001",
+			Barcodes:       []string{"B003-0001"},
+			Overrides:      []string{service.BlockStudentDisabled, service.BlockUnpaidFine},
+			OverrideReason: "  ",
+		}
+		if _, err := circulationService.Checkout(ctx, overridden); !apperr.Is(err, apperr.CodeOverrideReasonRequired) {
+			t.Errorf("未填写越过原因 = %v，期望 OVERRIDE_REASON_REQUIRED", err)
+		}
+
+		// 每个被越过的原因记录一条强制借出记录
+		overridden.OverrideReason = "校长批准"
+		result, err = circulationService.Checkout(ctx, overridden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Blocked || len(result.Overridden) != 2 || len(result.Loans) != 1 || result.Loans[0].Barcode != "B003-0001" {
+			t.Fatalf("强制借出 = %+v", result)
+		}
+		overrides, err := circulationService.ListOverrides(ctx, "20230001", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes := make([]string, len(overrides))
+		for i, override := range overrides {
+			codes[i] = override.BlockCode
+			if override.LibrarianID != "L001" || override.Reason != "校长批准" {
+				t.Errorf("强制借出记录 = %+v", override)
+			}
+		}
+		slices.Sort(codes)
+		if !slices.Equal(codes, []string{service.BlockStudentDisabled, service.BlockUnpaidFine}) {
+			t.Errorf("强制借出记录的原因 = %v", codes)
+		}
+
+		// 在预约架上但没有待取预约的单册不能借出
+		if err := dao.NewBookItemDAO(db).UpdateItemLocation(ctx, "B003-0002", "EAST", do.ItemStatusOnHold); err != nil {
+			t.Fatal(err)
+		}
+		result, err = circulationService.Checkout(ctx, &service.CheckoutRequest{
+			StuID:     "20230003",
+			Barcodes:  []string{"B003-0002"},
+			Overrides: []string{service.BlockItemOnHold},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Blocked || !slices.Equal(blockCodes(result.Blocks), []string{service.BlockItemUnavailable}) || result.Blocks[0].Overridable {
+			t.Errorf("借出没有预约的预约架单册 = %+v，期望因 item_unavailable 受阻且不可越过", result)
+		}
+	})
+}
+
+func TestCirculationCheckin(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		circulationService := service.NewCirculationService(db, nil)
+
+		result, err := circulationService.Checkout(ctx, &service.CheckoutRequest{
+			StuID:    "20230003",
+			Barcodes: []string{"B003-0001", "B003-0002"},
+		})
+		if err != nil || result.Blocked {
+			t.Fatalf("借出 = %+v, %v", result, err)
+		}
+		// B003-0001已逾期；两册都借出后20230002的预约排队等待
+		if _, err := db.Exec("UPDATE borrow_records SET due_date = '2024-01-01 00:00:00' WHERE barcode = 'B003-0001'"); err != nil {
+			t.Fatal(err)
+		}
+		hold, err := service.NewHoldService(db, nil).PlaceHold(ctx, "20230002", "B003", "MAIN")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hold.Status != do.HoldStatusWaiting {
+			t.Fatalf("预约状态 = %s，期望排队等待", hold.Status)
+		}
+
+		items := circulationService.Checkin(ctx, []string{"B003-0001", "NOPE", "B003-0002"}, "MAIN")
+		if len(items) != 3 {
+			t.Fatalf("还书结果 %d 条，期望 3 条", len(items))
+		}
+		if first := items[0]; !first.Success || first.StuID != "20230003" || !first.IsOverdue || first.FineAmount <= 0 ||
+			first.Routing == nil || first.Routing.HoldID != hold.ID || first.Routing.HoldStuID != "20230002" {
+			t.Errorf("逾期归还 = %+v，期望产生罚款并满足20230002的预约", first)
+		}
+		if failed := items[1]; failed.Success || failed.Barcode != "NOPE" || failed.Code != apperr.CodeLoanNotFound || failed.Error == "" {
+			t.Errorf("不存在的条码 = %+v，期望 LOAN_NOT_FOUND", failed)
+		}
+		// 前一册失败不影响后面的单册；预约已被满足，不再分配
+		if last := items[2]; !last.Success || last.IsOverdue || last.FineAmount != 0 || last.Routing == nil || last.Routing.HoldID != 0 {
+			t.Errorf("按期归还 = %+v", last)
+		}
+		if _, err := dao.NewBorrowDAO(db).GetActiveBorrowRecordByBarcode(ctx, "B003-0002"); !apperr.Is(err, apperr.CodeLoanNotFound) {
+			t.Errorf("B003-0002 归还后 = %v，期望没有在借记录", err)
+		}
+	})
+}
+
+func TestCirculationStats(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		statsService := service.NewStatsService(db)
+		borrowService := service.NewBorrowService(dao.NewStore(db), nil)
+
+		// 测试数据中有两条已过期未还的借阅
+		stats, err := statsService.CirculationStats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (do.CirculationStats{OverdueLoans: 2}); *stats != want {
+			t.Errorf("初始统计 = %+v，期望 %+v", *stats, want)
+		}
+
+		// 逾期归还产生罚款并禁止借阅；B003的两册都借出后没有可借副本
+		fine, err := borrowService.ReturnBook(ctx, "20230002", "B002", "")
+		if err != nil {
+			t.Fatalf("还书失败: %v", err)
+		}
+		for _, branchID := range []string{"MAIN", "EAST"} {
+			if _, err := borrowService.BorrowBook(ctx, "20230003", "B003", branchID); err != nil {
+				t.Fatalf("借书失败: %v", err)
+			}
+		}
+
+		stats, err = statsService.CirculationStats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := do.CirculationStats{OverdueLoans: 1, OutstandingFines: fine, FinedStudents: 1, BooksUnavailable: 1}
+		if *stats != want {
+			t.Errorf("统计 = %+v，期望 %+v", *stats, want)
+		}
+	})
+}
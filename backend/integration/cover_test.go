@@ -0,0 +1,264 @@
+package integration
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/server"
+	"backend/service"
+	"backend/storage"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func imageSize(t *testing.T, data []byte) (int, int) {
+	t.Helper()
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("封面不是JPEG: %v", err)
+	}
+	return cfg.Width, cfg.Height
+}
+
+func TestCoverUpload(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		ctx := service.WithActor(context.Background(), service.Actor{Role: do.RoleLibrarian, ID: "L001"})
+		store, err := storage.NewLocalStorage(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		coverService := service.NewCoverService(db, store)
+
+		// 上传后生成缩略图和详情图，按比例缩小
+		book, err := coverService.UploadCover(ctx, "B001", encodePNG(t, 1200, 2400))
+		if err != nil {
+			t.Fatalf("上传封面失败: %v", err)
+		}
+		for _, tt := range []struct {
+			size         string
+			wantW, wantH int
+		}{
+			{"thumb", 150, 300},
+			{"detail", 450, 900},
+		} {
+			data, contentType, _, err := coverService.GetCover(ctx, "B001", tt.size)
+			if err != nil || contentType != "image/jpeg" {
+				t.Fatalf("读取%s = %s, %v", tt.size, contentType, err)
+			}
+			if w, h := imageSize(t, data); w != tt.wantW || h != tt.wantH {
+				t.Errorf("%s 尺寸 = %dx%d，期望 %dx%d", tt.size, w, h, tt.wantW, tt.wantH)
+			}
+			if _, _, err := store.Get(ctx, book.CoverKey+"/"+tt.size+".jpg"); err != nil {
+				t.Errorf("存储中缺少 %s: %v", tt.size, err)
+			}
+		}
+		coverURL, err := url.Parse(book.CoverURL)
+		if err != nil || coverURL.Path != "/api/v1/books/B001/cover" || coverURL.Query().Get("size") != "detail" {
+			t.Errorf("CoverURL = %s", book.CoverURL)
+		}
+		firstKey, firstVersion := book.CoverKey, coverURL.Query().Get("v")
+
+		// 小图不放大；更换封面使用新的版本目录，旧文件被删除
+		book, err = coverService.UploadCover(ctx, "B001", encodePNG(t, 120, 80))
+		if err != nil {
+			t.Fatalf("更换封面失败: %v", err)
+		}
+		if book.CoverKey == firstKey {
+			t.Fatalf("更换封面后存储键未变化: %s", book.CoverKey)
+		}
+		data, _, version, err := coverService.GetCover(ctx, "B001", "detail")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w, h := imageSize(t, data); w != 120 || h != 80 {
+			t.Errorf("小图 detail 尺寸 = %dx%d，期望 120x80", w, h)
+		}
+		if version == firstVersion {
+			t.Errorf("更换封面后版本号未变化: %s", version)
+		}
+		for _, size := range []string{"thumb", "detail"} {
+			if _, _, err := store.Get(ctx, firstKey+"/"+size+".jpg"); !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("旧封面 %s 未删除: %v", size, err)
+			}
+		}
+
+		// 删除封面后清空存储键并删除文件
+		if err := coverService.DeleteCover(ctx, "B001"); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, err := coverService.GetCover(ctx, "B001", "detail"); !apperr.Is(err, apperr.CodeCoverNotFound) {
+			t.Errorf("删除后读取 = %v，期望 COVER_NOT_FOUND", err)
+		}
+		if _, _, err := store.Get(ctx, book.CoverKey+"/detail.jpg"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("删除后文件仍存在: %v", err)
+		}
+		if err := coverService.DeleteCover(ctx, "B001"); !apperr.Is(err, apperr.CodeCoverNotFound) {
+			t.Errorf("重复删除 = %v，期望 COVER_NOT_FOUND", err)
+		}
+	})
+}
+
+func TestCoverValidation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		ctx := service.WithActor(context.Background(), service.Actor{Role: do.RoleLibrarian, ID: "L001"})
+		store, err := storage.NewLocalStorage(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		coverService := service.NewCoverService(db, store)
+
+		// 按内容嗅探类型，PNG文件头后面是损坏的数据
+		validPNG := encodePNG(t, 10, 10)
+		corrupt := append(append([]byte{}, validPNG[:40]...), bytes.Repeat([]byte{0xff}, 100)...)
+		tooLarge := append(append([]byte{}, validPNG...), make([]byte, service.MaxCoverBytes)...)
+
+		for _, tt := range []struct {
+			name   string
+			bookID string
+			data   []byte
+			code   apperr.Code
+		}{
+			{"书籍不存在", "NOPE", validPNG, apperr.CodeBookNotFound},
+			{"超过5MB", "B001", tooLarge, apperr.CodeCoverTooLarge},
+			{"文本文件", "B001", []byte("not an image, just text"), apperr.CodeCoverUnsupportedType},
+			{"SVG", "B001", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), apperr.CodeCoverUnsupportedType},
+			{"尺寸超过8000像素", "B001", encodePNG(t, 9000, 1), apperr.CodeCoverInvalid},
+			{"图片损坏", "B001", corrupt, apperr.CodeCoverInvalid},
+		} {
+			if _, err := coverService.UploadCover(ctx, tt.bookID, tt.data); !apperr.Is(err, tt.code) {
+				t.Errorf("%s = %v，期望 %s", tt.name, err, tt.code)
+			}
+		}
+
+		if _, _, _, err := coverService.GetCover(ctx, "B001", "huge"); !apperr.Is(err, apperr.CodeInvalidArgument) {
+			t.Errorf("无效的尺寸 = %v，期望 INVALID_ARGUMENT", err)
+		}
+		if _, _, _, err := coverService.GetCover(ctx, "B001", "thumb"); !apperr.Is(err, apperr.CodeCoverNotFound) {
+			t.Errorf("校验失败后 = %v，期望没有封面", err)
+		}
+	})
+}
+
+func uploadCover(t *testing.T, app *server.Server, token, bookID string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("cover", "cover.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/books/"+bookID+"/cover", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	app.Router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCoverHTTP(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		app := newTestServer(t, db, driver)
+		admin := login(t, app, "/api/v1/sessions/librarian", map[string]string{"librarian_id": "L001", "password": "admin123"})
+		student := login(t, app, "/api/v1/sessions/student", map[string]string{"stu_id": "20230001", "password": "password123"})
+
+		if w := uploadCover(t, app, student, "B001", encodePNG(t, 100, 100)); w.Code != http.StatusForbidden {
+			t.Errorf("学生上传封面 = %d，期望 403", w.Code)
+		}
+		if w := uploadCover(t, app, admin, "B001", []byte("plain text")); w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("上传文本文件 = %d，期望 415: %s", w.Code, w.Body)
+		}
+
+		w := uploadCover(t, app, admin, "B001", encodePNG(t, 400, 600))
+		if w.Code != http.StatusOK {
+			t.Fatalf("上传封面 = %d: %s", w.Code, w.Body)
+		}
+		var resp envelope
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		var book do.Book
+		json.Unmarshal(resp.Data, &book)
+		if book.CoverURL == "" || book.CoverThumbURL == "" {
+			t.Fatalf("响应缺少封面地址: %s", w.Body)
+		}
+
+		get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+			return w
+		}
+
+		// 带当前版本号的地址允许长期缓存
+		w = get(book.CoverThumbURL, "")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" {
+			t.Fatalf("获取缩略图 = %d %s", w.Code, w.Header().Get("Content-Type"))
+		}
+		if got := w.Header().Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+			t.Errorf("带版本号的 Cache-Control = %s", got)
+		}
+		if width, height := imageSize(t, w.Body.Bytes()); width != 200 || height != 300 {
+			t.Errorf("缩略图尺寸 = %dx%d，期望 200x300", width, height)
+		}
+		etag := w.Header().Get("ETag")
+		thumbURL, _ := url.Parse(book.CoverThumbURL)
+		if etag != `"`+thumbURL.Query().Get("v")+`-thumb"` {
+			t.Errorf("ETag = %s", etag)
+		}
+
+		// 不带版本号或版本号过期时只缓存较短时间
+		for _, path := range []string{"/api/v1/books/B001/cover", "/api/v1/books/B001/cover?v=stale"} {
+			if got := get(path, "").Header().Get("Cache-Control"); got != "public, max-age=300" {
+				t.Errorf("%s 的 Cache-Control = %s", path, got)
+			}
+		}
+
+		// ETag 匹配时返回304
+		if w := get(book.CoverThumbURL, etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match = %d，期望 304", w.Code)
+		}
+		if w := get(book.CoverURL, etag); w.Code != http.StatusOK {
+			t.Errorf("不同尺寸的 ETag 不应匹配: %d", w.Code)
+		}
+
+		if w := get("/api/v1/books/B002/cover", ""); w.Code != http.StatusNotFound {
+			t.Errorf("没有封面 = %d，期望 404", w.Code)
+		}
+		if w := serve(t, app, http.MethodDelete, "/api/v1/books/B001/cover", admin, nil); w.Code != http.StatusOK {
+			t.Fatalf("删除封面 = %d: %s", w.Code, w.Body)
+		}
+		if w := get(book.CoverURL, ""); w.Code != http.StatusNotFound {
+			t.Errorf("删除后获取封面 = %d，期望 404", w.Code)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package integration
+
+import (
+	"backend/server"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string        `json:"message"`
+		Path       []interface{} `json:"path"`
+		Extensions struct {
+			Code string `json:"code"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// 执行GraphQL查询，data解析到out中
+func graphQL(t *testing.T, app *server.Server, token, query string, out interface{}) graphQLResponse {
+	t.Helper()
+	w := serve(t, app, http.MethodPost, "/api/v1/graphql", token, map[string]string{"query": query})
+	if w.Code != http.StatusOK {
+		t.Fatalf("GraphQL = %d: %s", w.Code, w.Body)
+	}
+	var resp graphQLResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if out != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp
+}
+
+// 检查结果中只有一个错误，错误码为code
+func assertGraphQLError(t *testing.T, resp graphQLResponse, code string) {
+	t.Helper()
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions.Code != code {
+		t.Fatalf("errors = %+v，期望错误码 %s", resp.Errors, code)
+	}
+}
+
+func TestGraphQL(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		app := newTestServer(t, db, driver)
+
+		// 图书匿名可查，学生数据需要令牌
+		var catalog struct {
+			Books []struct {
+				BookID string `json:"bookId"`
+				Title  string `json:"title"`
+			} `json:"books"`
+		}
+		resp := graphQL(t, app, "", `{ books { bookId title } }`, &catalog)
+		if len(resp.Errors) != 0 || len(catalog.Books) != 4 || catalog.Books[0].Title == "" {
+			t.Fatalf("books = %+v, %+v", catalog, resp.Errors)
+		}
+		assertGraphQLError(t, graphQL(t, app, "", `{ me { name } }`, nil), "UNAUTHORIZED")
+		if w := serve(t, app, http.MethodPost, "/api/v1/graphql", "bad", map[string]string{"query": `{ books { title } }`}); w.Code != http.StatusUnauthorized {
+			t.Errorf("无效令牌 = %d: %s", w.Code, w.Body)
+		}
+		if w := serve(t, app, http.MethodPost, "/api/v1/graphql", "", map[string]string{}); w.Code != http.StatusBadRequest {
+			t.Errorf("缺少query = %d: %s", w.Code, w.Body)
+		}
+		assertGraphQLError(t, graphQL(t, app, "", `{ books { title `, nil), "INVALID_ARGUMENT")
+		assertGraphQLError(t, graphQL(t, app, "", `{ books { isbn } }`, nil), "INVALID_ARGUMENT")
+
+		token := login(t, app, "/api/v1/sessions/student", map[string]string{"stu_id": "20230003", "password": "password123"})
+		for _, bookID := range []string{"B001", "B002", "B003"} {
+			if w := serve(t, app, http.MethodPost, "/api/v1/loans", token, map[string]string{"book_id": bookID}); w.Code != http.StatusOK {
+				t.Fatalf("借书 %s = %d: %s", bookID, w.Code, w.Body)
+			}
+		}
+
+		// 一次查询取回借阅、图书、预约和罚款，借阅的图书合并为一次查询
+		recorder := tracetest.NewSpanRecorder()
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		defer otel.SetTracerProvider(previous)
+
+		var me struct {
+			Me struct {
+				Name  string `json:"name"`
+				Loans []struct {
+					DueDate    string  `json:"dueDate"`
+					ReturnDate *string `json:"returnDate"`
+					Book       struct {
+						Title string `json:"title"`
+					} `json:"book"`
+				} `json:"loans"`
+				Holds []struct {
+					ID int `json:"id"`
+				} `json:"holds"`
+				Fines struct {
+					Total float64 `json:"total"`
+				} `json:"fines"`
+			} `json:"me"`
+		}
+		resp = graphQL(t, app, token, `{ me { name loans { dueDate returnDate book { title } } holds { id } fines { total } } }`, &me)
+		if len(resp.Errors) != 0 {
+			t.Fatalf("errors = %+v", resp.Errors)
+		}
+		if me.Me.Name != "王五" || len(me.Me.Loans) != 3 || me.Me.Holds == nil || me.Me.Fines.Total != 0 {
+			t.Fatalf("me = %+v", me.Me)
+		}
+		for _, loan := range me.Me.Loans {
+			if loan.Book.Title == "" || loan.DueDate == "" || loan.ReturnDate != nil {
+				t.Errorf("loan = %+v", loan)
+			}
+		}
+		batches := make(map[string][]int64)
+		for _, span := range recorder.Ended() {
+			attrs := attribute.NewSet(span.Attributes()...)
+			if v, ok := attrs.Value("library.batch_size"); ok {
+				batches[span.Name()] = append(batches[span.Name()], v.AsInt64())
+			}
+		}
+		// 借阅和罚款共用同一次借阅查询
+		for name, want := range map[string]int64{"LookupService.BooksByID": 3, "LookupService.LoansByStudent": 1, "LookupService.HoldsByStudent": 1} {
+			if got := batches[name]; len(got) != 1 || got[0] != want {
+				t.Errorf("%s 的批量查询 = %v，期望一次查询 %d 个ID", name, got, want)
+			}
+		}
+
+		// 学生不能查看其他学生和图书的当前借阅，被拒绝的字段为null
+		assertGraphQLError(t, graphQL(t, app, token, `{ student(stuId: "20230001") { name } }`, nil), "FORBIDDEN")
+		var book struct {
+			Book struct {
+				Title string `json:"title"`
+				Loans *[]struct {
+					Student struct {
+						StuID string `json:"stuId"`
+					} `json:"student"`
+				} `json:"loans"`
+			} `json:"book"`
+		}
+		resp = graphQL(t, app, token, `{ book(bookId: "B001") { title loans { student { stuId } } } }`, &book)
+		assertGraphQLError(t, resp, "FORBIDDEN")
+		if book.Book.Title == "" || book.Book.Loans != nil {
+			t.Errorf("学生查询book = %+v", book.Book)
+		}
+		assertGraphQLError(t, graphQL(t, app, "", `{ book(bookId: "NOPE") { title } }`, nil), "BOOK_NOT_FOUND")
+
+		librarianToken := login(t, app, "/api/v1/sessions/librarian", map[string]string{"librarian_id": "L001", "password": "admin123"})
+		resp = graphQL(t, app, librarianToken, `{ book(bookId: "B001") { title loans { student { stuId } } } }`, &book)
+		if len(resp.Errors) != 0 || book.Book.Loans == nil || len(*book.Book.Loans) == 0 || (*book.Book.Loans)[0].Student.StuID != "20230003" {
+			t.Errorf("馆员查询book = %s, %+v", resp.Data, resp.Errors)
+		}
+		assertGraphQLError(t, graphQL(t, app, librarianToken, `{ me { name } }`, nil), "FORBIDDEN")
+
+		// 超过嵌套层数和代价限制的查询不执行
+		resp = graphQL(t, app, librarianToken, `{ me { loans { book { loans { student { loans { book { loans { student { name } } } } } } } } } }`, nil)
+		assertGraphQLError(t, resp, "INVALID_ARGUMENT")
+		if string(resp.Data) != "" && string(resp.Data) != "null" {
+			t.Errorf("超过层数限制时data = %s", resp.Data)
+		}
+		assertGraphQLError(t, graphQL(t, app, librarianToken, `{ books { loans { student { loans { book { title } } } } } }`, nil), "INVALID_ARGUMENT")
+	})
+}
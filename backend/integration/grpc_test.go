@@ -0,0 +1,178 @@
+package integration
+
+import (
+	"backend/librarypb"
+	"backend/server"
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// 在内存连接上启动app的gRPC服务，返回客户端连接
+func dialGRPC(t *testing.T, app *server.Server) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	go app.GRPC.Serve(lis)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		app.GRPC.Shutdown(ctx)
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+// 检查gRPC状态码和ErrorInfo中的错误码
+func assertStatus(t *testing.T, err error, code codes.Code, reason string) {
+	t.Helper()
+	st := status.Convert(err)
+	if st.Code() != code {
+		t.Fatalf("状态码 = %s（%s），期望 %s", st.Code(), st.Message(), code)
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			if info.Reason != reason {
+				t.Errorf("ErrorInfo.Reason = %s，期望 %s", info.Reason, reason)
+			}
+			return
+		}
+	}
+	t.Errorf("状态中缺少ErrorInfo")
+}
+
+func TestGRPCLoans(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		app := newTestServer(t, db, driver)
+		conn := dialGRPC(t, app)
+		catalog := librarypb.NewCatalogServiceClient(conn)
+		loans := librarypb.NewLoanServiceClient(conn)
+		students := librarypb.NewStudentServiceClient(conn)
+		ctx := context.Background()
+
+		// 目录不需要令牌
+		book, err := catalog.GetBook(ctx, &librarypb.GetBookRequest{BookId: "B001"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if book.GetBookId() != "B001" || book.GetTitle() == "" || book.GetCreatedAt() == nil {
+			t.Errorf("GetBook = %v", book)
+		}
+		_, err = catalog.GetBook(metadata.AppendToOutgoingContext(ctx, "accept-language", "en"), &librarypb.GetBookRequest{BookId: "NOPE"})
+		assertStatus(t, err, codes.NotFound, "BOOK_NOT_FOUND")
+		list, err := catalog.ListBooks(ctx, &librarypb.ListBooksRequest{})
+		if err != nil || len(list.GetBooks()) == 0 {
+			t.Fatalf("ListBooks = %v, %v", list, err)
+		}
+
+		_, err = students.Login(ctx, &librarypb.LoginRequest{StuId: "20230003", Password: "wrong"})
+		assertStatus(t, err, codes.Unauthenticated, "INVALID_CREDENTIALS")
+		session, err := students.Login(ctx, &librarypb.LoginRequest{StuId: "20230003", Password: "password123"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if session.GetToken() == "" || session.GetStudent().GetName() == "" || !session.GetCanBorrow() {
+			t.Errorf("Login = %v", session)
+		}
+		studentCtx := withToken(ctx, session.GetToken())
+
+		// 没有令牌、令牌无效或访问其他学生的数据
+		_, err = loans.ListLoans(ctx, &librarypb.ListLoansRequest{StuId: "20230003"})
+		assertStatus(t, err, codes.Unauthenticated, "UNAUTHORIZED")
+		_, err = loans.ListLoans(withToken(ctx, "bad"), &librarypb.ListLoansRequest{StuId: "20230003"})
+		assertStatus(t, err, codes.Unauthenticated, "TOKEN_INVALID")
+		_, err = loans.ListLoans(studentCtx, &librarypb.ListLoansRequest{StuId: "20230001"})
+		assertStatus(t, err, codes.PermissionDenied, "FORBIDDEN")
+		_, err = students.GetStudent(studentCtx, &librarypb.GetStudentRequest{StuId: "20230001"})
+		assertStatus(t, err, codes.PermissionDenied, "FORBIDDEN")
+		_, err = loans.BorrowBook(studentCtx, &librarypb.BorrowBookRequest{StuId: "20230003"})
+		assertStatus(t, err, codes.InvalidArgument, "INVALID_ARGUMENT")
+
+		// 先订阅，收到响应头说明订阅已生效
+		watchCtx, cancelWatch := context.WithCancel(studentCtx)
+		defer cancelWatch()
+		watch, err := loans.WatchLoans(watchCtx, &librarypb.WatchLoansRequest{StuId: "20230003"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := watch.Header(); err != nil {
+			t.Fatal(err)
+		}
+
+		loan, err := loans.BorrowBook(studentCtx, &librarypb.BorrowBookRequest{StuId: "20230003", BookId: "B001"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loan.GetBookId() != "B001" || loan.GetDueDate() == nil || loan.GetReturnDate() != nil {
+			t.Errorf("BorrowBook = %v", loan)
+		}
+		_, err = loans.BorrowBook(studentCtx, &librarypb.BorrowBookRequest{StuId: "20230003", BookId: "NOPE"})
+		assertStatus(t, err, codes.NotFound, "BOOK_NOT_FOUND")
+
+		update, err := watch.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if update.GetKind() != librarypb.LoanUpdate_KIND_BORROWED || update.GetBookId() != "B001" || update.GetStuId() != "20230003" {
+			t.Errorf("WatchLoans = %v", update)
+		}
+
+		listed, err := loans.ListLoans(studentCtx, &librarypb.ListLoansRequest{StuId: "20230003"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(listed.GetLoans()) != 1 || listed.GetLoans()[0].GetBookTitle() == "" {
+			t.Errorf("ListLoans = %v", listed)
+		}
+		got, err := loans.GetLoan(studentCtx, &librarypb.GetLoanRequest{StuId: "20230003", BookId: "B001"})
+		if err != nil || got.GetId() != loan.GetId() {
+			t.Errorf("GetLoan = %v, %v", got, err)
+		}
+
+		// 经HTTP接口还书，订阅同样收到事件
+		w := serve(t, app, http.MethodPost, "/api/v1/returns", session.GetToken(), map[string]string{"book_id": "B001"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("还书 = %d: %s", w.Code, w.Body)
+		}
+		update, err = watch.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if update.GetKind() != librarypb.LoanUpdate_KIND_RETURNED || update.GetReturnDate() == nil {
+			t.Errorf("WatchLoans = %v", update)
+		}
+
+		// 馆员可以访问任何学生的数据；已归还的不再列出
+		librarianToken := login(t, app, "/api/v1/sessions/librarian", map[string]string{"librarian_id": "L001", "password": "admin123"})
+		librarianCtx := withToken(ctx, librarianToken)
+		student, err := students.GetStudent(librarianCtx, &librarypb.GetStudentRequest{StuId: "20230003"})
+		if err != nil || student.GetStuId() != "20230003" {
+			t.Errorf("馆员GetStudent = %v, %v", student, err)
+		}
+		listed, err = loans.ListLoans(librarianCtx, &librarypb.ListLoansRequest{StuId: "20230003"})
+		if err != nil || len(listed.GetLoans()) != 0 {
+			t.Errorf("馆员ListLoans = %v, %v", listed, err)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package integration
+
+import (
+	"backend/migrations"
+	"backend/service"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestReadiness(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		ctx := context.Background()
+		migrator, err := migrations.NewMigrator(db, driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		healthService := service.NewHealthService(db, migrator)
+		if report := healthService.Ready(ctx); !report.Ready {
+			t.Fatalf("迁移后应就绪: %+v", report.Checks)
+		}
+
+		// 后台任务开始运行前不就绪
+		webhookService := service.NewWebhookService(db)
+		healthService.Watch("webhook_worker", webhookService.Heartbeat())
+		if report := healthService.Ready(ctx); report.Ready || report.Checks["webhook_worker"].Status != service.HealthFailed {
+			t.Errorf("推送任务未运行时 = %+v", report.Checks)
+		}
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			webhookService.RunWorker(time.Hour, stop)
+			close(done)
+		}()
+		deadline := time.Now().Add(5 * time.Second)
+		for !healthService.Ready(ctx).Ready && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if report := healthService.Ready(ctx); !report.Ready {
+			t.Errorf("推送任务运行后应就绪: %+v", report.Checks)
+		}
+		close(stop)
+		<-done
+
+		// 回滚一个迁移后不就绪
+		if _, err := migrator.Down(1); err != nil {
+			t.Fatal(err)
+		}
+		if report := healthService.Ready(ctx); report.Ready || report.Checks["migrations"].Status != service.HealthFailed {
+			t.Errorf("有迁移未执行时 = %+v", report.Checks)
+		}
+		if _, err := migrator.Up(); err != nil {
+			t.Fatal(err)
+		}
+
+		// 开始退出后不就绪
+		healthService.Drain()
+		if report := healthService.Ready(ctx); report.Ready || report.Checks["shutdown"].Status != service.HealthDraining {
+			t.Errorf("退出过程中 = %+v", report.Checks)
+		}
+
+		// 数据库不可用时不再检查迁移
+		db.Close()
+		report := healthService.Ready(ctx)
+		if report.Checks["database"].Status != service.HealthFailed || report.Checks["migrations"].Status != service.HealthSkipped {
+			t.Errorf("数据库不可用时 = %+v", report.Checks)
+		}
+	})
+}
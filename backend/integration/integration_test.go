@@ -0,0 +1,117 @@
+// Package integration 在真实数据库上运行数据访问层和业务层的测试
+// SQLite总是参与测试（使用临时文件，无需外部数据库）；
+// 设置环境变量 LIBRARY_TEST_MYSQL_DSN、LIBRARY_TEST_POSTGRES_DSN 时同时在MySQL、PostgreSQL上运行，
+// 这些库会被清空重建，请使用专门的测试库
+package integration
+
+import (
+	"backend/config"
+	"backend/dao"
+	"backend/migrations"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// DAO的SQL方言是全局设置，各数据库依次测试，测试不能并行
+func forEachBackend(t *testing.T, fn func(t *testing.T, db *sql.DB, driver string)) {
+	t.Run(dao.DriverSQLite, func(t *testing.T) {
+		cfg := config.DatabaseConfig{Driver: dao.DriverSQLite, Path: filepath.Join(t.TempDir(), "library.db")}
+		fn(t, openDatabase(t, cfg.Driver, cfg.DSN()), cfg.Driver)
+	})
+
+	for _, backend := range []struct{ driver, env string }{
+		{dao.DriverMySQL, "LIBRARY_TEST_MYSQL_DSN"},
+		{dao.DriverPostgres, "LIBRARY_TEST_POSTGRES_DSN"},
+	} {
+		t.Run(backend.driver, func(t *testing.T) {
+			dsn := os.Getenv(backend.env)
+			if dsn == "" {
+				t.Skip("未设置" + backend.env)
+			}
+			fn(t, openDatabase(t, backend.driver, dsn), backend.driver)
+		})
+	}
+}
+
+// 连接数据库，回滚全部迁移后重新建表并导入测试数据
+func openDatabase(t *testing.T, driver, dsn string) *sql.DB {
+	t.Helper()
+	db, err := dao.Open(driver, dsn)
+	if err != nil {
+		t.Fatalf("连接%s失败: %v", driver, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewMigrator(db, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Down(len(statuses)); err != nil {
+		t.Fatalf("回滚迁移失败: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+
+	script, err := os.ReadFile("../test/test_data.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range strings.Split(string(script), ";") {
+		if strings.TrimSpace(stripComments(statement)) == "" {
+			continue
+		}
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("导入测试数据失败: %v\n%s", err, statement)
+		}
+	}
+	return db
+}
+
+func stripComments(statement string) string {
+	var lines []string
+	for _, line := range strings.Split(statement, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestMigrations(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		migrator, err := migrations.NewMigrator(db, driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pending, err := migrator.Pending()
+		if err != nil || pending != 0 {
+			t.Fatalf("未执行的迁移 = %d, %v，期望 0", pending, err)
+		}
+		if applied, err := migrator.Up(); err != nil || len(applied) != 0 {
+			t.Errorf("重复执行迁移 = %v, %v，期望不执行任何迁移", applied, err)
+		}
+
+		statuses, err := migrator.Status()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := migrator.Down(len(statuses)); err != nil {
+			t.Fatalf("回滚迁移失败: %v", err)
+		}
+		if pending, _ := migrator.Pending(); pending != len(statuses) {
+			t.Errorf("回滚后未执行的迁移 = %d，期望 %d", pending, len(statuses))
+		}
+		if _, err := migrator.Up(); err != nil {
+			t.Fatalf("重新执行迁移失败: %v", err)
+		}
+	})
+}
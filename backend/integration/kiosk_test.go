@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"backend/server"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serveKiosk(t *testing.T, app *server.Server, apiKey, path string, body interface{}) (int, envelope) {
+	t.Helper()
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("X-Device-Key", apiKey)
+	}
+	w := httptest.NewRecorder()
+	app.Router.ServeHTTP(w, req)
+	var resp envelope
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func registerKiosk(t *testing.T, app *server.Server, token, deviceID string, rateLimitPerMin int) string {
+	t.Helper()
+	w := serve(t, app, http.MethodPost, "/api/v1/kiosks", token, map[string]interface{}{
+		"device_id": deviceID, "name": "大厅" + deviceID, "branch_id": "MAIN", "rate_limit_per_min": rateLimitPerMin,
+	})
+	var resp envelope
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	var data struct {
+		APIKey string `json:"api_key"`
+	}
+	json.Unmarshal(resp.Data, &data)
+	if w.Code != http.StatusOK || data.APIKey == "" {
+		t.Fatalf("注册设备%s = %d: %s", deviceID, w.Code, w.Body)
+	}
+	return data.APIKey
+}
+
+func TestKioskAuthentication(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		app := newTestServer(t, db, driver)
+		admin := login(t, app, "/api/v1/sessions/librarian", map[string]string{"librarian_id": "L001", "password": "admin123"})
+		apiKey := registerKiosk(t, app, admin, "K1", 100)
+		student := login(t, app, "/api/v1/sessions/student", map[string]string{"stu_id": "20230003", "password": "password123"})
+
+		if w := serve(t, app, http.MethodPut, "/api/v1/students/20230003/pin", student, map[string]string{"password": "password123", "pin": "1234"}); w.Code != http.StatusOK {
+			t.Fatalf("设置PIN = %d: %s", w.Code, w.Body)
+		}
+		checkout := func(key, pin string) (int, envelope) {
+			return serveKiosk(t, app, key, "/api/v1/kiosk/checkouts", map[string]interface{}{
+				"stu_id": "20230003", "pin": pin, "barcodes": []string{"B003-0001"},
+			})
+		}
+		expect := func(name string, status int, resp envelope, wantStatus int, wantCode string) {
+			t.Helper()
+			if status != wantStatus || resp.Code != wantCode {
+				t.Errorf("%s = %d %s，期望 %d %s", name, status, resp.Code, wantStatus, wantCode)
+			}
+		}
+
+		status, resp := checkout("", "1234")
+		expect("缺少设备密钥", status, resp, http.StatusUnauthorized, "UNAUTHORIZED")
+		status, resp = checkout("not-a-key", "1234")
+		expect("设备密钥无效", status, resp, http.StatusUnauthorized, "DEVICE_KEY_INVALID")
+		status, resp = checkout(apiKey, "0000")
+		expect("PIN错误", status, resp, http.StatusUnauthorized, "INVALID_PIN")
+		status, resp = checkout(apiKey, "1234")
+		expect("自助借书", status, resp, http.StatusOK, "OK")
+
+		// 连续输错5次后锁定，正确的PIN也不能借书
+		for i := 0; i < 5; i++ {
+			status, resp = checkout(apiKey, "0000")
+			expect("PIN错误", status, resp, http.StatusUnauthorized, "INVALID_PIN")
+		}
+		status, resp = checkout(apiKey, "1234")
+		expect("PIN锁定", status, resp, http.StatusTooManyRequests, "PIN_LOCKED")
+
+		// 停用的设备与无效密钥返回相同的错误
+		if w := serve(t, app, http.MethodPost, "/api/v1/kiosks/K1/disable", admin, nil); w.Code != http.StatusOK {
+			t.Fatalf("停用设备 = %d: %s", w.Code, w.Body)
+		}
+		status, resp = checkout(apiKey, "1234")
+		expect("设备已停用", status, resp, http.StatusUnauthorized, "DEVICE_KEY_INVALID")
+		status, resp = serveKiosk(t, app, apiKey, "/api/v1/kiosk/returns", map[string]interface{}{"barcodes": []string{"B003-0001"}})
+		expect("停用设备还书", status, resp, http.StatusUnauthorized, "DEVICE_KEY_INVALID")
+
+		// 超出每分钟请求数时返回429
+		limitedKey := registerKiosk(t, app, admin, "K2", 1)
+		body := map[string]interface{}{"stu_id": "20230002", "pin": "1234", "barcodes": []string{"B003-0002"}}
+		status, resp = serveKiosk(t, app, limitedKey, "/api/v1/kiosk/checkouts", body)
+		expect("未设置PIN", status, resp, http.StatusUnauthorized, "INVALID_PIN")
+		status, resp = serveKiosk(t, app, limitedKey, "/api/v1/kiosk/checkouts", body)
+		expect("超出限流", status, resp, http.StatusTooManyRequests, "TOO_MANY_REQUESTS")
+	})
+}
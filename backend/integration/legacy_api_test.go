@@ -0,0 +1,332 @@
+package integration
+
+import (
+	"backend/server"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 旧版路由的弃用响应头，与 server/routes_legacy.go 中的日期对应
+const (
+	legacyDeprecationHeader = "@1792368000"
+	legacySunsetHeader      = "Thu, 01 Jul 2027 00:00:00 GMT"
+)
+
+type envelope struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func serve(t *testing.T, app *server.Server, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	app.Router.ServeHTTP(w, req)
+	return w
+}
+
+func login(t *testing.T, app *server.Server, path string, body map[string]string) string {
+	t.Helper()
+	w := serve(t, app, http.MethodPost, path, "", body)
+	var resp envelope
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	var data struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(resp.Data, &data)
+	if w.Code != http.StatusOK || data.Token == "" {
+		t.Fatalf("POST %s = %d: %s", path, w.Code, w.Body)
+	}
+	return data.Token
+}
+
+// 旧版路由的兼容性测试：状态码、错误码、data的结构和弃用响应头在停用日期之前保持不变
+func TestLegacyRoutes(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		app := newTestServer(t, db, driver)
+		studentToken := login(t, app, "/student/login", map[string]string{"stu_id": "20230003", "password": "password123"})
+		librarianToken := login(t, app, "/librarian/login", map[string]string{"librarian_id": "L001", "password": "admin123"})
+
+		loan := map[string]string{"stu_id": "20230003", "book_id": "B001"}
+		// 按顺序执行，后面的请求依赖前面借书的结果；fields为data中必有的字段，link为空时不应返回Link
+		steps := []struct {
+			method string
+			path   string
+			token  string
+			body   interface{}
+			status int
+			code   string
+			data   string // array、object或null，空列表为null
+			fields []string
+			link   string
+		}{
+			{http.MethodGet, "/books/list", "", nil, http.StatusOK, "OK", "array", nil, "/api/v1/books"},
+			{http.MethodGet, "/books/search?keyword=Go", "", nil, http.StatusOK, "OK", "array", nil, "/api/v1/books?keyword=Go"},
+			{http.MethodGet, "/books/search", "", nil, http.StatusBadRequest, "INVALID_ARGUMENT", "null", nil, ""},
+			{http.MethodGet, "/books/B001", "", nil, http.StatusOK, "OK", "object", []string{"book_id", "title", "author", "available_copies", "can_borrow"}, "/api/v1/books/B001"},
+			{http.MethodGet, "/books/NOPE", "", nil, http.StatusNotFound, "BOOK_NOT_FOUND", "null", nil, "/api/v1/books/NOPE"},
+			{http.MethodGet, "/branches", "", nil, http.StatusOK, "OK", "array", nil, "/api/v1/branches"},
+			{http.MethodGet, "/calendar/days/2025-01-01", "", nil, http.StatusOK, "OK", "object", nil, "/api/v1/calendar/days/2025-01-01"},
+
+			{http.MethodPost, "/student/login", "", map[string]string{"stu_id": "20230003", "password": "password123"}, http.StatusOK, "OK", "object", []string{"stu_id", "name", "trust", "can_borrow", "borrow_info", "token"}, "/api/v1/sessions/student"},
+			{http.MethodPost, "/student/login", "", map[string]string{"stu_id": "20230003", "password": "wrong"}, http.StatusUnauthorized, "INVALID_CREDENTIALS", "null", nil, "/api/v1/sessions/student"},
+			{http.MethodGet, "/student/info?stu_id=20230003", "", nil, http.StatusOK, "OK", "object", []string{"stu_id", "name", "trust", "can_borrow"}, "/api/v1/students/20230003"},
+			{http.MethodGet, "/student/info", "", nil, http.StatusBadRequest, "INVALID_ARGUMENT", "null", nil, ""},
+			{http.MethodGet, "/student/notifications", studentToken, nil, http.StatusOK, "OK", "object", nil, ""},
+			{http.MethodGet, "/student/notifications", "", nil, http.StatusUnauthorized, "UNAUTHORIZED", "null", nil, ""},
+			{http.MethodGet, "/holds?stu_id=20230003", "", nil, http.StatusOK, "OK", "null", nil, "/api/v1/students/20230003/holds"},
+
+			{http.MethodPost, "/borrow/borrow", "", loan, http.StatusOK, "OK", "object", []string{"due_date"}, "/api/v1/loans"},
+			{http.MethodPost, "/borrow/borrow", "", map[string]string{"stu_id": "20230003"}, http.StatusBadRequest, "INVALID_ARGUMENT", "null", nil, "/api/v1/loans"},
+			{http.MethodGet, "/borrow/record?stu_id=20230003&book_id=B001", "", nil, http.StatusOK, "OK", "object", []string{"stu_id", "book_id", "due_date"}, "/api/v1/students/20230003/loans/B001"},
+			{http.MethodGet, "/borrow/record?stu_id=20230003", "", nil, http.StatusBadRequest, "INVALID_ARGUMENT", "null", nil, ""},
+			{http.MethodGet, "/borrow/records?stu_id=20230003", "", nil, http.StatusOK, "OK", "array", nil, "/api/v1/students/20230003/loans"},
+			{http.MethodGet, "/borrow/records", "", nil, http.StatusBadRequest, "INVALID_ARGUMENT", "null", nil, ""},
+			{http.MethodPost, "/borrow/return", "", loan, http.StatusOK, "OK", "null", nil, "/api/v1/returns"},
+			{http.MethodPost, "/borrow/pay-fine", "", map[string]string{"stu_id": "20230003"}, http.StatusUnprocessableEntity, "NO_FINE_DUE", "null", nil, ""},
+
+			{http.MethodGet, "/circulation/patrons/20230001", librarianToken, nil, http.StatusOK, "OK", "object", nil, "/api/v1/circulation/patrons/20230001"},
+			{http.MethodGet, "/circulation/overrides", librarianToken, nil, http.StatusOK, "OK", "null", nil, "/api/v1/circulation/overrides"},
+			{http.MethodGet, "/circulation/overrides", "", nil, http.StatusUnauthorized, "UNAUTHORIZED", "null", nil, "/api/v1/circulation/overrides"},
+			{http.MethodGet, "/circulation/overrides", studentToken, nil, http.StatusForbidden, "FORBIDDEN", "null", nil, "/api/v1/circulation/overrides"},
+			{http.MethodGet, "/transfers", librarianToken, nil, http.StatusOK, "OK", "null", nil, "/api/v1/transfers"},
+			{http.MethodGet, "/audit", librarianToken, nil, http.StatusOK, "OK", "array", nil, "/api/v1/audit"},
+		}
+
+		for _, step := range steps {
+			name := step.method + " " + step.path
+			w := serve(t, app, step.method, step.path, step.token, step.body)
+			var resp envelope
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Errorf("%s 的响应不是JSON: %v", name, err)
+				continue
+			}
+			if w.Code != step.status || resp.Code != step.code {
+				t.Errorf("%s = %d %s，期望 %d %s: %s", name, w.Code, resp.Code, step.status, step.code, w.Body)
+			}
+			if resp.Message == "" && step.code != "OK" {
+				t.Errorf("%s 的错误响应没有message", name)
+			}
+			if kind := jsonKind(resp.Data); kind != step.data {
+				t.Errorf("%s 的data为 %s，期望 %s", name, kind, step.data)
+			}
+			if len(step.fields) > 0 {
+				var data map[string]json.RawMessage
+				json.Unmarshal(resp.Data, &data)
+				for _, field := range step.fields {
+					if _, ok := data[field]; !ok {
+						t.Errorf("%s 的data缺少字段 %s", name, field)
+					}
+				}
+			}
+
+			if got := w.Header().Get("Deprecation"); got != legacyDeprecationHeader {
+				t.Errorf("%s 的Deprecation = %q", name, got)
+			}
+			if got := w.Header().Get("Sunset"); got != legacySunsetHeader {
+				t.Errorf("%s 的Sunset = %q", name, got)
+			}
+			wantLink := ""
+			if step.link != "" {
+				wantLink = "<" + step.link + `>; rel="successor-version"`
+			}
+			if got := w.Header().Get("Link"); got != wantLink {
+				t.Errorf("%s 的Link = %q，期望 %q", name, got, wantLink)
+			}
+		}
+
+		// 学生信息中不返回密码
+		w := serve(t, app, http.MethodGet, "/student/info?stu_id=20230003", "", nil)
+		var resp envelope
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		var student struct {
+			Password string `json:"password"`
+		}
+		json.Unmarshal(resp.Data, &student)
+		if student.Password != "" {
+			t.Errorf("学生信息中包含密码: %s", w.Body)
+		}
+	})
+}
+
+// 旧版路由与/api/v1中对应的接口返回相同的响应，且/api/v1的响应不带弃用响应头
+func TestLegacyMatchesV1(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		app := newTestServer(t, db, driver)
+		studentToken := login(t, app, "/api/v1/sessions/student", map[string]string{"stu_id": "20230003", "password": "password123"})
+		librarianToken := login(t, app, "/api/v1/sessions/librarian", map[string]string{"librarian_id": "L002", "password": "librarian123"})
+		if w := serve(t, app, http.MethodPost, "/api/v1/loans", studentToken, map[string]string{"book_id": "B002"}); w.Code != http.StatusOK {
+			t.Fatalf("借书返回 %d: %s", w.Code, w.Body)
+		}
+
+		pairs := []struct {
+			legacy string
+			v1     string
+			token  string
+		}{
+			{"/books/list", "/api/v1/books", ""},
+			{"/books/search?keyword=Go", "/api/v1/books?keyword=Go", ""},
+			{"/books/B002", "/api/v1/books/B002", ""},
+			{"/books/NOPE", "/api/v1/books/NOPE", ""},
+			{"/branches", "/api/v1/branches", ""},
+			{"/calendar", "/api/v1/calendar", ""},
+			{"/student/info?stu_id=20230003", "/api/v1/students/20230003", ""},
+			{"/student/info?stu_id=NOPE", "/api/v1/students/NOPE", ""},
+			{"/borrow/records?stu_id=20230003", "/api/v1/students/20230003/loans", studentToken},
+			{"/borrow/record?stu_id=20230003&book_id=B002", "/api/v1/students/20230003/loans/B002", studentToken},
+			{"/borrow/record?stu_id=20230003&book_id=B004", "/api/v1/students/20230003/loans/B004", studentToken},
+			{"/holds?stu_id=20230003", "/api/v1/students/20230003/holds", studentToken},
+			{"/student/notifications", "/api/v1/students/20230003/notification-preferences", studentToken},
+			{"/circulation/patrons/20230003", "/api/v1/circulation/patrons/20230003", librarianToken},
+			{"/circulation/overrides", "/api/v1/circulation/overrides", librarianToken},
+			{"/books/B002/items", "/api/v1/books/B002/items", librarianToken},
+			{"/transfers", "/api/v1/transfers", librarianToken},
+			{"/notifications", "/api/v1/notifications", librarianToken},
+		}
+		for _, pair := range pairs {
+			legacy := serve(t, app, http.MethodGet, pair.legacy, pair.token, nil)
+			v1 := serve(t, app, http.MethodGet, pair.v1, pair.token, nil)
+			if legacy.Code != v1.Code || !bytes.Equal(legacy.Body.Bytes(), v1.Body.Bytes()) {
+				t.Errorf("GET %s = %d %s\nGET %s = %d %s", pair.legacy, legacy.Code, legacy.Body, pair.v1, v1.Code, v1.Body)
+			}
+			for _, header := range []string{"Deprecation", "Sunset", "Link"} {
+				if got := v1.Header().Get(header); got != "" {
+					t.Errorf("GET %s 返回了 %s: %q", pair.v1, header, got)
+				}
+			}
+		}
+
+		// 学生只能查看和修改自己的通知设置
+		for _, method := range []string{http.MethodGet, http.MethodPut} {
+			w := serve(t, app, method, "/api/v1/students/20230001/notification-preferences", studentToken, map[string]interface{}{})
+			if w.Code != http.StatusForbidden {
+				t.Errorf("%s 他人的通知设置返回 %d", method, w.Code)
+			}
+		}
+	})
+}
+
+// /api/v1中学生借阅、预约、罚款和PIN的接口需要令牌：学生只能操作本人的，学号取自令牌；馆员可以代学生操作。
+// 对应的旧版路由不需要令牌，行为不变
+func TestStudentRoutesAuthorization(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		app := newTestServer(t, db, driver)
+		student := login(t, app, "/api/v1/sessions/student", map[string]string{"stu_id": "20230003", "password": "password123"})
+		other := login(t, app, "/api/v1/sessions/student", map[string]string{"stu_id": "20230002", "password": "password123"})
+		librarian := login(t, app, "/api/v1/sessions/librarian", map[string]string{"librarian_id": "L002", "password": "librarian123"})
+
+		expect := func(name string, w *httptest.ResponseRecorder, status int) {
+			t.Helper()
+			if w.Code != status {
+				t.Errorf("%s = %d，期望 %d: %s", name, w.Code, status, w.Body)
+			}
+		}
+
+		// 未登录
+		for _, route := range []struct{ method, path string }{
+			{http.MethodPost, "/api/v1/loans"},
+			{http.MethodPost, "/api/v1/returns"},
+			{http.MethodPost, "/api/v1/holds"},
+			{http.MethodPost, "/api/v1/holds/1/cancel"},
+			{http.MethodPut, "/api/v1/students/20230003/pin"},
+			{http.MethodPost, "/api/v1/students/20230003/fine-payments"},
+			{http.MethodGet, "/api/v1/students/20230003/loans"},
+			{http.MethodGet, "/api/v1/students/20230003/loans/B001"},
+			{http.MethodGet, "/api/v1/students/20230003/holds"},
+		} {
+			expect("未登录 "+route.method+" "+route.path, serve(t, app, route.method, route.path, "", map[string]string{"stu_id": "20230003", "book_id": "B001"}), http.StatusUnauthorized)
+		}
+
+		// 学号取自令牌；请求中的stu_id与令牌不一致时拒绝，不能替他人借书
+		expect("替他人借书", serve(t, app, http.MethodPost, "/api/v1/loans", other, map[string]string{"stu_id": "20230003", "book_id": "B001"}), http.StatusForbidden)
+		expect("学生借书", serve(t, app, http.MethodPost, "/api/v1/loans", student, map[string]string{"book_id": "B001"}), http.StatusOK)
+		expect("替他人还书", serve(t, app, http.MethodPost, "/api/v1/returns", other, map[string]string{"stu_id": "20230003", "book_id": "B001"}), http.StatusForbidden)
+		expect("他人还书", serve(t, app, http.MethodPost, "/api/v1/returns", other, map[string]string{"book_id": "B001"}), http.StatusNotFound)
+
+		// 学生只能查看本人的借阅、预约，只能为本人交罚款、设置PIN
+		expect("查看他人的借阅", serve(t, app, http.MethodGet, "/api/v1/students/20230003/loans", other, nil), http.StatusForbidden)
+		expect("查看他人的借阅记录", serve(t, app, http.MethodGet, "/api/v1/students/20230003/loans/B001", other, nil), http.StatusForbidden)
+		expect("查看他人的预约", serve(t, app, http.MethodGet, "/api/v1/students/20230003/holds", other, nil), http.StatusForbidden)
+		expect("替他人交罚款", serve(t, app, http.MethodPost, "/api/v1/students/20230003/fine-payments", other, nil), http.StatusForbidden)
+		expect("设置他人的PIN", serve(t, app, http.MethodPut, "/api/v1/students/20230003/pin", other, map[string]string{"password": "password123", "pin": "1234"}), http.StatusForbidden)
+		expect("查看本人的借阅", serve(t, app, http.MethodGet, "/api/v1/students/20230003/loans/B001", student, nil), http.StatusOK)
+		expect("设置本人的PIN", serve(t, app, http.MethodPut, "/api/v1/students/20230003/pin", student, map[string]string{"password": "password123", "pin": "1234"}), http.StatusOK)
+
+		// 馆员可以查看任何学生，代学生借还时必须指定stu_id；PIN只能由学生本人设置
+		expect("馆员查看借阅", serve(t, app, http.MethodGet, "/api/v1/students/20230003/loans", librarian, nil), http.StatusOK)
+		expect("馆员查看预约", serve(t, app, http.MethodGet, "/api/v1/students/20230003/holds", librarian, nil), http.StatusOK)
+		expect("馆员还书未指定学生", serve(t, app, http.MethodPost, "/api/v1/returns", librarian, map[string]string{"book_id": "B001"}), http.StatusBadRequest)
+		expect("馆员代还书", serve(t, app, http.MethodPost, "/api/v1/returns", librarian, map[string]string{"stu_id": "20230003", "book_id": "B001"}), http.StatusOK)
+		expect("馆员设置PIN", serve(t, app, http.MethodPut, "/api/v1/students/20230003/pin", librarian, map[string]string{"password": "password123", "pin": "1234"}), http.StatusForbidden)
+
+		// 预约和取消预约同样只能为本人
+		w := serve(t, app, http.MethodPost, "/api/v1/holds", student, map[string]string{"book_id": "B003", "pickup_branch_id": "MAIN"})
+		expect("学生预约", w, http.StatusOK)
+		var resp envelope
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		var hold struct {
+			ID int `json:"id"`
+		}
+		json.Unmarshal(resp.Data, &hold)
+		cancel := "/api/v1/holds/" + strconv.Itoa(hold.ID) + "/cancel"
+		expect("替他人取消预约", serve(t, app, http.MethodPost, cancel, other, map[string]string{"stu_id": "20230003"}), http.StatusForbidden)
+		expect("学生取消预约", serve(t, app, http.MethodPost, cancel, student, nil), http.StatusOK)
+
+		// 旧版路由不需要令牌
+		expect("旧版借书", serve(t, app, http.MethodPost, "/borrow/borrow", "", map[string]string{"stu_id": "20230003", "book_id": "B001"}), http.StatusOK)
+		expect("旧版借阅记录", serve(t, app, http.MethodGet, "/borrow/records?stu_id=20230003", "", nil), http.StatusOK)
+		expect("旧版还书", serve(t, app, http.MethodPost, "/borrow/return", "", map[string]string{"stu_id": "20230003", "book_id": "B001"}), http.StatusOK)
+		expect("旧版借书缺少学号", serve(t, app, http.MethodPost, "/borrow/borrow", "", map[string]string{"book_id": "B001"}), http.StatusBadRequest)
+	})
+}
+
+// 所有旧版路由都带弃用响应头，/api/v1和运维相关的路由不带
+func TestLegacyRoutesDeprecated(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		app := newTestServer(t, db, driver)
+		unversioned := []string{"/metrics", "/health", "/health/live", "/health/ready", "/openapi.yaml", "/openapi.json", "/docs", "/docs/assets/*filepath"}
+		for _, route := range app.Router.Routes() {
+			if route.Method != http.MethodGet || route.Path == "/stream" || route.Path == "/api/v1/stream" {
+				continue
+			}
+			// 只检查响应头，不关心是否有权限或参数是否完整
+			path := routeParam.ReplaceAllString(route.Path, "x")
+			path = strings.Replace(path, "*filepath", "index.html", 1)
+			w := serve(t, app, route.Method, path, "", nil)
+			deprecated := w.Header().Get("Deprecation") != ""
+			legacy := !strings.HasPrefix(route.Path, "/api/v1/") && !slices.Contains(unversioned, route.Path)
+			if deprecated != legacy {
+				t.Errorf("GET %s 的Deprecation = %q", route.Path, w.Header().Get("Deprecation"))
+			}
+		}
+	})
+}
+
+func jsonKind(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case len(raw) == 0 || string(raw) == "null":
+		return "null"
+	case raw[0] == '[':
+		return "array"
+	case raw[0] == '{':
+		return "object"
+	}
+	return "scalar"
+}
@@ -0,0 +1,168 @@
+package integration
+
+import (
+	"backend/dao"
+	"backend/do"
+	"backend/notify"
+	"backend/service"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 记录发送的邮件，fail为true时发送失败
+type fakeSender struct {
+	mu   sync.Mutex
+	fail bool
+	sent []notify.Message
+}
+
+func (s *fakeSender) Send(ctx context.Context, msg *notify.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("SMTP服务器不可用")
+	}
+	s.sent = append(s.sent, *msg)
+	return nil
+}
+
+func TestEnqueueNotices(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		notificationService := service.NewNotificationService(db, &fakeSender{})
+		holdService := service.NewHoldService(db, nil)
+
+		// 20230001订阅全部通知，20230002全部关闭
+		for _, pref := range []*do.NotificationPreference{
+			{StuID: "20230001", Email: "zhangsan@example.com", DueSoon: true, Overdue: true, HoldReady: true},
+			{StuID: "20230002", Email: "lisi@example.com"},
+		} {
+			if err := notificationService.SetPreference(ctx, pref); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// 两人各有一条逾期借阅（测试数据）和一个已到馆的预约，20230001另有一条即将到期的借阅
+		record, err := service.NewBorrowService(dao.NewStore(db), nil).BorrowBook(ctx, "20230001", "B004", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range []struct{ stuID, branchID string }{{"20230001", "MAIN"}, {"20230002", "EAST"}} {
+			hold, err := holdService.PlaceHold(ctx, h.stuID, "B003", h.branchID)
+			if err != nil || hold.Status != do.HoldStatusReady {
+				t.Fatalf("%s 预约 = %+v, %v，期望直接到馆", h.stuID, hold, err)
+			}
+		}
+
+		now := record.DueDate.Add(-24 * time.Hour)
+		if count, err := notificationService.EnqueueNotices(ctx, now); err != nil || count != 3 {
+			t.Fatalf("生成通知 = %d, %v，期望 3 条", count, err)
+		}
+		// 每条借阅或预约只通知一次
+		if count, err := notificationService.EnqueueNotices(ctx, now.Add(time.Hour)); err != nil || count != 0 {
+			t.Errorf("再次生成通知 = %d, %v，期望 0 条", count, err)
+		}
+
+		notifications, err := notificationService.ListDeliveries(ctx, "20230001", "", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var kinds []string
+		for _, n := range notifications {
+			kinds = append(kinds, n.Kind)
+			if n.Email != "zhangsan@example.com" || n.Status != do.NotificationStatusPending || n.Subject == "" || n.Body == "" {
+				t.Errorf("通知 = %+v", n)
+			}
+			if n.Kind == do.NotificationKindDueSoon && n.RefID != record.ID {
+				t.Errorf("到期提醒对应借阅 %d，期望 %d", n.RefID, record.ID)
+			}
+		}
+		slices.Sort(kinds)
+		if want := []string{do.NotificationKindDueSoon, do.NotificationKindHoldReady, do.NotificationKindOverdue}; !slices.Equal(kinds, want) {
+			t.Errorf("20230001 的通知 = %v，期望 %v", kinds, want)
+		}
+
+		if notifications, err := notificationService.ListDeliveries(ctx, "20230002", "", 10); err != nil || len(notifications) != 0 {
+			t.Errorf("未订阅的 20230002 的通知 = %+v, %v，期望没有", notifications, err)
+		}
+	})
+}
+
+func TestDeliverNotifications(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		sender := &fakeSender{fail: true}
+		notificationService := service.NewNotificationService(db, sender)
+		err := notificationService.SetPreference(ctx, &do.NotificationPreference{StuID: "20230001", Email: "zhangsan@example.com", Overdue: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 时间按秒保存
+		now := time.Now().Truncate(time.Second)
+		if count, err := notificationService.EnqueueNotices(ctx, now); err != nil || count != 1 {
+			t.Fatalf("生成通知 = %d, %v，期望 1 条", count, err)
+		}
+
+		// 发送失败时按1、2、4、8分钟退避重试，第5次失败后不再重试
+		const maxAttempts = 5 // 与 service.maxDeliveryAttempts 一致
+		at := now
+		for attempt := 1; attempt <= maxAttempts; attempt++ {
+			if sent, failed, err := notificationService.DeliverPending(ctx, at); err != nil || sent != 0 || failed != 1 {
+				t.Fatalf("第%d次发送 = %d/%d, %v，期望失败 1", attempt, sent, failed, err)
+			}
+			notifications, err := notificationService.ListDeliveries(ctx, "20230001", "", 10)
+			if err != nil || len(notifications) != 1 {
+				t.Fatalf("通知 = %+v, %v", notifications, err)
+			}
+			n := notifications[0]
+			if n.Attempts != attempt || n.LastError == "" {
+				t.Fatalf("第%d次发送后 = %+v", attempt, n)
+			}
+			if attempt == maxAttempts {
+				if n.Status != do.NotificationStatusFailed {
+					t.Errorf("第%d次失败后状态 = %s，期望 failed", attempt, n.Status)
+				}
+				break
+			}
+			delay := time.Minute << (attempt - 1)
+			if n.Status != do.NotificationStatusPending || !n.NextAttemptAt.Equal(at.Add(delay)) {
+				t.Fatalf("第%d次失败后 = %s，下次发送 %v，期望 %v 后重试", attempt, n.Status, n.NextAttemptAt, delay)
+			}
+			// 未到重试时间时不发送
+			if sent, failed, err := notificationService.DeliverPending(ctx, at.Add(delay-time.Second)); err != nil || sent+failed != 0 {
+				t.Fatalf("未到重试时间发送 = %d/%d, %v", sent, failed, err)
+			}
+			at = at.Add(delay)
+		}
+
+		sender.mu.Lock()
+		sender.fail = false
+		sender.mu.Unlock()
+		if sent, failed, err := notificationService.DeliverPending(ctx, at.Add(24*time.Hour)); err != nil || sent+failed != 0 {
+			t.Errorf("失败的通知再次发送 = %d/%d, %v，期望不再发送", sent, failed, err)
+		}
+		if len(sender.sent) != 0 {
+			t.Errorf("发送了 %d 封邮件，期望 0 封", len(sender.sent))
+		}
+
+		// 手动重试后发送成功
+		failedList, err := notificationService.ListDeliveries(ctx, "20230001", do.NotificationStatusFailed, 10)
+		if err != nil || len(failedList) != 1 {
+			t.Fatalf("失败的通知 = %+v, %v", failedList, err)
+		}
+		if err := notificationService.RetryDelivery(ctx, failedList[0].ID); err != nil {
+			t.Fatal(err)
+		}
+		if sent, failed, err := notificationService.DeliverPending(ctx, time.Now().Add(time.Second)); err != nil || sent != 1 || failed != 0 {
+			t.Errorf("重试发送 = %d/%d, %v，期望成功 1", sent, failed, err)
+		}
+		if len(sender.sent) != 1 || sender.sent[0].To != "zhangsan@example.com" || sender.sent[0].Subject != failedList[0].Subject {
+			t.Errorf("发送的邮件 = %+v", sender.sent)
+		}
+	})
+}
@@ -0,0 +1,328 @@
+package integration
+
+import (
+	"backend/config"
+	"backend/do"
+	"backend/events"
+	"backend/openapi"
+	"backend/server"
+	"backend/service"
+	"backend/storage"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// 不写进接口文档的路由
+var undocumentedRoutes = map[string]string{
+	"GET /docs/assets/*filepath": "Swagger UI的静态文件",
+}
+
+// 接口文档中的结构与Go类型的对应关系，字段名和是否必有按json标签核对
+var documentedTypes = map[string]interface{}{
+	"Book":                   do.Book{},
+	"BranchAvailability":     do.BranchAvailability{},
+	"BorrowRecord":           do.BorrowRecord{},
+	"Student":                do.Student{},
+	"Branch":                 do.Branch{},
+	"BookItem":               do.BookItem{},
+	"Hold":                   do.Hold{},
+	"Transfer":               do.Transfer{},
+	"CheckoutBlock":          service.CheckoutBlock{},
+	"CheckoutLoan":           service.CheckoutLoan{},
+	"CheckoutResult":         service.CheckoutResult{},
+	"ItemRouting":            service.ItemRouting{},
+	"CheckinItem":            service.CheckinItem{},
+	"PatronSummary":          service.PatronSummary{},
+	"CirculationOverride":    do.CirculationOverride{},
+	"KioskDevice":            do.KioskDevice{},
+	"KioskSlip":              service.KioskSlip{},
+	"KioskSlipItem":          service.KioskSlipItem{},
+	"OpeningHours":           do.OpeningHours{},
+	"LibraryClosure":         do.LibraryClosure{},
+	"LibraryCalendar":        service.LibraryCalendarView{},
+	"CalendarDay":            service.CalendarDay{},
+	"NotificationPreference": do.NotificationPreference{},
+	"Notification":           do.Notification{},
+	"WebhookEndpoint":        do.WebhookEndpoint{},
+	"WebhookDelivery":        do.WebhookDelivery{},
+	"AuditEntry":             do.AuditEntry{},
+	"HealthCheck":            service.HealthCheck{},
+}
+
+var routeParam = regexp.MustCompile(`:([a-z_]+)`)
+
+type openAPIDoc struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Required   []string                   `json:"required"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadOpenAPI(t *testing.T) ([]byte, *openAPIDoc) {
+	t.Helper()
+	specJSON, err := openapi.JSON()
+	if err != nil {
+		t.Fatalf("解析接口文档失败: %v", err)
+	}
+	var doc openAPIDoc
+	if err := json.Unmarshal(specJSON, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return specJSON, &doc
+}
+
+func newTestServer(t *testing.T, db *sql.DB, driver string) *server.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.Database.Driver = driver
+	cfg.Metrics.Enabled = true
+	bus, err := events.NewBus(events.NewMemoryBroker())
+	if err != nil {
+		t.Fatal(err)
+	}
+	covers, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := server.New(server.Deps{Config: cfg, DB: db, Bus: bus, Covers: covers, AuthSecret: []byte("test-secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+// 注册的路由与文档中的路径一一对应
+func TestOpenAPIRoutes(t *testing.T) {
+	_, doc := loadOpenAPI(t)
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		app := newTestServer(t, db, driver)
+
+		registered := make(map[string]bool)
+		for _, route := range app.Router.Routes() {
+			key := route.Method + " " + route.Path
+			if _, ok := undocumentedRoutes[key]; ok {
+				continue
+			}
+			path := routeParam.ReplaceAllString(route.Path, "{$1}")
+			registered[route.Method+" "+path] = true
+			if _, ok := doc.Paths[path][strings.ToLower(route.Method)]; !ok {
+				t.Errorf("路由 %s 没有写进接口文档", key)
+			}
+		}
+		for path, operations := range doc.Paths {
+			for method := range operations {
+				if !registered[strings.ToUpper(method)+" "+path] {
+					t.Errorf("接口文档中的 %s %s 没有注册", strings.ToUpper(method), path)
+				}
+			}
+		}
+	})
+}
+
+// 文档中的结构与Go类型的json字段一致：字段相同，不带omitempty的字段为必有字段
+func TestOpenAPISchemas(t *testing.T) {
+	_, doc := loadOpenAPI(t)
+	for name, value := range documentedTypes {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			t.Errorf("接口文档缺少结构 %s", name)
+			continue
+		}
+		var fields, required []string
+		typ := reflect.TypeOf(value)
+		for i := 0; i < typ.NumField(); i++ {
+			tag := typ.Field(i).Tag.Get("json")
+			field, options, _ := strings.Cut(tag, ",")
+			if field == "-" || field == "" {
+				continue
+			}
+			fields = append(fields, field)
+			if !strings.Contains(options, "omitempty") {
+				required = append(required, field)
+			}
+		}
+		var properties []string
+		for property := range schema.Properties {
+			properties = append(properties, property)
+		}
+		sort.Strings(fields)
+		sort.Strings(required)
+		sort.Strings(properties)
+		documentedRequired := slices.Sorted(slices.Values(schema.Required))
+		if !slices.Equal(fields, properties) {
+			t.Errorf("%s 的字段 = %v，%s 的json字段 = %v", name, properties, typ, fields)
+		}
+		if !slices.Equal(required, documentedRequired) {
+			t.Errorf("%s 的必有字段 = %v，期望 %v", name, documentedRequired, required)
+		}
+	}
+}
+
+// 按接口文档校验实际的响应
+func TestOpenAPIResponses(t *testing.T) {
+	specJSON, _ := loadOpenAPI(t)
+	spec, err := jsonschema.UnmarshalJSON(bytes.NewReader(specJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	compiler.AssertFormat()
+	if err := compiler.AddResource("openapi.json", spec); err != nil {
+		t.Fatal(err)
+	}
+	operations := spec.(map[string]interface{})["paths"].(map[string]interface{})
+
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		app := newTestServer(t, db, driver)
+
+		// 发出请求，按文档中该路径、方法和状态码（没有时为default）的响应结构校验，返回响应中的data
+		call := func(method, path, template, token string, body interface{}) (int, map[string]interface{}) {
+			t.Helper()
+			var reader *bytes.Reader
+			if body != nil {
+				payload, _ := json.Marshal(body)
+				reader = bytes.NewReader(payload)
+			} else {
+				reader = bytes.NewReader(nil)
+			}
+			req := httptest.NewRequest(method, path, reader)
+			req.Header.Set("Content-Type", "application/json")
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			operation, ok := operations[template].(map[string]interface{})[strings.ToLower(method)].(map[string]interface{})
+			if !ok {
+				t.Fatalf("接口文档中没有 %s %s", method, template)
+			}
+			responses := operation["responses"].(map[string]interface{})
+			status := strconv.Itoa(w.Code)
+			if _, ok := responses[status]; !ok {
+				if _, ok := responses["default"]; !ok {
+					t.Errorf("%s %s 返回了文档中没有的状态码 %d: %s", method, path, w.Code, w.Body)
+					return w.Code, nil
+				}
+				status = "default"
+			}
+
+			pointer := "/paths/" + escapePointer(template) + "/" + strings.ToLower(method) + "/responses/" + status
+			if ref, ok := responses[status].(map[string]interface{})["$ref"].(string); ok {
+				pointer = strings.TrimPrefix(ref, "#")
+			}
+			schema, err := compiler.Compile("openapi.json#" + pointer + "/content/application~1json/schema")
+			if err != nil {
+				t.Fatalf("编译 %s 的响应结构失败: %v", pointer, err)
+			}
+			instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(w.Body.Bytes()))
+			if err != nil {
+				t.Fatalf("%s %s 的响应不是JSON: %v", method, path, err)
+			}
+			if err := schema.Validate(instance); err != nil {
+				t.Errorf("%s %s 的响应与接口文档不符: %v\n%s", method, path, err, w.Body)
+			}
+			var envelope struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &envelope)
+			return w.Code, envelope.Data
+		}
+
+		// 图书
+		call(http.MethodGet, "/api/v1/books", "/api/v1/books", "", nil)
+		call(http.MethodGet, "/api/v1/books?keyword=Go", "/api/v1/books", "", nil)
+		call(http.MethodGet, "/api/v1/books/B003", "/api/v1/books/{id}", "", nil)
+		if status, _ := call(http.MethodGet, "/api/v1/books/NOPE", "/api/v1/books/{id}", "", nil); status != http.StatusNotFound {
+			t.Errorf("不存在的书籍返回 %d", status)
+		}
+		call(http.MethodGet, "/api/v1/branches", "/api/v1/branches", "", nil)
+		call(http.MethodGet, "/api/v1/calendar", "/api/v1/calendar", "", nil)
+		call(http.MethodGet, "/api/v1/calendar/days/2025-01-01", "/api/v1/calendar/days/{date}", "", nil)
+
+		// 学生
+		_, login := call(http.MethodPost, "/api/v1/sessions/student", "/api/v1/sessions/student", "", map[string]string{"stu_id": "20230003", "password": "password123"})
+		if login == nil {
+			t.Fatal("学生登录失败")
+		}
+		if status, _ := call(http.MethodPost, "/api/v1/sessions/student", "/api/v1/sessions/student", "", map[string]string{"stu_id": "20230003", "password": "wrong"}); status != http.StatusUnauthorized {
+			t.Errorf("密码错误时返回 %d", status)
+		}
+		call(http.MethodGet, "/api/v1/students/20230003", "/api/v1/students/{id}", "", nil)
+		call(http.MethodGet, "/api/v1/students/20230003/notification-preferences", "/api/v1/students/{id}/notification-preferences", login["token"].(string), nil)
+		if status, _ := call(http.MethodGet, "/api/v1/students/20230001/notification-preferences", "/api/v1/students/{id}/notification-preferences", login["token"].(string), nil); status != http.StatusForbidden {
+			t.Errorf("查看他人的通知设置返回 %d", status)
+		}
+		call(http.MethodGet, "/api/v1/students/20230003/holds", "/api/v1/students/{id}/holds", login["token"].(string), nil)
+
+		// 借阅
+		loan := map[string]string{"book_id": "B001"}
+		if status, _ := call(http.MethodPost, "/api/v1/loans", "/api/v1/loans", login["token"].(string), loan); status != http.StatusOK {
+			t.Fatalf("借书返回 %d", status)
+		}
+		call(http.MethodGet, "/api/v1/students/20230003/loans/B001", "/api/v1/students/{id}/loans/{book_id}", login["token"].(string), nil)
+		call(http.MethodGet, "/api/v1/students/20230003/loans", "/api/v1/students/{id}/loans", login["token"].(string), nil)
+		call(http.MethodPost, "/api/v1/returns", "/api/v1/returns", login["token"].(string), loan)
+		if status, _ := call(http.MethodPost, "/api/v1/loans", "/api/v1/loans", login["token"].(string), map[string]string{}); status != http.StatusBadRequest {
+			t.Errorf("缺少参数时返回 %d", status)
+		}
+
+		// 馆员
+		_, librarian := call(http.MethodPost, "/api/v1/sessions/librarian", "/api/v1/sessions/librarian", "", map[string]string{"librarian_id": "L001", "password": "admin123"})
+		if librarian == nil {
+			t.Fatal("馆员登录失败")
+		}
+		token := librarian["token"].(string)
+		call(http.MethodGet, "/api/v1/circulation/patrons/20230001", "/api/v1/circulation/patrons/{stu_id}", token, nil)
+		call(http.MethodPost, "/api/v1/circulation/checkouts", "/api/v1/circulation/checkouts", token, map[string]interface{}{"stu_id": "20230002", "barcodes": []string{"B003-0001"}, "branch_id": "MAIN"})
+		call(http.MethodPost, "/api/v1/circulation/checkins", "/api/v1/circulation/checkins", token, map[string]interface{}{"barcodes": []string{"B003-0001", "NOPE"}, "branch_id": "EAST"})
+		call(http.MethodGet, "/api/v1/circulation/overrides", "/api/v1/circulation/overrides", token, nil)
+		call(http.MethodGet, "/api/v1/books/B003/items", "/api/v1/books/{id}/items", token, nil)
+		call(http.MethodGet, "/api/v1/transfers", "/api/v1/transfers", token, nil)
+		call(http.MethodGet, "/api/v1/kiosks", "/api/v1/kiosks", token, nil)
+		call(http.MethodGet, "/api/v1/webhooks", "/api/v1/webhooks", token, nil)
+		call(http.MethodGet, "/api/v1/webhooks/deliveries", "/api/v1/webhooks/deliveries", token, nil)
+		call(http.MethodGet, "/api/v1/notifications", "/api/v1/notifications", token, nil)
+		call(http.MethodGet, "/api/v1/audit", "/api/v1/audit", token, nil)
+		if status, _ := call(http.MethodGet, "/api/v1/audit", "/api/v1/audit", "", nil); status != http.StatusUnauthorized {
+			t.Errorf("未登录时返回 %d", status)
+		}
+
+		// 运维
+		call(http.MethodGet, "/health/live", "/health/live", "", nil)
+		call(http.MethodGet, "/health/ready", "/health/ready", "", nil)
+		call(http.MethodGet, "/openapi.json", "/openapi.json", "", nil)
+
+		// 文档页面及其静态文件
+		for _, path := range []string{"/docs", "/docs/assets/swagger-ui-bundle.js", "/openapi.yaml"} {
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Code != http.StatusOK || w.Body.Len() == 0 {
+				t.Errorf("GET %s = %d", path, w.Code)
+			}
+		}
+	})
+}
+
+// JSON Pointer中转义路径里的~和/
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
@@ -0,0 +1,182 @@
+package integration
+
+import (
+	"backend/events"
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const streamTimeout = 5 * time.Second
+
+func newDBBus(t *testing.T, db *sql.DB) *events.Bus {
+	t.Helper()
+	bus, err := events.NewBus(events.NewDBBroker(db, 10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bus.Close() })
+	return bus
+}
+
+func receive(t *testing.T, name string, sub *events.Subscription) *events.Event {
+	t.Helper()
+	select {
+	case event := <-sub.C:
+		return event
+	case <-time.After(streamTimeout):
+		t.Fatalf("%s 没有收到事件", name)
+		return nil
+	}
+}
+
+func expectNoEvent(t *testing.T, name string, sub *events.Subscription) {
+	t.Helper()
+	select {
+	case event := <-sub.C:
+		t.Errorf("%s 收到多余的事件 %+v", name, event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// 两个实例共用数据库：任一实例发布的事件各实例都会收到，按图书和学生过滤
+func TestDBBrokerFanOut(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		first, second := newDBBus(t, db), newDBBus(t, db)
+		firstBooks := first.Subscribe([]string{"B001"}, "")
+		secondBooks := second.Subscribe([]string{"B001"}, "")
+		secondStudent := second.Subscribe(nil, "20230001")
+
+		for _, event := range []*events.Event{
+			{Type: events.TypeAvailability, BookID: "B002"},
+			{Type: events.TypeAvailability, BookID: "B001"},
+			{Type: events.TypeLoanBorrowed, BookID: "B001", StuID: "20230001"},
+		} {
+			event.At = time.Now()
+			first.Publish(event)
+		}
+
+		for name, sub := range map[string]*events.Subscription{"实例1": firstBooks, "实例2": secondBooks} {
+			if event := receive(t, name, sub); event.Type != events.TypeAvailability || event.BookID != "B001" {
+				t.Errorf("%s 收到 %+v", name, event)
+			}
+			expectNoEvent(t, name, sub)
+		}
+		if event := receive(t, "学生", secondStudent); event.Type != events.TypeLoanBorrowed || event.StuID != "20230001" {
+			t.Errorf("学生收到 %+v", event)
+		}
+		expectNoEvent(t, "学生", secondStudent)
+	})
+}
+
+// 并发写入时较大的ID可能先提交；较小的ID稍后可见时仍然推送，且不重复
+func TestDBBrokerOutOfOrderCommit(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		var maxID int
+		if err := db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM stream_events").Scan(&maxID); err != nil {
+			t.Fatal(err)
+		}
+		bus := newDBBus(t, db)
+		sub := bus.Subscribe([]string{"B001", "B002"}, "")
+
+		// 直接写入指定ID的行，模拟ID较小的事务后提交
+		insert := func(id int, bookID string) {
+			t.Helper()
+			payload, _ := json.Marshal(events.Event{Type: events.TypeAvailability, BookID: bookID, At: time.Now()})
+			statement := fmt.Sprintf("INSERT INTO stream_events (id, payload) VALUES (%d, '%s')", id, payload)
+			if _, err := db.Exec(statement); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		insert(maxID+2, "B002")
+		if event := receive(t, "先提交的事件", sub); event.BookID != "B002" {
+			t.Errorf("收到 %+v，期望 B002", event)
+		}
+		insert(maxID+1, "B001")
+		if event := receive(t, "后提交的事件", sub); event.BookID != "B001" {
+			t.Errorf("收到 %+v，期望 B001", event)
+		}
+		expectNoEvent(t, "补齐空缺后", sub)
+	})
+}
+
+// 读取SSE响应中的事件名，直到收到want为止
+func readSSE(t *testing.T, names chan string, want string) {
+	t.Helper()
+	timeout := time.After(streamTimeout)
+	for {
+		select {
+		case name, ok := <-names:
+			if !ok {
+				t.Fatalf("连接已关闭，没有收到 %s", want)
+			}
+			if name == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("没有收到 %s", want)
+		}
+	}
+}
+
+func TestStreamController(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		app := newTestServer(t, db, driver)
+		srv := httptest.NewServer(app.Router)
+		defer srv.Close()
+		token := login(t, app, "/api/v1/sessions/student", map[string]string{"stu_id": "20230003", "password": "password123"})
+
+		for _, tt := range []struct {
+			name, query string
+			status      int
+		}{
+			{"没有订阅对象", "", http.StatusBadRequest},
+			{"令牌无效", "?book_id=B001&token=bad", http.StatusUnauthorized},
+			{"订阅过多图书", "?book_id=" + strings.Repeat("B001,", 201), http.StatusBadRequest},
+		} {
+			resp, err := http.Get(srv.URL + "/api/v1/stream" + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("%s = %d，期望 %d", tt.name, resp.StatusCode, tt.status)
+			}
+		}
+
+		resp, err := http.Get(srv.URL + "/api/v1/stream?book_id=B003&token=" + token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			t.Fatalf("订阅 = %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+
+		names := make(chan string, 16)
+		go func() {
+			defer close(names)
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				if name, ok := strings.CutPrefix(scanner.Text(), "event:"); ok {
+					names <- name
+				}
+			}
+		}()
+		readSSE(t, names, "ready")
+
+		// 借书后推送图书可借数量和本人的借阅事件
+		if w := serve(t, app, http.MethodPost, "/api/v1/loans", token, map[string]string{"book_id": "B003"}); w.Code != http.StatusOK {
+			t.Fatalf("借书 = %d: %s", w.Code, w.Body)
+		}
+		readSSE(t, names, events.TypeAvailability)
+		readSSE(t, names, events.TypeLoanBorrowed)
+	})
+}
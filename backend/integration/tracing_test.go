@@ -0,0 +1,61 @@
+package integration
+
+import (
+	"backend/dao"
+	"backend/service"
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQuerySpans(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		recorder := tracetest.NewSpanRecorder()
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		defer otel.SetTracerProvider(previous)
+
+		borrowService := service.NewBorrowService(dao.NewStore(db), nil)
+		if _, err := borrowService.BorrowBook(context.Background(), "20230003", "B003", "MAIN"); err != nil {
+			t.Fatalf("借书失败: %v", err)
+		}
+
+		var eligibility sdktrace.ReadOnlySpan
+		for _, span := range recorder.Ended() {
+			if span.Name() == "borrow.eligibility" {
+				eligibility = span
+			}
+		}
+		if eligibility == nil {
+			t.Fatal("缺少资格检查的span")
+		}
+
+		// 资格检查中的查询记录为它的子span，带数据库类型和SQL
+		var queries int
+		for _, span := range recorder.Ended() {
+			if span.Parent().SpanID() != eligibility.SpanContext().SpanID() {
+				continue
+			}
+			queries++
+			attrs := attribute.NewSet(span.Attributes()...)
+			if v, _ := attrs.Value("db.system.name"); v.AsString() != driver {
+				t.Errorf("%s 的数据库类型 = %v", span.Name(), v)
+			}
+			if v, _ := attrs.Value("db.query.text"); !strings.HasPrefix(v.AsString(), "SELECT") {
+				t.Errorf("%s 的SQL = %v", span.Name(), v)
+			}
+			if !strings.HasPrefix(span.Name(), "SELECT ") {
+				t.Errorf("span名 = %s，期望为 SELECT <表名>", span.Name())
+			}
+		}
+		if queries == 0 {
+			t.Error("资格检查下没有SQL的span")
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package integration
+
+import (
+	"backend/dao"
+	"backend/do"
+	"backend/service"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 推送地址收到的请求
+type webhookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// 模拟的推送地址，按status返回状态码并记录收到的请求
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []webhookRequest
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, webhookRequest{header: req.Header.Clone(), body: body})
+	w.WriteHeader(r.status)
+}
+
+func (r *webhookReceiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *webhookReceiver) received() []webhookRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]webhookRequest(nil), r.requests...)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		receiver := &webhookReceiver{status: http.StatusOK}
+		srv := httptest.NewServer(receiver)
+		defer srv.Close()
+
+		webhookService := service.NewWebhookService(db)
+		borrowService := service.NewBorrowService(dao.NewStore(db), nil)
+		endpoint, secret, err := webhookService.RegisterEndpoint(ctx, srv.URL, []string{do.EventBookBorrowed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		deliveries := func() []do.WebhookDelivery {
+			t.Helper()
+			list, err := webhookService.ListDeliveries(ctx, "", endpoint.ID, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return list
+		}
+
+		// 借书事件写入发件箱，分发后推送，签名可由密钥验证
+		if _, err := borrowService.BorrowBook(ctx, "20230003", "B004", ""); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		if dispatched, err := webhookService.DispatchOutbox(ctx, now); err != nil || dispatched != 1 {
+			t.Fatalf("分发 = %d, %v，期望 1", dispatched, err)
+		}
+		if delivered, failed, err := webhookService.DeliverPending(ctx, now); err != nil || delivered != 1 || failed != 0 {
+			t.Fatalf("推送 = %d/%d, %v，期望成功 1", delivered, failed, err)
+		}
+
+		requests := receiver.received()
+		if len(requests) != 1 {
+			t.Fatalf("推送地址收到 %d 个请求，期望 1 个", len(requests))
+		}
+		req := requests[0]
+		if got := req.header.Get("X-Webhook-Event"); got != do.EventBookBorrowed {
+			t.Errorf("X-Webhook-Event = %q", got)
+		}
+		want := "sha256=" + service.SignWebhook(secret, req.header.Get("X-Webhook-Timestamp"), req.body)
+		if got := req.header.Get("X-Webhook-Signature"); got != want {
+			t.Errorf("X-Webhook-Signature = %q，期望 %q", got, want)
+		}
+		var envelope struct {
+			Type string            `json:"type"`
+			Data service.LoanEvent `json:"data"`
+		}
+		if err := json.Unmarshal(req.body, &envelope); err != nil || envelope.Type != do.EventBookBorrowed ||
+			envelope.Data.StuID != "20230003" || envelope.Data.BookID != "B004" {
+			t.Errorf("推送内容 = %s, %v", req.body, err)
+		}
+		if list := deliveries(); len(list) != 1 || list[0].Status != do.WebhookDeliveryDelivered || list[0].Attempts != 1 {
+			t.Errorf("投递记录 = %+v，期望已推送", list)
+		}
+
+		// 对方返回500时按退避时间重试，用尽次数后进入死信
+		receiver.setStatus(http.StatusInternalServerError)
+		if _, err := borrowService.BorrowBook(ctx, "20230003", "B001", ""); err != nil {
+			t.Fatal(err)
+		}
+		if dispatched, err := webhookService.DispatchOutbox(ctx, now); err != nil || dispatched != 1 {
+			t.Fatalf("分发 = %d, %v，期望 1", dispatched, err)
+		}
+
+		const maxAttempts = 8 // 与 service.maxWebhookAttempts 一致
+		at := now
+		for attempt := 1; attempt <= maxAttempts; attempt++ {
+			if delivered, failed, err := webhookService.DeliverPending(ctx, at); err != nil || delivered != 0 || failed != 1 {
+				t.Fatalf("第%d次推送 = %d/%d, %v，期望失败 1", attempt, delivered, failed, err)
+			}
+			failedDelivery := deliveries()[0]
+			if failedDelivery.Attempts != attempt || failedDelivery.LastStatusCode != http.StatusInternalServerError {
+				t.Fatalf("第%d次推送后 = %+v", attempt, failedDelivery)
+			}
+			if attempt == maxAttempts {
+				if failedDelivery.Status != do.WebhookDeliveryDead {
+					t.Errorf("重试%d次后状态 = %s，期望 dead", attempt, failedDelivery.Status)
+				}
+				break
+			}
+			if failedDelivery.Status != do.WebhookDeliveryPending || !failedDelivery.NextAttemptAt.After(at) {
+				t.Fatalf("第%d次推送后 = %+v，期望等待重试", attempt, failedDelivery)
+			}
+			// 未到重试时间时不推送
+			if delivered, failed, err := webhookService.DeliverPending(ctx, at); err != nil || delivered+failed != 0 {
+				t.Fatalf("未到重试时间推送 = %d/%d, %v", delivered, failed, err)
+			}
+			at = failedDelivery.NextAttemptAt.Add(time.Second)
+		}
+
+		if delivered, failed, err := webhookService.DeliverPending(ctx, at.Add(24*time.Hour)); err != nil || delivered+failed != 0 {
+			t.Errorf("死信推送 = %d/%d, %v，期望不再推送", delivered, failed, err)
+		}
+		if got := len(receiver.received()); got != 1+maxAttempts {
+			t.Errorf("推送地址收到 %d 个请求，期望 %d 个", got, 1+maxAttempts)
+		}
+		dead, err := webhookService.ListDeliveries(ctx, do.WebhookDeliveryDead, 0, 10)
+		if err != nil || len(dead) != 1 {
+			t.Errorf("死信 = %+v, %v，期望 1 条", dead, err)
+		}
+	})
+}
+
+// 已被其他实例认领的投递在租期内不会重复推送
+func TestWebhookDeliveryLease(t *testing.T) {
+	ctx := context.Background()
+	forEachBackend(t, func(t *testing.T, db *sql.DB, driver string) {
+		receiver := &webhookReceiver{status: http.StatusOK}
+		srv := httptest.NewServer(receiver)
+		defer srv.Close()
+
+		webhookService := service.NewWebhookService(db)
+		endpoint, _, err := webhookService.RegisterEndpoint(ctx, srv.URL, []string{do.EventBookBorrowed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := service.NewBorrowService(dao.NewStore(db), nil).BorrowBook(ctx, "20230003", "B004", ""); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		if _, err := webhookService.DispatchOutbox(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+		list, err := webhookService.ListDeliveries(ctx, "", endpoint.ID, 10)
+		if err != nil || len(list) != 1 {
+			t.Fatalf("投递记录 = %+v, %v", list, err)
+		}
+
+		webhookDAO := dao.NewWebhookDAO(db)
+		if claimed, err := webhookDAO.ClaimDelivery(ctx, list[0].ID, now, now.Add(time.Minute)); err != nil || !claimed {
+			t.Fatalf("认领 = %v, %v", claimed, err)
+		}
+		if claimed, err := webhookDAO.ClaimDelivery(ctx, list[0].ID, now, now.Add(time.Minute)); err != nil || claimed {
+			t.Errorf("重复认领 = %v, %v，期望失败", claimed, err)
+		}
+		if delivered, failed, err := webhookService.DeliverPending(ctx, now); err != nil || delivered+failed != 0 {
+			t.Errorf("租期内推送 = %d/%d, %v，期望不推送", delivered, failed, err)
+		}
+		// 租期过后认领的实例仍未完成时，由其他实例重新推送
+		if delivered, _, err := webhookService.DeliverPending(ctx, now.Add(2*time.Minute)); err != nil || delivered != 1 {
+			t.Errorf("租期过后推送 = %d, %v，期望 1", delivered, err)
+		}
+		if got := len(receiver.received()); got != 1 {
+			t.Errorf("推送地址收到 %d 个请求，期望 1 个", got)
+		}
+	})
+}
@@ -0,0 +1,5 @@
+// Package librarypb gRPC接口的protobuf定义及生成的代码，接口实现在rpc包中
+// 修改 .proto 文件后在本目录执行 go generate 重新生成，需要安装protoc、protoc-gen-go和protoc-gen-go-grpc
+package librarypb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative catalog.proto loans.proto students.proto
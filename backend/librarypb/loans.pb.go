@@ -0,0 +1,900 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: loans.proto
+
+package librarypb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type LoanUpdate_Kind int32
+
+const (
+	LoanUpdate_KIND_UNSPECIFIED LoanUpdate_Kind = 0
+	// 借出
+	LoanUpdate_KIND_BORROWED LoanUpdate_Kind = 1
+	// 归还
+	LoanUpdate_KIND_RETURNED LoanUpdate_Kind = 2
+)
+
+// Enum value maps for LoanUpdate_Kind.
+var (
+	LoanUpdate_Kind_name = map[int32]string{
+		0: "KIND_UNSPECIFIED",
+		1: "KIND_BORROWED",
+		2: "KIND_RETURNED",
+	}
+	LoanUpdate_Kind_value = map[string]int32{
+		"KIND_UNSPECIFIED": 0,
+		"KIND_BORROWED":    1,
+		"KIND_RETURNED":    2,
+	}
+)
+
+func (x LoanUpdate_Kind) Enum() *LoanUpdate_Kind {
+	p := new(LoanUpdate_Kind)
+	*p = x
+	return p
+}
+
+func (x LoanUpdate_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LoanUpdate_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_loans_proto_enumTypes[0].Descriptor()
+}
+
+func (LoanUpdate_Kind) Type() protoreflect.EnumType {
+	return &file_loans_proto_enumTypes[0]
+}
+
+func (x LoanUpdate_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LoanUpdate_Kind.Descriptor instead.
+func (LoanUpdate_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{10, 0}
+}
+
+type Loan struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	StuId  string                 `protobuf:"bytes,2,opt,name=stu_id,json=stuId,proto3" json:"stu_id,omitempty"`
+	BookId string                 `protobuf:"bytes,3,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	// 借出的单册条码，书籍没有登记单册时为空
+	Barcode    string                 `protobuf:"bytes,4,opt,name=barcode,proto3" json:"barcode,omitempty"`
+	BorrowDate *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=borrow_date,json=borrowDate,proto3" json:"borrow_date,omitempty"`
+	DueDate    *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=due_date,json=dueDate,proto3" json:"due_date,omitempty"`
+	// 未归还时为空
+	ReturnDate *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=return_date,json=returnDate,proto3" json:"return_date,omitempty"`
+	IsOverdue  bool                   `protobuf:"varint,8,opt,name=is_overdue,json=isOverdue,proto3" json:"is_overdue,omitempty"`
+	FineAmount float64                `protobuf:"fixed64,9,opt,name=fine_amount,json=fineAmount,proto3" json:"fine_amount,omitempty"`
+	// 只在ListLoans中返回
+	BookTitle     string `protobuf:"bytes,10,opt,name=book_title,json=bookTitle,proto3" json:"book_title,omitempty"`
+	BookAuthor    string `protobuf:"bytes,11,opt,name=book_author,json=bookAuthor,proto3" json:"book_author,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Loan) Reset() {
+	*x = Loan{}
+	mi := &file_loans_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Loan) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Loan) ProtoMessage() {}
+
+func (x *Loan) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Loan.ProtoReflect.Descriptor instead.
+func (*Loan) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Loan) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Loan) GetStuId() string {
+	if x != nil {
+		return x.StuId
+	}
+	return ""
+}
+
+func (x *Loan) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *Loan) GetBarcode() string {
+	if x != nil {
+		return x.Barcode
+	}
+	return ""
+}
+
+func (x *Loan) GetBorrowDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.BorrowDate
+	}
+	return nil
+}
+
+func (x *Loan) GetDueDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DueDate
+	}
+	return nil
+}
+
+func (x *Loan) GetReturnDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReturnDate
+	}
+	return nil
+}
+
+func (x *Loan) GetIsOverdue() bool {
+	if x != nil {
+		return x.IsOverdue
+	}
+	return false
+}
+
+func (x *Loan) GetFineAmount() float64 {
+	if x != nil {
+		return x.FineAmount
+	}
+	return 0
+}
+
+func (x *Loan) GetBookTitle() string {
+	if x != nil {
+		return x.BookTitle
+	}
+	return ""
+}
+
+func (x *Loan) GetBookAuthor() string {
+	if x != nil {
+		return x.BookAuthor
+	}
+	return ""
+}
+
+type BorrowBookRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	StuId  string                 `protobuf:"bytes,1,opt,name=stu_id,json=stuId,proto3" json:"stu_id,omitempty"`
+	BookId string                 `protobuf:"bytes,2,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	// 借书分馆，可为空
+	BranchId      string `protobuf:"bytes,3,opt,name=branch_id,json=branchId,proto3" json:"branch_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BorrowBookRequest) Reset() {
+	*x = BorrowBookRequest{}
+	mi := &file_loans_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BorrowBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BorrowBookRequest) ProtoMessage() {}
+
+func (x *BorrowBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BorrowBookRequest.ProtoReflect.Descriptor instead.
+func (*BorrowBookRequest) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BorrowBookRequest) GetStuId() string {
+	if x != nil {
+		return x.StuId
+	}
+	return ""
+}
+
+func (x *BorrowBookRequest) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *BorrowBookRequest) GetBranchId() string {
+	if x != nil {
+		return x.BranchId
+	}
+	return ""
+}
+
+type ReturnBookRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	StuId  string                 `protobuf:"bytes,1,opt,name=stu_id,json=stuId,proto3" json:"stu_id,omitempty"`
+	BookId string                 `protobuf:"bytes,2,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	// 还书分馆，可为空；单册在非所属分馆归还时自动调拨回所属分馆
+	BranchId      string `protobuf:"bytes,3,opt,name=branch_id,json=branchId,proto3" json:"branch_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReturnBookRequest) Reset() {
+	*x = ReturnBookRequest{}
+	mi := &file_loans_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReturnBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReturnBookRequest) ProtoMessage() {}
+
+func (x *ReturnBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReturnBookRequest.ProtoReflect.Descriptor instead.
+func (*ReturnBookRequest) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ReturnBookRequest) GetStuId() string {
+	if x != nil {
+		return x.StuId
+	}
+	return ""
+}
+
+func (x *ReturnBookRequest) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *ReturnBookRequest) GetBranchId() string {
+	if x != nil {
+		return x.BranchId
+	}
+	return ""
+}
+
+type ReturnBookResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 逾期罚款，没有逾期时为0
+	FineAmount    float64 `protobuf:"fixed64,1,opt,name=fine_amount,json=fineAmount,proto3" json:"fine_amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReturnBookResponse) Reset() {
+	*x = ReturnBookResponse{}
+	mi := &file_loans_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReturnBookResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReturnBookResponse) ProtoMessage() {}
+
+func (x *ReturnBookResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReturnBookResponse.ProtoReflect.Descriptor instead.
+func (*ReturnBookResponse) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ReturnBookResponse) GetFineAmount() float64 {
+	if x != nil {
+		return x.FineAmount
+	}
+	return 0
+}
+
+type ListLoansRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StuId         string                 `protobuf:"bytes,1,opt,name=stu_id,json=stuId,proto3" json:"stu_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLoansRequest) Reset() {
+	*x = ListLoansRequest{}
+	mi := &file_loans_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLoansRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLoansRequest) ProtoMessage() {}
+
+func (x *ListLoansRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLoansRequest.ProtoReflect.Descriptor instead.
+func (*ListLoansRequest) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListLoansRequest) GetStuId() string {
+	if x != nil {
+		return x.StuId
+	}
+	return ""
+}
+
+type ListLoansResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Loans         []*Loan                `protobuf:"bytes,1,rep,name=loans,proto3" json:"loans,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLoansResponse) Reset() {
+	*x = ListLoansResponse{}
+	mi := &file_loans_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLoansResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLoansResponse) ProtoMessage() {}
+
+func (x *ListLoansResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLoansResponse.ProtoReflect.Descriptor instead.
+func (*ListLoansResponse) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListLoansResponse) GetLoans() []*Loan {
+	if x != nil {
+		return x.Loans
+	}
+	return nil
+}
+
+type GetLoanRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StuId         string                 `protobuf:"bytes,1,opt,name=stu_id,json=stuId,proto3" json:"stu_id,omitempty"`
+	BookId        string                 `protobuf:"bytes,2,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLoanRequest) Reset() {
+	*x = GetLoanRequest{}
+	mi := &file_loans_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLoanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLoanRequest) ProtoMessage() {}
+
+func (x *GetLoanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLoanRequest.ProtoReflect.Descriptor instead.
+func (*GetLoanRequest) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetLoanRequest) GetStuId() string {
+	if x != nil {
+		return x.StuId
+	}
+	return ""
+}
+
+func (x *GetLoanRequest) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+type PayFineRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StuId         string                 `protobuf:"bytes,1,opt,name=stu_id,json=stuId,proto3" json:"stu_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PayFineRequest) Reset() {
+	*x = PayFineRequest{}
+	mi := &file_loans_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PayFineRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PayFineRequest) ProtoMessage() {}
+
+func (x *PayFineRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PayFineRequest.ProtoReflect.Descriptor instead.
+func (*PayFineRequest) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PayFineRequest) GetStuId() string {
+	if x != nil {
+		return x.StuId
+	}
+	return ""
+}
+
+type PayFineResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PayFineResponse) Reset() {
+	*x = PayFineResponse{}
+	mi := &file_loans_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PayFineResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PayFineResponse) ProtoMessage() {}
+
+func (x *PayFineResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PayFineResponse.ProtoReflect.Descriptor instead.
+func (*PayFineResponse) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{8}
+}
+
+type WatchLoansRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StuId         string                 `protobuf:"bytes,1,opt,name=stu_id,json=stuId,proto3" json:"stu_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchLoansRequest) Reset() {
+	*x = WatchLoansRequest{}
+	mi := &file_loans_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchLoansRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchLoansRequest) ProtoMessage() {}
+
+func (x *WatchLoansRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchLoansRequest.ProtoReflect.Descriptor instead.
+func (*WatchLoansRequest) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WatchLoansRequest) GetStuId() string {
+	if x != nil {
+		return x.StuId
+	}
+	return ""
+}
+
+type LoanUpdate struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Kind    LoanUpdate_Kind        `protobuf:"varint,1,opt,name=kind,proto3,enum=library.v1.LoanUpdate_Kind" json:"kind,omitempty"`
+	StuId   string                 `protobuf:"bytes,2,opt,name=stu_id,json=stuId,proto3" json:"stu_id,omitempty"`
+	BookId  string                 `protobuf:"bytes,3,opt,name=book_id,json=bookId,proto3" json:"book_id,omitempty"`
+	Barcode string                 `protobuf:"bytes,4,opt,name=barcode,proto3" json:"barcode,omitempty"`
+	DueDate *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=due_date,json=dueDate,proto3" json:"due_date,omitempty"`
+	// 归还时才有
+	ReturnDate    *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=return_date,json=returnDate,proto3" json:"return_date,omitempty"`
+	IsOverdue     bool                   `protobuf:"varint,7,opt,name=is_overdue,json=isOverdue,proto3" json:"is_overdue,omitempty"`
+	FineAmount    float64                `protobuf:"fixed64,8,opt,name=fine_amount,json=fineAmount,proto3" json:"fine_amount,omitempty"`
+	At            *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=at,proto3" json:"at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoanUpdate) Reset() {
+	*x = LoanUpdate{}
+	mi := &file_loans_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoanUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoanUpdate) ProtoMessage() {}
+
+func (x *LoanUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_loans_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoanUpdate.ProtoReflect.Descriptor instead.
+func (*LoanUpdate) Descriptor() ([]byte, []int) {
+	return file_loans_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *LoanUpdate) GetKind() LoanUpdate_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return LoanUpdate_KIND_UNSPECIFIED
+}
+
+func (x *LoanUpdate) GetStuId() string {
+	if x != nil {
+		return x.StuId
+	}
+	return ""
+}
+
+func (x *LoanUpdate) GetBookId() string {
+	if x != nil {
+		return x.BookId
+	}
+	return ""
+}
+
+func (x *LoanUpdate) GetBarcode() string {
+	if x != nil {
+		return x.Barcode
+	}
+	return ""
+}
+
+func (x *LoanUpdate) GetDueDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DueDate
+	}
+	return nil
+}
+
+func (x *LoanUpdate) GetReturnDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReturnDate
+	}
+	return nil
+}
+
+func (x *LoanUpdate) GetIsOverdue() bool {
+	if x != nil {
+		return x.IsOverdue
+	}
+	return false
+}
+
+func (x *LoanUpdate) GetFineAmount() float64 {
+	if x != nil {
+		return x.FineAmount
+	}
+	return 0
+}
+
+func (x *LoanUpdate) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+var File_loans_proto protoreflect.FileDescriptor
+
+const file_loans_proto_rawDesc = "" +
+	"\n" +
+	"\vloans.proto\x12\n" +
+	"library.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x91\x03\n" +
+	"\x04Loan\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x15\n" +
+	"\x06stu_id\x18\x02 \x01(\tR\x05stuId\x12\x17\n" +
+	"\abook_id\x18\x03 \x01(\tR\x06bookId\x12\x18\n" +
+	"\abarcode\x18\x04 \x01(\tR\abarcode\x12;\n" +
+	"\vborrow_date\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"borrowDate\x125\n" +
+	"\bdue_date\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\adueDate\x12;\n" +
+	"\vreturn_date\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"returnDate\x12\x1d\n" +
+	"\n" +
+	"is_overdue\x18\b \x01(\bR\tisOverdue\x12\x1f\n" +
+	"\vfine_amount\x18\t \x01(\x01R\n" +
+	"fineAmount\x12\x1d\n" +
+	"\n" +
+	"book_title\x18\n" +
+	" \x01(\tR\tbookTitle\x12\x1f\n" +
+	"\vbook_author\x18\v \x01(\tR\n" +
+	"bookAuthor\"`\n" +
+	"\x11BorrowBookRequest\x12\x15\n" +
+	"\x06stu_id\x18\x01 \x01(\tR\x05stuId\x12\x17\n" +
+	"\abook_id\x18\x02 \x01(\tR\x06bookId\x12\x1b\n" +
+	"\tbranch_id\x18\x03 \x01(\tR\bbranchId\"`\n" +
+	"\x11ReturnBookRequest\x12\x15\n" +
+	"\x06stu_id\x18\x01 \x01(\tR\x05stuId\x12\x17\n" +
+	"\abook_id\x18\x02 \x01(\tR\x06bookId\x12\x1b\n" +
+	"\tbranch_id\x18\x03 \x01(\tR\bbranchId\"5\n" +
+	"\x12ReturnBookResponse\x12\x1f\n" +
+	"\vfine_amount\x18\x01 \x01(\x01R\n" +
+	"fineAmount\")\n" +
+	"\x10ListLoansRequest\x12\x15\n" +
+	"\x06stu_id\x18\x01 \x01(\tR\x05stuId\";\n" +
+	"\x11ListLoansResponse\x12&\n" +
+	"\x05loans\x18\x01 \x03(\v2\x10.library.v1.LoanR\x05loans\"@\n" +
+	"\x0eGetLoanRequest\x12\x15\n" +
+	"\x06stu_id\x18\x01 \x01(\tR\x05stuId\x12\x17\n" +
+	"\abook_id\x18\x02 \x01(\tR\x06bookId\"'\n" +
+	"\x0ePayFineRequest\x12\x15\n" +
+	"\x06stu_id\x18\x01 \x01(\tR\x05stuId\"\x11\n" +
+	"\x0fPayFineResponse\"*\n" +
+	"\x11WatchLoansRequest\x12\x15\n" +
+	"\x06stu_id\x18\x01 \x01(\tR\x05stuId\"\xab\x03\n" +
+	"\n" +
+	"LoanUpdate\x12/\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x1b.library.v1.LoanUpdate.KindR\x04kind\x12\x15\n" +
+	"\x06stu_id\x18\x02 \x01(\tR\x05stuId\x12\x17\n" +
+	"\abook_id\x18\x03 \x01(\tR\x06bookId\x12\x18\n" +
+	"\abarcode\x18\x04 \x01(\tR\abarcode\x125\n" +
+	"\bdue_date\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\adueDate\x12;\n" +
+	"\vreturn_date\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"returnDate\x12\x1d\n" +
+	"\n" +
+	"is_overdue\x18\a \x01(\bR\tisOverdue\x12\x1f\n" +
+	"\vfine_amount\x18\b \x01(\x01R\n" +
+	"fineAmount\x12*\n" +
+	"\x02at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x02at\"B\n" +
+	"\x04Kind\x12\x14\n" +
+	"\x10KIND_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rKIND_BORROWED\x10\x01\x12\x11\n" +
+	"\rKIND_RETURNED\x10\x022\xa7\x03\n" +
+	"\vLoanService\x12=\n" +
+	"\n" +
+	"BorrowBook\x12\x1d.library.v1.BorrowBookRequest\x1a\x10.library.v1.Loan\x12K\n" +
+	"\n" +
+	"ReturnBook\x12\x1d.library.v1.ReturnBookRequest\x1a\x1e.library.v1.ReturnBookResponse\x12H\n" +
+	"\tListLoans\x12\x1c.library.v1.ListLoansRequest\x1a\x1d.library.v1.ListLoansResponse\x127\n" +
+	"\aGetLoan\x12\x1a.library.v1.GetLoanRequest\x1a\x10.library.v1.Loan\x12B\n" +
+	"\aPayFine\x12\x1a.library.v1.PayFineRequest\x1a\x1b.library.v1.PayFineResponse\x12E\n" +
+	"\n" +
+	"WatchLoans\x12\x1d.library.v1.WatchLoansRequest\x1a\x16.library.v1.LoanUpdate0\x01B\x13Z\x11backend/librarypbb\x06proto3"
+
+var (
+	file_loans_proto_rawDescOnce sync.Once
+	file_loans_proto_rawDescData []byte
+)
+
+func file_loans_proto_rawDescGZIP() []byte {
+	file_loans_proto_rawDescOnce.Do(func() {
+		file_loans_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_loans_proto_rawDesc), len(file_loans_proto_rawDesc)))
+	})
+	return file_loans_proto_rawDescData
+}
+
+var file_loans_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_loans_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_loans_proto_goTypes = []any{
+	(LoanUpdate_Kind)(0),          // 0: library.v1.LoanUpdate.Kind
+	(*Loan)(nil),                  // 1: library.v1.Loan
+	(*BorrowBookRequest)(nil),     // 2: library.v1.BorrowBookRequest
+	(*ReturnBookRequest)(nil),     // 3: library.v1.ReturnBookRequest
+	(*ReturnBookResponse)(nil),    // 4: library.v1.ReturnBookResponse
+	(*ListLoansRequest)(nil),      // 5: library.v1.ListLoansRequest
+	(*ListLoansResponse)(nil),     // 6: library.v1.ListLoansResponse
+	(*GetLoanRequest)(nil),        // 7: library.v1.GetLoanRequest
+	(*PayFineRequest)(nil),        // 8: library.v1.PayFineRequest
+	(*PayFineResponse)(nil),       // 9: library.v1.PayFineResponse
+	(*WatchLoansRequest)(nil),     // 10: library.v1.WatchLoansRequest
+	(*LoanUpdate)(nil),            // 11: library.v1.LoanUpdate
+	(*timestamppb.Timestamp)(nil), // 12: google.protobuf.Timestamp
+}
+var file_loans_proto_depIdxs = []int32{
+	12, // 0: library.v1.Loan.borrow_date:type_name -> google.protobuf.Timestamp
+	12, // 1: library.v1.Loan.due_date:type_name -> google.protobuf.Timestamp
+	12, // 2: library.v1.Loan.return_date:type_name -> google.protobuf.Timestamp
+	1,  // 3: library.v1.ListLoansResponse.loans:type_name -> library.v1.Loan
+	0,  // 4: library.v1.LoanUpdate.kind:type_name -> library.v1.LoanUpdate.Kind
+	12, // 5: library.v1.LoanUpdate.due_date:type_name -> google.protobuf.Timestamp
+	12, // 6: library.v1.LoanUpdate.return_date:type_name -> google.protobuf.Timestamp
+	12, // 7: library.v1.LoanUpdate.at:type_name -> google.protobuf.Timestamp
+	2,  // 8: library.v1.LoanService.BorrowBook:input_type -> library.v1.BorrowBookRequest
+	3,  // 9: library.v1.LoanService.ReturnBook:input_type -> library.v1.ReturnBookRequest
+	5,  // 10: library.v1.LoanService.ListLoans:input_type -> library.v1.ListLoansRequest
+	7,  // 11: library.v1.LoanService.GetLoan:input_type -> library.v1.GetLoanRequest
+	8,  // 12: library.v1.LoanService.PayFine:input_type -> library.v1.PayFineRequest
+	10, // 13: library.v1.LoanService.WatchLoans:input_type -> library.v1.WatchLoansRequest
+	1,  // 14: library.v1.LoanService.BorrowBook:output_type -> library.v1.Loan
+	4,  // 15: library.v1.LoanService.ReturnBook:output_type -> library.v1.ReturnBookResponse
+	6,  // 16: library.v1.LoanService.ListLoans:output_type -> library.v1.ListLoansResponse
+	1,  // 17: library.v1.LoanService.GetLoan:output_type -> library.v1.Loan
+	9,  // 18: library.v1.LoanService.PayFine:output_type -> library.v1.PayFineResponse
+	11, // 19: library.v1.LoanService.WatchLoans:output_type -> library.v1.LoanUpdate
+	14, // [14:20] is the sub-list for method output_type
+	8,  // [8:14] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_loans_proto_init() }
+func file_loans_proto_init() {
+	if File_loans_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_loans_proto_rawDesc), len(file_loans_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_loans_proto_goTypes,
+		DependencyIndexes: file_loans_proto_depIdxs,
+		EnumInfos:         file_loans_proto_enumTypes,
+		MessageInfos:      file_loans_proto_msgTypes,
+	}.Build()
+	File_loans_proto = out.File
+	file_loans_proto_goTypes = nil
+	file_loans_proto_depIdxs = nil
+}
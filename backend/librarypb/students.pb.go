@@ -0,0 +1,353 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: students.proto
+
+package librarypb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Student struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StuId         string                 `protobuf:"bytes,1,opt,name=stu_id,json=stuId,proto3" json:"stu_id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Trust         float64                `protobuf:"fixed64,3,opt,name=trust,proto3" json:"trust,omitempty"`
+	CanBorrow     bool                   `protobuf:"varint,4,opt,name=can_borrow,json=canBorrow,proto3" json:"can_borrow,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Student) Reset() {
+	*x = Student{}
+	mi := &file_students_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Student) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Student) ProtoMessage() {}
+
+func (x *Student) ProtoReflect() protoreflect.Message {
+	mi := &file_students_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Student.ProtoReflect.Descriptor instead.
+func (*Student) Descriptor() ([]byte, []int) {
+	return file_students_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Student) GetStuId() string {
+	if x != nil {
+		return x.StuId
+	}
+	return ""
+}
+
+func (x *Student) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Student) GetTrust() float64 {
+	if x != nil {
+		return x.Trust
+	}
+	return 0
+}
+
+func (x *Student) GetCanBorrow() bool {
+	if x != nil {
+		return x.CanBorrow
+	}
+	return false
+}
+
+func (x *Student) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type LoginRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StuId         string                 `protobuf:"bytes,1,opt,name=stu_id,json=stuId,proto3" json:"stu_id,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoginRequest) Reset() {
+	*x = LoginRequest{}
+	mi := &file_students_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoginRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoginRequest) ProtoMessage() {}
+
+func (x *LoginRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_students_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoginRequest.ProtoReflect.Descriptor instead.
+func (*LoginRequest) Descriptor() ([]byte, []int) {
+	return file_students_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LoginRequest) GetStuId() string {
+	if x != nil {
+		return x.StuId
+	}
+	return ""
+}
+
+func (x *LoginRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type LoginResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Token   string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Student *Student               `protobuf:"bytes,2,opt,name=student,proto3" json:"student,omitempty"`
+	// 当前能否借书及原因
+	CanBorrow     bool   `protobuf:"varint,3,opt,name=can_borrow,json=canBorrow,proto3" json:"can_borrow,omitempty"`
+	BorrowInfo    string `protobuf:"bytes,4,opt,name=borrow_info,json=borrowInfo,proto3" json:"borrow_info,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoginResponse) Reset() {
+	*x = LoginResponse{}
+	mi := &file_students_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoginResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoginResponse) ProtoMessage() {}
+
+func (x *LoginResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_students_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoginResponse.ProtoReflect.Descriptor instead.
+func (*LoginResponse) Descriptor() ([]byte, []int) {
+	return file_students_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LoginResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *LoginResponse) GetStudent() *Student {
+	if x != nil {
+		return x.Student
+	}
+	return nil
+}
+
+func (x *LoginResponse) GetCanBorrow() bool {
+	if x != nil {
+		return x.CanBorrow
+	}
+	return false
+}
+
+func (x *LoginResponse) GetBorrowInfo() string {
+	if x != nil {
+		return x.BorrowInfo
+	}
+	return ""
+}
+
+type GetStudentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StuId         string                 `protobuf:"bytes,1,opt,name=stu_id,json=stuId,proto3" json:"stu_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStudentRequest) Reset() {
+	*x = GetStudentRequest{}
+	mi := &file_students_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStudentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStudentRequest) ProtoMessage() {}
+
+func (x *GetStudentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_students_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStudentRequest.ProtoReflect.Descriptor instead.
+func (*GetStudentRequest) Descriptor() ([]byte, []int) {
+	return file_students_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetStudentRequest) GetStuId() string {
+	if x != nil {
+		return x.StuId
+	}
+	return ""
+}
+
+var File_students_proto protoreflect.FileDescriptor
+
+const file_students_proto_rawDesc = "" +
+	"\n" +
+	"\x0estudents.proto\x12\n" +
+	"library.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa4\x01\n" +
+	"\aStudent\x12\x15\n" +
+	"\x06stu_id\x18\x01 \x01(\tR\x05stuId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05trust\x18\x03 \x01(\x01R\x05trust\x12\x1d\n" +
+	"\n" +
+	"can_borrow\x18\x04 \x01(\bR\tcanBorrow\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"A\n" +
+	"\fLoginRequest\x12\x15\n" +
+	"\x06stu_id\x18\x01 \x01(\tR\x05stuId\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\"\x94\x01\n" +
+	"\rLoginResponse\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12-\n" +
+	"\astudent\x18\x02 \x01(\v2\x13.library.v1.StudentR\astudent\x12\x1d\n" +
+	"\n" +
+	"can_borrow\x18\x03 \x01(\bR\tcanBorrow\x12\x1f\n" +
+	"\vborrow_info\x18\x04 \x01(\tR\n" +
+	"borrowInfo\"*\n" +
+	"\x11GetStudentRequest\x12\x15\n" +
+	"\x06stu_id\x18\x01 \x01(\tR\x05stuId2\x90\x01\n" +
+	"\x0eStudentService\x12<\n" +
+	"\x05Login\x12\x18.library.v1.LoginRequest\x1a\x19.library.v1.LoginResponse\x12@\n" +
+	"\n" +
+	"GetStudent\x12\x1d.library.v1.GetStudentRequest\x1a\x13.library.v1.StudentB\x13Z\x11backend/librarypbb\x06proto3"
+
+var (
+	file_students_proto_rawDescOnce sync.Once
+	file_students_proto_rawDescData []byte
+)
+
+func file_students_proto_rawDescGZIP() []byte {
+	file_students_proto_rawDescOnce.Do(func() {
+		file_students_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_students_proto_rawDesc), len(file_students_proto_rawDesc)))
+	})
+	return file_students_proto_rawDescData
+}
+
+var file_students_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_students_proto_goTypes = []any{
+	(*Student)(nil),               // 0: library.v1.Student
+	(*LoginRequest)(nil),          // 1: library.v1.LoginRequest
+	(*LoginResponse)(nil),         // 2: library.v1.LoginResponse
+	(*GetStudentRequest)(nil),     // 3: library.v1.GetStudentRequest
+	(*timestamppb.Timestamp)(nil), // 4: google.protobuf.Timestamp
+}
+var file_students_proto_depIdxs = []int32{
+	4, // 0: library.v1.Student.created_at:type_name -> google.protobuf.Timestamp
+	0, // 1: library.v1.LoginResponse.student:type_name -> library.v1.Student
+	1, // 2: library.v1.StudentService.Login:input_type -> library.v1.LoginRequest
+	3, // 3: library.v1.StudentService.GetStudent:input_type -> library.v1.GetStudentRequest
+	2, // 4: library.v1.StudentService.Login:output_type -> library.v1.LoginResponse
+	0, // 5: library.v1.StudentService.GetStudent:output_type -> library.v1.Student
+	4, // [4:6] is the sub-list for method output_type
+	2, // [2:4] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_students_proto_init() }
+func file_students_proto_init() {
+	if File_students_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_students_proto_rawDesc), len(file_students_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_students_proto_goTypes,
+		DependencyIndexes: file_students_proto_depIdxs,
+		MessageInfos:      file_students_proto_msgTypes,
+	}.Build()
+	File_students_proto = out.File
+	file_students_proto_goTypes = nil
+	file_students_proto_depIdxs = nil
+}
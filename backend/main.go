@@ -1,83 +1,277 @@
 package main
 
 import (
-	"backend/controller"
+	"backend/config"
 	"backend/dao"
+	"backend/events"
+	"backend/logging"
+	"backend/metrics"
+	"backend/notify"
+	"backend/rpc"
+	"backend/server"
 	"backend/service"
-	"fmt"
-	"log"
+	"backend/storage"
+	"backend/tracing"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"flag"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
-
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// 加载配置：默认值 < 配置文件 < 环境变量 < 命令行参数
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal("加载配置失败", err)
+	}
+
+	// 结构化日志，输出到标准输出
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fatal("日志初始化失败", err)
+	}
+	slog.SetDefault(logger)
+	dao.SetSlowQueryThreshold(cfg.Log.SlowQuery.Std())
+	slog.Info("当前配置", "config", cfg.Redacted())
+	service.SetLoanPolicy(service.LoanPolicy{
+		PeriodMonths: cfg.Loan.PeriodMonths,
+		FinePerDay:   cfg.Loan.FinePerDay,
+		DueSoonDays:  cfg.Loan.DueSoonDays,
+	})
+
+	// 链路追踪，tracing.exporter为none时只传递请求携带的trace context
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
+	if err != nil {
+		fatal("链路追踪初始化失败", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("导出剩余的链路数据失败", "error", err)
+		}
+	}()
+
 	// 初始化数据库连接
-	db, err := dao.NewSQLDB("root:12345678@tcp(localhost:13306)/bookTest?charset=utf8mb4&parseTime=True&loc=Local")
+	db, err := dao.Open(cfg.Database.Driver, cfg.Database.DSN())
 	if err != nil {
-		log.Fatalf("数据库连接失败: %v", err)
+		fatal("数据库连接失败", err)
 	}
 	defer db.Close()
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime.Std())
+	db.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime.Std())
+
+	// migrate子命令只执行迁移，不启动服务
+	if len(args) > 0 {
+		if args[0] != "migrate" {
+			slog.Error("未知的命令", "command", args[0])
+			os.Exit(1)
+		}
+		if err := runMigrate(db, cfg.Database.Driver, args[1:]); err != nil {
+			fatal("迁移失败", err)
+		}
+		return
+	}
+
+	// 检查表结构版本
+	if err := checkMigrations(db, cfg.Database.Driver, cfg.Database.AutoMigrate); err != nil {
+		fatal("迁移失败", err)
+	}
+
+	// 初始化封面存储
+	coverStorage, err := newCoverStorage(cfg.Storage)
+	if err != nil {
+		fatal("封面存储初始化失败", err)
+	}
+
+	// 初始化邮件发送，未配置smtp.host时不发送通知
+	mailSender, err := newMailSender(cfg.SMTP)
+	if err != nil {
+		fatal("邮件发送初始化失败", err)
+	}
+
+	// 初始化实时事件总线
+	bus, err := newEventBus(db, cfg.Events)
+	if err != nil {
+		fatal("实时事件总线初始化失败", err)
+	}
+	defer bus.Close()
+
+	// 组装业务层、控制器和路由
+	app, err := server.New(server.Deps{
+		Config:     cfg,
+		DB:         db,
+		Bus:        bus,
+		Covers:     coverStorage,
+		Mail:       mailSender,
+		AuthSecret: authSecret(cfg.Auth),
+	})
+	if err != nil {
+		fatal("初始化服务失败", err)
+	}
 
-	// 初始化服务
-	bookService := service.NewBookService(db)
-	borrowService := service.NewBorrowService(db)
-	studentService := service.NewStudentService(db)
-
-	// 初始化控制器
-	bookController := controller.NewBookController(bookService)
-	borrowController := controller.NewBorrowController(borrowService)
-	studentController := controller.NewStudentController(studentService)
-
-	// 创建Gin路由
-	r := gin.Default()
-
-	// 配置CORS中间件
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	// 图书相关路由
-	bookGroup := r.Group("/books")
-	{
-		bookGroup.GET("/search", bookController.SearchBooks)
-		bookGroup.GET("/:id", bookController.GetBookDetail)
-		bookGroup.GET("/list", bookController.GetAllBooks)
-	}
-
-	// 借阅相关路由
-	borrowGroup := r.Group("/borrow")
-	{
-		borrowGroup.POST("/borrow", borrowController.BorrowBook)
-		borrowGroup.POST("/return", borrowController.ReturnBook)
-		borrowGroup.POST("/pay-fine", borrowController.PayFine)
-		borrowGroup.GET("/record", borrowController.GetBorrowRecord)
-		borrowGroup.GET("/records", borrowController.GetStudentBorrowRecords)
-	}
-
-	// 学生相关路由
-	studentGroup := r.Group("/student")
-	{
-		studentGroup.POST("/login", studentController.Login)
-		studentGroup.GET("/info", studentController.GetStudentInfo)
-	}
-
-	// 健康检查
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "OK",
-			"message": "图书管理系统运行正常",
+	// Prometheus指标：HTTP请求、数据库连接池和流通概况
+	if cfg.Metrics.Enabled {
+		if err := metrics.RegisterDB(db, cfg.Database.Driver); err != nil {
+			fatal("注册数据库指标失败", err)
+		}
+		if err := metrics.RegisterCirculation(service.NewStatsService(db).CirculationStats); err != nil {
+			fatal("注册流通指标失败", err)
+		}
+	}
+
+	// 后台任务在退出时关闭stopWorkers后结束
+	stopWorkers := make(chan struct{})
+	var workers sync.WaitGroup
+
+	// 启动通知定时任务
+	if mailSender != nil {
+		app.Health.Watch("notification_scheduler", app.Notifications.Heartbeat())
+		workers.Go(func() { app.Notifications.RunScheduler(cfg.Scheduler.NotifyInterval.Std(), stopWorkers) })
+	} else {
+		slog.Info("未设置smtp.host，不发送邮件通知")
+	}
+
+	// 启动事件推送任务
+	app.Health.Watch("webhook_worker", app.Webhooks.Heartbeat())
+	workers.Go(func() { app.Webhooks.RunWorker(cfg.Scheduler.WebhookInterval.Std(), stopWorkers) })
+
+	srv := &http.Server{Addr: cfg.Server.Listen, Handler: app.Router}
+	srv.RegisterOnShutdown(app.Streams.Close)
+	serveErr := make(chan error, 2)
+	go func() {
+		slog.Info("服务器启动", "listen", cfg.Server.Listen, "tls", cfg.Server.TLS.Enabled())
+		if cfg.Server.TLS.Enabled() {
+			serveErr <- srv.ListenAndServeTLS(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	// gRPC接口，grpc.listen为空时不启动
+	if cfg.GRPC.Listen != "" {
+		lis, err := net.Listen("tcp", cfg.GRPC.Listen)
+		if err != nil {
+			fatal("gRPC服务启动失败", err)
+		}
+		go func() {
+			slog.Info("gRPC服务启动", "listen", cfg.GRPC.Listen, "tls", cfg.Server.TLS.Enabled())
+			serveErr <- app.GRPC.Serve(lis)
+		}()
+	}
+
+	// 收到SIGINT或SIGTERM后优雅退出，再次收到时立即退出
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	select {
+	case err := <-serveErr:
+		fatal("服务器启动失败", err)
+	case <-signals.Done():
+	}
+	stopSignals()
+	shutdown(srv, app.GRPC, app.Health, stopWorkers, &workers, cfg.Server)
+}
+
+// 优雅退出：就绪检查先返回不可用，等待shutdown_delay后停止接受新连接，等待处理中的请求（包括gRPC调用）完成后停止后台任务；
+// 超过shutdown_timeout时强制关闭连接。返回后由main中的defer关闭事件总线、链路追踪和数据库连接池
+func shutdown(srv *http.Server, grpcServer *rpc.Server, healthService *service.HealthService, stopWorkers chan struct{}, workers *sync.WaitGroup, cfg config.ServerConfig) {
+	slog.Info("开始退出", "delay", cfg.ShutdownDelay.Std(), "timeout", cfg.ShutdownTimeout.Std())
+	healthService.Drain()
+	time.Sleep(cfg.ShutdownDelay.Std())
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Std())
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("等待处理中的请求超时，强制关闭连接", "error", err)
+		srv.Close()
+	}
+	grpcServer.Shutdown(ctx)
+
+	close(stopWorkers)
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		slog.Info("后台任务已停止")
+	case <-ctx.Done():
+		slog.Warn("等待后台任务停止超时")
+	}
+}
+
+// 记录错误后退出
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// 令牌签名密钥，未配置auth.secret时每次启动随机生成（重启后已签发的令牌失效）
+func authSecret(cfg config.AuthConfig) []byte {
+	if cfg.Secret != "" {
+		return []byte(cfg.Secret)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		fatal("生成令牌密钥失败", err)
+	}
+	slog.Warn("未设置auth.secret，使用随机令牌密钥")
+	return secret
+}
+
+// 封面存储默认使用本地目录，storage.type=s3时使用S3兼容存储（如MinIO）
+func newCoverStorage(cfg config.StorageConfig) (storage.Storage, error) {
+	if cfg.Type == "s3" {
+		return storage.NewS3Storage(storage.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
 		})
+	}
+	return storage.NewLocalStorage(cfg.Dir)
+}
+
+// 邮件通知通过SMTP发送，可指向本地测试SMTP服务器（如MailHog: smtp.host=localhost smtp.port=1025）
+func newMailSender(cfg config.SMTPConfig) (notify.Sender, error) {
+	if cfg.Host == "" {
+		return nil, nil
+	}
+	return notify.NewSMTPSender(notify.SMTPConfig{
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		From:     cfg.From,
 	})
+}
 
-	fmt.Println("服务器启动在 :8085 端口")
-	if err := r.Run(":8085"); err != nil {
-		panic(err)
+// 实时事件默认只在本实例内转发；多实例部署时设置events.broker=db，经数据库在实例间转发
+func newEventBus(db *sql.DB, cfg config.EventsConfig) (*events.Bus, error) {
+	if cfg.Broker == "db" {
+		return events.NewBus(events.NewDBBroker(db, cfg.PollInterval.Std()))
 	}
+	return events.NewBus(events.NewMemoryBroker())
 }
@@ -0,0 +1,146 @@
+// Package metrics Prometheus监控指标
+// 指标注册在本包的registry中，由Handler输出；HTTP请求由控制器的中间件调用ObserveRequest记录，
+// 借还数量在事务提交后由业务层调用LoanBorrowed、LoanReturned累加，逾期、罚款等当前状态在每次抓取时查询数据库
+package metrics
+
+import (
+	"backend/do"
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// 指标名前缀
+const namespace = "library"
+
+// 抓取时查询流通概况的时限
+const statsTimeout = 5 * time.Second
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP请求处理时间，按方法、路由和状态码统计",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	borrowsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "borrows_total",
+		Help:      "借出册数",
+	})
+
+	returnsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "returns_total",
+		Help:      "归还册数，overdue表示是否逾期",
+	}, []string{"overdue"})
+
+	finesChargedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fines_charged_yuan_total",
+		Help:      "产生的逾期罚款金额（元）",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		borrowsTotal,
+		returnsTotal,
+		finesChargedTotal,
+	)
+}
+
+// 输出全部指标
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// 记录一个HTTP请求，route为注册的路由模板（如 /books/:id），未匹配到路由时为空
+func ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(elapsed.Seconds())
+}
+
+// 借出一册
+func LoanBorrowed() {
+	borrowsTotal.Inc()
+}
+
+// 归还一册，逾期时累计罚款
+func LoanReturned(isOverdue bool, fineAmount float64) {
+	returnsTotal.WithLabelValues(strconv.FormatBool(isOverdue)).Inc()
+	if fineAmount > 0 {
+		finesChargedTotal.Add(fineAmount)
+	}
+}
+
+// 注册数据库连接池指标（go_sql_*，来自sql.DB.Stats()），name为数据库名，作为db_name标签
+func RegisterDB(db *sql.DB, name string) error {
+	return registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// StatsFunc 查询当前的流通概况
+type StatsFunc func(ctx context.Context) (*do.CirculationStats, error)
+
+// 注册流通概况指标，每次抓取时调用stats查询
+func RegisterCirculation(stats StatsFunc) error {
+	return registry.Register(&circulationCollector{stats: stats})
+}
+
+var (
+	overdueLoansDesc = prometheus.NewDesc(namespace+"_overdue_loans",
+		"已过应还日期仍未归还的借阅数", nil, nil)
+	outstandingFinesDesc = prometheus.NewDesc(namespace+"_outstanding_fines_yuan",
+		"未支付的罚款合计（元）", nil, nil)
+	finedStudentsDesc = prometheus.NewDesc(namespace+"_fined_students",
+		"有未支付罚款的学生数", nil, nil)
+	booksUnavailableDesc = prometheus.NewDesc(namespace+"_books_unavailable",
+		"有馆藏但没有可借副本的图书数", nil, nil)
+	statsUpDesc = prometheus.NewDesc(namespace+"_circulation_stats_up",
+		"本次抓取是否成功查询流通概况（1成功，0失败）", nil, nil)
+)
+
+// circulationCollector 抓取时查询数据库得到流通概况；查询失败时只输出 library_circulation_stats_up 0
+type circulationCollector struct {
+	stats StatsFunc
+}
+
+func (c *circulationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- overdueLoansDesc
+	ch <- outstandingFinesDesc
+	ch <- finedStudentsDesc
+	ch <- booksUnavailableDesc
+	ch <- statsUpDesc
+}
+
+func (c *circulationCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	stats, err := c.stats(ctx)
+	if err != nil {
+		slog.Warn("查询流通概况失败", "error", err)
+		ch <- prometheus.MustNewConstMetric(statsUpDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(statsUpDesc, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(overdueLoansDesc, prometheus.GaugeValue, float64(stats.OverdueLoans))
+	ch <- prometheus.MustNewConstMetric(outstandingFinesDesc, prometheus.GaugeValue, stats.OutstandingFines)
+	ch <- prometheus.MustNewConstMetric(finedStudentsDesc, prometheus.GaugeValue, float64(stats.FinedStudents))
+	ch <- prometheus.MustNewConstMetric(booksUnavailableDesc, prometheus.GaugeValue, float64(stats.BooksUnavailable))
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"backend/do"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("抓取 = %d", w.Code)
+	}
+	body, err := io.ReadAll(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestScrape(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := RegisterDB(db, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := &do.CirculationStats{OverdueLoans: 3, OutstandingFines: 4.5, FinedStudents: 2, BooksUnavailable: 1}
+	var statsErr error
+	if err := RegisterCirculation(func(ctx context.Context) (*do.CirculationStats, error) {
+		return stats, statsErr
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ObserveRequest(http.MethodGet, "/books/:id", http.StatusOK, 20*time.Millisecond)
+	ObserveRequest(http.MethodGet, "", http.StatusNotFound, time.Millisecond)
+	LoanBorrowed()
+	LoanReturned(true, 1.5)
+	LoanReturned(false, 0)
+
+	body := scrape(t)
+	for _, want := range []string{
+		`library_http_request_duration_seconds_bucket{method="GET",route="/books/:id",status="200",le="0.025"} 1`,
+		`library_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+		`library_borrows_total 1`,
+		`library_returns_total{overdue="true"} 1`,
+		`library_returns_total{overdue="false"} 1`,
+		`library_fines_charged_yuan_total 1.5`,
+		`library_circulation_stats_up 1`,
+		`library_overdue_loans 3`,
+		`library_outstanding_fines_yuan 4.5`,
+		`library_fined_students 2`,
+		`library_books_unavailable 1`,
+		`go_sql_max_open_connections{db_name="sqlite"} 0`,
+		`go_sql_open_connections{db_name="sqlite"}`,
+		`go_sql_in_use_connections{db_name="sqlite"}`,
+		`go_sql_wait_count_total{db_name="sqlite"}`,
+		`go_goroutines `,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("指标中缺少 %s", want)
+		}
+	}
+
+	// 查询流通概况失败时不影响其他指标
+	statsErr = errors.New("数据库不可用")
+	body = scrape(t)
+	if !strings.Contains(body, "library_circulation_stats_up 0") || strings.Contains(body, "library_overdue_loans") {
+		t.Errorf("查询失败时应只输出 library_circulation_stats_up 0")
+	}
+	if !strings.Contains(body, "library_borrows_total 1") {
+		t.Errorf("查询失败时仍应输出其他指标")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"backend/migrations"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = `用法: library [配置参数] migrate <命令>
+  up        执行所有尚未执行的迁移
+  down [N]  回滚最近N个迁移，默认1个
+  status    列出迁移及执行状态`
+
+// migrate子命令，执行完毕后退出
+func runMigrate(db *sql.DB, driver string, args []string) error {
+	migrator, err := migrations.NewMigrator(db, driver)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("缺少迁移命令\n%s", migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("已执行 %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("没有需要执行的迁移")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("回滚数量必须是正整数: %s", args[1])
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Printf("已回滚 %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Println("没有可以回滚的迁移")
+		}
+		return err
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "版本\t名称\t执行时间")
+		for _, status := range statuses {
+			appliedAt := "未执行"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Unknown {
+				appliedAt += "（程序中不存在）"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("未知的迁移命令: %s\n%s", args[0], migrateUsage)
+	}
+}
+
+// 启动时检查表结构版本，开启database.auto_migrate时自动执行尚未执行的迁移
+func checkMigrations(db *sql.DB, driver string, autoMigrate bool) error {
+	migrator, err := migrations.NewMigrator(db, driver)
+	if err != nil {
+		return err
+	}
+
+	if autoMigrate {
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			slog.Info("已执行迁移", "version", migration.Version, "name", migration.Name)
+		}
+		return err
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		slog.Warn("有迁移尚未执行，请运行 migrate up 或开启database.auto_migrate", "pending", pending)
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+// Package migrations 管理数据库表结构的版本
+// 每种数据库的迁移脚本放在以数据库类型命名的目录中（mysql/、postgres/、sqlite/），以 编号_名称.up.sql / 编号_名称.down.sql 命名并编译进程序，
+// 已执行的版本记录在schema_migrations表中；MySQL和PostgreSQL执行迁移前获取数据库咨询锁，多个实例同时启动时只有一个执行
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// 咨询锁名称及等待时间
+const (
+	lockName    = "library_schema_migrations"
+	lockTimeout = 60 * time.Second
+)
+
+// Migration 一个版本的迁移脚本
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status 一个版本的执行状态，AppliedAt为空表示尚未执行
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+	// 数据库中已执行但程序中不存在的版本（通常是数据库由更新版本的程序迁移过）
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// Migrator 执行嵌入的迁移脚本
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration
+}
+
+// driver为数据库类型（mysql、postgres、sqlite），与dao.Open的参数一致
+func NewMigrator(db *sql.DB, driver string) (*Migrator, error) {
+	migrations, err := load(driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, driver: driver, migrations: migrations}, nil
+}
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// 读取某种数据库的迁移脚本，按版本号排序，每个版本必须同时有up和down脚本
+func load(driver string) ([]Migration, error) {
+	names, err := fs.Glob(files, driver+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("没有%s的迁移脚本", driver)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, fileName := range names {
+		match := fileNamePattern.FindStringSubmatch(path.Base(fileName))
+		if match == nil {
+			return nil, fmt.Errorf("迁移脚本命名错误: %s", fileName)
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := files.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("迁移版本%d存在多个名称: %s, %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("迁移版本%d缺少up或down脚本", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up 执行所有尚未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up() ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(func(conn *sql.Conn) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := execScript(conn, migration.Up); err != nil {
+				return fmt.Errorf("执行迁移%04d_%s失败: %v", migration.Version, migration.Name, err)
+			}
+			if _, err := conn.ExecContext(context.Background(),
+				m.bind("INSERT INTO schema_migrations (version, name) VALUES (?, ?)"), migration.Version, migration.Name); err != nil {
+				return fmt.Errorf("记录迁移%04d_%s失败: %v", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 按版本从新到旧回滚steps个已执行的迁移，返回本次回滚的迁移
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(func(conn *sql.Conn) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := execScript(conn, migration.Down); err != nil {
+				return fmt.Errorf("回滚迁移%04d_%s失败: %v", migration.Version, migration.Name, err)
+			}
+			if _, err := conn.ExecContext(context.Background(),
+				m.bind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version); err != nil {
+				return fmt.Errorf("删除迁移记录%04d_%s失败: %v", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status 列出程序中的全部迁移及数据库中已执行但程序中不存在的版本
+func (m *Migrator) Status() ([]Status, error) {
+	var statuses []Status
+	err := m.withConn(func(conn *sql.Conn) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if record, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &record.AppliedAt
+				delete(applied, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for version, record := range applied {
+			appliedAt := record.AppliedAt
+			statuses = append(statuses, Status{Version: version, Name: record.Name, AppliedAt: &appliedAt, Unknown: true})
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, err
+}
+
+// Pending 尚未执行的迁移数量
+func (m *Migrator) Pending() (int, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// 在同一个连接上建表并执行fn，咨询锁与连接绑定，因此全部操作都要使用这个连接
+func (m *Migrator) withConn(fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("创建schema_migrations表失败: %v", err)
+	}
+	return fn(conn)
+}
+
+// 持有咨询锁时执行fn：MySQL使用GET_LOCK，PostgreSQL使用pg_advisory_lock；SQLite只用于单机部署，不加锁
+func (m *Migrator) withLock(fn func(conn *sql.Conn) error) error {
+	return m.withConn(func(conn *sql.Conn) error {
+		switch m.driver {
+		case "mysql":
+			return withMySQLLock(conn, fn)
+		case "postgres":
+			return withPostgresLock(conn, fn)
+		}
+		return fn(conn)
+	})
+}
+
+func withMySQLLock(conn *sql.Conn, fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	var acquired sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, int(lockTimeout.Seconds())).Scan(&acquired)
+	if err != nil {
+		return fmt.Errorf("获取迁移锁失败: %v", err)
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return fmt.Errorf("获取迁移锁超时，可能有其他实例正在执行迁移")
+	}
+	defer conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", lockName)
+
+	return fn(conn)
+}
+
+// pg_advisory_lock没有超时参数，改为每秒尝试一次pg_try_advisory_lock
+func withPostgresLock(conn *sql.Conn, fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		var acquired bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", lockName).Scan(&acquired)
+		if err != nil {
+			return fmt.Errorf("获取迁移锁失败: %v", err)
+		}
+		if acquired {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("获取迁移锁超时，可能有其他实例正在执行迁移")
+		}
+		time.Sleep(time.Second)
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock(hashtext($1))", lockName)
+
+	return fn(conn)
+}
+
+// 把 ? 占位符改写为PostgreSQL的 $1、$2……，只用于本包中不含字符串常量的语句
+func (m *Migrator) bind(query string) string {
+	if m.driver != "postgres" {
+		return query
+	}
+	for n := 1; strings.Contains(query, "?"); n++ {
+		query = strings.Replace(query, "?", "$"+strconv.Itoa(n), 1)
+	}
+	return query
+}
+
+type appliedRecord struct {
+	Name      string
+	AppliedAt time.Time
+}
+
+func appliedVersions(conn *sql.Conn) (map[int]appliedRecord, error) {
+	rows, err := conn.QueryContext(context.Background(), "SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("查询迁移记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedRecord)
+	for rows.Next() {
+		var version int
+		var record appliedRecord
+		if err := rows.Scan(&version, &record.Name, &record.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = record
+	}
+	return applied, rows.Err()
+}
+
+// 逐条执行脚本中的语句（驱动默认不允许一次执行多条语句）
+// MySQL的DDL会隐式提交，无法整体回滚，因此脚本应尽量写成可重复执行的形式（如 if not exists）
+func execScript(conn *sql.Conn, script string) error {
+	for _, statement := range splitStatements(script) {
+		if _, err := conn.ExecContext(context.Background(), statement); err != nil {
+			return fmt.Errorf("%v\n%s", err, statement)
+		}
+	}
+	return nil
+}
+
+// 带 BEGIN ... END 语句块的触发器（SQLite），块内的分号不结束语句
+var (
+	triggerPattern  = regexp.MustCompile(`(?is)^\s*create\s+trigger\b.*\bbegin\b`)
+	blockEndPattern = regexp.MustCompile(`(?i)\bend\s*$`)
+)
+
+// 按分号拆分SQL语句，跳过引号内的分号、注释和触发器语句块中的分号
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// 引号内的内容原样保留，反斜杠转义和连续两个引号都不结束字符串
+			current.WriteByte(c)
+			for i++; i < len(script); i++ {
+				current.WriteByte(script[i])
+				if script[i] == '\\' && c != '`' && i+1 < len(script) {
+					i++
+					current.WriteByte(script[i])
+				} else if script[i] == c {
+					if i+1 < len(script) && script[i+1] == c {
+						i++
+						current.WriteByte(script[i])
+					} else {
+						break
+					}
+				}
+			}
+		case c == '-' && strings.HasPrefix(script[i:], "--"), c == '#':
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			current.WriteByte('\n')
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 3
+			}
+			current.WriteByte(' ')
+		case c == ';':
+			if statement := current.String(); triggerPattern.MatchString(statement) && !blockEndPattern.MatchString(statement) {
+				current.WriteByte(c)
+				continue
+			}
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
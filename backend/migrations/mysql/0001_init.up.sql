@@ -0,0 +1,211 @@
+-- 初始表结构
+create table if not exists students (
+    stu_id varchar(255) primary key, -- 学号
+    name varchar(50) unique not null, -- 姓名
+    password varchar(255) not null, -- 密码
+    trust float default 1, -- 信任度
+    can_borrow boolean default true, -- 是否可以借阅
+    pin_hash varchar(255) default null, -- 自助借还机PIN哈希（bcrypt）
+    pin_failed_attempts int not null default 0, -- 连续输错PIN的次数
+    pin_locked_until datetime null, -- PIN锁定截止时间
+    created_at timestamp default current_timestamp
+);
+
+create table if not exists books (
+    book_id varchar(255) primary key, -- 图书编号
+    title varchar(255) not null, -- 书名
+    author varchar(100) not null, -- 作者
+    description text, -- 简介
+    total_copies int default 0, -- 总馆藏数量
+    available_copies int default 0, -- 可借阅数量
+    can_borrow boolean default true, -- 是否可以借阅
+    cover_key varchar(255) default null, -- 封面存储键（版本目录）
+    created_at timestamp default current_timestamp
+);
+
+create table if not exists borrow_records (
+    id int auto_increment primary key,
+    stu_id varchar(255) not null, -- 学号
+    book_id varchar(255) not null, -- 图书编号
+    barcode varchar(255) default null, -- 借出的单册条码（未登记单册的书籍为空）
+    borrow_date timestamp default current_timestamp, -- 借书时间
+    due_date timestamp, -- 预计还书时间
+    return_date timestamp, -- 实际还书时间
+    is_overdue boolean default false, -- 是否逾期
+    fine_amount decimal(10,2) default 0, -- 罚款金额
+    created_at timestamp default current_timestamp,
+    foreign key (stu_id) references students(stu_id),
+    foreign key (book_id) references books(book_id)
+);
+
+create table if not exists librarians (
+    librarian_id varchar(255) primary key, -- 工号
+    name varchar(50) not null, -- 姓名
+    password varchar(255) not null, -- 密码
+    role varchar(20) not null default 'librarian', -- 角色：librarian/admin
+    created_at timestamp default current_timestamp
+);
+
+create table if not exists branches (
+    branch_id varchar(255) primary key, -- 分馆编号
+    name varchar(100) not null, -- 分馆名称
+    address varchar(255) default '', -- 地址
+    created_at timestamp default current_timestamp
+);
+
+create table if not exists book_items (
+    barcode varchar(255) primary key, -- 单册条码
+    book_id varchar(255) not null, -- 图书编号
+    home_branch_id varchar(255) not null, -- 所属分馆
+    current_branch_id varchar(255) not null, -- 当前所在分馆
+    status varchar(20) not null default 'available', -- available/on_loan/in_transit/on_hold_shelf
+    created_at timestamp default current_timestamp,
+    foreign key (book_id) references books(book_id),
+    foreign key (home_branch_id) references branches(branch_id),
+    foreign key (current_branch_id) references branches(branch_id),
+    index idx_book_items_book (book_id, status)
+);
+
+create table if not exists holds (
+    id int auto_increment primary key,
+    stu_id varchar(255) not null, -- 学号
+    book_id varchar(255) not null, -- 图书编号
+    pickup_branch_id varchar(255) not null, -- 取书分馆
+    barcode varchar(255) default null, -- 分配给该预约的单册
+    status varchar(20) not null default 'waiting', -- waiting/in_transit/ready/fulfilled/cancelled
+    ready_at timestamp null default null, -- 上预约架时间
+    created_at timestamp default current_timestamp,
+    foreign key (stu_id) references students(stu_id),
+    foreign key (book_id) references books(book_id),
+    foreign key (pickup_branch_id) references branches(branch_id),
+    index idx_holds_book (book_id, status, created_at)
+);
+
+create table if not exists transfers (
+    id int auto_increment primary key,
+    barcode varchar(255) not null, -- 单册条码
+    from_branch_id varchar(255) not null, -- 调出分馆
+    to_branch_id varchar(255) not null, -- 调入分馆
+    reason varchar(20) not null, -- manual/return_home/hold
+    hold_id int default null, -- 为预约发起的调拨
+    status varchar(20) not null default 'requested', -- requested/in_transit/received/cancelled
+    shipped_at timestamp null default null,
+    received_at timestamp null default null,
+    created_at timestamp default current_timestamp,
+    foreign key (barcode) references book_items(barcode),
+    foreign key (from_branch_id) references branches(branch_id),
+    foreign key (to_branch_id) references branches(branch_id),
+    foreign key (hold_id) references holds(id)
+);
+
+create table if not exists circulation_overrides (
+    id int auto_increment primary key,
+    librarian_id varchar(255) not null, -- 操作馆员
+    stu_id varchar(255) not null, -- 学号
+    barcode varchar(255) default null, -- 针对单册的受阻原因对应的条码
+    block_code varchar(50) not null, -- 被越过的受阻原因代码
+    reason varchar(500) not null, -- 强制借出原因
+    created_at timestamp default current_timestamp,
+    foreign key (librarian_id) references librarians(librarian_id),
+    foreign key (stu_id) references students(stu_id)
+);
+
+create table if not exists kiosk_devices (
+    device_id varchar(255) primary key, -- 设备编号
+    name varchar(100) not null, -- 设备名称
+    branch_id varchar(255) not null, -- 设备所在分馆
+    api_key_hash varchar(64) not null unique, -- 设备API密钥哈希
+    enabled boolean default true, -- 是否启用
+    rate_limit_per_min int default 30, -- 每分钟请求上限
+    last_seen_at datetime default null, -- 最近使用时间
+    created_at timestamp default current_timestamp,
+    foreign key (branch_id) references branches(branch_id)
+);
+
+create table if not exists library_hours (
+    weekday tinyint primary key, -- 星期，0为周日
+    open_time varchar(5) not null default '08:00', -- 开馆时间
+    close_time varchar(5) not null default '22:00', -- 闭馆时间
+    closed boolean default false -- 当天固定闭馆
+);
+
+create table if not exists library_closures (
+    id int auto_increment primary key,
+    name varchar(100) not null, -- 名称，如国庆节、寒假
+    kind varchar(20) not null default 'holiday', -- holiday / break / other
+    start_date date not null, -- 开始日期（含）
+    end_date date not null, -- 结束日期（含）
+    created_at timestamp default current_timestamp,
+    index idx_library_closures_dates (start_date, end_date)
+);
+
+create table if not exists notification_preferences (
+    stu_id varchar(255) primary key, -- 学号
+    email varchar(255) not null default '', -- 接收通知的邮箱
+    language varchar(10) not null default 'zh-CN', -- 通知语言 zh-CN / en-US
+    due_soon boolean default false, -- 订阅到期提醒
+    overdue boolean default false, -- 订阅逾期提醒
+    hold_ready boolean default false, -- 订阅预约到馆提醒
+    updated_at timestamp default current_timestamp on update current_timestamp,
+    foreign key (stu_id) references students(stu_id)
+);
+
+create table if not exists notifications (
+    id int auto_increment primary key,
+    stu_id varchar(255) not null, -- 学号
+    kind varchar(20) not null, -- due_soon / overdue / hold_ready
+    ref_id int not null, -- 借阅记录ID或预约ID
+    email varchar(255) not null, -- 收件邮箱
+    subject varchar(255) not null, -- 邮件主题
+    body text not null, -- 邮件正文
+    status varchar(20) not null default 'pending', -- pending / sent / failed
+    attempts int not null default 0, -- 已发送次数
+    last_error varchar(1000) default null, -- 最近一次发送失败原因
+    next_attempt_at datetime not null, -- 下次发送时间
+    sent_at datetime default null, -- 发送成功时间
+    created_at timestamp default current_timestamp,
+    unique key uk_notifications_kind_ref (kind, ref_id),
+    index idx_notifications_status (status, next_attempt_at),
+    foreign key (stu_id) references students(stu_id)
+);
+
+create table if not exists webhook_endpoints (
+    id int auto_increment primary key,
+    url varchar(1000) not null, -- 推送地址
+    secret varchar(64) not null, -- HMAC签名密钥
+    events varchar(500) not null, -- 订阅的事件，逗号分隔
+    enabled boolean default true, -- 是否启用
+    created_at timestamp default current_timestamp
+);
+
+create table if not exists webhook_outbox (
+    id int auto_increment primary key,
+    event_type varchar(50) not null, -- 事件类型，如 book.borrowed
+    payload text not null, -- 事件数据（JSON）
+    dispatched_at datetime default null, -- 分发到各推送地址的时间
+    created_at timestamp default current_timestamp,
+    index idx_webhook_outbox_dispatched (dispatched_at)
+);
+
+create table if not exists webhook_deliveries (
+    id int auto_increment primary key,
+    event_id int not null, -- 发件箱事件
+    endpoint_id int not null, -- 推送地址
+    status varchar(20) not null default 'pending', -- pending / delivered / dead
+    attempts int not null default 0, -- 已推送次数
+    last_status_code int default null, -- 最近一次响应状态码
+    last_error varchar(1000) default null, -- 最近一次失败原因
+    next_attempt_at datetime not null, -- 下次推送时间
+    delivered_at datetime default null, -- 推送成功时间
+    created_at timestamp default current_timestamp,
+    index idx_webhook_deliveries_status (status, next_attempt_at),
+    foreign key (event_id) references webhook_outbox(id),
+    foreign key (endpoint_id) references webhook_endpoints(id)
+);
+
+create table if not exists stream_events (
+    id int auto_increment primary key,
+    payload text not null, -- 实时事件（JSON），仅events.broker=db时使用，保留一小时
+    created_at timestamp default current_timestamp,
+    index idx_stream_events_created (created_at)
+);
@@ -0,0 +1,2 @@
+-- 删除审计日志，触发器随表一起删除
+drop table if exists audit_log;
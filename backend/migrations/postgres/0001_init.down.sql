@@ -0,0 +1,19 @@
+-- 回滚初始表结构，按外键依赖的逆序删除
+drop table if exists stream_events;
+drop table if exists webhook_deliveries;
+drop table if exists webhook_outbox;
+drop table if exists webhook_endpoints;
+drop table if exists notifications;
+drop table if exists notification_preferences;
+drop table if exists library_closures;
+drop table if exists library_hours;
+drop table if exists kiosk_devices;
+drop table if exists circulation_overrides;
+drop table if exists transfers;
+drop table if exists holds;
+drop table if exists book_items;
+drop table if exists branches;
+drop table if exists librarians;
+drop table if exists borrow_records;
+drop table if exists books;
+drop table if exists students;
@@ -0,0 +1,3 @@
+-- 删除审计日志，触发器随表一起删除
+drop table if exists audit_log;
+drop function if exists audit_log_append_only();
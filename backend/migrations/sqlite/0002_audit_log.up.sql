@@ -0,0 +1,27 @@
+-- 审计日志：记录谁在何时做了什么及操作前后的值，只允许追加，触发器拒绝修改和删除
+create table if not exists audit_log (
+    id integer primary key autoincrement,
+    occurred_at timestamp not null, -- 操作时间
+    actor_role varchar(20) not null, -- 操作人角色：student / librarian / admin / kiosk / anonymous / system
+    actor_id varchar(255) not null default '', -- 学号、工号或借还机编号
+    client_ip varchar(64) not null default '', -- 客户端地址
+    request_id varchar(64) not null default '', -- 请求ID，与日志中的request_id一致
+    action varchar(50) not null, -- 操作，如 loan.borrow
+    entity_type varchar(20) not null, -- 对象类型，如 loan
+    entity_id varchar(255) not null, -- 对象ID
+    before_value text, -- 操作前的值（JSON）
+    after_value text -- 操作后的值（JSON）
+);
+create index if not exists idx_audit_log_entity on audit_log (entity_type, entity_id);
+create index if not exists idx_audit_log_actor on audit_log (actor_id);
+create index if not exists idx_audit_log_action on audit_log (action, occurred_at);
+
+create trigger if not exists audit_log_no_update before update on audit_log
+begin
+    select raise(abort, 'audit_log is append-only');
+end;
+
+create trigger if not exists audit_log_no_delete before delete on audit_log
+begin
+    select raise(abort, 'audit_log is append-only');
+end;
@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"mime"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 在本地启动一个SMTP服务器，每个连接交给handle处理，返回服务器端口
+func startSMTPServer(t *testing.T, handle func(conn net.Conn)) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func newTestSender(t *testing.T, port int) *SMTPSender {
+	t.Helper()
+	sender, err := NewSMTPSender(SMTPConfig{Host: "127.0.0.1", Port: port, From: "library@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sender
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	received := make(chan string, 1)
+	port := startSMTPServer(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received <- data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	})
+
+	msg := &Message{To: "zhangsan@example.com", Subject: "图书到期提醒", Body: "《数据结构》将于3天后到期"}
+	if err := newTestSender(t, port).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	data := <-received
+	header, body, _ := strings.Cut(data, "\r\n\r\n")
+	for _, want := range []string{
+		"From: library@example.com",
+		"To: zhangsan@example.com",
+		"Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject),
+		"Content-Type: text/plain; charset=UTF-8",
+	} {
+		if !strings.Contains(header, want+"\r\n") {
+			t.Errorf("邮件头中缺少 %q:\n%s", want, header)
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\r\n", ""))
+	if err != nil || string(decoded) != msg.Body {
+		t.Errorf("邮件正文 = %q, %v，期望 %q", decoded, err, msg.Body)
+	}
+}
+
+// 服务器接受连接后不响应时，按ctx的截止时间或取消返回，不会一直阻塞
+func TestSMTPSenderHonorsContext(t *testing.T) {
+	port := startSMTPServer(t, func(conn net.Conn) {
+		conn.Read(make([]byte, 1))
+	})
+	sender := newTestSender(t, port)
+	msg := &Message{To: "zhangsan@example.com", Subject: "测试", Body: "测试"}
+
+	for _, tc := range []struct {
+		name   string
+		newCtx func() (context.Context, context.CancelFunc)
+	}{
+		{"超时", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 200*time.Millisecond)
+		}},
+		{"取消", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(200*time.Millisecond, cancel)
+			return ctx, cancel
+		}},
+	} {
+		ctx, cancel := tc.newCtx()
+		start := time.Now()
+		if err := sender.Send(ctx, msg); err == nil {
+			t.Errorf("%s: 服务器无响应时发送成功", tc.name)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%s: 发送耗时 %v，期望在ctx结束后立即返回", tc.name, elapsed)
+		}
+		cancel()
+	}
+}
+
+func TestSMTPSenderRejectsHeaderInjection(t *testing.T) {
+	sender := newTestSender(t, 25)
+	err := sender.Send(context.Background(), &Message{To: "a@example.com\r\nBcc: b@example.com", Subject: "测试", Body: "测试"})
+	if err == nil || !strings.Contains(err.Error(), "收件人地址非法") {
+		t.Errorf("收件人中含换行 = %v，期望拒绝", err)
+	}
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"backend/do"
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// TemplateData 通知模板可用的字段
+type TemplateData struct {
+	StudentName  string
+	BookTitle    string
+	Barcode      string
+	DueDate      string
+	DaysLeft     int
+	DaysOverdue  int
+	FineEstimate float64
+	PickupBranch string
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// 模板按通知类型和语言索引
+var templates = map[string]map[string]messageTemplate{
+	do.NotificationKindDueSoon: {
+		do.LanguageZhCN: newTemplate(
+			"图书即将到期：《{{.BookTitle}}》",
+			`{{.StudentName}} 同学：
+
+您借阅的《{{.BookTitle}}》将于 {{.DueDate}} 到期（还剩 {{.DaysLeft}} 天），请按时归还。
+逾期每天罚款0.5元，闭馆日不计。
+
+图书管理系统`),
+		do.LanguageEnUS: newTemplate(
+			"Due soon: {{.BookTitle}}",
+			`Dear {{.StudentName}},
+
+"{{.BookTitle}}" is due on {{.DueDate}} ({{.DaysLeft}} day(s) left). Please return it on time.
+Overdue items are fined 0.5 per open day.
+
+Library Management System`),
+	},
+	do.NotificationKindOverdue: {
+		do.LanguageZhCN: newTemplate(
+			"图书已逾期：《{{.BookTitle}}》",
+			`{{.StudentName}} 同学：
+
+您借阅的《{{.BookTitle}}》已于 {{.DueDate}} 到期，目前已逾期 {{.DaysOverdue}} 天，
+预计罚款 {{printf "%.2f" .FineEstimate}} 元。请尽快归还，归还后需支付罚款才能继续借书。
+
+图书管理系统`),
+		do.LanguageEnUS: newTemplate(
+			"Overdue: {{.BookTitle}}",
+			`Dear {{.StudentName}},
+
+"{{.BookTitle}}" was due on {{.DueDate}} and is {{.DaysOverdue}} day(s) overdue.
+The estimated fine is {{printf "%.2f" .FineEstimate}}. Please return it as soon as possible;
+borrowing is suspended until the fine is paid.
+
+Library Management System`),
+	},
+	do.NotificationKindHoldReady: {
+		do.LanguageZhCN: newTemplate(
+			"预约图书已到馆：《{{.BookTitle}}》",
+			`{{.StudentName}} 同学：
+
+您预约的《{{.BookTitle}}》（条码 {{.Barcode}}）已到达 {{.PickupBranch}}，请尽快到馆借阅。
+
+图书管理系统`),
+		do.LanguageEnUS: newTemplate(
+			"Hold ready for pickup: {{.BookTitle}}",
+			`Dear {{.StudentName}},
+
+Your hold on "{{.BookTitle}}" (barcode {{.Barcode}}) is ready for pickup at {{.PickupBranch}}.
+
+Library Management System`),
+	},
+}
+
+func newTemplate(subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+// 是否支持该通知语言
+func SupportedLanguage(language string) bool {
+	_, ok := templates[do.NotificationKindDueSoon][language]
+	return ok
+}
+
+// 渲染通知的主题和正文，不支持的语言使用中文
+func Render(kind, language string, data *TemplateData) (string, string, error) {
+	byLanguage, ok := templates[kind]
+	if !ok {
+		return "", "", fmt.Errorf("未知的通知类型: %s", kind)
+	}
+	tmpl, ok := byLanguage[language]
+	if !ok {
+		tmpl = byLanguage[do.LanguageZhCN]
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	return subject.String(), body.String(), nil
+}
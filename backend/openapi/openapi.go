@@ -0,0 +1,24 @@
+// Package openapi 接口文档（OpenAPI 3.1），编译进程序，由服务在/openapi.yaml、/openapi.json和/docs提供
+// 修改路由或响应结构时同步修改openapi.yaml，integration中的契约测试会核对路由、字段和响应
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAML 接口文档原文
+//
+//go:embed openapi.yaml
+var YAML []byte
+
+// 转换为JSON格式的接口文档
+func JSON() ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(YAML, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
@@ -0,0 +1,697 @@
+package memory
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/repository"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// repositories 内存仓储，语义与dao包中对应的SQL保持一致
+type repositories struct {
+	h *handle
+}
+
+func (r *repositories) Books() repository.BookRepository         { return &bookRepo{r.h} }
+func (r *repositories) BookItems() repository.BookItemRepository { return &itemRepo{r.h} }
+func (r *repositories) Borrows() repository.BorrowRepository     { return &borrowRepo{r.h} }
+func (r *repositories) Students() repository.StudentRepository   { return &studentRepo{r.h} }
+func (r *repositories) Holds() repository.HoldRepository         { return &holdRepo{r.h} }
+func (r *repositories) Transfers() repository.TransferRepository { return &transferRepo{r.h} }
+func (r *repositories) Calendar() repository.CalendarRepository  { return &calendarRepo{r.h} }
+func (r *repositories) Outbox() repository.OutboxRepository      { return &outboxRepo{r.h} }
+func (r *repositories) Audit() repository.AuditRepository        { return &auditRepo{r.h} }
+
+type bookRepo struct{ h *handle }
+
+func (r *bookRepo) FindBooksByTitleOrAuthor(ctx context.Context, keyword string) ([]do.Book, error) {
+	var books []do.Book
+	err := r.h.run(func(st *state) error {
+		for _, book := range st.books {
+			if book.CanBorrow && (strings.Contains(book.Title, keyword) || strings.Contains(book.Author, keyword)) {
+				books = append(books, book)
+			}
+		}
+		return nil
+	})
+	sort.Slice(books, func(i, j int) bool { return books[i].BookID < books[j].BookID })
+	return books, err
+}
+
+func (r *bookRepo) GetBookByID(ctx context.Context, bookID string) (*do.Book, error) {
+	var book do.Book
+	err := r.h.run(func(st *state) error {
+		var ok bool
+		if book, ok = st.books[bookID]; !ok {
+			return apperr.New(apperr.CodeBookNotFound)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+func (r *bookRepo) GetBooksByIDs(ctx context.Context, bookIDs []string) ([]do.Book, error) {
+	var books []do.Book
+	err := r.h.run(func(st *state) error {
+		for _, bookID := range uniqueIDs(bookIDs) {
+			if book, ok := st.books[bookID]; ok {
+				books = append(books, book)
+			}
+		}
+		return nil
+	})
+	return books, err
+}
+
+func (r *bookRepo) GetAllBooks(ctx context.Context) ([]do.Book, error) {
+	var books []do.Book
+	err := r.h.run(func(st *state) error {
+		for _, book := range st.books {
+			books = append(books, book)
+		}
+		return nil
+	})
+	sort.Slice(books, func(i, j int) bool {
+		if !books[i].CreatedAt.Equal(books[j].CreatedAt) {
+			return books[i].CreatedAt.After(books[j].CreatedAt)
+		}
+		return books[i].BookID < books[j].BookID
+	})
+	return books, err
+}
+
+func (r *bookRepo) CreateBook(ctx context.Context, book *do.Book) error {
+	return r.h.run(func(st *state) error {
+		if _, ok := st.books[book.BookID]; ok {
+			return fmt.Errorf("主键冲突: 书籍%s已存在", book.BookID)
+		}
+		st.books[book.BookID] = *book
+		return nil
+	})
+}
+
+func (r *bookRepo) UpdateBookCopies(ctx context.Context, bookID string, totalCopies, availableCopies int) error {
+	return r.h.run(func(st *state) error {
+		if book, ok := st.books[bookID]; ok {
+			book.TotalCopies = totalCopies
+			book.AvailableCopies = availableCopies
+			st.books[bookID] = book
+		}
+		return nil
+	})
+}
+
+func (r *bookRepo) UpdateBookAvailableCopies(ctx context.Context, bookID string, availableCopies int) error {
+	return r.h.run(func(st *state) error {
+		if book, ok := st.books[bookID]; ok {
+			book.AvailableCopies = availableCopies
+			st.books[bookID] = book
+		}
+		return nil
+	})
+}
+
+func (r *bookRepo) DecrementAvailableCopies(ctx context.Context, bookID string) error {
+	return r.h.run(func(st *state) error {
+		book, ok := st.books[bookID]
+		if !ok || book.AvailableCopies <= 0 {
+			return apperr.New(apperr.CodeBookUnavailable)
+		}
+		book.AvailableCopies--
+		st.books[bookID] = book
+		return nil
+	})
+}
+
+func (r *bookRepo) IncrementAvailableCopies(ctx context.Context, bookID string) error {
+	return r.h.run(func(st *state) error {
+		if book, ok := st.books[bookID]; ok {
+			book.AvailableCopies++
+			st.books[bookID] = book
+		}
+		return nil
+	})
+}
+
+func (r *bookRepo) SyncCopiesFromItems(ctx context.Context, bookID string) error {
+	return r.h.run(func(st *state) error {
+		st.syncCopies(bookID)
+		return nil
+	})
+}
+
+func (st *state) syncCopies(bookID string) {
+	book, ok := st.books[bookID]
+	if !ok {
+		return
+	}
+	total, available := 0, 0
+	for _, item := range st.items {
+		if item.BookID != bookID {
+			continue
+		}
+		total++
+		if item.Status == do.ItemStatusAvailable {
+			available++
+		}
+	}
+	if total == 0 {
+		return
+	}
+	book.TotalCopies = total
+	book.AvailableCopies = available
+	st.books[bookID] = book
+}
+
+type itemRepo struct{ h *handle }
+
+func (r *itemRepo) GetItemByBarcode(ctx context.Context, barcode string) (*do.BookItem, error) {
+	var item do.BookItem
+	err := r.h.run(func(st *state) error {
+		var ok bool
+		if item, ok = st.items[barcode]; !ok {
+			return apperr.New(apperr.CodeItemNotFound)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *itemRepo) CountItems(ctx context.Context, bookID string) (int, error) {
+	count := 0
+	err := r.h.run(func(st *state) error {
+		for _, item := range st.items {
+			if item.BookID == bookID {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *itemRepo) FindAvailableItem(ctx context.Context, bookID, branchID string) (*do.BookItem, error) {
+	var found *do.BookItem
+	err := r.h.run(func(st *state) error {
+		for _, item := range st.items {
+			if item.BookID != bookID || item.Status != do.ItemStatusAvailable {
+				continue
+			}
+			if branchID != "" && item.CurrentBranchID != branchID {
+				continue
+			}
+			// 优先位于所属分馆的单册，其次按条码排序
+			if found == nil || betterItem(item, *found) {
+				item := item
+				found = &item
+			}
+		}
+		return nil
+	})
+	return found, err
+}
+
+func betterItem(a, b do.BookItem) bool {
+	aHome, bHome := a.HomeBranchID == a.CurrentBranchID, b.HomeBranchID == b.CurrentBranchID
+	if aHome != bHome {
+		return aHome
+	}
+	return a.Barcode < b.Barcode
+}
+
+func (r *itemRepo) UpdateItemLocation(ctx context.Context, barcode, currentBranchID, status string) error {
+	return r.h.run(func(st *state) error {
+		if item, ok := st.items[barcode]; ok {
+			item.CurrentBranchID = currentBranchID
+			item.Status = status
+			st.items[barcode] = item
+		}
+		return nil
+	})
+}
+
+func (r *itemRepo) GetBranchAvailability(ctx context.Context, bookID string) ([]do.BranchAvailability, error) {
+	var result []do.BranchAvailability
+	err := r.h.run(func(st *state) error {
+		for _, branch := range st.branches {
+			availability := do.BranchAvailability{BranchID: branch.BranchID, BranchName: branch.Name}
+			for _, item := range st.items {
+				if item.BookID != bookID {
+					continue
+				}
+				if item.HomeBranchID == branch.BranchID {
+					availability.Total++
+				}
+				if item.CurrentBranchID == branch.BranchID && item.Status == do.ItemStatusAvailable {
+					availability.Available++
+				}
+			}
+			result = append(result, availability)
+		}
+		return nil
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].BranchID < result[j].BranchID })
+	return result, err
+}
+
+type borrowRepo struct{ h *handle }
+
+func (r *borrowRepo) CreateBorrowRecord(ctx context.Context, record *do.BorrowRecord) error {
+	return r.h.run(func(st *state) error {
+		if _, ok := st.students[record.StuID]; !ok {
+			return fmt.Errorf("外键约束失败: 学生%s不存在", record.StuID)
+		}
+		if _, ok := st.books[record.BookID]; !ok {
+			return fmt.Errorf("外键约束失败: 书籍%s不存在", record.BookID)
+		}
+		record.ID = st.nextID()
+		record.CreatedAt = time.Now()
+		st.records[record.ID] = *record
+		return nil
+	})
+}
+
+func (r *borrowRepo) GetBorrowRecord(ctx context.Context, stuID, bookID string) (*do.BorrowRecord, error) {
+	var found *do.BorrowRecord
+	err := r.h.run(func(st *state) error {
+		for _, record := range st.activeRecords(stuID) {
+			if record.BookID == bookID {
+				record := record
+				found = &record
+				return nil
+			}
+		}
+		return apperr.New(apperr.CodeLoanNotFound)
+	})
+	return found, err
+}
+
+func (r *borrowRepo) ReturnBorrowRecord(ctx context.Context, id int, returnDate time.Time, isOverdue bool, fineAmount float64) error {
+	return r.h.run(func(st *state) error {
+		record, ok := st.records[id]
+		if !ok || record.ReturnDate != nil {
+			return apperr.New(apperr.CodeLoanNotFound)
+		}
+		record.ReturnDate = &returnDate
+		record.IsOverdue = isOverdue
+		record.FineAmount = fineAmount
+		st.records[id] = record
+		return nil
+	})
+}
+
+func (r *borrowRepo) GetStudentBorrowRecords(ctx context.Context, stuID string) ([]do.BorrowRecord, error) {
+	var records []do.BorrowRecord
+	err := r.h.run(func(st *state) error {
+		records = st.activeRecords(stuID)
+		return nil
+	})
+	return records, err
+}
+
+func (r *borrowRepo) GetStudentBorrowRecordsWithBookInfo(ctx context.Context, stuID string) ([]map[string]interface{}, error) {
+	var records []map[string]interface{}
+	err := r.h.run(func(st *state) error {
+		active := st.activeRecords(stuID)
+		sort.SliceStable(active, func(i, j int) bool { return active[i].BorrowDate.After(active[j].BorrowDate) })
+		for _, record := range active {
+			book := st.books[record.BookID]
+			records = append(records, map[string]interface{}{
+				"id":          record.ID,
+				"stu_id":      record.StuID,
+				"book_id":     record.BookID,
+				"barcode":     record.Barcode,
+				"borrow_date": record.BorrowDate,
+				"due_date":    record.DueDate,
+				"return_date": record.ReturnDate,
+				"is_overdue":  record.IsOverdue,
+				"fine_amount": record.FineAmount,
+				"created_at":  record.CreatedAt,
+				"book_title":  book.Title,
+				"book_author": book.Author,
+			})
+		}
+		return nil
+	})
+	return records, err
+}
+
+func (r *borrowRepo) GetOverdueBorrowRecords(ctx context.Context, now time.Time) ([]do.BorrowRecord, error) {
+	var records []do.BorrowRecord
+	err := r.h.run(func(st *state) error {
+		for _, record := range st.records {
+			if record.ReturnDate == nil && record.DueDate.Before(now) {
+				records = append(records, record)
+			}
+		}
+		return nil
+	})
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].DueDate.Equal(records[j].DueDate) {
+			return records[i].DueDate.Before(records[j].DueDate)
+		}
+		return records[i].ID < records[j].ID
+	})
+	return records, err
+}
+
+func (r *borrowRepo) GetStudentFinedRecords(ctx context.Context, stuID string) ([]do.BorrowRecord, error) {
+	var records []do.BorrowRecord
+	err := r.h.run(func(st *state) error {
+		for _, record := range st.records {
+			if record.StuID == stuID && record.FineAmount > 0 {
+				records = append(records, record)
+			}
+		}
+		return nil
+	})
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records, err
+}
+
+func (r *borrowRepo) UpdateFine(ctx context.Context, id int, isOverdue bool, fineAmount float64) error {
+	return r.h.run(func(st *state) error {
+		if record, ok := st.records[id]; ok {
+			record.IsOverdue = isOverdue
+			record.FineAmount = fineAmount
+			st.records[id] = record
+		}
+		return nil
+	})
+}
+
+// 学生未归还的借阅记录，按ID排序
+func (st *state) activeRecords(stuID string) []do.BorrowRecord {
+	var records []do.BorrowRecord
+	for _, record := range st.records {
+		if record.StuID == stuID && record.ReturnDate == nil {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records
+}
+
+type studentRepo struct{ h *handle }
+
+func (r *studentRepo) GetStudentByID(ctx context.Context, stuID string) (*do.Student, error) {
+	var found do.Student
+	err := r.h.run(func(st *state) error {
+		stu, ok := st.students[stuID]
+		if !ok {
+			return apperr.New(apperr.CodeStudentNotFound)
+		}
+		found = stu.Student
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &found, nil
+}
+
+func (r *studentRepo) GetStudentsByIDs(ctx context.Context, stuIDs []string) ([]do.Student, error) {
+	var students []do.Student
+	err := r.h.run(func(st *state) error {
+		for _, stuID := range uniqueIDs(stuIDs) {
+			if stu, ok := st.students[stuID]; ok {
+				stu.Password = ""
+				students = append(students, stu.Student)
+			}
+		}
+		return nil
+	})
+	return students, err
+}
+
+func (r *studentRepo) GetAllStudents(ctx context.Context) ([]do.Student, error) {
+	var students []do.Student
+	err := r.h.run(func(st *state) error {
+		for _, stu := range st.students {
+			stu.Password = ""
+			students = append(students, stu.Student)
+		}
+		return nil
+	})
+	sort.Slice(students, func(i, j int) bool { return students[i].StuId < students[j].StuId })
+	return students, err
+}
+
+func (r *studentRepo) CreateStudent(ctx context.Context, stu *do.Student) error {
+	return r.h.run(func(st *state) error {
+		if _, ok := st.students[stu.StuId]; ok {
+			return fmt.Errorf("主键冲突: 学生%s已存在", stu.StuId)
+		}
+		st.students[stu.StuId] = student{Student: *stu}
+		return nil
+	})
+}
+
+func (r *studentRepo) UpdateStudentPassword(ctx context.Context, stuID, password string) error {
+	return r.h.run(func(st *state) error {
+		if stu, ok := st.students[stuID]; ok {
+			stu.Password = password
+			st.students[stuID] = stu
+		}
+		return nil
+	})
+}
+
+func (r *studentRepo) UpdateStudentBorrowStatus(ctx context.Context, stuID string, canBorrow bool) error {
+	return r.h.run(func(st *state) error {
+		if stu, ok := st.students[stuID]; ok {
+			stu.CanBorrow = canBorrow
+			st.students[stuID] = stu
+		}
+		return nil
+	})
+}
+
+func (r *studentRepo) HasUnpaidFine(ctx context.Context, stuID string) (bool, error) {
+	unpaid := false
+	err := r.h.run(func(st *state) error {
+		for _, record := range st.activeRecords(stuID) {
+			if record.IsOverdue && record.FineAmount > 0 {
+				unpaid = true
+			}
+		}
+		return nil
+	})
+	return unpaid, err
+}
+
+func (r *studentRepo) GetStudentPIN(ctx context.Context, stuID string) (*do.StudentPIN, error) {
+	var pin do.StudentPIN
+	err := r.h.run(func(st *state) error {
+		stu, ok := st.students[stuID]
+		if !ok {
+			return apperr.New(apperr.CodeStudentNotFound)
+		}
+		pin = stu.pin
+		return nil
+	})
+	return &pin, err
+}
+
+func (r *studentRepo) UpdateStudentPINHash(ctx context.Context, stuID, pinHash string) error {
+	return r.h.run(func(st *state) error {
+		if stu, ok := st.students[stuID]; ok {
+			stu.pin = do.StudentPIN{Hash: pinHash}
+			st.students[stuID] = stu
+		}
+		return nil
+	})
+}
+
+func (r *studentRepo) RecordPINFailure(ctx context.Context, stuID string, maxAttempts int, lockedUntil time.Time) error {
+	return r.h.run(func(st *state) error {
+		if stu, ok := st.students[stuID]; ok {
+			stu.pin.FailedAttempts++
+			if stu.pin.FailedAttempts >= maxAttempts {
+				stu.pin.FailedAttempts = 0
+				stu.pin.LockedUntil = &lockedUntil
+			}
+			st.students[stuID] = stu
+		}
+		return nil
+	})
+}
+
+func (r *studentRepo) ResetPINFailures(ctx context.Context, stuID string) error {
+	return r.h.run(func(st *state) error {
+		if stu, ok := st.students[stuID]; ok {
+			stu.pin.FailedAttempts = 0
+			stu.pin.LockedUntil = nil
+			st.students[stuID] = stu
+		}
+		return nil
+	})
+}
+
+type holdRepo struct{ h *handle }
+
+func (r *holdRepo) GetActiveHold(ctx context.Context, stuID, bookID string) (*do.Hold, error) {
+	var found *do.Hold
+	err := r.h.run(func(st *state) error {
+		for _, hold := range st.sortedHolds() {
+			if hold.StuID != stuID || hold.BookID != bookID {
+				continue
+			}
+			switch hold.Status {
+			case do.HoldStatusWaiting, do.HoldStatusInTransit, do.HoldStatusReady:
+				hold := hold
+				found = &hold
+				return nil
+			}
+		}
+		return nil
+	})
+	return found, err
+}
+
+func (r *holdRepo) GetNextWaitingHold(ctx context.Context, bookID string) (*do.Hold, error) {
+	var found *do.Hold
+	err := r.h.run(func(st *state) error {
+		for _, hold := range st.sortedHolds() {
+			if hold.BookID == bookID && hold.Status == do.HoldStatusWaiting {
+				hold := hold
+				found = &hold
+				return nil
+			}
+		}
+		return nil
+	})
+	return found, err
+}
+
+func (r *holdRepo) UpdateHoldStatus(ctx context.Context, id int, status, barcode string) error {
+	return r.h.run(func(st *state) error {
+		if hold, ok := st.holds[id]; ok {
+			hold.Status = status
+			hold.Barcode = barcode
+			st.holds[id] = hold
+		}
+		return nil
+	})
+}
+
+func (r *holdRepo) MarkHoldReady(ctx context.Context, id int, barcode string, readyAt time.Time) error {
+	return r.h.run(func(st *state) error {
+		if hold, ok := st.holds[id]; ok {
+			hold.Status = do.HoldStatusReady
+			hold.Barcode = barcode
+			hold.ReadyAt = &readyAt
+			st.holds[id] = hold
+		}
+		return nil
+	})
+}
+
+// 按创建时间和ID排序的预约
+func (st *state) sortedHolds() []do.Hold {
+	holds := make([]do.Hold, 0, len(st.holds))
+	for _, hold := range st.holds {
+		holds = append(holds, hold)
+	}
+	sort.Slice(holds, func(i, j int) bool {
+		if !holds[i].CreatedAt.Equal(holds[j].CreatedAt) {
+			return holds[i].CreatedAt.Before(holds[j].CreatedAt)
+		}
+		return holds[i].ID < holds[j].ID
+	})
+	return holds
+}
+
+type transferRepo struct{ h *handle }
+
+func (r *transferRepo) CreateTransfer(ctx context.Context, transfer *do.Transfer) (int, error) {
+	var id int
+	err := r.h.run(func(st *state) error {
+		if _, ok := st.items[transfer.Barcode]; !ok {
+			return fmt.Errorf("外键约束失败: 单册%s不存在", transfer.Barcode)
+		}
+		created := *transfer
+		created.ID = st.nextID()
+		created.CreatedAt = time.Now()
+		st.transfers[created.ID] = created
+		id = created.ID
+		return nil
+	})
+	return id, err
+}
+
+type calendarRepo struct{ h *handle }
+
+func (r *calendarRepo) GetOpeningHours(ctx context.Context) ([]do.OpeningHours, error) {
+	var hours []do.OpeningHours
+	err := r.h.run(func(st *state) error {
+		for _, h := range st.hours {
+			hours = append(hours, h)
+		}
+		return nil
+	})
+	sort.Slice(hours, func(i, j int) bool { return hours[i].Weekday < hours[j].Weekday })
+	return hours, err
+}
+
+func (r *calendarRepo) GetClosures(ctx context.Context, from, to string) ([]do.LibraryClosure, error) {
+	var closures []do.LibraryClosure
+	err := r.h.run(func(st *state) error {
+		for _, closure := range st.closures {
+			// 日期均为 2006-01-02 格式，可直接按字符串比较
+			if closure.EndDate >= from && closure.StartDate <= to {
+				closures = append(closures, closure)
+			}
+		}
+		return nil
+	})
+	sort.Slice(closures, func(i, j int) bool {
+		if closures[i].StartDate != closures[j].StartDate {
+			return closures[i].StartDate < closures[j].StartDate
+		}
+		return closures[i].ID < closures[j].ID
+	})
+	return closures, err
+}
+
+type outboxRepo struct{ h *handle }
+
+func (r *outboxRepo) CreateEvent(ctx context.Context, eventType, payload string) error {
+	return r.h.run(func(st *state) error {
+		st.outbox = append(st.outbox, OutboxEvent{ID: st.nextID(), Type: eventType, Payload: payload, CreatedAt: time.Now()})
+		return nil
+	})
+}
+
+type auditRepo struct{ h *handle }
+
+func (r *auditRepo) RecordAudit(ctx context.Context, entry *do.AuditEntry) error {
+	return r.h.run(func(st *state) error {
+		entry.ID = int64(st.nextID())
+		st.audit = append(st.audit, *entry)
+		return nil
+	})
+}
+
+// 去掉重复的ID，与SQL中的IN一致，每条记录只返回一次
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	var unique []string
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
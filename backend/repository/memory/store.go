@@ -0,0 +1,291 @@
+// Package memory 提供repository.Store的内存实现，无需数据库即可测试业务层和HTTP接口
+package memory
+
+import (
+	"backend/do"
+	"backend/repository"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OutboxEvent 写入发件箱的事件
+type OutboxEvent struct {
+	ID        int
+	Type      string
+	Payload   string
+	CreatedAt time.Time
+}
+
+// Store 并发安全的内存存储
+// 事务在数据副本上执行，提交时整体替换，回滚时丢弃副本；事务执行期间其他操作等待，效果相当于串行化隔离
+type Store struct {
+	mu    sync.Mutex
+	state *state
+}
+
+func NewStore() *Store {
+	return &Store{state: newState()}
+}
+
+// 全部数据，各字段均按值保存，避免调用方修改返回值影响存储
+type state struct {
+	students  map[string]student
+	books     map[string]do.Book
+	branches  map[string]do.Branch
+	items     map[string]do.BookItem
+	records   map[int]do.BorrowRecord
+	holds     map[int]do.Hold
+	transfers map[int]do.Transfer
+	hours     map[int]do.OpeningHours
+	closures  map[int]do.LibraryClosure
+	outbox    []OutboxEvent
+	audit     []do.AuditEntry
+	lastID    int
+}
+
+type student struct {
+	do.Student
+	pin do.StudentPIN
+}
+
+func newState() *state {
+	return &state{
+		students:  make(map[string]student),
+		books:     make(map[string]do.Book),
+		branches:  make(map[string]do.Branch),
+		items:     make(map[string]do.BookItem),
+		records:   make(map[int]do.BorrowRecord),
+		holds:     make(map[int]do.Hold),
+		transfers: make(map[int]do.Transfer),
+		hours:     make(map[int]do.OpeningHours),
+		closures:  make(map[int]do.LibraryClosure),
+	}
+}
+
+func (s *state) clone() *state {
+	c := newState()
+	for k, v := range s.students {
+		c.students[k] = v
+	}
+	for k, v := range s.books {
+		c.books[k] = v
+	}
+	for k, v := range s.branches {
+		c.branches[k] = v
+	}
+	for k, v := range s.items {
+		c.items[k] = v
+	}
+	for k, v := range s.records {
+		c.records[k] = v
+	}
+	for k, v := range s.holds {
+		c.holds[k] = v
+	}
+	for k, v := range s.transfers {
+		c.transfers[k] = v
+	}
+	for k, v := range s.hours {
+		c.hours[k] = v
+	}
+	for k, v := range s.closures {
+		c.closures[k] = v
+	}
+	c.outbox = append([]OutboxEvent(nil), s.outbox...)
+	c.audit = append([]do.AuditEntry(nil), s.audit...)
+	c.lastID = s.lastID
+	return c
+}
+
+// 自增ID在所有表间共用，便于测试中区分记录
+func (s *state) nextID() int {
+	s.lastID++
+	return s.lastID
+}
+
+// 访问数据的入口：事务内直接使用事务的副本，事务外每次操作加锁
+type handle struct {
+	store *Store
+	tx    *state
+}
+
+func (h *handle) run(fn func(st *state) error) error {
+	if h.tx != nil {
+		return fn(h.tx)
+	}
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	return fn(h.store.state)
+}
+
+func (s *Store) repositories() *repositories {
+	return &repositories{h: &handle{store: s}}
+}
+
+func (s *Store) Books() repository.BookRepository         { return s.repositories().Books() }
+func (s *Store) BookItems() repository.BookItemRepository { return s.repositories().BookItems() }
+func (s *Store) Borrows() repository.BorrowRepository     { return s.repositories().Borrows() }
+func (s *Store) Students() repository.StudentRepository   { return s.repositories().Students() }
+func (s *Store) Holds() repository.HoldRepository         { return s.repositories().Holds() }
+func (s *Store) Transfers() repository.TransferRepository { return s.repositories().Transfers() }
+func (s *Store) Calendar() repository.CalendarRepository  { return s.repositories().Calendar() }
+func (s *Store) Outbox() repository.OutboxRepository      { return s.repositories().Outbox() }
+func (s *Store) Audit() repository.AuditRepository        { return s.repositories().Audit() }
+
+func (s *Store) Transaction(ctx context.Context, fn func(tx repository.Repositories) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := s.state.clone()
+	if err := fn(&repositories{h: &handle{store: s, tx: tx}}); err != nil {
+		return err
+	}
+	// 与数据库事务一致：ctx已取消时不提交
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.state = tx
+	return nil
+}
+
+// 以下方法用于在测试中准备数据和检查结果
+
+// AddStudent 添加学生
+func (s *Store) AddStudent(stu do.Student) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stu.CreatedAt.IsZero() {
+		stu.CreatedAt = time.Now()
+	}
+	s.state.students[stu.StuId] = student{Student: stu}
+}
+
+// AddBook 添加图书
+func (s *Store) AddBook(book do.Book) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if book.CreatedAt.IsZero() {
+		book.CreatedAt = time.Now()
+	}
+	s.state.books[book.BookID] = book
+}
+
+// AddBranch 添加分馆
+func (s *Store) AddBranch(branch do.Branch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if branch.CreatedAt.IsZero() {
+		branch.CreatedAt = time.Now()
+	}
+	s.state.branches[branch.BranchID] = branch
+}
+
+// AddItem 添加单册并按单册重新统计图书的馆藏数量
+func (s *Store) AddItem(item do.BookItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if item.Status == "" {
+		item.Status = do.ItemStatusAvailable
+	}
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = time.Now()
+	}
+	s.state.items[item.Barcode] = item
+	s.state.syncCopies(item.BookID)
+}
+
+// AddBorrowRecord 添加借阅记录（如构造已逾期的借阅），返回ID
+func (s *Store) AddBorrowRecord(record do.BorrowRecord) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record.ID = s.state.nextID()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = record.BorrowDate
+	}
+	s.state.records[record.ID] = record
+	return record.ID
+}
+
+// AddHold 添加预约，返回ID
+func (s *Store) AddHold(hold do.Hold) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hold.ID = s.state.nextID()
+	if hold.Status == "" {
+		hold.Status = do.HoldStatusWaiting
+	}
+	if hold.CreatedAt.IsZero() {
+		hold.CreatedAt = time.Now()
+	}
+	s.state.holds[hold.ID] = hold
+	return hold.ID
+}
+
+// SetOpeningHours 设置某个星期的开放时间
+func (s *Store) SetOpeningHours(hours do.OpeningHours) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.hours[hours.Weekday] = hours
+}
+
+// AddClosure 添加闭馆日期段，返回ID
+func (s *Store) AddClosure(closure do.LibraryClosure) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	closure.ID = s.state.nextID()
+	s.state.closures[closure.ID] = closure
+	return closure.ID
+}
+
+// BorrowRecords 全部借阅记录（含已归还），按ID排序
+func (s *Store) BorrowRecords() []do.BorrowRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := make([]do.BorrowRecord, 0, len(s.state.records))
+	for _, record := range s.state.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records
+}
+
+// Hold 根据ID获取预约，不存在时返回nil
+func (s *Store) Hold(id int) *do.Hold {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hold, ok := s.state.holds[id]
+	if !ok {
+		return nil
+	}
+	return &hold
+}
+
+// TransferList 全部调拨单，按ID排序
+func (s *Store) TransferList() []do.Transfer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	transfers := make([]do.Transfer, 0, len(s.state.transfers))
+	for _, transfer := range s.state.transfers {
+		transfers = append(transfers, transfer)
+	}
+	sort.Slice(transfers, func(i, j int) bool { return transfers[i].ID < transfers[j].ID })
+	return transfers
+}
+
+// OutboxEvents 发件箱中的全部事件
+func (s *Store) OutboxEvents() []OutboxEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]OutboxEvent(nil), s.state.outbox...)
+}
+
+// AuditEntries 全部审计日志，按写入顺序排列
+func (s *Store) AuditEntries() []do.AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]do.AuditEntry(nil), s.state.audit...)
+}
+
+var _ repository.Store = (*Store)(nil)
@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"backend/do"
+	"backend/repository"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestTransactionCommit(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	store.AddBook(do.Book{BookID: "B1", Title: "Go", TotalCopies: 2, AvailableCopies: 2, CanBorrow: true})
+
+	err := store.Transaction(ctx, func(tx repository.Repositories) error {
+		if err := tx.Books().UpdateBookAvailableCopies(ctx, "B1", 1); err != nil {
+			return err
+		}
+		return tx.Outbox().CreateEvent(ctx, do.EventBookBorrowed, `{}`)
+	})
+	if err != nil {
+		t.Fatalf("事务执行失败: %v", err)
+	}
+
+	book, err := store.Books().GetBookByID(ctx, "B1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.AvailableCopies != 1 {
+		t.Errorf("可借数量 = %d，期望 1", book.AvailableCopies)
+	}
+	if got := len(store.OutboxEvents()); got != 1 {
+		t.Errorf("发件箱事件数 = %d，期望 1", got)
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	store.AddBook(do.Book{BookID: "B1", Title: "Go", TotalCopies: 2, AvailableCopies: 2, CanBorrow: true})
+
+	failed := errors.New("失败")
+	err := store.Transaction(ctx, func(tx repository.Repositories) error {
+		if err := tx.Books().UpdateBookAvailableCopies(ctx, "B1", 0); err != nil {
+			return err
+		}
+		if err := tx.Outbox().CreateEvent(ctx, do.EventBookBorrowed, `{}`); err != nil {
+			return err
+		}
+		// 事务内能看到自己的修改
+		book, err := tx.Books().GetBookByID(ctx, "B1")
+		if err != nil {
+			return err
+		}
+		if book.AvailableCopies != 0 {
+			t.Errorf("事务内可借数量 = %d，期望 0", book.AvailableCopies)
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Transaction返回 %v，期望原样返回fn的错误", err)
+	}
+
+	book, err := store.Books().GetBookByID(ctx, "B1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.AvailableCopies != 2 {
+		t.Errorf("回滚后可借数量 = %d，期望 2", book.AvailableCopies)
+	}
+	if got := len(store.OutboxEvents()); got != 0 {
+		t.Errorf("回滚后发件箱事件数 = %d，期望 0", got)
+	}
+}
+
+func TestConcurrentTransactions(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	store.AddBook(do.Book{BookID: "B1", Title: "Go", TotalCopies: 50, AvailableCopies: 50, CanBorrow: true})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.Transaction(ctx, func(tx repository.Repositories) error {
+				book, err := tx.Books().GetBookByID(ctx, "B1")
+				if err != nil {
+					return err
+				}
+				return tx.Books().UpdateBookAvailableCopies(ctx, "B1", book.AvailableCopies-1)
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	book, err := store.Books().GetBookByID(ctx, "B1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.AvailableCopies != 0 {
+		t.Errorf("并发借出后可借数量 = %d，期望 0", book.AvailableCopies)
+	}
+}
+
+func TestItemsSyncCopies(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	store.AddBook(do.Book{BookID: "B1", Title: "Go"})
+	store.AddBranch(do.Branch{BranchID: "MAIN", Name: "总馆"})
+	store.AddItem(do.BookItem{Barcode: "B1-1", BookID: "B1", HomeBranchID: "MAIN", CurrentBranchID: "MAIN"})
+	store.AddItem(do.BookItem{Barcode: "B1-2", BookID: "B1", HomeBranchID: "MAIN", CurrentBranchID: "MAIN", Status: do.ItemStatusOnLoan})
+
+	book, err := store.Books().GetBookByID(ctx, "B1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.TotalCopies != 2 || book.AvailableCopies != 1 {
+		t.Errorf("图书 = %+v，期望总数2、可借1", book)
+	}
+
+	if _, err := store.BookItems().GetItemByBarcode(ctx, "NONE"); err == nil {
+		t.Error("查询不存在的单册应返回错误")
+	}
+}
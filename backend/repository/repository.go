@@ -0,0 +1,138 @@
+// Package repository 定义业务层使用的数据访问接口
+// 所有方法的第一个参数为请求的ctx，请求取消或超时时中止数据库操作
+// dao包提供基于SQL数据库（MySQL、PostgreSQL、SQLite）的实现，repository/memory提供用于测试的内存实现。
+// 目前图书、借还书、学生和管理命令业务只依赖Store；其余业务仍使用*sql.DB和dao，只在事务中通过dao.NewRepositoriesTx使用这些接口
+package repository
+
+import (
+	"backend/do"
+	"context"
+	"time"
+)
+
+// BookRepository 图书
+type BookRepository interface {
+	// 根据书名或作者查找可借阅的书籍
+	FindBooksByTitleOrAuthor(ctx context.Context, keyword string) ([]do.Book, error)
+	// 书籍不存在时返回错误；在事务中会锁定该书籍
+	GetBookByID(ctx context.Context, bookID string) (*do.Book, error)
+	// 按图书ID批量获取，不存在的ID不返回
+	GetBooksByIDs(ctx context.Context, bookIDs []string) ([]do.Book, error)
+	GetAllBooks(ctx context.Context) ([]do.Book, error)
+	// 新增书籍，由调用方设置created_at
+	CreateBook(ctx context.Context, book *do.Book) error
+	UpdateBookCopies(ctx context.Context, bookID string, totalCopies, availableCopies int) error
+	UpdateBookAvailableCopies(ctx context.Context, bookID string, availableCopies int) error
+	// 可借阅数量减1，没有可借副本时返回错误
+	DecrementAvailableCopies(ctx context.Context, bookID string) error
+	IncrementAvailableCopies(ctx context.Context, bookID string) error
+	// 根据单册重新计算总馆藏和可借数量，没有单册的书籍不变
+	SyncCopiesFromItems(ctx context.Context, bookID string) error
+}
+
+// BookItemRepository 单册
+type BookItemRepository interface {
+	// 单册不存在时返回错误；在事务中会锁定该单册
+	GetItemByBarcode(ctx context.Context, barcode string) (*do.BookItem, error)
+	CountItems(ctx context.Context, bookID string) (int, error)
+	// 查找一册在架可借的单册，branchID为空时不限分馆，没有时返回nil
+	FindAvailableItem(ctx context.Context, bookID, branchID string) (*do.BookItem, error)
+	UpdateItemLocation(ctx context.Context, barcode, currentBranchID, status string) error
+	GetBranchAvailability(ctx context.Context, bookID string) ([]do.BranchAvailability, error)
+}
+
+// BorrowRepository 借阅记录
+type BorrowRepository interface {
+	// 创建借阅记录并回填ID
+	CreateBorrowRecord(ctx context.Context, record *do.BorrowRecord) error
+	// 获取学生对某本书未归还的借阅记录，不存在时返回错误；在事务中会锁定该记录
+	GetBorrowRecord(ctx context.Context, stuID, bookID string) (*do.BorrowRecord, error)
+	// 借阅已归还时返回错误
+	ReturnBorrowRecord(ctx context.Context, id int, returnDate time.Time, isOverdue bool, fineAmount float64) error
+	// 获取学生未归还的借阅记录
+	GetStudentBorrowRecords(ctx context.Context, stuID string) ([]do.BorrowRecord, error)
+	GetStudentBorrowRecordsWithBookInfo(ctx context.Context, stuID string) ([]map[string]interface{}, error)
+	// 获取now之前到期仍未归还的借阅记录，按应还日期排序
+	GetOverdueBorrowRecords(ctx context.Context, now time.Time) ([]do.BorrowRecord, error)
+	// 获取学生名下有罚款的借阅记录，包括已归还的
+	GetStudentFinedRecords(ctx context.Context, stuID string) ([]do.BorrowRecord, error)
+	// 更新逾期状态和罚款金额，不改变归还时间
+	UpdateFine(ctx context.Context, id int, isOverdue bool, fineAmount float64) error
+}
+
+// StudentRepository 学生
+type StudentRepository interface {
+	// 学生不存在时返回错误
+	GetStudentByID(ctx context.Context, stuID string) (*do.Student, error)
+	// 按学号批量获取，不存在的学号不返回；不读取密码
+	GetStudentsByIDs(ctx context.Context, stuIDs []string) ([]do.Student, error)
+	// 按学号排序；不读取密码
+	GetAllStudents(ctx context.Context) ([]do.Student, error)
+	// 新增学生，由调用方设置created_at
+	CreateStudent(ctx context.Context, student *do.Student) error
+	UpdateStudentPassword(ctx context.Context, stuID, password string) error
+	UpdateStudentBorrowStatus(ctx context.Context, stuID string, canBorrow bool) error
+	HasUnpaidFine(ctx context.Context, stuID string) (bool, error)
+	// 未设置PIN时Hash为空
+	GetStudentPIN(ctx context.Context, stuID string) (*do.StudentPIN, error)
+	// 设置PIN哈希并解除锁定
+	UpdateStudentPINHash(ctx context.Context, stuID, pinHash string) error
+	// 记录一次PIN输错，连续输错达到maxAttempts次时锁定到lockedUntil并清零计数
+	RecordPINFailure(ctx context.Context, stuID string, maxAttempts int, lockedUntil time.Time) error
+	ResetPINFailures(ctx context.Context, stuID string) error
+}
+
+// HoldRepository 预约
+type HoldRepository interface {
+	// 学生对某本书尚未结束的预约，没有时返回nil
+	GetActiveHold(ctx context.Context, stuID, bookID string) (*do.Hold, error)
+	// 某本书最早的排队预约，没有时返回nil；在事务中会锁定该预约
+	GetNextWaitingHold(ctx context.Context, bookID string) (*do.Hold, error)
+	UpdateHoldStatus(ctx context.Context, id int, status, barcode string) error
+	MarkHoldReady(ctx context.Context, id int, barcode string, readyAt time.Time) error
+}
+
+// TransferRepository 分馆调拨
+type TransferRepository interface {
+	// 创建调拨单，返回ID
+	CreateTransfer(ctx context.Context, transfer *do.Transfer) (int, error)
+}
+
+// CalendarRepository 开馆日历
+type CalendarRepository interface {
+	GetOpeningHours(ctx context.Context) ([]do.OpeningHours, error)
+	// 与[from, to]有交集的闭馆日期段，日期格式为 2006-01-02
+	GetClosures(ctx context.Context, from, to string) ([]do.LibraryClosure, error)
+}
+
+// OutboxRepository 事件推送发件箱
+type OutboxRepository interface {
+	CreateEvent(ctx context.Context, eventType, payload string) error
+}
+
+// AuditRepository 审计日志，只能追加
+type AuditRepository interface {
+	// 写入一条审计日志并回填ID
+	RecordAudit(ctx context.Context, entry *do.AuditEntry) error
+}
+
+// Repositories 一组仓储，在事务内使用时所有操作属于同一个事务
+type Repositories interface {
+	Books() BookRepository
+	BookItems() BookItemRepository
+	Borrows() BorrowRepository
+	Students() StudentRepository
+	Holds() HoldRepository
+	Transfers() TransferRepository
+	Calendar() CalendarRepository
+	Outbox() OutboxRepository
+	Audit() AuditRepository
+}
+
+// Store 数据存储
+// Transaction在一个事务中执行fn：fn返回nil时提交，返回错误时回滚并原样返回该错误，ctx取消时事务回滚；
+// fn中只能使用传入的仓储，不能再使用Store本身
+type Store interface {
+	Repositories
+	Transaction(ctx context.Context, fn func(tx Repositories) error) error
+}
@@ -0,0 +1,47 @@
+package rpc
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/librarypb"
+	"backend/service"
+	"context"
+)
+
+// catalogServer 图书目录，不需要令牌
+type catalogServer struct {
+	librarypb.UnimplementedCatalogServiceServer
+	books *service.BookService
+}
+
+// 列出图书，keyword不为空时按书名或作者搜索
+func (s *catalogServer) ListBooks(ctx context.Context, req *librarypb.ListBooksRequest) (*librarypb.ListBooksResponse, error) {
+	var books []do.Book
+	var err error
+	if req.GetKeyword() != "" {
+		books, err = s.books.SearchBooks(ctx, req.GetKeyword())
+	} else {
+		books, err = s.books.GetAllBooks(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &librarypb.ListBooksResponse{Books: make([]*librarypb.Book, 0, len(books))}
+	for i := range books {
+		resp.Books = append(resp.Books, bookMessage(&books[i]))
+	}
+	return resp, nil
+}
+
+// 获取图书详情，包括各分馆的可借数量
+func (s *catalogServer) GetBook(ctx context.Context, req *librarypb.GetBookRequest) (*librarypb.Book, error) {
+	if req.GetBookId() == "" {
+		return nil, apperr.Invalid("invalid.book_id_required")
+	}
+	book, err := s.books.GetBookDetail(ctx, req.GetBookId())
+	if err != nil {
+		return nil, err
+	}
+	return bookMessage(book), nil
+}
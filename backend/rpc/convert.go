@@ -0,0 +1,100 @@
+package rpc
+
+import (
+	"backend/do"
+	"backend/librarypb"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// 零值时间不输出，调用方据此区分"未设置"
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamp(*t)
+}
+
+func bookMessage(book *do.Book) *librarypb.Book {
+	msg := &librarypb.Book{
+		BookId:          book.BookID,
+		Title:           book.Title,
+		Author:          book.Author,
+		Description:     book.Description,
+		TotalCopies:     int32(book.TotalCopies),
+		AvailableCopies: int32(book.AvailableCopies),
+		CanBorrow:       book.CanBorrow,
+		CoverUrl:        book.CoverURL,
+		CoverThumbUrl:   book.CoverThumbURL,
+		CreatedAt:       timestamp(book.CreatedAt),
+	}
+	for _, branch := range book.Branches {
+		msg.Branches = append(msg.Branches, &librarypb.BranchAvailability{
+			BranchId:   branch.BranchID,
+			BranchName: branch.BranchName,
+			Total:      int32(branch.Total),
+			Available:  int32(branch.Available),
+		})
+	}
+	return msg
+}
+
+func loanMessage(record *do.BorrowRecord) *librarypb.Loan {
+	return &librarypb.Loan{
+		Id:         int32(record.ID),
+		StuId:      record.StuID,
+		BookId:     record.BookID,
+		Barcode:    record.Barcode,
+		BorrowDate: timestamp(record.BorrowDate),
+		DueDate:    timestamp(record.DueDate),
+		ReturnDate: optionalTimestamp(record.ReturnDate),
+		IsOverdue:  record.IsOverdue,
+		FineAmount: record.FineAmount,
+	}
+}
+
+// 转换GetStudentBorrowRecordsWithBookInfo返回的借阅记录（含书名和作者）
+func loanWithBookMessage(row map[string]interface{}) *librarypb.Loan {
+	id, _ := row["id"].(int)
+	stuID, _ := row["stu_id"].(string)
+	bookID, _ := row["book_id"].(string)
+	barcode, _ := row["barcode"].(string)
+	borrowDate, _ := row["borrow_date"].(time.Time)
+	dueDate, _ := row["due_date"].(time.Time)
+	returnDate, _ := row["return_date"].(*time.Time)
+	isOverdue, _ := row["is_overdue"].(bool)
+	fineAmount, _ := row["fine_amount"].(float64)
+	title, _ := row["book_title"].(string)
+	author, _ := row["book_author"].(string)
+	return &librarypb.Loan{
+		Id:         int32(id),
+		StuId:      stuID,
+		BookId:     bookID,
+		Barcode:    barcode,
+		BorrowDate: timestamp(borrowDate),
+		DueDate:    timestamp(dueDate),
+		ReturnDate: optionalTimestamp(returnDate),
+		IsOverdue:  isOverdue,
+		FineAmount: fineAmount,
+		BookTitle:  title,
+		BookAuthor: author,
+	}
+}
+
+func studentMessage(student *do.Student) *librarypb.Student {
+	return &librarypb.Student{
+		StuId:     student.StuId,
+		Name:      student.Name,
+		Trust:     student.Trust,
+		CanBorrow: student.CanBorrow,
+		CreatedAt: timestamp(student.CreatedAt),
+	}
+}
@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"backend/apperr"
+	"backend/i18n"
+	"context"
+	"log/slog"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorInfo详情中的错误域
+const errorDomain = "library"
+
+// HTTP状态码对应的gRPC状态码，错误码的HTTP状态码登记在apperr/codes.go中
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.FailedPrecondition,
+	http.StatusRequestEntityTooLarge: codes.InvalidArgument,
+	http.StatusUnsupportedMediaType:  codes.InvalidArgument,
+	http.StatusUnprocessableEntity:   codes.FailedPrecondition,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	499:                              codes.Canceled,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+	http.StatusGatewayTimeout:        codes.DeadlineExceeded,
+}
+
+// 把处理函数返回的错误转为gRPC状态：提示按协商的语言输出，错误码放在ErrorInfo的reason中；
+// 已经是gRPC状态的错误原样返回
+func toStatus(ctx context.Context, method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	appErr := apperr.From(err)
+	httpStatus := appErr.Status()
+	code, ok := grpcCodes[httpStatus]
+	if !ok {
+		code = codes.Internal
+	}
+	if code == codes.Internal {
+		slog.ErrorContext(ctx, "gRPC调用失败", "method", method, "error", err)
+	}
+
+	st := status.New(code, appErr.Localize(i18n.Locale(ctx)))
+	detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{Reason: string(appErr.Code), Domain: errorDomain})
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
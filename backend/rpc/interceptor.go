@@ -0,0 +1,198 @@
+package rpc
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/i18n"
+	"backend/librarypb"
+	"backend/logging"
+	"backend/service"
+	"context"
+	"log/slog"
+	"net"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// 请求ID的metadata键，与HTTP接口的X-Request-ID相同
+const requestIDKey = "x-request-id"
+
+// 调用方传入的请求ID只接受较短的字母、数字和 - _ .，避免日志被注入任意内容
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// 不需要令牌的方法
+var publicMethods = map[string]bool{
+	librarypb.CatalogService_ListBooks_FullMethodName: true,
+	librarypb.CatalogService_GetBook_FullMethodName:   true,
+	librarypb.StudentService_Login_FullMethodName:     true,
+	"/grpc.health.v1.Health/Check":                    true,
+	"/grpc.health.v1.Health/List":                     true,
+	"/grpc.health.v1.Health/Watch":                    true,
+}
+
+type principalKey struct{}
+
+// interceptors 每次调用前分配请求ID、协商语言、识别调用方并检查令牌，调用后记录日志并把错误转为gRPC状态
+type interceptors struct {
+	auth    *service.AuthService
+	timeout time.Duration
+}
+
+func (i *interceptors) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	start := time.Now()
+	ctx, err = i.prepare(ctx, info.FullMethod)
+	if err == nil {
+		if i.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, i.timeout)
+			defer cancel()
+		}
+		err = invoke(ctx, info.FullMethod, func(ctx context.Context) (err error) {
+			resp, err = handler(ctx, req)
+			return err
+		})
+	}
+	err = toStatus(ctx, info.FullMethod, err)
+	accessLog(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func (i *interceptors) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, err := i.prepare(ss.Context(), info.FullMethod)
+	if err == nil {
+		err = invoke(ctx, info.FullMethod, func(ctx context.Context) error {
+			return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		})
+	}
+	err = toStatus(ctx, info.FullMethod, err)
+	accessLog(ctx, info.FullMethod, start, err)
+	return err
+}
+
+// 保存请求ID、语言和操作人；不在publicMethods中的方法要求携带有效令牌
+func (i *interceptors) prepare(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := first(md, requestIDKey)
+	if !requestIDPattern.MatchString(id) {
+		id = logging.NewRequestID()
+	}
+	ctx = logging.WithRequestID(ctx, id)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+	ctx = i18n.WithLocale(ctx, i18n.Negotiate(first(md, "accept-language")))
+
+	actor := service.Actor{Role: do.ActorAnonymous, IP: peerIP(ctx)}
+	token, _ := strings.CutPrefix(first(md, "authorization"), "Bearer ")
+	var principal *service.Principal
+	var tokenErr error
+	if token != "" {
+		principal, tokenErr = i.auth.ParseToken(token)
+		if tokenErr == nil {
+			actor.Role = principal.Role
+			actor.ID = principal.Subject
+			ctx = context.WithValue(ctx, principalKey{}, principal)
+		}
+	}
+	ctx = service.WithActor(ctx, actor)
+
+	if publicMethods[method] {
+		return ctx, nil
+	}
+	if tokenErr != nil {
+		return ctx, tokenErr
+	}
+	if principal == nil {
+		return ctx, apperr.New(apperr.CodeUnauthorized)
+	}
+	return ctx, nil
+}
+
+// 执行处理函数，panic时记录调用栈并返回内部错误
+func invoke(ctx context.Context, method string, handler func(ctx context.Context) error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			slog.ErrorContext(ctx, "gRPC调用panic", "method", method, "panic", recovered, "stack", string(debug.Stack()))
+			err = apperr.New(apperr.CodeInternal)
+		}
+	}()
+	return handler(ctx)
+}
+
+// 每次调用结束后记录一条访问日志，服务端错误记为错误，其余失败记为警告
+func accessLog(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss, codes.Unimplemented:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+	slog.LogAttrs(ctx, level, "gRPC调用",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("client_ip", peerIP(ctx)),
+	)
+}
+
+// 当前调用的调用方，没有携带有效令牌时返回nil
+func currentPrincipal(ctx context.Context) *service.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*service.Principal)
+	return principal
+}
+
+// 检查调用方能否访问学生stuID的数据：学生只能访问本人的，馆员和管理员可以访问任何学生的
+func authorizeStudent(ctx context.Context, stuID string) error {
+	principal := currentPrincipal(ctx)
+	if principal == nil {
+		return apperr.New(apperr.CodeUnauthorized)
+	}
+	switch principal.Role {
+	case do.RoleLibrarian, do.RoleAdmin:
+		return nil
+	case service.RoleStudent:
+		if principal.Subject == stuID {
+			return nil
+		}
+	}
+	return apperr.New(apperr.CodeForbidden)
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// serverStream 替换流式调用的ctx
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,165 @@
+package rpc
+
+import (
+	"backend/apperr"
+	"backend/events"
+	"backend/librarypb"
+	"backend/service"
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"google.golang.org/grpc"
+)
+
+// loanServer 借阅，学生只能操作本人的借阅，馆员和管理员可以代任何学生操作
+type loanServer struct {
+	librarypb.UnimplementedLoanServiceServer
+	borrows *service.BorrowService
+	bus     *events.Bus
+	closing <-chan struct{}
+}
+
+// 借书，branch_id为空时从任一有可借单册的分馆借出
+func (s *loanServer) BorrowBook(ctx context.Context, req *librarypb.BorrowBookRequest) (*librarypb.Loan, error) {
+	if req.GetStuId() == "" || req.GetBookId() == "" {
+		return nil, apperr.Invalid("invalid.stu_id_book_id")
+	}
+	if err := authorizeStudent(ctx, req.GetStuId()); err != nil {
+		return nil, err
+	}
+	record, err := s.borrows.BorrowBook(ctx, req.GetStuId(), req.GetBookId(), req.GetBranchId())
+	if err != nil {
+		return nil, err
+	}
+	return loanMessage(record), nil
+}
+
+// 还书，返回产生的罚款
+func (s *loanServer) ReturnBook(ctx context.Context, req *librarypb.ReturnBookRequest) (*librarypb.ReturnBookResponse, error) {
+	if req.GetStuId() == "" || req.GetBookId() == "" {
+		return nil, apperr.Invalid("invalid.stu_id_book_id")
+	}
+	if err := authorizeStudent(ctx, req.GetStuId()); err != nil {
+		return nil, err
+	}
+	fine, err := s.borrows.ReturnBook(ctx, req.GetStuId(), req.GetBookId(), req.GetBranchId())
+	if err != nil {
+		return nil, err
+	}
+	return &librarypb.ReturnBookResponse{FineAmount: fine}, nil
+}
+
+// 列出学生的全部借阅记录，包括书名和作者
+func (s *loanServer) ListLoans(ctx context.Context, req *librarypb.ListLoansRequest) (*librarypb.ListLoansResponse, error) {
+	if req.GetStuId() == "" {
+		return nil, apperr.Invalid("invalid.stu_id_required")
+	}
+	if err := authorizeStudent(ctx, req.GetStuId()); err != nil {
+		return nil, err
+	}
+	rows, err := s.borrows.GetStudentBorrowRecordsWithBookInfo(ctx, req.GetStuId())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &librarypb.ListLoansResponse{Loans: make([]*librarypb.Loan, 0, len(rows))}
+	for _, row := range rows {
+		resp.Loans = append(resp.Loans, loanWithBookMessage(row))
+	}
+	return resp, nil
+}
+
+// 获取学生借阅某本书的记录
+func (s *loanServer) GetLoan(ctx context.Context, req *librarypb.GetLoanRequest) (*librarypb.Loan, error) {
+	if req.GetStuId() == "" || req.GetBookId() == "" {
+		return nil, apperr.Invalid("invalid.stu_id_book_id")
+	}
+	if err := authorizeStudent(ctx, req.GetStuId()); err != nil {
+		return nil, err
+	}
+	record, err := s.borrows.GetBorrowRecord(ctx, req.GetStuId(), req.GetBookId())
+	if err != nil {
+		return nil, err
+	}
+	return loanMessage(record), nil
+}
+
+// 缴清学生的全部罚款
+func (s *loanServer) PayFine(ctx context.Context, req *librarypb.PayFineRequest) (*librarypb.PayFineResponse, error) {
+	if req.GetStuId() == "" {
+		return nil, apperr.Invalid("invalid.stu_id_required")
+	}
+	if err := authorizeStudent(ctx, req.GetStuId()); err != nil {
+		return nil, err
+	}
+	if err := s.borrows.PayFine(ctx, req.GetStuId()); err != nil {
+		return nil, err
+	}
+	return &librarypb.PayFineResponse{}, nil
+}
+
+// 订阅学生的借还变化，直到客户端取消或服务退出。订阅生效后先发送响应头，
+// 客户端可以等到响应头再开始操作，避免错过事件；处理不过来的事件会被丢弃
+func (s *loanServer) WatchLoans(req *librarypb.WatchLoansRequest, stream grpc.ServerStreamingServer[librarypb.LoanUpdate]) error {
+	ctx := stream.Context()
+	if req.GetStuId() == "" {
+		return apperr.Invalid("invalid.stu_id_required")
+	}
+	if err := authorizeStudent(ctx, req.GetStuId()); err != nil {
+		return err
+	}
+
+	sub := s.bus.Subscribe(nil, req.GetStuId())
+	defer sub.Close()
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case event := <-sub.C:
+			update, ok := loanUpdate(ctx, event)
+			if !ok {
+				continue
+			}
+			if err := stream.Send(update); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		case <-s.closing:
+			return nil
+		}
+	}
+}
+
+// 把借还事件转为LoanUpdate，其他个人事件（如预约）返回false
+func loanUpdate(ctx context.Context, event *events.Event) (*librarypb.LoanUpdate, bool) {
+	var kind librarypb.LoanUpdate_Kind
+	switch event.Type {
+	case events.TypeLoanBorrowed:
+		kind = librarypb.LoanUpdate_KIND_BORROWED
+	case events.TypeLoanReturned:
+		kind = librarypb.LoanUpdate_KIND_RETURNED
+	default:
+		return nil, false
+	}
+
+	var change service.LoanChange
+	if err := json.Unmarshal(event.Data, &change); err != nil {
+		slog.ErrorContext(ctx, "解析借阅事件失败", "type", event.Type, "error", err)
+		return nil, false
+	}
+	return &librarypb.LoanUpdate{
+		Kind:       kind,
+		StuId:      event.StuID,
+		BookId:     change.BookID,
+		Barcode:    change.Barcode,
+		DueDate:    timestamp(change.DueDate),
+		ReturnDate: optionalTimestamp(change.ReturnDate),
+		IsOverdue:  change.IsOverdue,
+		FineAmount: change.FineAmount,
+		At:         timestamp(event.At),
+	}, true
+}
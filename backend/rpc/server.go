@@ -0,0 +1,79 @@
+// Package rpc gRPC接口，与HTTP接口共用业务层，接口定义见librarypb中的 .proto 文件
+// 令牌放在metadata的authorization中（Bearer <token>），与HTTP接口的令牌通用；出错时按apperr的错误码返回gRPC状态码，
+// 错误码放在状态的ErrorInfo详情中（reason），提示按metadata中accept-language协商的语言输出
+package rpc
+
+import (
+	"backend/events"
+	"backend/librarypb"
+	"backend/service"
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Deps gRPC接口使用的业务层
+type Deps struct {
+	Auth     *service.AuthService
+	Books    *service.BookService
+	Borrows  *service.BorrowService
+	Students *service.StudentService
+	Bus      *events.Bus
+	// 普通调用的处理时限，调用方设置的时限更短时以调用方为准；订阅类的流式调用不设时限
+	RequestTimeout time.Duration
+}
+
+// Server gRPC服务，同时提供标准的健康检查服务（grpc.health.v1.Health）
+type Server struct {
+	grpc   *grpc.Server
+	health *health.Server
+
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// 创建gRPC服务并注册全部接口，opts为额外的选项（如TLS证书）
+func New(deps Deps, opts ...grpc.ServerOption) *Server {
+	s := &Server{health: health.NewServer(), closing: make(chan struct{})}
+	interceptors := &interceptors{auth: deps.Auth, timeout: deps.RequestTimeout}
+	opts = append(opts,
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(interceptors.unary),
+		grpc.ChainStreamInterceptor(interceptors.stream),
+	)
+	s.grpc = grpc.NewServer(opts...)
+
+	librarypb.RegisterCatalogServiceServer(s.grpc, &catalogServer{books: deps.Books})
+	librarypb.RegisterLoanServiceServer(s.grpc, &loanServer{borrows: deps.Borrows, bus: deps.Bus, closing: s.closing})
+	librarypb.RegisterStudentServiceServer(s.grpc, &studentServer{auth: deps.Auth, students: deps.Students})
+	healthpb.RegisterHealthServer(s.grpc, s.health)
+	return s
+}
+
+// 在lis上提供服务，直到Shutdown
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpc.Serve(lis)
+}
+
+// 优雅退出：健康检查改为NOT_SERVING，结束订阅类的长连接，等待处理中的调用完成；ctx到期时强制关闭连接
+func (s *Server) Shutdown(ctx context.Context) {
+	s.health.Shutdown()
+	s.closeOnce.Do(func() { close(s.closing) })
+
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpc.Stop()
+	}
+}
@@ -0,0 +1,58 @@
+package rpc
+
+import (
+	"backend/apperr"
+	"backend/librarypb"
+	"backend/service"
+	"context"
+)
+
+// studentServer 学生登录和学生信息
+type studentServer struct {
+	librarypb.UnimplementedStudentServiceServer
+	auth     *service.AuthService
+	students *service.StudentService
+}
+
+// 学生登录，返回的令牌在后续调用的metadata中使用，也可用于HTTP接口
+func (s *studentServer) Login(ctx context.Context, req *librarypb.LoginRequest) (*librarypb.LoginResponse, error) {
+	if req.GetStuId() == "" {
+		return nil, apperr.Invalid("invalid.stu_id_required")
+	}
+	student, err := s.students.Authenticate(ctx, req.GetStuId(), req.GetPassword())
+	if err != nil {
+		return nil, err
+	}
+
+	canBorrow, message, err := s.students.CanStudentBorrow(ctx, student.StuId)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.auth.IssueToken(service.RoleStudent, student.StuId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &librarypb.LoginResponse{
+		Token:      token,
+		Student:    studentMessage(student),
+		CanBorrow:  canBorrow,
+		BorrowInfo: message,
+	}, nil
+}
+
+// 获取学生信息，学生只能获取本人的
+func (s *studentServer) GetStudent(ctx context.Context, req *librarypb.GetStudentRequest) (*librarypb.Student, error) {
+	if req.GetStuId() == "" {
+		return nil, apperr.Invalid("invalid.stu_id_required")
+	}
+	if err := authorizeStudent(ctx, req.GetStuId()); err != nil {
+		return nil, err
+	}
+	student, err := s.students.GetStudentInfo(ctx, req.GetStuId())
+	if err != nil {
+		return nil, err
+	}
+	return studentMessage(student), nil
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"backend/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handlers 控制器和鉴权中间件，/api/v1和旧版路由共用
+type handlers struct {
+	book         *controller.BookController
+	borrow       *controller.BorrowController
+	student      *controller.StudentController
+	librarian    *controller.LibrarianController
+	cover        *controller.CoverController
+	branch       *controller.BranchController
+	hold         *controller.HoldController
+	transfer     *controller.TransferController
+	circulation  *controller.CirculationController
+	kiosk        *controller.KioskController
+	calendar     *controller.CalendarController
+	notification *controller.NotificationController
+	webhook      *controller.WebhookController
+	audit        *controller.AuditController
+	stream       *controller.StreamController
+	graphql      *controller.GraphQLController
+
+	requireLibrarian gin.HandlerFunc
+	requireAdmin     gin.HandlerFunc
+	requireStudent   gin.HandlerFunc
+	requirePatron    gin.HandlerFunc // 学生、馆员或管理员
+	requireKiosk     gin.HandlerFunc
+}
+
+// 注册/api/v1的路由：按资源命名，学号等标识放在路径中，响应格式与旧版相同
+func registerRoutes(api *gin.RouterGroup, h *handlers) {
+	// 图书
+	books := api.Group("/books")
+	{
+		books.GET("", h.book.ListBooks)
+		books.GET("/:id", h.book.GetBookDetail)
+		books.GET("/:id/cover", h.cover.GetCover)
+		books.PUT("/:id/cover", h.requireLibrarian, h.cover.UploadCover)
+		books.DELETE("/:id/cover", h.requireLibrarian, h.cover.DeleteCover)
+		books.GET("/:id/items", h.requireLibrarian, h.branch.GetBookItems)
+		books.POST("/:id/items", h.requireLibrarian, h.branch.AddBookItem)
+	}
+
+	// 分馆
+	branches := api.Group("/branches")
+	{
+		branches.GET("", h.branch.GetAllBranches)
+		branches.POST("", h.requireLibrarian, h.branch.CreateBranch)
+	}
+
+	// 学生：学生本人的借阅、预约和罚款都在 /students/:id 下，学生只能访问本人的，馆员可以访问任何学生的
+	students := api.Group("/students")
+	{
+		studentAccess := controller.RequireStudentAccess("id")
+		students.GET("/:id", h.student.GetStudent)
+		students.PUT("/:id/pin", h.requireStudent, controller.RequireSelf("id"), h.student.SetStudentPIN)
+		students.GET("/:id/loans", h.requirePatron, studentAccess, h.borrow.GetStudentLoans)
+		students.GET("/:id/loans/:book_id", h.requirePatron, studentAccess, h.borrow.GetStudentLoan)
+		students.GET("/:id/holds", h.requirePatron, studentAccess, h.hold.ListStudentHolds)
+		students.POST("/:id/fine-payments", h.requirePatron, studentAccess, h.borrow.PayStudentFine)
+		students.GET("/:id/notification-preferences", h.requireStudent, controller.RequireSelf("id"), h.notification.GetPreference)
+		students.PUT("/:id/notification-preferences", h.requireStudent, controller.RequireSelf("id"), h.notification.SetPreference)
+	}
+
+	// 登录
+	sessions := api.Group("/sessions")
+	{
+		sessions.POST("/student", h.student.Login)
+		sessions.POST("/librarian", h.librarian.Login)
+	}
+
+	// 借书和还书：学生为本人借还，学号取自令牌；馆员代学生借还时在请求中指定stu_id
+	api.POST("/loans", h.requirePatron, h.borrow.BorrowBook)
+	api.POST("/returns", h.requirePatron, h.borrow.ReturnBook)
+
+	// 预约，规则同借书
+	holds := api.Group("/holds", h.requirePatron)
+	{
+		holds.POST("", h.hold.PlaceHold)
+		holds.POST("/:id/cancel", h.hold.CancelHold)
+	}
+
+	// 分馆调拨（馆员）
+	transfers := api.Group("/transfers", h.requireLibrarian)
+	{
+		transfers.POST("", h.transfer.RequestTransfer)
+		transfers.GET("", h.transfer.ListTransfers)
+		transfers.POST("/:id/ship", h.transfer.ShipTransfer)
+		transfers.POST("/:id/receive", h.transfer.ReceiveTransfer)
+	}
+
+	// 流通台（馆员）
+	circulation := api.Group("/circulation", h.requireLibrarian)
+	{
+		circulation.GET("/patrons/:stu_id", h.circulation.GetPatron)
+		circulation.POST("/checkouts", h.circulation.Checkout)
+		circulation.POST("/checkins", h.circulation.Checkin)
+		circulation.GET("/overrides", h.circulation.ListOverrides)
+	}
+
+	// 自助借还机（设备密钥认证）
+	kiosk := api.Group("/kiosk", h.requireKiosk)
+	{
+		kiosk.POST("/checkouts", h.kiosk.Checkout)
+		kiosk.POST("/returns", h.kiosk.Return)
+	}
+
+	// 自助借还机设备管理（管理员）
+	kiosks := api.Group("/kiosks", h.requireAdmin)
+	{
+		kiosks.GET("", h.kiosk.GetAllDevices)
+		kiosks.POST("", h.kiosk.RegisterDevice)
+		kiosks.POST("/:id/enable", h.kiosk.EnableDevice)
+		kiosks.POST("/:id/disable", h.kiosk.DisableDevice)
+		kiosks.POST("/:id/rotate-key", h.kiosk.RotateDeviceKey)
+		kiosks.PUT("/:id/rate-limit", h.kiosk.SetDeviceRateLimit)
+	}
+
+	// 开馆日历，修改仅管理员可用
+	calendar := api.Group("/calendar")
+	{
+		calendar.GET("", h.calendar.GetCalendar)
+		calendar.GET("/days/:date", h.calendar.GetDay)
+		calendar.PUT("/hours/:weekday", h.requireAdmin, h.calendar.SetOpeningHours)
+		calendar.POST("/closures", h.requireAdmin, h.calendar.AddClosure)
+		calendar.DELETE("/closures/:id", h.requireAdmin, h.calendar.DeleteClosure)
+	}
+
+	// 事件推送（管理员）
+	webhooks := api.Group("/webhooks", h.requireAdmin)
+	{
+		webhooks.GET("", h.webhook.GetEndpoints)
+		webhooks.POST("", h.webhook.RegisterEndpoint)
+		webhooks.POST("/:id/enable", h.webhook.EnableEndpoint)
+		webhooks.POST("/:id/disable", h.webhook.DisableEndpoint)
+		webhooks.GET("/deliveries", h.webhook.ListDeliveries)
+		webhooks.POST("/deliveries/:id/retry", h.webhook.RetryDelivery)
+	}
+
+	// 通知投递记录（馆员）
+	notifications := api.Group("/notifications", h.requireLibrarian)
+	{
+		notifications.GET("", h.notification.ListDeliveries)
+		notifications.POST("/:id/retry", h.notification.RetryDelivery)
+	}
+
+	// 审计日志（管理员）
+	api.GET("/audit", h.requireAdmin, h.audit.List)
+
+	// 实时事件推送（Server-Sent Events）
+	api.GET("/stream", h.stream.Stream)
+
+	// GraphQL，字段按令牌中的角色授权
+	api.POST("/graphql", h.graphql.Query)
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"backend/controller"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 旧版路由（没有/api/v1前缀）的弃用日期和停用日期，停用日期之后可能删除
+var legacyDeprecation = controller.Deprecation{
+	Since:  time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+	Sunset: time.Date(2027, 7, 1, 0, 0, 0, 0, time.UTC),
+}
+
+// 注册旧版路由，与/api/v1中对应的接口共用处理函数，请求和响应保持不变，
+// 另外返回Deprecation、Sunset响应头，Link指向/api/v1中对应的接口；行为由integration中的兼容性测试固定
+func registerLegacyRoutes(r *gin.Engine, h *handlers) {
+	successor := func(path string) gin.HandlerFunc {
+		return controller.Deprecated(legacyDeprecation, path)
+	}
+
+	// 图书相关路由
+	bookGroup := r.Group("/books")
+	{
+		bookGroup.GET("/search", successor("/api/v1/books?keyword=:keyword"), h.book.SearchBooks)
+		bookGroup.GET("/:id", successor("/api/v1/books/:id"), h.book.GetBookDetail)
+		bookGroup.GET("/list", successor("/api/v1/books"), h.book.GetAllBooks)
+		bookGroup.GET("/:id/cover", successor("/api/v1/books/:id/cover"), h.cover.GetCover)
+		bookGroup.POST("/:id/cover", successor("/api/v1/books/:id/cover"), h.requireLibrarian, h.cover.UploadCover)
+		bookGroup.DELETE("/:id/cover", successor("/api/v1/books/:id/cover"), h.requireLibrarian, h.cover.DeleteCover)
+		bookGroup.GET("/:id/items", successor("/api/v1/books/:id/items"), h.requireLibrarian, h.branch.GetBookItems)
+		bookGroup.POST("/:id/items", successor("/api/v1/books/:id/items"), h.requireLibrarian, h.branch.AddBookItem)
+	}
+
+	// 分馆相关路由
+	branchGroup := r.Group("/branches")
+	{
+		branchGroup.GET("", successor("/api/v1/branches"), h.branch.GetAllBranches)
+		branchGroup.POST("", successor("/api/v1/branches"), h.requireLibrarian, h.branch.CreateBranch)
+	}
+
+	// 预约相关路由
+	holdGroup := r.Group("/holds")
+	{
+		holdGroup.POST("", successor("/api/v1/holds"), h.hold.PlaceHold)
+		holdGroup.GET("", successor("/api/v1/students/:stu_id/holds"), h.hold.GetStudentHolds)
+		holdGroup.POST("/:id/cancel", successor("/api/v1/holds/:id/cancel"), h.hold.CancelHold)
+	}
+
+	// 分馆调拨路由（馆员）
+	transferGroup := r.Group("/transfers")
+	{
+		transferGroup.POST("", successor("/api/v1/transfers"), h.requireLibrarian, h.transfer.RequestTransfer)
+		transferGroup.GET("", successor("/api/v1/transfers"), h.requireLibrarian, h.transfer.ListTransfers)
+		transferGroup.POST("/:id/ship", successor("/api/v1/transfers/:id/ship"), h.requireLibrarian, h.transfer.ShipTransfer)
+		transferGroup.POST("/:id/receive", successor("/api/v1/transfers/:id/receive"), h.requireLibrarian, h.transfer.ReceiveTransfer)
+	}
+
+	// 借阅相关路由
+	borrowGroup := r.Group("/borrow")
+	{
+		borrowGroup.POST("/borrow", successor("/api/v1/loans"), h.borrow.BorrowBook)
+		borrowGroup.POST("/return", successor("/api/v1/returns"), h.borrow.ReturnBook)
+		borrowGroup.POST("/pay-fine", successor("/api/v1/students/:stu_id/fine-payments"), h.borrow.PayFine)
+		borrowGroup.GET("/record", successor("/api/v1/students/:stu_id/loans/:book_id"), h.borrow.GetBorrowRecord)
+		borrowGroup.GET("/records", successor("/api/v1/students/:stu_id/loans"), h.borrow.GetStudentBorrowRecords)
+	}
+
+	// 流通台路由（馆员）
+	circulationGroup := r.Group("/circulation")
+	{
+		circulationGroup.GET("/patrons/:stu_id", successor("/api/v1/circulation/patrons/:stu_id"), h.requireLibrarian, h.circulation.GetPatron)
+		circulationGroup.POST("/checkout", successor("/api/v1/circulation/checkouts"), h.requireLibrarian, h.circulation.Checkout)
+		circulationGroup.POST("/checkin", successor("/api/v1/circulation/checkins"), h.requireLibrarian, h.circulation.Checkin)
+		circulationGroup.GET("/overrides", successor("/api/v1/circulation/overrides"), h.requireLibrarian, h.circulation.ListOverrides)
+	}
+
+	// 自助借还机路由（设备密钥认证）
+	kioskGroup := r.Group("/kiosk")
+	{
+		kioskGroup.POST("/checkout", successor("/api/v1/kiosk/checkouts"), h.requireKiosk, h.kiosk.Checkout)
+		kioskGroup.POST("/return", successor("/api/v1/kiosk/returns"), h.requireKiosk, h.kiosk.Return)
+	}
+
+	// 自助借还机设备管理路由（管理员）
+	kioskAdminGroup := r.Group("/kiosks")
+	{
+		kioskAdminGroup.GET("", successor("/api/v1/kiosks"), h.requireAdmin, h.kiosk.GetAllDevices)
+		kioskAdminGroup.POST("", successor("/api/v1/kiosks"), h.requireAdmin, h.kiosk.RegisterDevice)
+		kioskAdminGroup.POST("/:id/enable", successor("/api/v1/kiosks/:id/enable"), h.requireAdmin, h.kiosk.EnableDevice)
+		kioskAdminGroup.POST("/:id/disable", successor("/api/v1/kiosks/:id/disable"), h.requireAdmin, h.kiosk.DisableDevice)
+		kioskAdminGroup.POST("/:id/rotate-key", successor("/api/v1/kiosks/:id/rotate-key"), h.requireAdmin, h.kiosk.RotateDeviceKey)
+		kioskAdminGroup.PUT("/:id/rate-limit", successor("/api/v1/kiosks/:id/rate-limit"), h.requireAdmin, h.kiosk.SetDeviceRateLimit)
+	}
+
+	// 开馆日历路由，修改仅管理员可用
+	calendarGroup := r.Group("/calendar")
+	{
+		calendarGroup.GET("", successor("/api/v1/calendar"), h.calendar.GetCalendar)
+		calendarGroup.GET("/days/:date", successor("/api/v1/calendar/days/:date"), h.calendar.GetDay)
+		calendarGroup.PUT("/hours/:weekday", successor("/api/v1/calendar/hours/:weekday"), h.requireAdmin, h.calendar.SetOpeningHours)
+		calendarGroup.POST("/closures", successor("/api/v1/calendar/closures"), h.requireAdmin, h.calendar.AddClosure)
+		calendarGroup.DELETE("/closures/:id", successor("/api/v1/calendar/closures/:id"), h.requireAdmin, h.calendar.DeleteClosure)
+	}
+
+	// 学生相关路由
+	studentGroup := r.Group("/student")
+	{
+		studentGroup.POST("/login", successor("/api/v1/sessions/student"), h.student.Login)
+		studentGroup.GET("/info", successor("/api/v1/students/:stu_id"), h.student.GetStudentInfo)
+		studentGroup.POST("/pin", successor("/api/v1/students/:stu_id/pin"), h.student.SetPIN)
+		studentGroup.GET("/notifications", successor("/api/v1/students/:stu_id/notification-preferences"), h.requireStudent, h.notification.GetPreference)
+		studentGroup.PUT("/notifications", successor("/api/v1/students/:stu_id/notification-preferences"), h.requireStudent, h.notification.SetPreference)
+	}
+
+	// 事件推送路由（管理员）
+	webhookGroup := r.Group("/webhooks")
+	{
+		webhookGroup.GET("", successor("/api/v1/webhooks"), h.requireAdmin, h.webhook.GetEndpoints)
+		webhookGroup.POST("", successor("/api/v1/webhooks"), h.requireAdmin, h.webhook.RegisterEndpoint)
+		webhookGroup.POST("/:id/enable", successor("/api/v1/webhooks/:id/enable"), h.requireAdmin, h.webhook.EnableEndpoint)
+		webhookGroup.POST("/:id/disable", successor("/api/v1/webhooks/:id/disable"), h.requireAdmin, h.webhook.DisableEndpoint)
+		webhookGroup.GET("/deliveries", successor("/api/v1/webhooks/deliveries"), h.requireAdmin, h.webhook.ListDeliveries)
+		webhookGroup.POST("/deliveries/:id/retry", successor("/api/v1/webhooks/deliveries/:id/retry"), h.requireAdmin, h.webhook.RetryDelivery)
+	}
+
+	// 审计日志路由（管理员）
+	r.GET("/audit", successor("/api/v1/audit"), h.requireAdmin, h.audit.List)
+
+	// 通知投递记录路由（馆员）
+	notificationGroup := r.Group("/notifications")
+	{
+		notificationGroup.GET("", successor("/api/v1/notifications"), h.requireLibrarian, h.notification.ListDeliveries)
+		notificationGroup.POST("/:id/retry", successor("/api/v1/notifications/:id/retry"), h.requireLibrarian, h.notification.RetryDelivery)
+	}
+
+	// 馆员相关路由
+	librarianGroup := r.Group("/librarian")
+	{
+		librarianGroup.POST("/login", successor("/api/v1/sessions/librarian"), h.librarian.Login)
+	}
+
+	// 实时事件推送（Server-Sent Events）
+	r.GET("/stream", successor("/api/v1/stream"), h.stream.Stream)
+}
@@ -0,0 +1,191 @@
+// Package server 组装业务层、控制器和路由
+// 数据库、封面存储、邮件发送和事件总线等外部资源由main按配置创建后传入，集成测试用同样的方式得到完整的路由
+package server
+
+import (
+	"backend/config"
+	"backend/controller"
+	"backend/dao"
+	"backend/do"
+	"backend/events"
+	"backend/graph"
+	"backend/migrations"
+	"backend/notify"
+	"backend/rpc"
+	"backend/service"
+	"backend/storage"
+	"database/sql"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Deps 组装服务需要的外部资源
+type Deps struct {
+	Config *config.Config
+	DB     *sql.DB
+	Bus    *events.Bus
+	Covers storage.Storage
+	// 为nil时不发送邮件通知
+	Mail       notify.Sender
+	AuthSecret []byte
+}
+
+// Server 组装好的路由，以及需要由main单独启动或关闭的部分
+type Server struct {
+	Router        *gin.Engine
+	Health        *service.HealthService
+	Notifications *service.NotificationService
+	Webhooks      *service.WebhookService
+	Streams       *controller.StreamController
+	// gRPC接口，与路由共用业务层；是否监听由main按grpc.listen决定
+	GRPC *rpc.Server
+}
+
+func New(deps Deps) (*Server, error) {
+	cfg, db, bus := deps.Config, deps.DB, deps.Bus
+
+	// 初始化服务
+	store := dao.NewStore(db)
+	authService := service.NewAuthService(deps.AuthSecret, cfg.Auth.TokenTTL.Std())
+	bookService := service.NewBookService(store)
+	borrowService := service.NewBorrowService(store, bus)
+	studentService := service.NewStudentService(store)
+	librarianService := service.NewLibrarianService(db)
+	coverService := service.NewCoverService(db, deps.Covers)
+	branchService := service.NewBranchService(db)
+	holdService := service.NewHoldService(db, bus)
+	transferService := service.NewTransferService(db, bus)
+	circulationService := service.NewCirculationService(db, bus)
+	kioskService := service.NewKioskService(db, bus)
+	calendarService := service.NewCalendarService(db)
+	notificationService := service.NewNotificationService(db, deps.Mail)
+	webhookService := service.NewWebhookService(db)
+	auditService := service.NewAuditService(db)
+	migrator, err := migrations.NewMigrator(db, cfg.Database.Driver)
+	if err != nil {
+		return nil, err
+	}
+	healthService := service.NewHealthService(db, migrator)
+
+	// 初始化控制器
+	streamController := controller.NewStreamController(bus, authService)
+	healthController := controller.NewHealthController(healthService)
+	docsController, err := controller.NewDocsController()
+	if err != nil {
+		return nil, err
+	}
+	graphSchema, err := graph.New(graph.Deps{
+		Books:  bookService,
+		Lookup: service.NewLookupService(db),
+		Limits: graph.Limits{MaxDepth: cfg.GraphQL.MaxDepth, MaxComplexity: cfg.GraphQL.MaxComplexity},
+	})
+	if err != nil {
+		return nil, err
+	}
+	h := &handlers{
+		book:             controller.NewBookController(bookService),
+		borrow:           controller.NewBorrowController(borrowService),
+		student:          controller.NewStudentController(studentService, authService),
+		librarian:        controller.NewLibrarianController(librarianService, authService),
+		cover:            controller.NewCoverController(coverService),
+		branch:           controller.NewBranchController(branchService),
+		hold:             controller.NewHoldController(holdService),
+		transfer:         controller.NewTransferController(transferService),
+		circulation:      controller.NewCirculationController(circulationService),
+		kiosk:            controller.NewKioskController(kioskService),
+		calendar:         controller.NewCalendarController(calendarService),
+		notification:     controller.NewNotificationController(notificationService),
+		webhook:          controller.NewWebhookController(webhookService),
+		audit:            controller.NewAuditController(auditService),
+		stream:           streamController,
+		graphql:          controller.NewGraphQLController(graphSchema, authService),
+		requireLibrarian: controller.RequireRoles(authService, do.RoleLibrarian, do.RoleAdmin),
+		requireAdmin:     controller.RequireRoles(authService, do.RoleAdmin),
+		requireStudent:   controller.RequireRoles(authService, service.RoleStudent),
+		requirePatron:    controller.RequireRoles(authService, service.RoleStudent, do.RoleLibrarian, do.RoleAdmin),
+		requireKiosk:     controller.RequireKiosk(kioskService),
+	}
+
+	// 创建Gin路由，使用结构化日志记录访问日志和panic，不使用gin默认的Logger和Recovery
+	r := gin.New()
+	r.Use(controller.Recovery(), controller.RequestID(), controller.Tracing(), controller.AccessLog(), controller.Metrics())
+
+	// 配置CORS中间件
+	r.Use(cors.New(cors.Config{
+		AllowOrigins:     cfg.CORS.AllowOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Device-Key", "X-Request-ID", "traceparent", "tracestate"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID", "Deprecation", "Sunset", "Link"},
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	// 请求处理时限：上传封面允许更长时间，实时推送是长连接，不设时限
+	r.Use(controller.RequestTimeout(cfg.Server.RequestTimeout.Std(), map[string]time.Duration{
+		"PUT /api/v1/books/:id/cover": cfg.Server.UploadTimeout.Std(),
+		"GET /api/v1/stream":          0,
+		"POST /books/:id/cover":       cfg.Server.UploadTimeout.Std(),
+		"GET /stream":                 0,
+	}))
+
+	// 统一错误响应，需要在RequestTimeout之后注册，才能判断出错时请求是否已超时
+	r.Use(controller.ErrorHandler())
+	r.NoRoute(controller.NotFound)
+
+	// 按lang参数、学生的语言设置或Accept-Language协商提示的语言
+	r.Use(controller.Localize(authService, notificationService))
+
+	// 识别操作人，写入审计日志
+	r.Use(controller.Identify(authService))
+
+	// 路由：/api/v1为当前版本，没有前缀的旧版路由保留为已弃用的别名
+	registerRoutes(r.Group("/api/v1"), h)
+	registerLegacyRoutes(r, h)
+
+	// Prometheus指标，指标的注册在main中
+	if cfg.Metrics.Enabled {
+		r.GET("/metrics", controller.MetricsHandler(cfg.Metrics.Token))
+	}
+
+	// 健康检查：live为存活检查，ready和/health为就绪检查
+	r.GET("/health", healthController.Ready)
+	r.GET("/health/live", healthController.Live)
+	r.GET("/health/ready", healthController.Ready)
+
+	// 接口文档：OpenAPI文档和Swagger UI页面
+	r.GET("/openapi.yaml", docsController.SpecYAML)
+	r.GET("/openapi.json", docsController.SpecJSON)
+	r.GET("/docs", docsController.Page)
+	r.GET("/docs/assets/*filepath", docsController.Assets)
+
+	// gRPC接口，设置了server.tls时使用同一证书
+	var grpcOpts []grpc.ServerOption
+	if cfg.Server.TLS.Enabled() {
+		creds, err := credentials.NewServerTLSFromFile(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(creds))
+	}
+	grpcServer := rpc.New(rpc.Deps{
+		Auth:           authService,
+		Books:          bookService,
+		Borrows:        borrowService,
+		Students:       studentService,
+		Bus:            bus,
+		RequestTimeout: cfg.Server.RequestTimeout.Std(),
+	}, grpcOpts...)
+
+	return &Server{
+		Router:        r,
+		Health:        healthService,
+		Notifications: notificationService,
+		Webhooks:      webhookService,
+		Streams:       streamController,
+		GRPC:          grpcServer,
+	}, nil
+}
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/i18n"
+	"backend/repository"
+	"context"
+	"strings"
+	"time"
+)
+
+// AdminService 运维操作：新建学生、重置密码、调整馆藏、减免罚款、逾期扫描、批量导入导出和报表，
+// 供命令行管理工具librarian使用；修改数据的操作写入审计日志
+type AdminService struct {
+	store repository.Store
+}
+
+func NewAdminService(store repository.Store) *AdminService {
+	return &AdminService{store: store}
+}
+
+// bookCopies 审计日志中记录的馆藏数量
+type bookCopies struct {
+	TotalCopies     int `json:"total_copies"`
+	AvailableCopies int `json:"available_copies"`
+}
+
+// FineWaiver 一次罚款减免
+type FineWaiver struct {
+	StuID   string  `json:"stu_id"`
+	Records int     `json:"records"`
+	Amount  float64 `json:"amount"`
+}
+
+// OverdueSweep 一次逾期扫描的结果
+type OverdueSweep struct {
+	OverdueLoans int     `json:"overdue_loans"` // 已过应还日期仍未归还的借阅
+	Updated      int     `json:"updated"`       // 逾期状态或罚款有变化的借阅
+	TotalFines   float64 `json:"total_fines"`   // 这些借阅截至扫描时的罚款合计
+}
+
+// OverdueLoan 逾期报表中的一条借阅，罚款按截至查询时的逾期天数计算
+type OverdueLoan struct {
+	RecordID    int       `json:"record_id"`
+	StuID       string    `json:"stu_id"`
+	StudentName string    `json:"student_name"`
+	BookID      string    `json:"book_id"`
+	Title       string    `json:"title"`
+	Barcode     string    `json:"barcode,omitempty"`
+	DueDate     time.Time `json:"due_date"`
+	DaysOverdue int       `json:"days_overdue"`
+	FineAmount  float64   `json:"fine_amount"`
+}
+
+// 新建学生，学号已存在时返回STUDENT_EXISTS
+func (s *AdminService) CreateStudent(ctx context.Context, student *do.Student) error {
+	return s.store.Transaction(ctx, func(tx repository.Repositories) error {
+		return createStudent(ctx, tx, student)
+	})
+}
+
+// 批量导入学生，在同一事务中执行，任一条失败时全部不导入；返回导入的数量
+func (s *AdminService) ImportStudents(ctx context.Context, students []do.Student) (int, error) {
+	err := s.store.Transaction(ctx, func(tx repository.Repositories) error {
+		for i := range students {
+			if err := createStudent(ctx, tx, &students[i]); err != nil {
+				return importError(ctx, i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(students), nil
+}
+
+// 在事务中新建学生，审计日志中不记录密码
+func createStudent(ctx context.Context, tx repository.Repositories, student *do.Student) error {
+	student.StuId = strings.TrimSpace(student.StuId)
+	student.Name = strings.TrimSpace(student.Name)
+	switch {
+	case student.StuId == "":
+		return apperr.Invalid("invalid.stu_id_required")
+	case student.Name == "":
+		return apperr.Invalid("invalid.student_name_required")
+	case student.Password == "":
+		return apperr.Invalid("invalid.password_required")
+	}
+	if student.CreatedAt.IsZero() {
+		student.CreatedAt = time.Now()
+	}
+
+	students := tx.Students()
+	if _, err := students.GetStudentByID(ctx, student.StuId); err == nil {
+		return apperr.New(apperr.CodeStudentExists)
+	} else if !apperr.Is(err, apperr.CodeStudentNotFound) {
+		return err
+	}
+	if err := students.CreateStudent(ctx, student); err != nil {
+		return err
+	}
+
+	after := *student
+	after.Password = ""
+	return recordAudit(ctx, tx, do.AuditStudentCreate, do.AuditEntityStudent, student.StuId, nil, after)
+}
+
+// 重置学生的登录密码，审计日志中只记录操作，不记录密码
+func (s *AdminService) ResetPassword(ctx context.Context, stuID, password string) error {
+	if password == "" {
+		return apperr.Invalid("invalid.password_required")
+	}
+
+	return s.store.Transaction(ctx, func(tx repository.Repositories) error {
+		students := tx.Students()
+		if _, err := students.GetStudentByID(ctx, stuID); err != nil {
+			return err
+		}
+		if err := students.UpdateStudentPassword(ctx, stuID, password); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, do.AuditPasswordReset, do.AuditEntityStudent, stuID, nil, nil)
+	})
+}
+
+// 批量导入书籍，在同一事务中执行，任一条失败时全部不导入；返回导入的数量
+func (s *AdminService) ImportBooks(ctx context.Context, books []do.Book) (int, error) {
+	err := s.store.Transaction(ctx, func(tx repository.Repositories) error {
+		for i := range books {
+			if err := createBook(ctx, tx, &books[i]); err != nil {
+				return importError(ctx, i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(books), nil
+}
+
+// 在事务中新增书籍，书籍ID已存在时返回BOOK_EXISTS
+func createBook(ctx context.Context, tx repository.Repositories, book *do.Book) error {
+	book.BookID = strings.TrimSpace(book.BookID)
+	switch {
+	case book.BookID == "":
+		return apperr.Invalid("invalid.book_id_required")
+	case strings.TrimSpace(book.Title) == "" || strings.TrimSpace(book.Author) == "":
+		return apperr.Invalid("invalid.book_title_required")
+	case book.TotalCopies < 0:
+		return apperr.Invalid("invalid.total_copies").With("on_loan", 0)
+	case book.AvailableCopies < 0 || book.AvailableCopies > book.TotalCopies:
+		return apperr.Invalid("invalid.available_copies")
+	}
+	if book.CreatedAt.IsZero() {
+		book.CreatedAt = time.Now()
+	}
+
+	books := tx.Books()
+	if _, err := books.GetBookByID(ctx, book.BookID); err == nil {
+		return apperr.New(apperr.CodeBookExists)
+	} else if !apperr.Is(err, apperr.CodeBookNotFound) {
+		return err
+	}
+	if err := books.CreateBook(ctx, book); err != nil {
+		return err
+	}
+	return recordAudit(ctx, tx, do.AuditBookCreate, do.AuditEntityBook, book.BookID, nil, book)
+}
+
+// 批量导入中第i条记录失败，保留原错误码，提示中注明是第几条
+func importError(ctx context.Context, i int, err error) error {
+	appErr := apperr.From(err)
+	if appErr.Code == apperr.CodeInternal {
+		return err
+	}
+	return apperr.NewMessage(appErr.Code, "error.import_record").
+		With("index", i+1).
+		With("detail", appErr.Localize(i18n.Locale(ctx)))
+}
+
+// 调整没有登记单册的书籍的总馆藏数量，可借数量随之增减，已借出的数量不变；
+// 登记了单册的书籍按单册统计，返回BOOK_HAS_ITEMS
+func (s *AdminService) SetBookCopies(ctx context.Context, bookID string, totalCopies int) (*do.Book, error) {
+	var book *do.Book
+	err := s.store.Transaction(ctx, func(tx repository.Repositories) error {
+		books := tx.Books()
+		var err error
+		if book, err = books.GetBookByID(ctx, bookID); err != nil {
+			return err
+		}
+		itemCount, err := tx.BookItems().CountItems(ctx, bookID)
+		if err != nil {
+			return err
+		}
+		if itemCount > 0 {
+			return apperr.New(apperr.CodeBookHasItems)
+		}
+
+		onLoan := max(book.TotalCopies-book.AvailableCopies, 0)
+		if totalCopies < onLoan {
+			return apperr.Invalid("invalid.total_copies").With("on_loan", onLoan)
+		}
+		before := bookCopies{TotalCopies: book.TotalCopies, AvailableCopies: book.AvailableCopies}
+		book.TotalCopies = totalCopies
+		book.AvailableCopies = totalCopies - onLoan
+		if err := books.UpdateBookCopies(ctx, bookID, book.TotalCopies, book.AvailableCopies); err != nil {
+			return err
+		}
+		after := bookCopies{TotalCopies: book.TotalCopies, AvailableCopies: book.AvailableCopies}
+		return recordAudit(ctx, tx, do.AuditBookCopies, do.AuditEntityBook, bookID, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	fillCoverURLs(book)
+	return book, nil
+}
+
+// 减免学生名下的全部罚款并恢复借阅权限，没有罚款时返回NO_FINE_DUE；
+// 仍未归还的逾期借阅在下次逾期扫描或还书时按逾期天数重新计算罚款
+func (s *AdminService) WaiveFines(ctx context.Context, stuID string) (*FineWaiver, error) {
+	waiver := &FineWaiver{StuID: stuID}
+	err := s.store.Transaction(ctx, func(tx repository.Repositories) error {
+		if _, err := tx.Students().GetStudentByID(ctx, stuID); err != nil {
+			return err
+		}
+		borrows := tx.Borrows()
+		records, err := borrows.GetStudentFinedRecords(ctx, stuID)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return apperr.New(apperr.CodeNoFineDue)
+		}
+
+		waiver.Records = len(records)
+		for _, record := range records {
+			waiver.Amount += record.FineAmount
+			if err := borrows.UpdateFine(ctx, record.ID, record.IsOverdue, 0); err != nil {
+				return err
+			}
+		}
+
+		if err := setBorrowPermission(ctx, tx, stuID, true); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, do.AuditFineWaive, do.AuditEntityStudent, stuID, nil, waiver)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return waiver, nil
+}
+
+// 逾期扫描：按截至now的逾期天数更新未归还借阅的逾期状态和罚款，算法与还书时一致；
+// 有罚款的未归还借阅会阻止学生继续借书
+func (s *AdminService) SweepOverdue(ctx context.Context, now time.Time) (*OverdueSweep, error) {
+	sweep := &OverdueSweep{}
+	err := s.store.Transaction(ctx, func(tx repository.Repositories) error {
+		borrows := tx.Borrows()
+		records, err := borrows.GetOverdueBorrowRecords(ctx, now)
+		if err != nil {
+			return err
+		}
+		sweep.OverdueLoans = len(records)
+		if len(records) == 0 {
+			return nil
+		}
+
+		// 记录按应还日期排序，第一条最早
+		calendar, err := loadCalendar(ctx, tx.Calendar(), records[0].DueDate, now)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			isOverdue, fineAmount := calculateFine(calendar, record.DueDate, now)
+			sweep.TotalFines += fineAmount
+			if isOverdue == record.IsOverdue && fineAmount == record.FineAmount {
+				continue
+			}
+			if err := borrows.UpdateFine(ctx, record.ID, isOverdue, fineAmount); err != nil {
+				return err
+			}
+			sweep.Updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sweep, nil
+}
+
+// 逾期报表：截至now已过应还日期仍未归还的借阅，按应还日期排序
+func (s *AdminService) OverdueReport(ctx context.Context, now time.Time) ([]OverdueLoan, error) {
+	records, err := s.store.Borrows().GetOverdueBorrowRecords(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return []OverdueLoan{}, nil
+	}
+
+	var bookIDs, stuIDs []string
+	for _, record := range records {
+		bookIDs = append(bookIDs, record.BookID)
+		stuIDs = append(stuIDs, record.StuID)
+	}
+	books, err := s.store.Books().GetBooksByIDs(ctx, bookIDs)
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[string]string, len(books))
+	for _, book := range books {
+		titles[book.BookID] = book.Title
+	}
+	students, err := s.store.Students().GetStudentsByIDs(ctx, stuIDs)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(students))
+	for _, student := range students {
+		names[student.StuId] = student.Name
+	}
+
+	calendar, err := loadCalendar(ctx, s.store.Calendar(), records[0].DueDate, now)
+	if err != nil {
+		return nil, err
+	}
+	report := make([]OverdueLoan, 0, len(records))
+	for _, record := range records {
+		_, fineAmount := calculateFine(calendar, record.DueDate, now)
+		report = append(report, OverdueLoan{
+			RecordID:    record.ID,
+			StuID:       record.StuID,
+			StudentName: names[record.StuID],
+			BookID:      record.BookID,
+			Title:       titles[record.BookID],
+			Barcode:     record.Barcode,
+			DueDate:     record.DueDate,
+			DaysOverdue: calendar.OverdueDays(record.DueDate, now),
+			FineAmount:  fineAmount,
+		})
+	}
+	return report, nil
+}
+
+// 导出全部学生，不含密码
+func (s *AdminService) ExportStudents(ctx context.Context) ([]do.Student, error) {
+	return s.store.Students().GetAllStudents(ctx)
+}
+
+// 导出全部书籍
+func (s *AdminService) ExportBooks(ctx context.Context) ([]do.Book, error) {
+	return s.store.Books().GetAllBooks(ctx)
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func adminContext() context.Context {
+	return WithActor(context.Background(), Actor{Role: do.ActorCLI, ID: "operator"})
+}
+
+func auditActions(entries []do.AuditEntry) []string {
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+	}
+	return actions
+}
+
+// 批量导入中任一条有误时整批回滚，错误保留原错误码并注明第几条
+func TestImportStudentsErrorRows(t *testing.T) {
+	ctx := adminContext()
+	for _, tt := range []struct {
+		name     string
+		students []do.Student
+		code     apperr.Code
+		index    int
+	}{
+		{"缺少学号", []do.Student{{StuId: "N1", Name: "甲", Password: "p"}, {StuId: " ", Name: "乙", Password: "p"}}, apperr.CodeInvalidArgument, 2},
+		{"缺少姓名", []do.Student{{StuId: "N1", Password: "p"}}, apperr.CodeInvalidArgument, 1},
+		{"缺少密码", []do.Student{{StuId: "N1", Name: "甲"}, {StuId: "N2", Name: "乙", Password: "p"}}, apperr.CodeInvalidArgument, 1},
+		{"学号已存在", []do.Student{{StuId: "N1", Name: "甲", Password: "p"}, {StuId: "S1", Name: "重复", Password: "p"}}, apperr.CodeStudentExists, 2},
+		{"批内学号重复", []do.Student{{StuId: "N1", Name: "甲", Password: "p"}, {StuId: "N2", Name: "乙", Password: "p"}, {StuId: "N1", Name: "丙", Password: "p"}}, apperr.CodeStudentExists, 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestLibrary()
+			imported, err := NewAdminService(store).ImportStudents(ctx, tt.students)
+			if !apperr.Is(err, tt.code) || apperr.From(err).Params["index"] != tt.index || imported != 0 {
+				t.Fatalf("导入 = %d, %v，期望第%d条 %s", imported, err, tt.index, tt.code)
+			}
+			for _, stuID := range []string{"N1", "N2"} {
+				if _, err := store.Students().GetStudentByID(ctx, stuID); !apperr.Is(err, apperr.CodeStudentNotFound) {
+					t.Errorf("导入失败后 %s = %v，期望不存在", stuID, err)
+				}
+			}
+			if entries := store.AuditEntries(); len(entries) != 0 {
+				t.Errorf("导入失败后仍有审计日志 %v", auditActions(entries))
+			}
+		})
+	}
+
+	store := newTestLibrary()
+	students := []do.Student{{StuId: " N1 ", Name: " 甲 ", Password: "p1"}, {StuId: "N2", Name: "乙", Password: "p2", CanBorrow: true}}
+	imported, err := NewAdminService(store).ImportStudents(ctx, students)
+	if err != nil || imported != 2 {
+		t.Fatalf("导入 = %d, %v", imported, err)
+	}
+	student, err := store.Students().GetStudentByID(ctx, "N1")
+	if err != nil || student.Name != "甲" || student.Password != "p1" {
+		t.Errorf("导入的学生 = %+v, %v，期望去掉首尾空格", student, err)
+	}
+	entries := store.AuditEntries()
+	if !equalStrings(auditActions(entries), []string{do.AuditStudentCreate, do.AuditStudentCreate}) {
+		t.Fatalf("审计日志 = %v", auditActions(entries))
+	}
+	if entries[0].ActorRole != do.ActorCLI || entries[0].EntityID != "N1" || len(entries[0].After) == 0 {
+		t.Errorf("审计日志 = %+v", entries[0])
+	}
+	for _, entry := range entries {
+		if strings.Contains(string(entry.After), "p1") || strings.Contains(string(entry.After), "p2") {
+			t.Errorf("审计日志中有密码: %s", entry.After)
+		}
+	}
+}
+
+func TestImportBooksErrorRows(t *testing.T) {
+	ctx := adminContext()
+	valid := do.Book{BookID: "N1", Title: "编译原理", Author: "周八", TotalCopies: 2, AvailableCopies: 2, CanBorrow: true}
+	for _, tt := range []struct {
+		name string
+		book do.Book
+		code apperr.Code
+	}{
+		{"缺少书籍ID", do.Book{Title: "书", Author: "作者"}, apperr.CodeInvalidArgument},
+		{"缺少书名", do.Book{BookID: "N2", Author: "作者"}, apperr.CodeInvalidArgument},
+		{"缺少作者", do.Book{BookID: "N2", Title: "书", Author: " "}, apperr.CodeInvalidArgument},
+		{"总馆藏为负数", do.Book{BookID: "N2", Title: "书", Author: "作者", TotalCopies: -1}, apperr.CodeInvalidArgument},
+		{"可借数量超过总馆藏", do.Book{BookID: "N2", Title: "书", Author: "作者", TotalCopies: 1, AvailableCopies: 2}, apperr.CodeInvalidArgument},
+		{"可借数量为负数", do.Book{BookID: "N2", Title: "书", Author: "作者", TotalCopies: 1, AvailableCopies: -1}, apperr.CodeInvalidArgument},
+		{"书籍ID已存在", do.Book{BookID: "B1", Title: "书", Author: "作者"}, apperr.CodeBookExists},
+		{"批内书籍ID重复", valid, apperr.CodeBookExists},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestLibrary()
+			imported, err := NewAdminService(store).ImportBooks(ctx, []do.Book{valid, tt.book})
+			if !apperr.Is(err, tt.code) || apperr.From(err).Params["index"] != 2 || imported != 0 {
+				t.Fatalf("导入 = %d, %v，期望第2条 %s", imported, err, tt.code)
+			}
+			if _, err := store.Books().GetBookByID(ctx, "N1"); !apperr.Is(err, apperr.CodeBookNotFound) {
+				t.Errorf("导入失败后 N1 = %v，期望不存在", err)
+			}
+			if entries := store.AuditEntries(); len(entries) != 0 {
+				t.Errorf("导入失败后仍有审计日志 %v", auditActions(entries))
+			}
+		})
+	}
+
+	store := newTestLibrary()
+	imported, err := NewAdminService(store).ImportBooks(ctx, []do.Book{valid})
+	if err != nil || imported != 1 {
+		t.Fatalf("导入 = %d, %v", imported, err)
+	}
+	if book, err := store.Books().GetBookByID(ctx, "N1"); err != nil || book.AvailableCopies != 2 || book.CreatedAt.IsZero() {
+		t.Errorf("导入的书籍 = %+v, %v", book, err)
+	}
+	if actions := auditActions(store.AuditEntries()); !equalStrings(actions, []string{do.AuditBookCreate}) {
+		t.Errorf("审计日志 = %v", actions)
+	}
+}
+
+// 减免已归还和未归还借阅的罚款，恢复借阅权限；未归还借阅的逾期状态保留
+func TestWaiveFines(t *testing.T) {
+	ctx := adminContext()
+	store := newTestLibrary()
+	s := NewAdminService(store)
+
+	now := time.Now()
+	dueDate := now.AddDate(0, 0, -5)
+	returned := now.AddDate(0, 0, -1)
+	store.AddBorrowRecord(do.BorrowRecord{StuID: "S2", BookID: "B1", BorrowDate: dueDate.AddDate(0, -2, 0), DueDate: dueDate, ReturnDate: &returned, IsOverdue: true, FineAmount: 2})
+	store.AddBorrowRecord(do.BorrowRecord{StuID: "S2", BookID: "B2", BorrowDate: dueDate.AddDate(0, -2, 0), DueDate: dueDate, IsOverdue: true, FineAmount: 1.5})
+	store.AddBorrowRecord(do.BorrowRecord{StuID: "S3", BookID: "B1", BorrowDate: dueDate.AddDate(0, -2, 0), DueDate: dueDate, IsOverdue: true, FineAmount: 3})
+
+	waiver, err := s.WaiveFines(ctx, "S2")
+	if err != nil {
+		t.Fatalf("减免罚款失败: %v", err)
+	}
+	if *waiver != (FineWaiver{StuID: "S2", Records: 2, Amount: 3.5}) {
+		t.Errorf("减免 = %+v", *waiver)
+	}
+	for _, record := range store.BorrowRecords() {
+		switch {
+		case record.StuID == "S2" && record.FineAmount != 0:
+			t.Errorf("减免后借阅记录 %d 罚款 = %v", record.ID, record.FineAmount)
+		case record.StuID == "S2" && !record.IsOverdue:
+			t.Errorf("减免后借阅记录 %d 不再记为逾期", record.ID)
+		case record.StuID == "S3" && record.FineAmount != 3:
+			t.Errorf("其他学生的罚款被减免: %+v", record)
+		}
+	}
+	if canBorrow, _, err := NewStudentService(store).CanStudentBorrow(ctx, "S2"); err != nil || !canBorrow {
+		t.Errorf("减免后可借 = %v, %v，期望可借", canBorrow, err)
+	}
+	actions := auditActions(store.AuditEntries())
+	if !equalStrings(actions, []string{do.AuditBorrowPermission, do.AuditFineWaive}) {
+		t.Errorf("审计日志 = %v，期望恢复借阅权限和减免罚款", actions)
+	}
+
+	if _, err := s.WaiveFines(ctx, "S2"); !apperr.Is(err, apperr.CodeNoFineDue) {
+		t.Errorf("再次减免 = %v，期望 NO_FINE_DUE", err)
+	}
+	if _, err := s.WaiveFines(ctx, "S1"); !apperr.Is(err, apperr.CodeNoFineDue) {
+		t.Errorf("减免没有罚款的学生 = %v，期望 NO_FINE_DUE", err)
+	}
+	if _, err := s.WaiveFines(ctx, "NOBODY"); !apperr.Is(err, apperr.CodeStudentNotFound) {
+		t.Errorf("减免不存在的学生 = %v，期望 STUDENT_NOT_FOUND", err)
+	}
+	if got := len(store.AuditEntries()); got != 2 {
+		t.Errorf("减免失败后审计日志 %d 条，期望仍为 2 条", got)
+	}
+}
+
+// 按开馆日历重算未归还借阅的罚款，只更新有变化的记录；已归还和未到期的借阅不变
+func TestSweepOverdue(t *testing.T) {
+	ctx := adminContext()
+	store := newTestLibrary()
+	s := NewAdminService(store)
+
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.Local)
+	early := time.Date(2024, 3, 10, 10, 0, 0, 0, time.Local)
+	late := time.Date(2024, 3, 17, 10, 0, 0, 0, time.Local)
+	returned := time.Date(2024, 3, 12, 10, 0, 0, 0, time.Local)
+	// 10天前和3天前到期，其中3月12日至14日闭馆
+	first := store.AddBorrowRecord(do.BorrowRecord{StuID: "S1", BookID: "B1", BorrowDate: early.AddDate(0, -2, 0), DueDate: early})
+	second := store.AddBorrowRecord(do.BorrowRecord{StuID: "S3", BookID: "B1", BorrowDate: late.AddDate(0, -2, 0), DueDate: late, IsOverdue: true, FineAmount: 1.5})
+	store.AddBorrowRecord(do.BorrowRecord{StuID: "S3", BookID: "B2", BorrowDate: early.AddDate(0, -2, 0), DueDate: early, ReturnDate: &returned, IsOverdue: true, FineAmount: 1})
+	store.AddBorrowRecord(do.BorrowRecord{StuID: "S1", BookID: "B2", BorrowDate: now.AddDate(0, -1, 0), DueDate: now.AddDate(0, 0, 1)})
+	store.AddClosure(do.LibraryClosure{Name: "校庆", Kind: do.ClosureKindHoliday, StartDate: "2024-03-12", EndDate: "2024-03-14"})
+
+	sweep, err := s.SweepOverdue(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := OverdueSweep{OverdueLoans: 2, Updated: 1, TotalFines: 7*loanPolicy.FinePerDay + 3*loanPolicy.FinePerDay}
+	if *sweep != want {
+		t.Errorf("逾期扫描 = %+v，期望 %+v", *sweep, want)
+	}
+	fines := make(map[int]do.BorrowRecord)
+	for _, record := range store.BorrowRecords() {
+		fines[record.ID] = record
+	}
+	if record := fines[first]; !record.IsOverdue || record.FineAmount != 7*loanPolicy.FinePerDay {
+		t.Errorf("扫描后借阅 = %+v，期望逾期7个开馆日", record)
+	}
+	if record := fines[second]; record.FineAmount != 3*loanPolicy.FinePerDay {
+		t.Errorf("罚款没有变化的借阅 = %+v", record)
+	}
+	if canBorrow, _, err := NewStudentService(store).CanStudentBorrow(ctx, "S1"); err != nil || canBorrow {
+		t.Errorf("有罚款时可借 = %v, %v，期望不可借", canBorrow, err)
+	}
+
+	// 罚款没有变化时不重复更新
+	if sweep, err := s.SweepOverdue(ctx, now); err != nil || sweep.Updated != 0 || sweep.OverdueLoans != 2 {
+		t.Errorf("再次扫描 = %+v, %v，期望没有更新", sweep, err)
+	}
+	if sweep, err := s.SweepOverdue(ctx, early); err != nil || *sweep != (OverdueSweep{}) {
+		t.Errorf("没有逾期借阅时 = %+v, %v", sweep, err)
+	}
+}
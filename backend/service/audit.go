@@ -0,0 +1,89 @@
+package service
+
+import (
+	"backend/dao"
+	"backend/do"
+	"backend/logging"
+	"backend/repository"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// 审计日志每次查询的默认和最大条数
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 500
+)
+
+// Actor 发起操作的人，由控制器的中间件根据令牌或借还机设备写入请求的ctx
+type Actor struct {
+	Role string // 令牌中的角色、do.RoleKiosk、do.ActorAnonymous、do.ActorSystem或do.ActorCLI
+	ID   string
+	IP   string
+}
+
+type actorKey struct{}
+
+// 在ctx中保存操作人
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// 获取ctx中的操作人，没有时（如后台任务）为系统
+func ActorFrom(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return Actor{Role: do.ActorSystem}
+}
+
+// 在事务中写入审计日志，before、after为操作前后的值，为nil时不记录
+func recordAudit(ctx context.Context, tx repository.Repositories, action, entityType, entityID string, before, after interface{}) error {
+	actor := ActorFrom(ctx)
+	entry := &do.AuditEntry{
+		OccurredAt: time.Now(),
+		ActorRole:  actor.Role,
+		ActorID:    actor.ID,
+		ClientIP:   actor.IP,
+		RequestID:  logging.RequestID(ctx),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+	var err error
+	if entry.Before, err = auditValue(before); err != nil {
+		return err
+	}
+	if entry.After, err = auditValue(after); err != nil {
+		return err
+	}
+	return tx.Audit().RecordAudit(ctx, entry)
+}
+
+func auditValue(value interface{}) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
+
+// AuditService 审计日志查询，仅管理员可用
+type AuditService struct {
+	auditDAO *dao.AuditDAO
+}
+
+func NewAuditService(db *sql.DB) *AuditService {
+	return &AuditService{auditDAO: dao.NewAuditDAO(db)}
+}
+
+// 按条件查询审计日志，从新到旧排列；未指定条数时返回100条，最多500条
+func (s *AuditService) ListEntries(ctx context.Context, filter do.AuditFilter) ([]do.AuditEntry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	} else if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+	return s.auditDAO.ListAuditEntries(ctx, filter)
+}
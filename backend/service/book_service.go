@@ -1,75 +1,116 @@
 package service
 
 import (
-	"backend/dao"
 	"backend/do"
-	"database/sql"
+	"backend/repository"
+	"backend/tracing"
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type BookService struct {
-	bookDAO *dao.BookDAO
+	bookDAO repository.BookRepository
+	itemDAO repository.BookItemRepository
 }
 
-func NewBookService(db *sql.DB) *BookService {
+func NewBookService(store repository.Store) *BookService {
 	return &BookService{
-		bookDAO: dao.NewBookDAO(db),
+		bookDAO: store.Books(),
+		itemDAO: store.BookItems(),
 	}
 }
 
+// 书籍相关操作的span属性
+func bookAttribute(bookID string) attribute.KeyValue {
+	return attribute.String("library.book_id", bookID)
+}
+
 // 查找书籍 - 根据书名或作者
-func (s *BookService) SearchBooks(keyword string) ([]do.Book, error) {
-	return s.bookDAO.FindBooksByTitleOrAuthor(keyword)
+func (s *BookService) SearchBooks(ctx context.Context, keyword string) (_ []do.Book, err error) {
+	ctx, span := tracing.Start(ctx, "BookService.SearchBooks", attribute.String("library.keyword", keyword))
+	defer tracing.End(span, &err)
+
+	books, err := s.bookDAO.FindBooksByTitleOrAuthor(ctx, keyword)
+	if err != nil {
+		return nil, err
+	}
+	for i := range books {
+		fillCoverURLs(&books[i])
+	}
+	return books, nil
 }
 
-// 获取书籍详情
-func (s *BookService) GetBookDetail(bookID string) (*do.Book, error) {
-	return s.bookDAO.GetBookByID(bookID)
+// 获取书籍详情，登记了单册的书籍附带各分馆的馆藏情况
+func (s *BookService) GetBookDetail(ctx context.Context, bookID string) (_ *do.Book, err error) {
+	ctx, span := tracing.Start(ctx, "BookService.GetBookDetail", bookAttribute(bookID))
+	defer tracing.End(span, &err)
+
+	book, err := s.bookDAO.GetBookByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	fillCoverURLs(book)
+
+	itemCount, err := s.itemDAO.CountItems(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if itemCount > 0 {
+		book.Branches, err = s.itemDAO.GetBranchAvailability(ctx, bookID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return book, nil
 }
 
 // 检查书籍是否可以借阅
-func (s *BookService) CanBorrowBook(bookID string) (bool, error) {
-	book, err := s.bookDAO.GetBookByID(bookID)
+func (s *BookService) CanBorrowBook(ctx context.Context, bookID string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "BookService.CanBorrowBook", bookAttribute(bookID))
+	defer tracing.End(span, &err)
+
+	book, err := s.bookDAO.GetBookByID(ctx, bookID)
 	if err != nil {
 		return false, err
 	}
-	
+
 	return book.CanBorrow && book.AvailableCopies > 0, nil
 }
 
 // 减少可借阅数量
-func (s *BookService) DecreaseAvailableCopies(bookID string) error {
-	book, err := s.bookDAO.GetBookByID(bookID)
-	if err != nil {
+func (s *BookService) DecreaseAvailableCopies(ctx context.Context, bookID string) (err error) {
+	ctx, span := tracing.Start(ctx, "BookService.DecreaseAvailableCopies", bookAttribute(bookID))
+	defer tracing.End(span, &err)
+
+	if _, err := s.bookDAO.GetBookByID(ctx, bookID); err != nil {
 		return err
 	}
-	
-	if book.AvailableCopies <= 0 {
-		return &BorrowError{Message: "书籍已全部借出"}
-	}
-	
-	return s.bookDAO.UpdateBookAvailableCopies(bookID, book.AvailableCopies-1)
+	return s.bookDAO.DecrementAvailableCopies(ctx, bookID)
 }
 
 // 增加可借阅数量
-func (s *BookService) IncreaseAvailableCopies(bookID string) error {
-	book, err := s.bookDAO.GetBookByID(bookID)
-	if err != nil {
+func (s *BookService) IncreaseAvailableCopies(ctx context.Context, bookID string) (err error) {
+	ctx, span := tracing.Start(ctx, "BookService.IncreaseAvailableCopies", bookAttribute(bookID))
+	defer tracing.End(span, &err)
+
+	if _, err := s.bookDAO.GetBookByID(ctx, bookID); err != nil {
 		return err
 	}
-	
-	return s.bookDAO.UpdateBookAvailableCopies(bookID, book.AvailableCopies+1)
+	return s.bookDAO.IncrementAvailableCopies(ctx, bookID)
 }
 
 // 获取所有书籍列表
-func (s *BookService) GetAllBooks() ([]do.Book, error) {
-	return s.bookDAO.GetAllBooks()
-}
-
-// 自定义错误类型
-type BorrowError struct {
-	Message string
-}
+func (s *BookService) GetAllBooks(ctx context.Context) (_ []do.Book, err error) {
+	ctx, span := tracing.Start(ctx, "BookService.GetAllBooks")
+	defer tracing.End(span, &err)
 
-func (e *BorrowError) Error() string {
-	return e.Message
+	books, err := s.bookDAO.GetAllBooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range books {
+		fillCoverURLs(&books[i])
+	}
+	return books, nil
 }
@@ -1,174 +1,388 @@
 package service
 
 import (
-	"backend/dao"
+	"backend/apperr"
 	"backend/do"
-	"database/sql"
-	"fmt"
+	"backend/events"
+	"backend/repository"
+	"backend/tracing"
+	"context"
+	"strconv"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type BorrowService struct {
-	bookService    *BookService
-	studentService *StudentService
-	borrowDAO      *dao.BorrowDAO
-	db             *sql.DB
+	store repository.Store
+	bus   *events.Bus
 }
 
-func NewBorrowService(db *sql.DB) *BorrowService {
-	return &BorrowService{
-		bookService:    NewBookService(db),
-		studentService: NewStudentService(db),
-		borrowDAO:      dao.NewBorrowDAO(db),
-		db:             db,
-	}
+// bus为nil时不发布实时事件
+func NewBorrowService(store repository.Store, bus *events.Bus) *BorrowService {
+	return &BorrowService{store: store, bus: bus}
 }
 
-// 借书操作
-func (s *BorrowService) BorrowBook(stuID, bookID string) error {
-	// 开始事务
-	tx, err := s.db.Begin()
-	if err != nil {
+// LoanPolicy 借阅规则，启动时按配置设置
+type LoanPolicy struct {
+	// 借阅期限（月）
+	PeriodMonths int
+	// 每天逾期罚款金额（元）
+	FinePerDay float64
+	// 到期前几天发送到期提醒
+	DueSoonDays int
+}
+
+var loanPolicy = LoanPolicy{PeriodMonths: 2, FinePerDay: 0.5, DueSoonDays: 3}
+
+// SetLoanPolicy 设置借阅规则，需在处理请求前调用
+func SetLoanPolicy(policy LoanPolicy) {
+	loanPolicy = policy
+}
+
+// ReturnResult 一次还书的结果
+type ReturnResult struct {
+	Record     *do.BorrowRecord `json:"record"`
+	IsOverdue  bool             `json:"is_overdue"`
+	FineAmount float64          `json:"fine_amount"`
+	Routing    *ItemRouting     `json:"routing,omitempty"`
+}
+
+// 借书操作，branchID为借书所在分馆，为空时不限分馆；返回新建的借阅记录
+func (s *BorrowService) BorrowBook(ctx context.Context, stuID, bookID, branchID string) (record *do.BorrowRecord, err error) {
+	ctx, span := tracing.Start(ctx, "BorrowService.BorrowBook", loanAttributes(stuID, bookID, branchID)...)
+	defer tracing.End(span, &err)
+
+	err = s.store.Transaction(ctx, func(tx repository.Repositories) error {
+		// 检查学生是否可以借书
+		blocked, err := borrowBlock(ctx, tx.Students(), stuID)
+		if err != nil {
+			return err
+		}
+		if blocked != nil {
+			return blocked
+		}
+
+		// 检查书籍是否可以借阅
+		book, err := tx.Books().GetBookByID(ctx, bookID)
+		if err != nil {
+			return err
+		}
+		if !book.CanBorrow {
+			return apperr.New(apperr.CodeBookNotBorrowable)
+		}
+
+		// 登记了单册的书籍按单册借出，否则按数量借出
+		itemCount, err := tx.BookItems().CountItems(ctx, bookID)
+		if err != nil {
+			return err
+		}
+		var barcode string
+		if itemCount > 0 {
+			barcode, err = checkoutItem(ctx, tx, stuID, bookID, branchID)
+			if err != nil {
+				return err
+			}
+		} else if book.AvailableCopies <= 0 {
+			return apperr.New(apperr.CodeBookUnavailable)
+		}
+
+		// 创建借阅记录并减少书籍可借阅数量
+		record, err = createLoan(ctx, tx, stuID, book, barcode, time.Now())
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishAvailability(ctx, s.bus, s.store.Books(), bookID)
+	publishLoan(s.bus, events.TypeLoanBorrowed, record)
+	return record, nil
+}
+
+// 借还操作的span属性，branchID为空时不记录
+func loanAttributes(stuID, bookID, branchID string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{studentAttribute(stuID), bookAttribute(bookID)}
+	if branchID != "" {
+		attrs = append(attrs, attribute.String("library.branch_id", branchID))
 	}
-	defer tx.Rollback()
+	return attrs
+}
+
+// 选出要借出的单册：优先取学生在该分馆预约架上的书，否则取一册在架副本
+func checkoutItem(ctx context.Context, tx repository.Repositories, stuID, bookID, branchID string) (barcode string, err error) {
+	ctx, span := tracing.Start(ctx, "borrow.checkout_item")
+	defer tracing.End(span, &err)
 
-	// 检查学生是否可以借书
-	canBorrow, reason, err := s.studentService.CanStudentBorrow(stuID)
+	items := tx.BookItems()
+	holds := tx.Holds()
+
+	hold, err := holds.GetActiveHold(ctx, stuID, bookID)
 	if err != nil {
-		return err
+		return "", err
 	}
-	if !canBorrow {
-		return fmt.Errorf("借阅失败: %s", reason)
+
+	var item *do.BookItem
+	if hold != nil && hold.Status == do.HoldStatusReady && (branchID == "" || branchID == hold.PickupBranchID) {
+		item, err = items.GetItemByBarcode(ctx, hold.Barcode)
+		if err != nil {
+			return "", err
+		}
+		if err := holds.UpdateHoldStatus(ctx, hold.ID, do.HoldStatusFulfilled, hold.Barcode); err != nil {
+			return "", err
+		}
+	} else {
+		item, err = items.FindAvailableItem(ctx, bookID, branchID)
+		if err != nil {
+			return "", err
+		}
+		if item == nil {
+			if branchID != "" {
+				return "", apperr.New(apperr.CodeNoCopyAtBranch)
+			}
+			return "", apperr.New(apperr.CodeBookUnavailable)
+		}
 	}
 
-	// 检查书籍是否可以借阅
-	canBorrowBook, err := s.bookService.CanBorrowBook(bookID)
-	if err != nil {
+	if err := items.UpdateItemLocation(ctx, item.Barcode, item.CurrentBranchID, do.ItemStatusOnLoan); err != nil {
+		return "", err
+	}
+	span.SetAttributes(attribute.String("library.barcode", item.Barcode))
+	return item.Barcode, nil
+}
+
+// 还书操作，branchID为还书所在分馆，为空时视为在借出时所在分馆归还
+func (s *BorrowService) ReturnBook(ctx context.Context, stuID, bookID, branchID string) (fine float64, err error) {
+	ctx, span := tracing.Start(ctx, "BorrowService.ReturnBook", loanAttributes(stuID, bookID, branchID)...)
+	defer tracing.End(span, &err)
+
+	var result *ReturnResult
+	err = s.store.Transaction(ctx, func(tx repository.Repositories) error {
+		record, err := tx.Borrows().GetBorrowRecord(ctx, stuID, bookID)
+		if err != nil {
+			return err
+		}
+
+		result, err = returnLoan(ctx, tx, record, branchID, time.Now())
 		return err
+	})
+	if err != nil {
+		return 0, err
 	}
-	if !canBorrowBook {
-		return fmt.Errorf("借阅失败: 书籍不可借阅或已全部借出")
+
+	publishAvailability(ctx, s.bus, s.store.Books(), bookID)
+	publishLoan(s.bus, events.TypeLoanReturned, result.Record)
+	publishRouting(s.bus, bookID, result.Routing)
+	return result.FineAmount, nil
+}
+
+// 在事务中创建借阅记录并减少书籍可借阅数量，按单册借出时单册状态需已更新为借出
+func createLoan(ctx context.Context, tx repository.Repositories, stuID string, book *do.Book, barcode string, now time.Time) (_ *do.BorrowRecord, err error) {
+	ctx, span := tracing.Start(ctx, "loan.create")
+	defer tracing.End(span, &err)
+
+	// 两个月后，落在闭馆日时顺延到下一个开馆日
+	dueDate, err := loanDueDate(ctx, tx, now)
+	if err != nil {
+		return nil, err
 	}
 
-	// 创建借阅记录
 	borrowRecord := &do.BorrowRecord{
 		StuID:      stuID,
-		BookID:     bookID,
-		BorrowDate: time.Now(),
-		DueDate:    time.Now().AddDate(0, 2, 0), // 两个月后
+		BookID:     book.BookID,
+		Barcode:    barcode,
+		BorrowDate: now,
+		DueDate:    dueDate,
 		ReturnDate: nil,
 		IsOverdue:  false,
 		FineAmount: 0,
 	}
 
-	// 使用事务中的DAO
-	borrowDAOTx := dao.NewBorrowDAOTx(tx)
-	if err := borrowDAOTx.CreateBorrowRecord(borrowRecord); err != nil {
-		return err
+	// 使用事务中的仓储
+	if err := tx.Borrows().CreateBorrowRecord(ctx, borrowRecord); err != nil {
+		return nil, err
 	}
-
-	// 减少书籍可借阅数量
-	bookDAOTx := dao.NewBookDAOTx(tx)
-	book, err := bookDAOTx.GetBookByID(bookID)
-	if err != nil {
-		return err
+	if err := recordEvent(ctx, tx, do.EventBookBorrowed, loanEvent(borrowRecord, "")); err != nil {
+		return nil, err
 	}
-	if err := bookDAOTx.UpdateBookAvailableCopies(bookID, book.AvailableCopies-1); err != nil {
-		return err
+	if err := recordAudit(ctx, tx, do.AuditLoanBorrow, do.AuditEntityLoan, strconv.Itoa(borrowRecord.ID), nil, borrowRecord); err != nil {
+		return nil, err
+	}
+
+	// 减少书籍可借阅数量，登记了单册的书籍按单册重新统计
+	books := tx.Books()
+	if barcode != "" {
+		if err := books.SyncCopiesFromItems(ctx, book.BookID); err != nil {
+			return nil, err
+		}
+	} else if err := books.DecrementAvailableCopies(ctx, book.BookID); err != nil {
+		return nil, err
 	}
 
-	// 提交事务
-	return tx.Commit()
+	return borrowRecord, nil
 }
 
-// 还书操作
-func (s *BorrowService) ReturnBook(stuID, bookID string) (float64, error) {
-	// 开始事务
-	tx, err := s.db.Begin()
-	if err != nil {
-		return 0, err
-	}
-	defer tx.Rollback()
+// 在事务中归还一条借阅记录：计算逾期罚款、安排单册去向，有罚款时禁用学生借阅权限
+func returnLoan(ctx context.Context, tx repository.Repositories, record *do.BorrowRecord, branchID string, now time.Time) (_ *ReturnResult, err error) {
+	ctx, span := tracing.Start(ctx, "loan.return", attribute.Int("library.record_id", record.ID))
+	defer tracing.End(span, &err)
 
-	// 检查是否逾期并计算罚款
-	borrowDAOTx := dao.NewBorrowDAOTx(tx)
-	isOverdue, fineAmount, err := borrowDAOTx.CheckOverdueAndCalculateFine(stuID, bookID, time.Now())
+	// 检查是否逾期并计算罚款，闭馆日不计逾期天数
+	calendar, err := loadCalendar(ctx, tx.Calendar(), record.DueDate, now)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	isOverdue, fineAmount := calculateFine(calendar, record.DueDate, now)
+	span.SetAttributes(attribute.Bool("library.overdue", isOverdue), attribute.Float64("library.fine_amount", fineAmount))
 
 	// 执行还书操作
-	if err := borrowDAOTx.ReturnBook(stuID, bookID, time.Now()); err != nil {
-		return 0, err
+	if err := tx.Borrows().ReturnBorrowRecord(ctx, record.ID, now, isOverdue, fineAmount); err != nil {
+		return nil, err
+	}
+	before := *record
+	record.ReturnDate = &now
+	record.IsOverdue = isOverdue
+	record.FineAmount = fineAmount
+	if err := recordAudit(ctx, tx, do.AuditLoanReturn, do.AuditEntityLoan, strconv.Itoa(record.ID), &before, record); err != nil {
+		return nil, err
 	}
+	result := &ReturnResult{Record: record, IsOverdue: isOverdue, FineAmount: fineAmount}
 
-	// 增加书籍可借阅数量
-	bookDAOTx := dao.NewBookDAOTx(tx)
-	book, err := bookDAOTx.GetBookByID(bookID)
-	if err != nil {
-		return 0, err
+	// 增加书籍可借阅数量；按单册借出的书需要决定单册去向（上架、调回所属分馆或满足预约）
+	books := tx.Books()
+	if record.Barcode != "" {
+		item, err := tx.BookItems().GetItemByBarcode(ctx, record.Barcode)
+		if err != nil {
+			return nil, err
+		}
+		if branchID == "" {
+			branchID = item.CurrentBranchID
+		}
+		result.Routing, err = routeItem(ctx, tx, item, branchID, true)
+		if err != nil {
+			return nil, err
+		}
+		if err := books.SyncCopiesFromItems(ctx, record.BookID); err != nil {
+			return nil, err
+		}
+	} else if err := books.IncrementAvailableCopies(ctx, record.BookID); err != nil {
+		return nil, err
 	}
-	if err := bookDAOTx.UpdateBookAvailableCopies(bookID, book.AvailableCopies+1); err != nil {
-		return 0, err
+
+	if err := recordEvent(ctx, tx, do.EventBookReturned, loanEvent(record, branchID)); err != nil {
+		return nil, err
 	}
 
 	// 如果有逾期罚款，禁用学生借阅权限
 	if isOverdue && fineAmount > 0 {
-		studentDAOTx := dao.NewStudentDAOTx(tx)
-		if err := studentDAOTx.UpdateStudentBorrowStatus(stuID, false); err != nil {
-			return 0, err
+		if err := setBorrowPermission(ctx, tx, record.StuID, false); err != nil {
+			return nil, err
+		}
+
+		fine := FineEvent{StuID: record.StuID, RecordID: record.ID, BookID: record.BookID, Amount: fineAmount, At: now}
+		if err := recordEvent(ctx, tx, do.EventFineCharged, fine); err != nil {
+			return nil, err
+		}
+		blocked := StudentBlockedEvent{StuID: record.StuID, Reason: "unpaid_fine", At: now}
+		if err := recordEvent(ctx, tx, do.EventStudentBlocked, blocked); err != nil {
+			return nil, err
 		}
 	}
 
-	// 提交事务
-	if err := tx.Commit(); err != nil {
-		return 0, err
+	return result, nil
+}
+
+func loanEvent(record *do.BorrowRecord, branchID string) *LoanEvent {
+	return &LoanEvent{
+		RecordID:   record.ID,
+		StuID:      record.StuID,
+		BookID:     record.BookID,
+		Barcode:    record.Barcode,
+		BranchID:   branchID,
+		BorrowDate: record.BorrowDate,
+		DueDate:    record.DueDate,
+		ReturnDate: record.ReturnDate,
+		IsOverdue:  record.IsOverdue,
+		FineAmount: record.FineAmount,
 	}
+}
 
-	return fineAmount, nil
+// 计算逾期罚款，不足一天的部分和闭馆日不计罚款
+func calculateFine(calendar *LibraryCalendar, dueDate, returnDate time.Time) (bool, float64) {
+	if !returnDate.After(dueDate) {
+		return false, 0
+	}
+	return true, float64(calendar.OverdueDays(dueDate, returnDate)) * loanPolicy.FinePerDay
 }
 
 // 获取借阅记录详情
-func (s *BorrowService) GetBorrowRecord(stuID, bookID string) (*do.BorrowRecord, error) {
-	return s.borrowDAO.GetBorrowRecord(stuID, bookID)
+func (s *BorrowService) GetBorrowRecord(ctx context.Context, stuID, bookID string) (_ *do.BorrowRecord, err error) {
+	ctx, span := tracing.Start(ctx, "BorrowService.GetBorrowRecord", loanAttributes(stuID, bookID, "")...)
+	defer tracing.End(span, &err)
+	return s.store.Borrows().GetBorrowRecord(ctx, stuID, bookID)
 }
 
 // 获取学生的所有借阅记录
-func (s *BorrowService) GetStudentBorrowRecords(stuID string) ([]do.BorrowRecord, error) {
-	return s.borrowDAO.GetStudentBorrowRecords(stuID)
+func (s *BorrowService) GetStudentBorrowRecords(ctx context.Context, stuID string) (_ []do.BorrowRecord, err error) {
+	ctx, span := tracing.Start(ctx, "BorrowService.GetStudentBorrowRecords", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+	return s.store.Borrows().GetStudentBorrowRecords(ctx, stuID)
 }
 
 // 获取学生的借阅记录（包含图书信息）
-func (s *BorrowService) GetStudentBorrowRecordsWithBookInfo(stuID string) ([]map[string]interface{}, error) {
-	return s.borrowDAO.GetStudentBorrowRecordsWithBookInfo(stuID)
+func (s *BorrowService) GetStudentBorrowRecordsWithBookInfo(ctx context.Context, stuID string) (_ []map[string]interface{}, err error) {
+	ctx, span := tracing.Start(ctx, "BorrowService.GetStudentBorrowRecordsWithBookInfo", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+	return s.store.Borrows().GetStudentBorrowRecordsWithBookInfo(ctx, stuID)
 }
 
 // 处理罚款支付
-func (s *BorrowService) PayFine(stuID string) error {
-	// 开始事务
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
+func (s *BorrowService) PayFine(ctx context.Context, stuID string) (err error) {
+	ctx, span := tracing.Start(ctx, "BorrowService.PayFine", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+
+	return s.store.Transaction(ctx, func(tx repository.Repositories) error {
+		// 检查是否还有未支付的罚款
+		students := tx.Students()
+		hasUnpaidFine, err := students.HasUnpaidFine(ctx, stuID)
+		if err != nil {
+			return err
+		}
+
+		if !hasUnpaidFine {
+			return apperr.New(apperr.CodeNoFineDue)
+		}
+
+		// 启用学生借阅权限
+		if err := setBorrowPermission(ctx, tx, stuID, true); err != nil {
+			return err
+		}
+		paid := FineEvent{StuID: stuID, At: time.Now()}
+		if err := recordAudit(ctx, tx, do.AuditFinePay, do.AuditEntityStudent, stuID, nil, paid); err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, do.EventFinePaid, paid)
+	})
+}
+
+// borrowPermission 审计日志中记录的借阅权限
+type borrowPermission struct {
+	CanBorrow bool `json:"can_borrow"`
+}
 
-	// 检查是否还有未支付的罚款
-	studentDAOTx := dao.NewStudentDAOTx(tx)
-	hasUnpaidFine, err := studentDAOTx.HasUnpaidFine(stuID)
+// 在事务中修改学生借阅权限，权限有变化时写入审计日志
+func setBorrowPermission(ctx context.Context, tx repository.Repositories, stuID string, canBorrow bool) error {
+	students := tx.Students()
+	student, err := students.GetStudentByID(ctx, stuID)
 	if err != nil {
 		return err
 	}
-
-	if !hasUnpaidFine {
-		return fmt.Errorf("没有需要支付的罚款")
-	}
-
-	// 启用学生借阅权限
-	if err := studentDAOTx.UpdateStudentBorrowStatus(stuID, true); err != nil {
+	if err := students.UpdateStudentBorrowStatus(ctx, stuID, canBorrow); err != nil {
 		return err
 	}
-
-	// 提交事务
-	return tx.Commit()
+	if student.CanBorrow == canBorrow {
+		return nil
+	}
+	return recordAudit(ctx, tx, do.AuditBorrowPermission, do.AuditEntityStudent, stuID,
+		borrowPermission{CanBorrow: student.CanBorrow}, borrowPermission{CanBorrow: canBorrow})
 }
@@ -0,0 +1,484 @@
+package service
+
+import (
+	"backend/apperr"
+	"backend/do"
+	"backend/logging"
+	"backend/repository/memory"
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// 准备测试数据：两个分馆、可借和被禁用的学生、按数量管理的图书B1、按单册管理的图书B2
+func newTestLibrary() *memory.Store {
+	store := memory.NewStore()
+	store.AddStudent(do.Student{StuId: "S1", Name: "张三", Password: "pass1", Trust: 100, CanBorrow: true})
+	store.AddStudent(do.Student{StuId: "S2", Name: "李四", Password: "pass2", Trust: 100, CanBorrow: false})
+	store.AddStudent(do.Student{StuId: "S3", Name: "王五", Password: "pass3", Trust: 100, CanBorrow: true})
+
+	store.AddBranch(do.Branch{BranchID: "MAIN", Name: "总馆"})
+	store.AddBranch(do.Branch{BranchID: "EAST", Name: "东区分馆"})
+
+	store.AddBook(do.Book{BookID: "B1", Title: "Go语言程序设计", Author: "张明", TotalCopies: 2, AvailableCopies: 2, CanBorrow: true})
+	store.AddBook(do.Book{BookID: "B2", Title: "数据库系统概论", Author: "王珊", CanBorrow: true})
+	store.AddItem(do.BookItem{Barcode: "B2-EAST", BookID: "B2", HomeBranchID: "EAST", CurrentBranchID: "EAST"})
+	store.AddItem(do.BookItem{Barcode: "B2-MAIN", BookID: "B2", HomeBranchID: "MAIN", CurrentBranchID: "MAIN"})
+	return store
+}
+
+func availableCopies(t *testing.T, store *memory.Store, bookID string) int {
+	t.Helper()
+	book, err := store.Books().GetBookByID(context.Background(), bookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return book.AvailableCopies
+}
+
+func outboxTypes(store *memory.Store) []string {
+	var types []string
+	for _, event := range store.OutboxEvents() {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBorrowAndReturnByCopies(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLibrary()
+	s := NewBorrowService(store, nil)
+
+	if _, err := s.BorrowBook(ctx, "S1", "B1", ""); err != nil {
+		t.Fatalf("借书失败: %v", err)
+	}
+	if got := availableCopies(t, store, "B1"); got != 1 {
+		t.Errorf("借出后可借数量 = %d，期望 1", got)
+	}
+
+	record, err := s.GetBorrowRecord(ctx, "S1", "B1")
+	if err != nil {
+		t.Fatalf("获取借阅记录失败: %v", err)
+	}
+	want := time.Now().AddDate(0, loanPolicy.PeriodMonths, 0)
+	if d := record.DueDate.Sub(want); d > time.Minute || d < -time.Minute {
+		t.Errorf("应还日期 = %v，期望约为 %v", record.DueDate, want)
+	}
+
+	fine, err := s.ReturnBook(ctx, "S1", "B1", "")
+	if err != nil {
+		t.Fatalf("还书失败: %v", err)
+	}
+	if fine != 0 {
+		t.Errorf("按期归还的罚款 = %v，期望 0", fine)
+	}
+	if got := availableCopies(t, store, "B1"); got != 2 {
+		t.Errorf("归还后可借数量 = %d，期望 2", got)
+	}
+	if _, err := s.GetBorrowRecord(ctx, "S1", "B1"); err == nil {
+		t.Error("归还后不应再有未归还的借阅记录")
+	}
+
+	if types := outboxTypes(store); !equalStrings(types, []string{do.EventBookBorrowed, do.EventBookReturned}) {
+		t.Errorf("发件箱事件 = %v", types)
+	}
+}
+
+func TestBorrowRejected(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLibrary()
+	s := NewBorrowService(store, nil)
+
+	if _, err := s.BorrowBook(ctx, "S2", "B1", ""); !apperr.Is(err, apperr.CodeBorrowDisabled) {
+		t.Errorf("被禁用借阅权限的学生借书 = %v，期望 %s", err, apperr.CodeBorrowDisabled)
+	}
+	if _, err := s.BorrowBook(ctx, "NOBODY", "B1", ""); !apperr.Is(err, apperr.CodeStudentNotFound) {
+		t.Errorf("不存在的学生借书 = %v，期望 %s", err, apperr.CodeStudentNotFound)
+	}
+	if _, err := s.BorrowBook(ctx, "S1", "NONE", ""); !apperr.Is(err, apperr.CodeBookNotFound) {
+		t.Errorf("借不存在的书籍 = %v，期望 %s", err, apperr.CodeBookNotFound)
+	}
+
+	// 借完全部副本后再借失败
+	for _, stuID := range []string{"S1", "S3"} {
+		if _, err := s.BorrowBook(ctx, stuID, "B1", ""); err != nil {
+			t.Fatalf("%s 借书失败: %v", stuID, err)
+		}
+	}
+	store.AddStudent(do.Student{StuId: "S4", Name: "赵六", Password: "pass4", CanBorrow: true})
+	if _, err := s.BorrowBook(ctx, "S4", "B1", ""); !apperr.Is(err, apperr.CodeBookUnavailable) {
+		t.Errorf("全部借出后借书 = %v，期望 %s", err, apperr.CodeBookUnavailable)
+	}
+
+	if got := availableCopies(t, store, "B1"); got != 0 {
+		t.Errorf("可借数量 = %d，期望 0", got)
+	}
+	if got := len(store.BorrowRecords()); got != 2 {
+		t.Errorf("借阅记录数 = %d，期望 2", got)
+	}
+}
+
+func TestBorrowRollsBackOnFailure(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLibrary()
+	s := NewBorrowService(store, nil)
+
+	// 在东区分馆借走唯一一册后，再指定东区分馆借书：选单册失败，事务内的修改不应保留
+	if _, err := s.BorrowBook(ctx, "S1", "B2", "EAST"); err != nil {
+		t.Fatalf("借书失败: %v", err)
+	}
+	before := len(store.OutboxEvents())
+	if _, err := s.BorrowBook(ctx, "S3", "B2", "EAST"); !apperr.Is(err, apperr.CodeNoCopyAtBranch) {
+		t.Fatalf("分馆无可借副本时借书 = %v，期望 %s", err, apperr.CodeNoCopyAtBranch)
+	}
+
+	if got := len(store.BorrowRecords()); got != 1 {
+		t.Errorf("借阅记录数 = %d，期望 1", got)
+	}
+	if got := len(store.OutboxEvents()); got != before {
+		t.Errorf("失败的借书写入了 %d 条事件", got-before)
+	}
+	if got := availableCopies(t, store, "B2"); got != 1 {
+		t.Errorf("可借数量 = %d，期望 1", got)
+	}
+}
+
+// 请求已取消时事务不提交
+func TestBorrowCanceledRequest(t *testing.T) {
+	store := newTestLibrary()
+	s := NewBorrowService(store, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.BorrowBook(ctx, "S1", "B1", ""); err != context.Canceled {
+		t.Fatalf("取消后借书 = %v，期望 context.Canceled", err)
+	}
+	if got := len(store.BorrowRecords()); got != 0 {
+		t.Errorf("借阅记录数 = %d，期望 0", got)
+	}
+	if got := availableCopies(t, store, "B1"); got != 2 {
+		t.Errorf("可借数量 = %d，期望 2", got)
+	}
+}
+
+func TestBorrowItemAtBranch(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLibrary()
+	s := NewBorrowService(store, nil)
+
+	if _, err := s.BorrowBook(ctx, "S1", "B2", "MAIN"); err != nil {
+		t.Fatalf("借书失败: %v", err)
+	}
+	record, err := s.GetBorrowRecord(ctx, "S1", "B2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Barcode != "B2-MAIN" {
+		t.Errorf("借出单册 = %s，期望 B2-MAIN", record.Barcode)
+	}
+	item, err := store.BookItems().GetItemByBarcode(ctx, "B2-MAIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != do.ItemStatusOnLoan {
+		t.Errorf("单册状态 = %s，期望 %s", item.Status, do.ItemStatusOnLoan)
+	}
+	if got := availableCopies(t, store, "B2"); got != 1 {
+		t.Errorf("可借数量 = %d，期望 1", got)
+	}
+}
+
+func TestReturnItemAtOtherBranchSendsHome(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLibrary()
+	s := NewBorrowService(store, nil)
+
+	if _, err := s.BorrowBook(ctx, "S1", "B2", "MAIN"); err != nil {
+		t.Fatalf("借书失败: %v", err)
+	}
+	if _, err := s.ReturnBook(ctx, "S1", "B2", "EAST"); err != nil {
+		t.Fatalf("还书失败: %v", err)
+	}
+
+	transfers := store.TransferList()
+	if len(transfers) != 1 {
+		t.Fatalf("调拨单数 = %d，期望 1", len(transfers))
+	}
+	if transfer := transfers[0]; transfer.Barcode != "B2-MAIN" || transfer.FromBranchID != "EAST" || transfer.ToBranchID != "MAIN" {
+		t.Errorf("调拨单 = %+v，期望从EAST调回MAIN", transfer)
+	}
+	item, err := store.BookItems().GetItemByBarcode(ctx, "B2-MAIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != do.ItemStatusInTransit {
+		t.Errorf("单册状态 = %s，期望 %s", item.Status, do.ItemStatusInTransit)
+	}
+}
+
+func TestReturnItemFillsWaitingHold(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLibrary()
+	s := NewBorrowService(store, nil)
+
+	if _, err := s.BorrowBook(ctx, "S1", "B2", "MAIN"); err != nil {
+		t.Fatalf("借书失败: %v", err)
+	}
+	holdID := store.AddHold(do.Hold{StuID: "S3", BookID: "B2", PickupBranchID: "MAIN"})
+
+	if _, err := s.ReturnBook(ctx, "S1", "B2", "MAIN"); err != nil {
+		t.Fatalf("还书失败: %v", err)
+	}
+
+	hold := store.Hold(holdID)
+	if hold.Status != do.HoldStatusReady || hold.Barcode != "B2-MAIN" || hold.ReadyAt == nil {
+		t.Errorf("预约 = %+v，期望已到馆并分配B2-MAIN", hold)
+	}
+	item, err := store.BookItems().GetItemByBarcode(ctx, "B2-MAIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != do.ItemStatusOnHold {
+		t.Errorf("单册状态 = %s，期望 %s", item.Status, do.ItemStatusOnHold)
+	}
+
+	// 预约人在取书分馆借到预约架上的这一册
+	if _, err := s.BorrowBook(ctx, "S3", "B2", "MAIN"); err != nil {
+		t.Fatalf("预约人借书失败: %v", err)
+	}
+	record, err := s.GetBorrowRecord(ctx, "S3", "B2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Barcode != "B2-MAIN" {
+		t.Errorf("借出单册 = %s，期望预约架上的 B2-MAIN", record.Barcode)
+	}
+	if hold := store.Hold(holdID); hold.Status != do.HoldStatusFulfilled {
+		t.Errorf("预约状态 = %s，期望 %s", hold.Status, do.HoldStatusFulfilled)
+	}
+}
+
+func TestReturnOverdueChargesFine(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLibrary()
+	s := NewBorrowService(store, nil)
+
+	// 10天前到期，其中3天闭馆
+	now := time.Now()
+	dueDate := now.AddDate(0, 0, -10).Add(-time.Hour)
+	store.AddBorrowRecord(do.BorrowRecord{StuID: "S1", BookID: "B1", BorrowDate: dueDate.AddDate(0, -2, 0), DueDate: dueDate})
+	store.AddClosure(do.LibraryClosure{
+		Name:      "校庆",
+		Kind:      do.ClosureKindHoliday,
+		StartDate: dueDate.AddDate(0, 0, 2).Format(do.DateLayout),
+		EndDate:   dueDate.AddDate(0, 0, 4).Format(do.DateLayout),
+	})
+
+	fine, err := s.ReturnBook(ctx, "S1", "B1", "")
+	if err != nil {
+		t.Fatalf("还书失败: %v", err)
+	}
+	if want := 7 * loanPolicy.FinePerDay; fine != want {
+		t.Errorf("罚款 = %v，期望 %v", fine, want)
+	}
+
+	records := store.BorrowRecords()
+	if record := records[0]; !record.IsOverdue || record.FineAmount != fine || record.ReturnDate == nil {
+		t.Errorf("借阅记录 = %+v，期望已归还并记为逾期", record)
+	}
+
+	canBorrow, _, err := NewStudentService(store).CanStudentBorrow(ctx, "S1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canBorrow {
+		t.Error("产生罚款后学生应被禁止借阅")
+	}
+	if _, err := s.BorrowBook(ctx, "S1", "B1", ""); err == nil {
+		t.Error("产生罚款后不应借书成功")
+	}
+
+	want := []string{do.EventBookReturned, do.EventFineCharged, do.EventStudentBlocked}
+	if types := outboxTypes(store); !equalStrings(types, want) {
+		t.Errorf("发件箱事件 = %v，期望 %v", types, want)
+	}
+}
+
+func TestDueDateSkipsClosedDay(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLibrary()
+	s := NewBorrowService(store, nil)
+
+	due := time.Now().AddDate(0, loanPolicy.PeriodMonths, 0)
+	store.SetOpeningHours(do.OpeningHours{Weekday: int(due.Weekday()), Closed: true})
+
+	if _, err := s.BorrowBook(ctx, "S1", "B1", ""); err != nil {
+		t.Fatalf("借书失败: %v", err)
+	}
+	record, err := s.GetBorrowRecord(ctx, "S1", "B1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := record.DueDate.Format(do.DateLayout), due.AddDate(0, 0, 1).Format(do.DateLayout); got != want {
+		t.Errorf("应还日期 = %s，期望顺延到 %s", got, want)
+	}
+}
+
+func TestPayFineWithoutFine(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLibrary()
+	s := NewBorrowService(store, nil)
+
+	if err := s.PayFine(ctx, "S1"); !apperr.Is(err, apperr.CodeNoFineDue) {
+		t.Errorf("没有罚款时支付 = %v，期望 %s", err, apperr.CodeNoFineDue)
+	}
+	if got := len(store.OutboxEvents()); got != 0 {
+		t.Errorf("发件箱事件数 = %d，期望 0", got)
+	}
+}
+
+func TestAuditTrail(t *testing.T) {
+	store := newTestLibrary()
+	s := NewBorrowService(store, nil)
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	ctx = WithActor(ctx, Actor{Role: do.RoleLibrarian, ID: "L1", IP: "10.0.0.1"})
+
+	record, err := s.BorrowBook(ctx, "S1", "B1", "")
+	if err != nil {
+		t.Fatalf("借书失败: %v", err)
+	}
+	// S3逾期归还被禁止借阅；S2支付罚款后恢复借阅
+	dueDate := time.Now().AddDate(0, 0, -3)
+	store.AddBorrowRecord(do.BorrowRecord{StuID: "S3", BookID: "B1", BorrowDate: dueDate.AddDate(0, -2, 0), DueDate: dueDate})
+	if _, err := s.ReturnBook(ctx, "S3", "B1", ""); err != nil {
+		t.Fatalf("还书失败: %v", err)
+	}
+	store.AddBorrowRecord(do.BorrowRecord{StuID: "S2", BookID: "B1", BorrowDate: dueDate.AddDate(0, -2, 0), DueDate: dueDate, IsOverdue: true, FineAmount: 1.5})
+	if err := s.PayFine(ctx, "S2"); err != nil {
+		t.Fatalf("支付罚款失败: %v", err)
+	}
+
+	entries := store.AuditEntries()
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+	}
+	want := []string{do.AuditLoanBorrow, do.AuditLoanReturn, do.AuditBorrowPermission, do.AuditBorrowPermission, do.AuditFinePay}
+	if !equalStrings(actions, want) {
+		t.Fatalf("审计日志 = %v，期望 %v", actions, want)
+	}
+
+	borrowed := entries[0]
+	if borrowed.ActorRole != do.RoleLibrarian || borrowed.ActorID != "L1" || borrowed.ClientIP != "10.0.0.1" || borrowed.RequestID != "req-1" {
+		t.Errorf("操作人 = %+v，期望馆员L1及请求ID", borrowed)
+	}
+	if borrowed.EntityType != do.AuditEntityLoan || borrowed.EntityID != strconv.Itoa(record.ID) || borrowed.Before != nil || borrowed.After == nil {
+		t.Errorf("借出记录 = %+v，期望只有操作后的借阅记录", borrowed)
+	}
+
+	var before, after do.BorrowRecord
+	if err := json.Unmarshal(entries[1].Before, &before); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(entries[1].After, &after); err != nil {
+		t.Fatal(err)
+	}
+	if before.ReturnDate != nil || after.ReturnDate == nil || !after.IsOverdue || after.FineAmount <= 0 {
+		t.Errorf("归还前后 = %+v / %+v", before, after)
+	}
+
+	blocked, unblocked := entries[2], entries[3]
+	if string(blocked.Before) != `{"can_borrow":true}` || string(blocked.After) != `{"can_borrow":false}` {
+		t.Errorf("禁用借阅权限 = %s -> %s", blocked.Before, blocked.After)
+	}
+	if string(unblocked.Before) != `{"can_borrow":false}` || string(unblocked.After) != `{"can_borrow":true}` {
+		t.Errorf("恢复借阅权限 = %s -> %s", unblocked.Before, unblocked.After)
+	}
+}
+
+func TestAuditRolledBackWithTransaction(t *testing.T) {
+	store := newTestLibrary()
+	s := NewBorrowService(store, nil)
+
+	// 被禁用的学生借书失败，不留下审计日志
+	if _, err := s.BorrowBook(context.Background(), "S2", "B1", ""); err == nil {
+		t.Fatal("被禁用的学生借书应失败")
+	}
+	if entries := store.AuditEntries(); len(entries) != 0 {
+		t.Errorf("审计日志 = %+v，期望没有记录", entries)
+	}
+	// 没有操作人时记为系统
+	if actor := ActorFrom(context.Background()); actor.Role != do.ActorSystem {
+		t.Errorf("默认操作人 = %+v，期望 %s", actor, do.ActorSystem)
+	}
+}
+
+func TestBorrowSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	store := newTestLibrary()
+	borrowService := NewBorrowService(store, nil)
+	ctx := context.Background()
+	if _, err := borrowService.BorrowBook(ctx, "S1", "B2", "EAST"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 资格检查、选取单册和创建借阅记录都是借书span的子span
+	spans := recorder.Ended()
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans {
+		byName[span.Name()] = span
+	}
+	root, ok := byName["BorrowService.BorrowBook"]
+	if !ok {
+		t.Fatalf("缺少借书span，已记录 %d 个span", len(spans))
+	}
+	for _, name := range []string{"borrow.eligibility", "borrow.checkout_item", "loan.create"} {
+		span, ok := byName[name]
+		if !ok {
+			t.Errorf("缺少span %s", name)
+			continue
+		}
+		if span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("%s 不是借书span的子span", name)
+		}
+	}
+	attrs := attribute.NewSet(byName["borrow.checkout_item"].Attributes()...)
+	if v, _ := attrs.Value("library.barcode"); v.AsString() != "B2-EAST" {
+		t.Errorf("借出单册属性 = %v", v)
+	}
+
+	// 借书被拒绝时记录错误
+	recorder.Reset()
+	if _, err := borrowService.BorrowBook(ctx, "S2", "B1", ""); err == nil {
+		t.Fatal("被禁用的学生借书应失败")
+	}
+	for _, span := range recorder.Ended() {
+		if span.Name() == "BorrowService.BorrowBook" && span.Status().Code != codes.Error {
+			t.Errorf("借书失败时span状态 = %v，期望 Error", span.Status().Code)
+		}
+	}
+}
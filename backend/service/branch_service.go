@@ -0,0 +1,96 @@
+package service
+
+import (
+	"backend/dao"
+	"backend/do"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type BranchService struct {
+	branchDAO *dao.BranchDAO
+	itemDAO   *dao.BookItemDAO
+	db        *sql.DB
+}
+
+func NewBranchService(db *sql.DB) *BranchService {
+	return &BranchService{
+		branchDAO: dao.NewBranchDAO(db),
+		itemDAO:   dao.NewBookItemDAO(db),
+		db:        db,
+	}
+}
+
+// 创建分馆
+func (s *BranchService) CreateBranch(ctx context.Context, branch *do.Branch) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := dao.NewBranchDAOTx(tx).CreateBranch(ctx, branch); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, dao.NewRepositoriesTx(tx), do.AuditBranchCreate, do.AuditEntityBranch, branch.BranchID, nil, branch); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// 获取所有分馆
+func (s *BranchService) GetAllBranches(ctx context.Context) ([]do.Branch, error) {
+	return s.branchDAO.GetAllBranches(ctx)
+}
+
+// 为书籍登记一册馆藏，新单册在所属分馆上架
+// 书籍一旦登记了单册，总馆藏和可借数量都改为按单册统计
+func (s *BranchService) AddBookItem(ctx context.Context, bookID, barcode, homeBranchID string) (*do.BookItem, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bookDAOTx := dao.NewBookDAOTx(tx)
+	if _, err := bookDAOTx.GetBookByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	if _, err := dao.NewBranchDAOTx(tx).GetBranchByID(ctx, homeBranchID); err != nil {
+		return nil, err
+	}
+
+	item := &do.BookItem{
+		Barcode:         barcode,
+		BookID:          bookID,
+		HomeBranchID:    homeBranchID,
+		CurrentBranchID: homeBranchID,
+		Status:          do.ItemStatusAvailable,
+	}
+
+	// 新单册可能正好满足排队中的预约
+	itemDAOTx := dao.NewBookItemDAOTx(tx)
+	if err := itemDAOTx.CreateItem(ctx, item); err != nil {
+		return nil, fmt.Errorf("登记馆藏失败: %w", err)
+	}
+	if _, err := routeItem(ctx, dao.NewRepositoriesTx(tx), item, homeBranchID, false); err != nil {
+		return nil, err
+	}
+	if err := bookDAOTx.SyncCopiesFromItems(ctx, bookID); err != nil {
+		return nil, err
+	}
+	if err := recordAudit(ctx, dao.NewRepositoriesTx(tx), do.AuditItemCreate, do.AuditEntityItem, barcode, nil, item); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.itemDAO.GetItemByBarcode(ctx, barcode)
+}
+
+// 获取书籍的所有单册
+func (s *BranchService) GetBookItems(ctx context.Context, bookID string) ([]do.BookItem, error) {
+	return s.itemDAO.GetItemsByBookID(ctx, bookID)
+}
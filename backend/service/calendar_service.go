@@ -0,0 +1,234 @@
+package service
+
+import (
+	"backend/apperr"
+	"backend/dao"
+	"backend/do"
+	"backend/repository"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// 顺延应还日期时最多向后查找的天数，防止日历全部设为闭馆时死循环
+const maxRollDays = 366
+
+// LibraryCalendar 某段日期内的开馆日历快照
+type LibraryCalendar struct {
+	closedWeekdays map[time.Weekday]bool
+	closures       []do.LibraryClosure
+}
+
+// 读取覆盖[from, to]的开馆日历
+func loadCalendar(ctx context.Context, calendarDAO repository.CalendarRepository, from, to time.Time) (*LibraryCalendar, error) {
+	hours, err := calendarDAO.GetOpeningHours(ctx)
+	if err != nil {
+		return nil, err
+	}
+	closures, err := calendarDAO.GetClosures(ctx, from.Format(do.DateLayout), to.Format(do.DateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &LibraryCalendar{closedWeekdays: make(map[time.Weekday]bool), closures: closures}
+	for _, h := range hours {
+		if h.Closed {
+			calendar.closedWeekdays[time.Weekday(h.Weekday)] = true
+		}
+	}
+	return calendar, nil
+}
+
+// 某天是否闭馆（每周固定闭馆日或在闭馆日期段内）
+func (c *LibraryCalendar) IsClosed(t time.Time) bool {
+	if c.closedWeekdays[t.Weekday()] {
+		return true
+	}
+	return c.closureOn(t) != nil
+}
+
+// 某天所在的闭馆日期段，不在任何闭馆日期段内时返回nil
+func (c *LibraryCalendar) closureOn(t time.Time) *do.LibraryClosure {
+	date := t.Format(do.DateLayout)
+	for i := range c.closures {
+		if c.closures[i].StartDate <= date && date <= c.closures[i].EndDate {
+			return &c.closures[i]
+		}
+	}
+	return nil
+}
+
+// 落在闭馆日的时间顺延到下一个开馆日，时刻不变
+func (c *LibraryCalendar) NextOpenDay(t time.Time) time.Time {
+	for i := 0; i < maxRollDays && c.IsClosed(t); i++ {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// 应还日期之后已满的逾期天数，闭馆日不计入
+func (c *LibraryCalendar) OverdueDays(dueDate, returnDate time.Time) int {
+	if !returnDate.After(dueDate) {
+		return 0
+	}
+	days := int(returnDate.Sub(dueDate).Hours() / 24)
+	overdue := 0
+	for i := 1; i <= days; i++ {
+		if !c.IsClosed(dueDate.AddDate(0, 0, i)) {
+			overdue++
+		}
+	}
+	return overdue
+}
+
+// 按开馆日历计算借出时的应还日期
+func loanDueDate(ctx context.Context, tx repository.Repositories, now time.Time) (time.Time, error) {
+	dueDate := now.AddDate(0, loanPolicy.PeriodMonths, 0)
+	calendar, err := loadCalendar(ctx, tx.Calendar(), dueDate, dueDate.AddDate(0, 0, maxRollDays))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return calendar.NextOpenDay(dueDate), nil
+}
+
+// CalendarDay 某一天的开馆情况
+type CalendarDay struct {
+	Date         string             `json:"date"`
+	Open         bool               `json:"open"`
+	Hours        *do.OpeningHours   `json:"hours,omitempty"`
+	Closure      *do.LibraryClosure `json:"closure,omitempty"`
+	NextOpenDate string             `json:"next_open_date"`
+}
+
+// LibraryCalendarView 日历查询结果
+type LibraryCalendarView struct {
+	Hours    []do.OpeningHours   `json:"hours"`
+	Closures []do.LibraryClosure `json:"closures"`
+}
+
+// CalendarService 开馆日历管理
+type CalendarService struct {
+	calendarDAO *dao.CalendarDAO
+}
+
+func NewCalendarService(db *sql.DB) *CalendarService {
+	return &CalendarService{calendarDAO: dao.NewCalendarDAO(db)}
+}
+
+// 获取每周开放时间和[from, to]内的闭馆日期
+func (s *CalendarService) GetCalendar(ctx context.Context, from, to string) (*LibraryCalendarView, error) {
+	if _, _, err := parseDateRange(from, to); err != nil {
+		return nil, err
+	}
+
+	hours, err := s.calendarDAO.GetOpeningHours(ctx)
+	if err != nil {
+		return nil, err
+	}
+	closures, err := s.calendarDAO.GetClosures(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if hours == nil {
+		hours = []do.OpeningHours{}
+	}
+	if closures == nil {
+		closures = []do.LibraryClosure{}
+	}
+	return &LibraryCalendarView{Hours: hours, Closures: closures}, nil
+}
+
+// 查询某天是否开馆以及下一个开馆日
+func (s *CalendarService) GetDay(ctx context.Context, date string) (*CalendarDay, error) {
+	day, err := time.ParseInLocation(do.DateLayout, date, time.Local)
+	if err != nil {
+		return nil, apperr.Invalid("invalid.date")
+	}
+
+	calendar, err := loadCalendar(ctx, s.calendarDAO, day, day.AddDate(0, 0, maxRollDays))
+	if err != nil {
+		return nil, err
+	}
+	hours, err := s.calendarDAO.GetOpeningHours(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CalendarDay{
+		Date:         date,
+		Open:         !calendar.IsClosed(day),
+		Closure:      calendar.closureOn(day),
+		NextOpenDate: calendar.NextOpenDay(day).Format(do.DateLayout),
+	}
+	for i := range hours {
+		if hours[i].Weekday == int(day.Weekday()) {
+			result.Hours = &hours[i]
+		}
+	}
+	return result, nil
+}
+
+// 设置某个星期的开放时间
+func (s *CalendarService) SetOpeningHours(ctx context.Context, hours *do.OpeningHours) error {
+	if hours.Weekday < 0 || hours.Weekday > 6 {
+		return apperr.Invalid("invalid.weekday_range")
+	}
+	if !hours.Closed {
+		open, err := time.Parse("15:04", hours.OpenTime)
+		if err != nil {
+			return apperr.Invalid("invalid.open_time")
+		}
+		closeAt, err := time.Parse("15:04", hours.CloseTime)
+		if err != nil {
+			return apperr.Invalid("invalid.close_time")
+		}
+		if !closeAt.After(open) {
+			return apperr.Invalid("invalid.close_before_open")
+		}
+	}
+	return s.calendarDAO.SetOpeningHours(ctx, hours)
+}
+
+// 添加闭馆日期段（节假日、寒暑假）
+func (s *CalendarService) AddClosure(ctx context.Context, closure *do.LibraryClosure) error {
+	switch closure.Kind {
+	case "":
+		closure.Kind = do.ClosureKindHoliday
+	case do.ClosureKindHoliday, do.ClosureKindBreak, do.ClosureKindOther:
+	default:
+		return apperr.Invalid("invalid.closure_kind")
+	}
+	if closure.EndDate == "" {
+		closure.EndDate = closure.StartDate
+	}
+	if _, _, err := parseDateRange(closure.StartDate, closure.EndDate); err != nil {
+		return err
+	}
+
+	id, err := s.calendarDAO.CreateClosure(ctx, closure)
+	if err != nil {
+		return err
+	}
+	closure.ID = id
+	return nil
+}
+
+// 删除闭馆日期段，已借出图书的应还日期不随之改变
+func (s *CalendarService) DeleteClosure(ctx context.Context, id int) error {
+	return s.calendarDAO.DeleteClosure(ctx, id)
+}
+
+func parseDateRange(from, to string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation(do.DateLayout, from, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, apperr.Invalid("invalid.start_date")
+	}
+	end, err := time.ParseInLocation(do.DateLayout, to, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, apperr.Invalid("invalid.end_date")
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, apperr.Invalid("invalid.end_before_start")
+	}
+	return start, end, nil
+}
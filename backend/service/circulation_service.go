@@ -0,0 +1,396 @@
+package service
+
+import (
+	"backend/apperr"
+	"backend/dao"
+	"backend/do"
+	"backend/events"
+	"backend/i18n"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// 借出受阻原因代码
+const (
+	BlockStudentDisabled   = "student_disabled"    // 学生借阅权限被禁用
+	BlockUnpaidFine        = "unpaid_fine"         // 有未支付的罚款
+	BlockBookNotBorrowable = "book_not_borrowable" // 书籍设为不可外借
+	BlockItemOnHold        = "item_on_hold"        // 单册在预约架上，为其他学生保留
+	BlockItemNotFound      = "item_not_found"      // 条码不存在
+	BlockItemUnavailable   = "item_unavailable"    // 单册已借出、调拨中，或在预约架上但没有待取的预约
+	BlockDuplicateBarcode  = "duplicate_barcode"   // 同一批次重复扫描
+)
+
+// 可由馆员强制越过的受阻原因
+var overridableBlocks = map[string]bool{
+	BlockStudentDisabled:   true,
+	BlockUnpaidFine:        true,
+	BlockBookNotBorrowable: true,
+	BlockItemOnHold:        true,
+}
+
+// CheckoutBlock 借出受阻原因
+type CheckoutBlock struct {
+	Code        string `json:"code"`
+	Message     string `json:"message"`
+	Barcode     string `json:"barcode,omitempty"`
+	Overridable bool   `json:"overridable"`
+}
+
+// CheckoutRequest 馆员办理借出的请求
+type CheckoutRequest struct {
+	LibrarianID    string
+	StuID          string
+	BranchID       string
+	Barcodes       []string
+	Overrides      []string // 要强制越过的受阻原因代码
+	OverrideReason string
+}
+
+// CheckoutLoan 借出成功的一册
+type CheckoutLoan struct {
+	Barcode string    `json:"barcode"`
+	BookID  string    `json:"book_id"`
+	Title   string    `json:"title"`
+	DueDate time.Time `json:"due_date"`
+}
+
+// CheckoutResult 借出结果；Blocked为true时没有借出任何一册
+type CheckoutResult struct {
+	Blocked    bool            `json:"blocked"`
+	Blocks     []CheckoutBlock `json:"blocks,omitempty"`
+	Overridden []CheckoutBlock `json:"overridden,omitempty"`
+	Loans      []CheckoutLoan  `json:"loans,omitempty"`
+}
+
+// CheckinItem 一册还书的处理结果
+type CheckinItem struct {
+	Barcode    string       `json:"barcode"`
+	Success    bool         `json:"success"`
+	Code       apperr.Code  `json:"code,omitempty"`  // 失败时的错误码
+	Error      string       `json:"error,omitempty"` // 失败时的提示
+	StuID      string       `json:"stu_id,omitempty"`
+	BookID     string       `json:"book_id,omitempty"`
+	Title      string       `json:"title,omitempty"`
+	IsOverdue  bool         `json:"is_overdue"`
+	FineAmount float64      `json:"fine_amount"`
+	Routing    *ItemRouting `json:"routing,omitempty"`
+}
+
+// PatronSummary 扫描借书证后展示给馆员的学生概况
+type PatronSummary struct {
+	Student *do.Student       `json:"student"`
+	Blocks  []CheckoutBlock   `json:"blocks"`
+	Loans   []do.BorrowRecord `json:"loans"`
+	Holds   []do.Hold         `json:"holds"`
+}
+
+// CirculationService 流通台业务：馆员代学生办理批量借还
+type CirculationService struct {
+	overrideDAO *dao.CirculationOverrideDAO
+	borrowDAO   *dao.BorrowDAO
+	holdDAO     *dao.HoldDAO
+	db          *sql.DB
+	bus         *events.Bus
+}
+
+func NewCirculationService(db *sql.DB, bus *events.Bus) *CirculationService {
+	return &CirculationService{
+		overrideDAO: dao.NewCirculationOverrideDAO(db),
+		borrowDAO:   dao.NewBorrowDAO(db),
+		holdDAO:     dao.NewHoldDAO(db),
+		db:          db,
+		bus:         bus,
+	}
+}
+
+// 扫描借书证：返回学生信息、受阻原因、在借记录和预约
+func (s *CirculationService) GetPatron(ctx context.Context, stuID string) (*PatronSummary, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	student, blocks, err := studentBlocks(ctx, tx, stuID)
+	if err != nil {
+		return nil, err
+	}
+	student.Password = ""
+
+	loans, err := s.borrowDAO.GetStudentBorrowRecords(ctx, stuID)
+	if err != nil {
+		return nil, err
+	}
+	holds, err := s.holdDAO.GetStudentHolds(ctx, stuID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PatronSummary{Student: student, Blocks: blocks, Loans: loans, Holds: holds}, nil
+}
+
+// 批量借出：所有单册在同一事务中借出，任何一个受阻原因未被越过时整批不借出
+func (s *CirculationService) Checkout(ctx context.Context, req *CheckoutRequest) (*CheckoutResult, error) {
+	if len(req.Barcodes) == 0 {
+		return nil, apperr.Invalid("invalid.barcodes_required")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, blocks, err := studentBlocks(ctx, tx, req.StuID)
+	if err != nil {
+		return nil, err
+	}
+
+	type checkoutItem struct {
+		item *do.BookItem
+		book *do.Book
+		hold *do.Hold // 该册所在预约架对应的预约
+	}
+
+	itemDAOTx := dao.NewBookItemDAOTx(tx)
+	bookDAOTx := dao.NewBookDAOTx(tx)
+	holdDAOTx := dao.NewHoldDAOTx(tx)
+
+	var items []checkoutItem
+	seen := make(map[string]bool)
+	for _, barcode := range req.Barcodes {
+		if seen[barcode] {
+			blocks = append(blocks, newBlock(BlockDuplicateBarcode, i18n.T(ctx, "block.duplicate_barcode", nil), barcode))
+			continue
+		}
+		seen[barcode] = true
+
+		item, err := itemDAOTx.GetItemByBarcode(ctx, barcode)
+		if apperr.Is(err, apperr.CodeItemNotFound) {
+			blocks = append(blocks, newBlock(BlockItemNotFound, i18n.T(ctx, "block.item_not_found", nil), barcode))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		book, err := bookDAOTx.GetBookByID(ctx, item.BookID)
+		if err != nil {
+			return nil, err
+		}
+		if !book.CanBorrow {
+			blocks = append(blocks, newBlock(BlockBookNotBorrowable, i18n.T(ctx, "block.book_not_borrowable", i18n.Params{"title": book.Title}), barcode))
+		}
+
+		var hold *do.Hold
+		switch item.Status {
+		case do.ItemStatusAvailable:
+		case do.ItemStatusOnHold:
+			hold, err = holdDAOTx.GetReadyHoldByBarcode(ctx, barcode)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case hold == nil:
+				// 预约已取消或过期但单册还没下架，不能直接借出
+				blocks = append(blocks, newBlock(BlockItemUnavailable, i18n.T(ctx, "block.item_unavailable", i18n.Params{"status": item.Status}), barcode))
+			case hold.StuID != req.StuID:
+				blocks = append(blocks, newBlock(BlockItemOnHold, i18n.T(ctx, "block.item_on_hold", i18n.Params{"stu_id": hold.StuID}), barcode))
+			}
+		default:
+			blocks = append(blocks, newBlock(BlockItemUnavailable, i18n.T(ctx, "block.item_unavailable", i18n.Params{"status": item.Status}), barcode))
+		}
+
+		items = append(items, checkoutItem{item: item, book: book, hold: hold})
+	}
+
+	// 区分已被越过和仍然受阻的原因
+	overrides := make(map[string]bool)
+	for _, code := range req.Overrides {
+		overrides[code] = true
+	}
+	result := &CheckoutResult{}
+	for _, block := range blocks {
+		if block.Overridable && overrides[block.Code] {
+			result.Overridden = append(result.Overridden, block)
+		} else {
+			result.Blocks = append(result.Blocks, block)
+		}
+	}
+	if len(result.Blocks) > 0 {
+		result.Blocked = true
+		return result, nil
+	}
+	if len(result.Overridden) > 0 && strings.TrimSpace(req.OverrideReason) == "" {
+		return nil, apperr.New(apperr.CodeOverrideReasonRequired)
+	}
+
+	now := time.Now()
+	var records []*do.BorrowRecord
+	for _, ci := range items {
+		// 借走为他人保留的书时，该预约重新排队
+		if ci.hold != nil {
+			status := do.HoldStatusFulfilled
+			barcode := ci.hold.Barcode
+			if ci.hold.StuID != req.StuID {
+				status, barcode = do.HoldStatusWaiting, ""
+			}
+			if err := holdDAOTx.UpdateHoldStatus(ctx, ci.hold.ID, status, barcode); err != nil {
+				return nil, err
+			}
+		}
+
+		branchID := ci.item.CurrentBranchID
+		if req.BranchID != "" {
+			branchID = req.BranchID
+		}
+		if err := itemDAOTx.UpdateItemLocation(ctx, ci.item.Barcode, branchID, do.ItemStatusOnLoan); err != nil {
+			return nil, err
+		}
+
+		record, err := createLoan(ctx, dao.NewRepositoriesTx(tx), req.StuID, ci.book, ci.item.Barcode, now)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+		result.Loans = append(result.Loans, CheckoutLoan{
+			Barcode: ci.item.Barcode,
+			BookID:  ci.book.BookID,
+			Title:   ci.book.Title,
+			DueDate: record.DueDate,
+		})
+	}
+
+	overrideDAOTx := dao.NewCirculationOverrideDAOTx(tx)
+	for _, block := range result.Overridden {
+		err := overrideDAOTx.CreateOverride(ctx, &do.CirculationOverride{
+			LibrarianID: req.LibrarianID,
+			StuID:       req.StuID,
+			Barcode:     block.Barcode,
+			BlockCode:   block.Code,
+			Reason:      req.OverrideReason,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		publishAvailability(ctx, s.bus, dao.NewBookDAO(s.db), record.BookID)
+		publishLoan(s.bus, events.TypeLoanBorrowed, record)
+	}
+	return result, nil
+}
+
+// 批量还书：每册单独提交，一册失败不影响其他册
+func (s *CirculationService) Checkin(ctx context.Context, barcodes []string, branchID string) []CheckinItem {
+	results := make([]CheckinItem, 0, len(barcodes))
+	for _, barcode := range barcodes {
+		item, err := s.checkinOne(ctx, barcode, branchID)
+		if err != nil {
+			appErr := apperr.From(err)
+			if appErr.Status() >= 500 {
+				slog.ErrorContext(ctx, "还书失败", "barcode", barcode, "error", err)
+			}
+			item = &CheckinItem{Barcode: barcode, Code: appErr.Code, Error: appErr.Localize(i18n.Locale(ctx))}
+		}
+		results = append(results, *item)
+	}
+	return results
+}
+
+func (s *CirculationService) checkinOne(ctx context.Context, barcode, branchID string) (*CheckinItem, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	record, err := dao.NewBorrowDAOTx(tx).GetActiveBorrowRecordByBarcode(ctx, barcode)
+	if err != nil {
+		return nil, err
+	}
+	book, err := dao.NewBookDAOTx(tx).GetBookByID(ctx, record.BookID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := returnLoan(ctx, dao.NewRepositoriesTx(tx), record, branchID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	publishAvailability(ctx, s.bus, dao.NewBookDAO(s.db), record.BookID)
+	publishLoan(s.bus, events.TypeLoanReturned, result.Record)
+	publishRouting(s.bus, record.BookID, result.Routing)
+
+	return &CheckinItem{
+		Barcode:    barcode,
+		Success:    true,
+		StuID:      record.StuID,
+		BookID:     record.BookID,
+		Title:      book.Title,
+		IsOverdue:  result.IsOverdue,
+		FineAmount: result.FineAmount,
+		Routing:    result.Routing,
+	}, nil
+}
+
+// 查询强制借出记录
+func (s *CirculationService) ListOverrides(ctx context.Context, stuID string, limit int) ([]do.CirculationOverride, error) {
+	return s.overrideDAO.ListOverrides(ctx, stuID, limit)
+}
+
+// 借书资格检查的错误码对应的受阻原因和提示
+var studentBlockCodes = map[apperr.Code]struct{ code, message string }{
+	apperr.CodeBorrowDisabled: {BlockStudentDisabled, "block.student_disabled"},
+	apperr.CodeUnpaidFine:     {BlockUnpaidFine, "block.unpaid_fine"},
+}
+
+// 在事务中获取学生及其借阅受阻原因，与普通借书使用同一套资格检查
+func studentBlocks(ctx context.Context, tx *sql.Tx, stuID string) (*do.Student, []CheckoutBlock, error) {
+	student, reasons, err := borrowBlocks(ctx, dao.NewStudentDAOTx(tx), stuID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var blocks []CheckoutBlock
+	for _, reason := range reasons {
+		block, ok := studentBlockCodes[reason.Code]
+		if !ok {
+			return nil, nil, fmt.Errorf("借书资格检查返回了未知的错误码: %s", reason.Code)
+		}
+		blocks = append(blocks, newBlock(block.code, i18n.T(ctx, block.message, nil), ""))
+	}
+	return student, blocks, nil
+}
+
+func newBlock(code, message, barcode string) CheckoutBlock {
+	return CheckoutBlock{
+		Code:        code,
+		Message:     message,
+		Barcode:     barcode,
+		Overridable: overridableBlocks[code],
+	}
+}
+
+// 校验强制借出原因代码
+func ValidateOverrideCodes(codes []string) error {
+	for _, code := range codes {
+		if !overridableBlocks[code] {
+			return apperr.New(apperr.CodeOverrideNotAllowed).With("code", code)
+		}
+	}
+	return nil
+}
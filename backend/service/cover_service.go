@@ -0,0 +1,246 @@
+package service
+
+import (
+	"backend/apperr"
+	"backend/dao"
+	"backend/do"
+	"backend/storage"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// 封面上传限制
+const (
+	MaxCoverBytes     = 5 << 20
+	maxCoverDimension = 8000
+)
+
+// 允许上传的封面类型，按文件内容嗅探而不是信任客户端声明
+var allowedCoverTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// CoverSize 封面尺寸规格，图片按比例缩放至不超过该宽高
+type CoverSize struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+var (
+	CoverSizeThumb  = CoverSize{Name: "thumb", Width: 200, Height: 300}
+	CoverSizeDetail = CoverSize{Name: "detail", Width: 600, Height: 900}
+	coverSizes      = []CoverSize{CoverSizeThumb, CoverSizeDetail}
+)
+
+type CoverService struct {
+	bookDAO *dao.BookDAO
+	storage storage.Storage
+	db      *sql.DB
+}
+
+func NewCoverService(db *sql.DB, store storage.Storage) *CoverService {
+	return &CoverService{
+		bookDAO: dao.NewBookDAO(db),
+		storage: store,
+		db:      db,
+	}
+}
+
+// 上传封面：校验格式后生成缩略图和详情图，替换旧封面
+func (s *CoverService) UploadCover(ctx context.Context, bookID string, data []byte) (*do.Book, error) {
+	book, err := s.bookDAO.GetBookByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > MaxCoverBytes {
+		return nil, apperr.New(apperr.CodeCoverTooLarge).With("max_mb", MaxCoverBytes>>20)
+	}
+	contentType := http.DetectContentType(data)
+	if !allowedCoverTypes[contentType] {
+		return nil, apperr.New(apperr.CodeCoverUnsupportedType).With("content_type", contentType)
+	}
+
+	// 先读取尺寸，避免解码超大图片耗尽内存
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, apperr.New(apperr.CodeCoverInvalid)
+	}
+	if cfg.Width > maxCoverDimension || cfg.Height > maxCoverDimension {
+		return nil, apperr.NewMessage(apperr.CodeCoverInvalid, "error.cover_dimension").With("max", maxCoverDimension)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, apperr.New(apperr.CodeCoverInvalid)
+	}
+
+	// 每次上传使用新的版本目录，URL随之变化，便于客户端长期缓存
+	version := strconv.FormatInt(time.Now().UnixNano(), 36)
+	coverKey := "covers/" + url.PathEscape(bookID) + "/" + version
+
+	for _, size := range coverSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resizeToFit(img, size), &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+		if err := s.storage.Put(ctx, coverObjectKey(coverKey, size), buf.Bytes(), "image/jpeg"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.updateCoverKey(ctx, do.AuditCoverUpdate, bookID, book.CoverKey, coverKey); err != nil {
+		return nil, err
+	}
+
+	// 旧封面清理失败不影响本次上传
+	if book.CoverKey != "" {
+		s.deleteObjects(ctx, book.CoverKey)
+	}
+
+	book.CoverKey = coverKey
+	fillCoverURLs(book)
+	return book, nil
+}
+
+// 删除封面
+func (s *CoverService) DeleteCover(ctx context.Context, bookID string) error {
+	book, err := s.bookDAO.GetBookByID(ctx, bookID)
+	if err != nil {
+		return err
+	}
+	if book.CoverKey == "" {
+		return apperr.NewMessage(apperr.CodeCoverNotFound, "error.book_has_no_cover")
+	}
+
+	if err := s.updateCoverKey(ctx, do.AuditCoverDelete, bookID, book.CoverKey, ""); err != nil {
+		return err
+	}
+	s.deleteObjects(ctx, book.CoverKey)
+	return nil
+}
+
+// coverAudit 审计日志中记录的封面
+type coverAudit struct {
+	CoverKey string `json:"cover_key"`
+}
+
+// 在事务中更新封面存储键并写入审计日志
+func (s *CoverService) updateCoverKey(ctx context.Context, action, bookID, oldKey, newKey string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := dao.NewBookDAOTx(tx).UpdateBookCoverKey(ctx, bookID, newKey); err != nil {
+		return err
+	}
+	err = recordAudit(ctx, dao.NewRepositoriesTx(tx), action, do.AuditEntityBook, bookID, coverAudit{CoverKey: oldKey}, coverAudit{CoverKey: newKey})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// 获取封面图片，返回图片数据、Content-Type和封面版本号
+func (s *CoverService) GetCover(ctx context.Context, bookID, sizeName string) ([]byte, string, string, error) {
+	size, ok := coverSizeByName(sizeName)
+	if !ok {
+		return nil, "", "", apperr.Invalid("invalid.cover_size").With("size", sizeName)
+	}
+
+	book, err := s.bookDAO.GetBookByID(ctx, bookID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if book.CoverKey == "" {
+		return nil, "", "", apperr.New(apperr.CodeCoverNotFound)
+	}
+
+	data, contentType, err := s.storage.Get(ctx, coverObjectKey(book.CoverKey, size))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, "", "", apperr.Wrap(apperr.CodeCoverNotFound, err)
+	}
+	if err != nil {
+		return nil, "", "", err
+	}
+	return data, contentType, path.Base(book.CoverKey), nil
+}
+
+// 数据库中的封面已经替换，请求被取消时仍然清理旧文件
+func (s *CoverService) deleteObjects(ctx context.Context, coverKey string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, size := range coverSizes {
+		s.storage.Delete(ctx, coverObjectKey(coverKey, size))
+	}
+}
+
+func coverSizeByName(name string) (CoverSize, bool) {
+	for _, size := range coverSizes {
+		if size.Name == name {
+			return size, true
+		}
+	}
+	return CoverSize{}, false
+}
+
+func coverObjectKey(coverKey string, size CoverSize) string {
+	return coverKey + "/" + size.Name + ".jpg"
+}
+
+// 按比例缩放到规格范围内，小图不放大
+func resizeToFit(img image.Image, size CoverSize) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	scale := 1.0
+	if sw := float64(size.Width) / float64(w); sw < scale {
+		scale = sw
+	}
+	if sh := float64(size.Height) / float64(h); sh < scale {
+		scale = sh
+	}
+
+	dw, dh := int(float64(w)*scale+0.5), int(float64(h)*scale+0.5)
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	// 透明背景铺白色，JPEG不支持透明通道
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// 根据封面存储键填充书籍JSON中的封面地址，URL带版本号以便缓存
+func fillCoverURLs(book *do.Book) {
+	if book.CoverKey == "" {
+		return
+	}
+	base := "/api/v1/books/" + url.PathEscape(book.BookID) + "/cover?v=" + path.Base(book.CoverKey)
+	book.CoverURL = base + "&size=" + CoverSizeDetail.Name
+	book.CoverThumbURL = base + "&size=" + CoverSizeThumb.Name
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"backend/do"
+	"image"
+	"testing"
+)
+
+func TestResizeToFit(t *testing.T) {
+	tests := []struct {
+		name         string
+		width        int
+		height       int
+		size         CoverSize
+		wantW, wantH int
+	}{
+		{"按高度缩小", 1200, 2400, CoverSizeThumb, 150, 300},
+		{"按宽度缩小", 1800, 900, CoverSizeDetail, 600, 300},
+		{"比例一致", 400, 600, CoverSizeThumb, 200, 300},
+		{"小图不放大", 120, 80, CoverSizeDetail, 120, 80},
+		{"极窄图片至少1像素", 8000, 1, CoverSizeThumb, 200, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+			got := resizeToFit(img, tt.size).Bounds()
+			if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+				t.Errorf("resizeToFit(%dx%d, %s) = %dx%d，期望 %dx%d",
+					tt.width, tt.height, tt.size.Name, got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+// 封面地址带有版本号，换封面后URL随之变化
+func TestFillCoverURLs(t *testing.T) {
+	book := &do.Book{BookID: "B 001", CoverKey: "covers/B%20001/abc123"}
+	fillCoverURLs(book)
+	if book.CoverURL != "/api/v1/books/B%20001/cover?v=abc123&size=detail" {
+		t.Errorf("CoverURL = %s", book.CoverURL)
+	}
+	if book.CoverThumbURL != "/api/v1/books/B%20001/cover?v=abc123&size=thumb" {
+		t.Errorf("CoverThumbURL = %s", book.CoverThumbURL)
+	}
+
+	book = &do.Book{BookID: "B002"}
+	fillCoverURLs(book)
+	if book.CoverURL != "" || book.CoverThumbURL != "" {
+		t.Errorf("没有封面时 URL = %q, %q", book.CoverURL, book.CoverThumbURL)
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"backend/migrations"
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 就绪检查中单项检查的时限
+const healthCheckTimeout = 2 * time.Second
+
+// 单项检查的结果
+const (
+	HealthOK       = "ok"
+	HealthFailed   = "failed"
+	HealthSkipped  = "skipped"
+	HealthDraining = "draining"
+)
+
+// HealthCheck 一项检查的结果，Error为失败原因
+type HealthCheck struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency,omitempty"`
+}
+
+// HealthReport 就绪检查的结果，全部检查通过时Ready为true
+type HealthReport struct {
+	Ready  bool                   `json:"ready"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+// Heartbeat 后台任务每轮开始时记录时间，超过3个执行间隔再加1分钟没有开始新一轮时视为停止
+type Heartbeat struct {
+	interval atomic.Int64
+	last     atomic.Int64
+}
+
+// 开始一轮任务
+func (h *Heartbeat) beat(interval time.Duration) {
+	h.interval.Store(int64(interval))
+	h.last.Store(time.Now().UnixNano())
+}
+
+// 检查任务是否在按时运行，尚未开始第一轮时也视为停止
+func (h *Heartbeat) check(now time.Time) error {
+	last := h.last.Load()
+	if last == 0 {
+		return fmt.Errorf("任务尚未运行")
+	}
+	since := now.Sub(time.Unix(0, last))
+	if since > 3*time.Duration(h.interval.Load())+time.Minute {
+		return fmt.Errorf("任务已%s没有运行", since.Truncate(time.Second))
+	}
+	return nil
+}
+
+// HealthService 存活和就绪检查：就绪要求数据库可以连接、表结构为最新版本、已启动的后台任务在按时运行，且服务不在退出过程中
+type HealthService struct {
+	db       *sql.DB
+	migrator *migrations.Migrator
+
+	mu       sync.Mutex
+	workers  map[string]*Heartbeat
+	draining atomic.Bool
+}
+
+func NewHealthService(db *sql.DB, migrator *migrations.Migrator) *HealthService {
+	return &HealthService{db: db, migrator: migrator, workers: make(map[string]*Heartbeat)}
+}
+
+// 把后台任务加入就绪检查，在启动任务时调用
+func (s *HealthService) Watch(name string, heartbeat *Heartbeat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workers[name] = heartbeat
+}
+
+// 开始退出，之后就绪检查始终失败，负载均衡不再转发新请求
+func (s *HealthService) Drain() {
+	s.draining.Store(true)
+}
+
+// 执行全部就绪检查
+func (s *HealthService) Ready(ctx context.Context) *HealthReport {
+	report := &HealthReport{Ready: true, Checks: make(map[string]HealthCheck)}
+	set := func(name string, check HealthCheck) {
+		if check.Status != HealthOK {
+			report.Ready = false
+		}
+		report.Checks[name] = check
+	}
+
+	if s.draining.Load() {
+		set("shutdown", HealthCheck{Status: HealthDraining})
+	}
+
+	database := s.pingDatabase(ctx)
+	set("database", database)
+
+	// 数据库不可用时无法检查迁移
+	if database.Status == HealthOK {
+		set("migrations", s.checkMigrations())
+	} else {
+		set("migrations", HealthCheck{Status: HealthSkipped})
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, heartbeat := range s.workers {
+		if err := heartbeat.check(now); err != nil {
+			set(name, HealthCheck{Status: HealthFailed, Error: err.Error()})
+		} else {
+			set(name, HealthCheck{Status: HealthOK})
+		}
+	}
+	return report
+}
+
+func (s *HealthService) pingDatabase(ctx context.Context) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := s.db.PingContext(ctx); err != nil {
+		return HealthCheck{Status: HealthFailed, Error: err.Error()}
+	}
+	return HealthCheck{Status: HealthOK, Latency: time.Since(start).String()}
+}
+
+func (s *HealthService) checkMigrations() HealthCheck {
+	pending, err := s.migrator.Pending()
+	if err != nil {
+		return HealthCheck{Status: HealthFailed, Error: err.Error()}
+	}
+	if pending > 0 {
+		return HealthCheck{Status: HealthFailed, Error: fmt.Sprintf("有%d个迁移尚未执行", pending)}
+	}
+	return HealthCheck{Status: HealthOK}
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	var heartbeat Heartbeat
+	now := time.Now()
+	if err := heartbeat.check(now); err == nil {
+		t.Error("尚未运行的任务应检查失败")
+	}
+
+	heartbeat.beat(30 * time.Second)
+	if err := heartbeat.check(now.Add(2 * time.Minute)); err != nil {
+		t.Errorf("间隔内检查失败: %v", err)
+	}
+	// 超过3个执行间隔加1分钟没有开始新一轮
+	if err := heartbeat.check(now.Add(3 * time.Minute)); err == nil {
+		t.Error("任务停止后应检查失败")
+	}
+}
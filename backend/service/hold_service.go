@@ -0,0 +1,184 @@
+package service
+
+import (
+	"backend/apperr"
+	"backend/dao"
+	"backend/do"
+	"backend/events"
+	"context"
+	"database/sql"
+)
+
+type HoldService struct {
+	studentService *StudentService
+	holdDAO        *dao.HoldDAO
+	db             *sql.DB
+	bus            *events.Bus
+}
+
+func NewHoldService(db *sql.DB, bus *events.Bus) *HoldService {
+	return &HoldService{
+		studentService: NewStudentService(dao.NewStore(db)),
+		holdDAO:        dao.NewHoldDAO(db),
+		db:             db,
+		bus:            bus,
+	}
+}
+
+// 预约图书并指定取书分馆
+// 取书分馆有在架副本时直接上预约架；其他分馆有在架副本时调拨过来；都没有则排队等待归还
+func (s *HoldService) PlaceHold(ctx context.Context, stuID, bookID, pickupBranchID string) (*do.Hold, error) {
+	blocked, err := borrowBlock(ctx, s.studentService.studentDAO, stuID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked != nil {
+		return nil, blocked
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	book, err := dao.NewBookDAOTx(tx).GetBookByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if !book.CanBorrow {
+		return nil, apperr.New(apperr.CodeBookNotBorrowable)
+	}
+	if _, err := dao.NewBranchDAOTx(tx).GetBranchByID(ctx, pickupBranchID); err != nil {
+		return nil, err
+	}
+
+	itemDAOTx := dao.NewBookItemDAOTx(tx)
+	itemCount, err := itemDAOTx.CountItems(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if itemCount == 0 {
+		return nil, apperr.New(apperr.CodeNoHoldings)
+	}
+
+	holdDAOTx := dao.NewHoldDAOTx(tx)
+	active, err := holdDAOTx.GetActiveHold(ctx, stuID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return nil, apperr.New(apperr.CodeHoldExists)
+	}
+
+	hold := &do.Hold{
+		StuID:          stuID,
+		BookID:         bookID,
+		PickupBranchID: pickupBranchID,
+		Status:         do.HoldStatusWaiting,
+	}
+	hold.ID, err = holdDAOTx.CreateHold(ctx, hold)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := itemDAOTx.FindAvailableItem(ctx, bookID, pickupBranchID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		item, err = itemDAOTx.FindAvailableItem(ctx, bookID, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	var routing *ItemRouting
+	if item != nil {
+		if routing, err = assignItemToHold(ctx, dao.NewRepositoriesTx(tx), item, item.CurrentBranchID, hold); err != nil {
+			return nil, err
+		}
+		if err := dao.NewBookDAOTx(tx).SyncCopiesFromItems(ctx, bookID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	hold, err = s.holdDAO.GetHoldByID(ctx, hold.ID)
+	if err != nil {
+		return nil, err
+	}
+	publishHold(s.bus, events.TypeHoldPlaced, hold)
+	if routing != nil {
+		publishAvailability(ctx, s.bus, dao.NewBookDAO(s.db), bookID)
+		publishRouting(s.bus, bookID, routing)
+	}
+	return hold, nil
+}
+
+// 取消预约，已上预约架的单册重新分配
+func (s *HoldService) CancelHold(ctx context.Context, id int, stuID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	holdDAOTx := dao.NewHoldDAOTx(tx)
+	hold, err := holdDAOTx.GetHoldByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hold.StuID != stuID {
+		return apperr.New(apperr.CodeHoldNotFound)
+	}
+
+	switch hold.Status {
+	case do.HoldStatusWaiting, do.HoldStatusInTransit:
+		// 运送中的单册在签收时发现预约已取消，会按普通归还处理
+	case do.HoldStatusReady:
+		item, err := dao.NewBookItemDAOTx(tx).GetItemByBarcode(ctx, hold.Barcode)
+		if err != nil {
+			return err
+		}
+		if err := holdDAOTx.UpdateHoldStatus(ctx, id, do.HoldStatusCancelled, hold.Barcode); err != nil {
+			return err
+		}
+		routing, err := routeItem(ctx, dao.NewRepositoriesTx(tx), item, item.CurrentBranchID, true)
+		if err != nil {
+			return err
+		}
+		if err := dao.NewBookDAOTx(tx).SyncCopiesFromItems(ctx, item.BookID); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		hold.Status = do.HoldStatusCancelled
+		publishHold(s.bus, events.TypeHoldCancelled, hold)
+		publishAvailability(ctx, s.bus, dao.NewBookDAO(s.db), item.BookID)
+		publishRouting(s.bus, item.BookID, routing)
+		return nil
+	default:
+		return apperr.New(apperr.CodeHoldClosed)
+	}
+
+	if err := holdDAOTx.UpdateHoldStatus(ctx, id, do.HoldStatusCancelled, hold.Barcode); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	hold.Status = do.HoldStatusCancelled
+	publishHold(s.bus, events.TypeHoldCancelled, hold)
+	return nil
+}
+
+// 获取学生的预约列表
+func (s *HoldService) GetStudentHolds(ctx context.Context, stuID string) ([]do.Hold, error) {
+	return s.holdDAO.GetStudentHolds(ctx, stuID)
+}
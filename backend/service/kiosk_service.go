@@ -0,0 +1,234 @@
+package service
+
+import (
+	"backend/apperr"
+	"backend/dao"
+	"backend/do"
+	"backend/events"
+	"backend/i18n"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// 设备未指定时的每分钟请求上限
+const defaultKioskRateLimit = 30
+
+// 借书证或PIN校验失败
+var ErrInvalidPIN = apperr.New(apperr.CodeInvalidPIN)
+
+// KioskSlip 自助借还机打印的凭条
+type KioskSlip struct {
+	Type        string          `json:"type"` // checkout 或 return
+	DeviceID    string          `json:"device_id"`
+	BranchID    string          `json:"branch_id"`
+	StuID       string          `json:"stu_id,omitempty"`
+	StudentName string          `json:"student_name,omitempty"`
+	Items       []KioskSlipItem `json:"items"`
+	TotalFine   float64         `json:"total_fine"`
+	PrintedAt   time.Time       `json:"printed_at"`
+}
+
+// KioskSlipItem 凭条上的一册
+type KioskSlipItem struct {
+	Barcode    string     `json:"barcode"`
+	Title      string     `json:"title,omitempty"`
+	DueDate    *time.Time `json:"due_date,omitempty"`
+	FineAmount float64    `json:"fine_amount,omitempty"`
+	Message    string     `json:"message,omitempty"`
+}
+
+// KioskService 自助借还机：设备登记与认证，借还复用流通台的借还规则
+type KioskService struct {
+	deviceDAO          *dao.KioskDeviceDAO
+	branchDAO          *dao.BranchDAO
+	studentService     *StudentService
+	circulationService *CirculationService
+}
+
+func NewKioskService(db *sql.DB, bus *events.Bus) *KioskService {
+	return &KioskService{
+		deviceDAO:          dao.NewKioskDeviceDAO(db),
+		branchDAO:          dao.NewBranchDAO(db),
+		studentService:     NewStudentService(dao.NewStore(db)),
+		circulationService: NewCirculationService(db, bus),
+	}
+}
+
+// 登记设备，返回的API密钥只在此时出现一次，数据库中只保存哈希
+func (s *KioskService) RegisterDevice(ctx context.Context, deviceID, name, branchID string, rateLimitPerMin int) (*do.KioskDevice, string, error) {
+	if _, err := s.branchDAO.GetBranchByID(ctx, branchID); err != nil {
+		return nil, "", err
+	}
+	if rateLimitPerMin <= 0 {
+		rateLimitPerMin = defaultKioskRateLimit
+	}
+
+	apiKey, err := newAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	device := &do.KioskDevice{
+		DeviceID:        deviceID,
+		Name:            name,
+		BranchID:        branchID,
+		APIKeyHash:      hashAPIKey(apiKey),
+		Enabled:         true,
+		RateLimitPerMin: rateLimitPerMin,
+	}
+	if err := s.deviceDAO.CreateDevice(ctx, device); err != nil {
+		return nil, "", fmt.Errorf("登记设备失败: %w", err)
+	}
+
+	device, err = s.deviceDAO.GetDeviceByID(ctx, deviceID)
+	if err != nil {
+		return nil, "", err
+	}
+	return device, apiKey, nil
+}
+
+// 重新生成设备API密钥，旧密钥立即失效
+func (s *KioskService) RotateDeviceKey(ctx context.Context, deviceID string) (string, error) {
+	if _, err := s.deviceDAO.GetDeviceByID(ctx, deviceID); err != nil {
+		return "", err
+	}
+
+	apiKey, err := newAPIKey()
+	if err != nil {
+		return "", err
+	}
+	if err := s.deviceDAO.UpdateDeviceKeyHash(ctx, deviceID, hashAPIKey(apiKey)); err != nil {
+		return "", err
+	}
+	return apiKey, nil
+}
+
+// 启用或停用设备
+func (s *KioskService) SetDeviceEnabled(ctx context.Context, deviceID string, enabled bool) error {
+	if _, err := s.deviceDAO.GetDeviceByID(ctx, deviceID); err != nil {
+		return err
+	}
+	return s.deviceDAO.UpdateDeviceEnabled(ctx, deviceID, enabled)
+}
+
+// 修改设备每分钟请求上限
+func (s *KioskService) SetDeviceRateLimit(ctx context.Context, deviceID string, rateLimitPerMin int) error {
+	if rateLimitPerMin <= 0 {
+		return apperr.Invalid("invalid.rate_limit")
+	}
+	if _, err := s.deviceDAO.GetDeviceByID(ctx, deviceID); err != nil {
+		return err
+	}
+	return s.deviceDAO.UpdateDeviceRateLimit(ctx, deviceID, rateLimitPerMin)
+}
+
+// 获取所有设备
+func (s *KioskService) GetAllDevices(ctx context.Context) ([]do.KioskDevice, error) {
+	return s.deviceDAO.GetAllDevices(ctx)
+}
+
+// 根据API密钥认证设备，停用的设备不能使用
+func (s *KioskService) AuthenticateDevice(ctx context.Context, apiKey string) (*do.KioskDevice, error) {
+	device, err := s.deviceDAO.GetDeviceByKeyHash(ctx, hashAPIKey(apiKey))
+	if err != nil {
+		return nil, err
+	}
+	if !device.Enabled {
+		return nil, apperr.New(apperr.CodeDeviceDisabled)
+	}
+
+	// 最近使用时间只用于运维查看，更新失败不影响请求
+	now := time.Now()
+	s.deviceDAO.TouchDevice(ctx, device.DeviceID, now)
+	device.LastSeenAt = &now
+	return device, nil
+}
+
+// 自助借书：校验借书证和PIN后在设备所在分馆借出，受阻时不借出任何一册
+func (s *KioskService) Checkout(ctx context.Context, device *do.KioskDevice, stuID, pin string, barcodes []string) (*KioskSlip, *CheckoutResult, error) {
+	ok, err := s.studentService.VerifyPIN(ctx, stuID, pin)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, ErrInvalidPIN
+	}
+
+	result, err := s.circulationService.Checkout(ctx, &CheckoutRequest{
+		StuID:    stuID,
+		BranchID: device.BranchID,
+		Barcodes: barcodes,
+	})
+	if err != nil || result.Blocked {
+		return nil, result, err
+	}
+
+	student, err := s.studentService.GetStudentInfo(ctx, stuID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	slip := s.newSlip("checkout", device)
+	slip.StuID = stuID
+	slip.StudentName = student.Name
+	for _, loan := range result.Loans {
+		dueDate := loan.DueDate
+		slip.Items = append(slip.Items, KioskSlipItem{
+			Barcode: loan.Barcode,
+			Title:   loan.Title,
+			DueDate: &dueDate,
+		})
+	}
+	return slip, result, nil
+}
+
+// 自助还书：在设备所在分馆逐册归还，凭条列出每册的罚款
+func (s *KioskService) Return(ctx context.Context, device *do.KioskDevice, barcodes []string) *KioskSlip {
+	slip := s.newSlip("return", device)
+	for _, item := range s.circulationService.Checkin(ctx, barcodes, device.BranchID) {
+		slipItem := KioskSlipItem{
+			Barcode:    item.Barcode,
+			Title:      item.Title,
+			FineAmount: item.FineAmount,
+		}
+		switch {
+		case !item.Success:
+			slipItem.Message = i18n.T(ctx, "kiosk.return_failed", i18n.Params{"error": item.Error})
+		case item.FineAmount > 0:
+			slipItem.Message = i18n.T(ctx, "kiosk.returned_late", i18n.Params{"fine": i18n.Money(item.FineAmount)})
+		default:
+			slipItem.Message = i18n.T(ctx, "kiosk.returned", nil)
+		}
+		slip.Items = append(slip.Items, slipItem)
+		slip.TotalFine += item.FineAmount
+	}
+	return slip
+}
+
+func (s *KioskService) newSlip(slipType string, device *do.KioskDevice) *KioskSlip {
+	return &KioskSlip{
+		Type:      slipType,
+		DeviceID:  device.DeviceID,
+		BranchID:  device.BranchID,
+		Items:     []KioskSlipItem{},
+		PrintedAt: time.Now(),
+	}
+}
+
+func newAPIKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func hashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"backend/dao"
+	"backend/do"
+	"context"
+	"database/sql"
+)
+
+type LibrarianService struct {
+	librarianDAO *dao.LibrarianDAO
+}
+
+func NewLibrarianService(db *sql.DB) *LibrarianService {
+	return &LibrarianService{
+		librarianDAO: dao.NewLibrarianDAO(db),
+	}
+}
+
+// 获取馆员信息
+func (s *LibrarianService) GetLibrarianInfo(ctx context.Context, librarianID string) (*do.Librarian, error) {
+	return s.librarianDAO.GetLibrarianByID(ctx, librarianID)
+}
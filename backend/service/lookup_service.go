@@ -0,0 +1,115 @@
+package service
+
+import (
+	"backend/dao"
+	"backend/do"
+	"backend/tracing"
+	"context"
+	"database/sql"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// LookupService 按ID批量读取图书、学生、借阅和预约，一次查询取回一批ID的数据，
+// 供GraphQL接口合并嵌套字段的查询；结果按ID分组，查不到的ID不出现在结果中
+type LookupService struct {
+	bookDAO    *dao.BookDAO
+	studentDAO *dao.StudentDAO
+	borrowDAO  *dao.BorrowDAO
+	holdDAO    *dao.HoldDAO
+}
+
+func NewLookupService(db *sql.DB) *LookupService {
+	return &LookupService{
+		bookDAO:    dao.NewBookDAO(db),
+		studentDAO: dao.NewStudentDAO(db),
+		borrowDAO:  dao.NewBorrowDAO(db),
+		holdDAO:    dao.NewHoldDAO(db),
+	}
+}
+
+// 批量读取的span属性
+func batchAttribute(ids []string) attribute.KeyValue {
+	return attribute.Int("library.batch_size", len(ids))
+}
+
+// 按图书ID批量获取图书，附带封面地址
+func (s *LookupService) BooksByID(ctx context.Context, bookIDs []string) (_ map[string]*do.Book, err error) {
+	ctx, span := tracing.Start(ctx, "LookupService.BooksByID", batchAttribute(bookIDs))
+	defer tracing.End(span, &err)
+
+	books, err := s.bookDAO.GetBooksByIDs(ctx, bookIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*do.Book, len(books))
+	for i := range books {
+		fillCoverURLs(&books[i])
+		result[books[i].BookID] = &books[i]
+	}
+	return result, nil
+}
+
+// 按学号批量获取学生
+func (s *LookupService) StudentsByID(ctx context.Context, stuIDs []string) (_ map[string]*do.Student, err error) {
+	ctx, span := tracing.Start(ctx, "LookupService.StudentsByID", batchAttribute(stuIDs))
+	defer tracing.End(span, &err)
+
+	students, err := s.studentDAO.GetStudentsByIDs(ctx, stuIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*do.Student, len(students))
+	for i := range students {
+		result[students[i].StuId] = &students[i]
+	}
+	return result, nil
+}
+
+// 批量获取若干学生未归还的借阅，按学号分组
+func (s *LookupService) LoansByStudent(ctx context.Context, stuIDs []string) (_ map[string][]do.BorrowRecord, err error) {
+	ctx, span := tracing.Start(ctx, "LookupService.LoansByStudent", batchAttribute(stuIDs))
+	defer tracing.End(span, &err)
+
+	records, err := s.borrowDAO.GetActiveBorrowRecordsByStudentIDs(ctx, stuIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]do.BorrowRecord)
+	for _, record := range records {
+		result[record.StuID] = append(result[record.StuID], record)
+	}
+	return result, nil
+}
+
+// 批量获取若干图书未归还的借阅，按图书ID分组
+func (s *LookupService) LoansByBook(ctx context.Context, bookIDs []string) (_ map[string][]do.BorrowRecord, err error) {
+	ctx, span := tracing.Start(ctx, "LookupService.LoansByBook", batchAttribute(bookIDs))
+	defer tracing.End(span, &err)
+
+	records, err := s.borrowDAO.GetActiveBorrowRecordsByBookIDs(ctx, bookIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]do.BorrowRecord)
+	for _, record := range records {
+		result[record.BookID] = append(result[record.BookID], record)
+	}
+	return result, nil
+}
+
+// 批量获取若干学生的预约，按学号分组
+func (s *LookupService) HoldsByStudent(ctx context.Context, stuIDs []string) (_ map[string][]do.Hold, err error) {
+	ctx, span := tracing.Start(ctx, "LookupService.HoldsByStudent", batchAttribute(stuIDs))
+	defer tracing.End(span, &err)
+
+	holds, err := s.holdDAO.GetHoldsByStudentIDs(ctx, stuIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]do.Hold)
+	for _, hold := range holds {
+		result[hold.StuID] = append(result[hold.StuID], hold)
+	}
+	return result, nil
+}
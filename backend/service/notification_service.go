@@ -0,0 +1,269 @@
+package service
+
+import (
+	"backend/apperr"
+	"backend/dao"
+	"backend/do"
+	"backend/notify"
+	"context"
+	"database/sql"
+	"log/slog"
+	"math"
+	"net/mail"
+	"time"
+)
+
+const (
+	// 每条通知最多发送次数，用尽后标记为failed
+	maxDeliveryAttempts = 5
+	// 每轮最多发送的通知数
+	deliveryBatchSize = 100
+	// 认领通知后的发送租期，进程在发送中途退出时过期后由其他实例重试
+	deliveryLease = 5 * time.Minute
+)
+
+// NotificationService 生成到期、逾期和预约到馆通知并通过邮件发送
+type NotificationService struct {
+	notificationDAO *dao.NotificationDAO
+	calendarDAO     *dao.CalendarDAO
+	sender          notify.Sender
+	heartbeat       Heartbeat
+}
+
+// sender为nil时只能管理通知设置和投递记录，不能发送
+func NewNotificationService(db *sql.DB, sender notify.Sender) *NotificationService {
+	return &NotificationService{
+		notificationDAO: dao.NewNotificationDAO(db),
+		calendarDAO:     dao.NewCalendarDAO(db),
+		sender:          sender,
+	}
+}
+
+// 获取学生的通知设置，未设置时返回全部关闭的默认设置
+func (s *NotificationService) GetPreference(ctx context.Context, stuID string) (*do.NotificationPreference, error) {
+	pref, err := s.notificationDAO.GetPreference(ctx, stuID)
+	if err != nil {
+		return nil, err
+	}
+	if pref == nil {
+		pref = &do.NotificationPreference{StuID: stuID, Language: do.LanguageZhCN}
+	}
+	return pref, nil
+}
+
+// 获取学生在通知设置中选择的语言，未保存过通知设置时返回空
+func (s *NotificationService) PreferredLanguage(ctx context.Context, stuID string) (string, error) {
+	pref, err := s.notificationDAO.GetPreference(ctx, stuID)
+	if err != nil || pref == nil {
+		return "", err
+	}
+	return pref.Language, nil
+}
+
+// 保存学生的通知设置，订阅任一通知时必须填写邮箱
+func (s *NotificationService) SetPreference(ctx context.Context, pref *do.NotificationPreference) error {
+	if pref.Language == "" {
+		pref.Language = do.LanguageZhCN
+	}
+	if !notify.SupportedLanguage(pref.Language) {
+		return apperr.Invalid("invalid.language").With("language", pref.Language)
+	}
+	if pref.Email != "" {
+		addr, err := mail.ParseAddress(pref.Email)
+		if err != nil {
+			return apperr.Invalid("invalid.email")
+		}
+		pref.Email = addr.Address
+	} else if pref.DueSoon || pref.Overdue || pref.HoldReady {
+		return apperr.Invalid("invalid.email_required")
+	}
+	return s.notificationDAO.SavePreference(ctx, pref)
+}
+
+// 扫描借阅记录和预约，为需要提醒的学生生成通知，返回新生成的数量
+func (s *NotificationService) EnqueueNotices(ctx context.Context, now time.Time) (int, error) {
+	var candidates []do.NotificationCandidate
+
+	dueSoon, err := s.notificationDAO.FindDueSoonCandidates(ctx, now, now.AddDate(0, 0, loanPolicy.DueSoonDays))
+	if err != nil {
+		return 0, err
+	}
+	candidates = append(candidates, dueSoon...)
+
+	overdue, err := s.notificationDAO.FindOverdueCandidates(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	candidates = append(candidates, overdue...)
+
+	holdReady, err := s.notificationDAO.FindHoldReadyCandidates(ctx)
+	if err != nil {
+		return 0, err
+	}
+	candidates = append(candidates, holdReady...)
+
+	for _, c := range candidates {
+		data, err := s.templateData(ctx, &c, now)
+		if err != nil {
+			return 0, err
+		}
+		subject, body, err := notify.Render(c.Kind, c.Language, data)
+		if err != nil {
+			return 0, err
+		}
+
+		err = s.notificationDAO.CreateNotification(ctx, &do.Notification{
+			StuID:         c.StuID,
+			Kind:          c.Kind,
+			RefID:         c.RefID,
+			Email:         c.Email,
+			Subject:       subject,
+			Body:          body,
+			Status:        do.NotificationStatusPending,
+			NextAttemptAt: now,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(candidates), nil
+}
+
+func (s *NotificationService) templateData(ctx context.Context, c *do.NotificationCandidate, now time.Time) (*notify.TemplateData, error) {
+	data := &notify.TemplateData{
+		StudentName:  c.StudentName,
+		BookTitle:    c.BookTitle,
+		Barcode:      c.Barcode,
+		PickupBranch: c.PickupBranch,
+	}
+
+	switch c.Kind {
+	case do.NotificationKindDueSoon:
+		data.DueDate = c.DueDate.Format(do.DateLayout)
+		data.DaysLeft = int(math.Ceil(c.DueDate.Sub(now).Hours() / 24))
+	case do.NotificationKindOverdue:
+		// 预计罚款与还书时的算法一致，闭馆日不计
+		calendar, err := loadCalendar(ctx, s.calendarDAO, c.DueDate, now)
+		if err != nil {
+			return nil, err
+		}
+		data.DueDate = c.DueDate.Format(do.DateLayout)
+		data.DaysOverdue = calendar.OverdueDays(c.DueDate, now)
+		data.FineEstimate = float64(data.DaysOverdue) * loanPolicy.FinePerDay
+	}
+	return data, nil
+}
+
+// 发送到了发送时间的通知，失败的按指数退避重试，返回发送成功和失败的数量
+func (s *NotificationService) DeliverPending(ctx context.Context, now time.Time) (int, int, error) {
+	if s.sender == nil {
+		return 0, 0, apperr.New(apperr.CodeMailNotConfigured)
+	}
+
+	notifications, err := s.notificationDAO.GetDueNotifications(ctx, now, deliveryBatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sent, failed := 0, 0
+	for _, n := range notifications {
+		claimed, err := s.notificationDAO.ClaimNotification(ctx, n.ID, now, now.Add(deliveryLease))
+		if err != nil {
+			return sent, failed, err
+		}
+		if !claimed {
+			continue
+		}
+
+		sendErr := s.sender.Send(context.Background(), &notify.Message{To: n.Email, Subject: n.Subject, Body: n.Body})
+		if sendErr == nil {
+			if err := s.notificationDAO.MarkSent(ctx, n.ID, time.Now()); err != nil {
+				return sent, failed, err
+			}
+			sent++
+			continue
+		}
+
+		failed++
+		status, nextAttemptAt := do.NotificationStatusPending, now.Add(retryDelay(n.Attempts+1))
+		if n.Attempts+1 >= maxDeliveryAttempts {
+			status = do.NotificationStatusFailed
+		}
+		if err := s.notificationDAO.MarkFailed(ctx, n.ID, status, sendErr.Error(), nextAttemptAt); err != nil {
+			return sent, failed, err
+		}
+	}
+	return sent, failed, nil
+}
+
+// 第n次失败后的重试间隔：1、2、4、8分钟……
+func retryDelay(attempts int) time.Duration {
+	return time.Minute << (attempts - 1)
+}
+
+// 定时任务的心跳，供就绪检查使用
+func (s *NotificationService) Heartbeat() *Heartbeat {
+	return &s.heartbeat
+}
+
+// 定时生成并发送通知，直到stop被关闭；stop关闭时正在执行的查询随之中止
+func (s *NotificationService) RunScheduler(interval time.Duration, stop <-chan struct{}) {
+	ctx, cancel := stopContext(stop)
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.heartbeat.beat(interval)
+		s.runOnce(ctx, time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// 返回在stop关闭时取消的ctx，用于中止定时任务中正在执行的查询
+func stopContext(stop <-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+func (s *NotificationService) runOnce(ctx context.Context, now time.Time) {
+	if _, err := s.EnqueueNotices(ctx, now); err != nil {
+		slog.ErrorContext(ctx, "生成通知失败", "error", err)
+	}
+	sent, failed, err := s.DeliverPending(ctx, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "发送通知失败", "error", err)
+	}
+	if sent > 0 || failed > 0 {
+		slog.InfoContext(ctx, "通知发送完成", "sent", sent, "failed", failed)
+	}
+}
+
+// 查询投递记录
+func (s *NotificationService) ListDeliveries(ctx context.Context, stuID, status string, limit int) ([]do.Notification, error) {
+	return s.notificationDAO.ListNotifications(ctx, stuID, status, limit)
+}
+
+// 立即重新发送一条未成功的通知
+func (s *NotificationService) RetryDelivery(ctx context.Context, id int) error {
+	ok, err := s.notificationDAO.ResetNotification(ctx, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return apperr.New(apperr.CodeNotificationNotFound)
+	}
+	return nil
+}
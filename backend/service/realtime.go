@@ -0,0 +1,120 @@
+package service
+
+import (
+	"backend/do"
+	"backend/events"
+	"backend/metrics"
+	"backend/repository"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// AvailabilityChange availability事件数据
+type AvailabilityChange struct {
+	BookID          string `json:"book_id"`
+	TotalCopies     int    `json:"total_copies"`
+	AvailableCopies int    `json:"available_copies"`
+	CanBorrow       bool   `json:"can_borrow"`
+}
+
+// LoanChange loan.borrowed / loan.returned 事件数据
+type LoanChange struct {
+	BookID     string     `json:"book_id"`
+	Barcode    string     `json:"barcode,omitempty"`
+	DueDate    time.Time  `json:"due_date"`
+	ReturnDate *time.Time `json:"return_date,omitempty"`
+	IsOverdue  bool       `json:"is_overdue"`
+	FineAmount float64    `json:"fine_amount"`
+}
+
+// HoldChange hold.* 事件数据
+type HoldChange struct {
+	HoldID         int    `json:"hold_id"`
+	BookID         string `json:"book_id"`
+	PickupBranchID string `json:"pickup_branch_id,omitempty"`
+	Barcode        string `json:"barcode,omitempty"`
+	Status         string `json:"status"`
+}
+
+// 事务提交后发布图书最新的可借数量；变化已经生效，请求在此时被取消也要通知其他客户端
+func publishAvailability(ctx context.Context, bus *events.Bus, bookDAO repository.BookRepository, bookIDs ...string) {
+	if bus == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	published := make(map[string]bool)
+	for _, bookID := range bookIDs {
+		if published[bookID] {
+			continue
+		}
+		published[bookID] = true
+
+		book, err := bookDAO.GetBookByID(ctx, bookID)
+		if err != nil {
+			slog.ErrorContext(ctx, "读取图书可借数量失败", "book_id", bookID, "error", err)
+			continue
+		}
+		publishEvent(bus, events.TypeAvailability, bookID, "", &AvailabilityChange{
+			BookID:          book.BookID,
+			TotalCopies:     book.TotalCopies,
+			AvailableCopies: book.AvailableCopies,
+			CanBorrow:       book.CanBorrow,
+		})
+	}
+}
+
+// 事务提交后调用：累计借还数量指标，并把借阅记录变化发布给学生本人
+func publishLoan(bus *events.Bus, eventType string, record *do.BorrowRecord) {
+	switch eventType {
+	case events.TypeLoanBorrowed:
+		metrics.LoanBorrowed()
+	case events.TypeLoanReturned:
+		metrics.LoanReturned(record.IsOverdue, record.FineAmount)
+	}
+	publishEvent(bus, eventType, record.BookID, record.StuID, &LoanChange{
+		BookID:     record.BookID,
+		Barcode:    record.Barcode,
+		DueDate:    record.DueDate,
+		ReturnDate: record.ReturnDate,
+		IsOverdue:  record.IsOverdue,
+		FineAmount: record.FineAmount,
+	})
+}
+
+// 发布预约变化给学生本人
+func publishHold(bus *events.Bus, eventType string, hold *do.Hold) {
+	publishEvent(bus, eventType, hold.BookID, hold.StuID, &HoldChange{
+		HoldID:         hold.ID,
+		BookID:         hold.BookID,
+		PickupBranchID: hold.PickupBranchID,
+		Barcode:        hold.Barcode,
+		Status:         hold.Status,
+	})
+}
+
+// 单册被分配给预约并上了预约架时通知预约的学生
+func publishRouting(bus *events.Bus, bookID string, routing *ItemRouting) {
+	if routing == nil || routing.HoldID == 0 || routing.Status != do.ItemStatusOnHold {
+		return
+	}
+	publishEvent(bus, events.TypeHoldReady, bookID, routing.HoldStuID, &HoldChange{
+		HoldID:         routing.HoldID,
+		BookID:         bookID,
+		PickupBranchID: routing.BranchID,
+		Status:         do.HoldStatusReady,
+	})
+}
+
+func publishEvent(bus *events.Bus, eventType, bookID, stuID string, data interface{}) {
+	if bus == nil {
+		return
+	}
+	event, err := events.NewEvent(eventType, bookID, stuID, data)
+	if err != nil {
+		slog.Error("创建实时事件失败", "type", eventType, "error", err)
+		return
+	}
+	bus.Publish(event)
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"backend/dao"
+	"backend/do"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// StatsService 流通统计，供监控指标使用
+type StatsService struct {
+	statsDAO *dao.StatsDAO
+}
+
+func NewStatsService(db *sql.DB) *StatsService {
+	return &StatsService{statsDAO: dao.NewStatsDAO(db)}
+}
+
+// 当前的流通概况
+func (s *StatsService) CirculationStats(ctx context.Context) (*do.CirculationStats, error) {
+	return s.statsDAO.GetCirculationStats(ctx, time.Now())
+}
@@ -1,70 +1,149 @@
 package service
 
 import (
-	"backend/dao"
+	"backend/apperr"
 	"backend/do"
-	"database/sql"
+	"backend/i18n"
+	"backend/repository"
+	"backend/tracing"
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 连续输错PIN达到pinMaxAttempts次后锁定pinLockout，期间不再校验PIN
+const (
+	pinMaxAttempts = 5
+	pinLockout     = 15 * time.Minute
 )
 
 type StudentService struct {
-	studentDAO *dao.StudentDAO
-	borrowDAO  *dao.BorrowDAO
+	store      repository.Store
+	studentDAO repository.StudentRepository
+	borrowDAO  repository.BorrowRepository
 }
 
-func NewStudentService(db *sql.DB) *StudentService {
+func NewStudentService(store repository.Store) *StudentService {
 	return &StudentService{
-		studentDAO: dao.NewStudentDAO(db),
-		borrowDAO:  dao.NewBorrowDAO(db),
+		store:      store,
+		studentDAO: store.Students(),
+		borrowDAO:  store.Borrows(),
 	}
 }
 
+// 学生相关操作的span属性
+func studentAttribute(stuID string) attribute.KeyValue {
+	return attribute.String("library.student_id", stuID)
+}
+
 // 获取学生信息
-func (s *StudentService) GetStudentInfo(stuID string) (*do.Student, error) {
-	return s.studentDAO.GetStudentByID(stuID)
+func (s *StudentService) GetStudentInfo(ctx context.Context, stuID string) (_ *do.Student, err error) {
+	ctx, span := tracing.Start(ctx, "StudentService.GetStudentInfo", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+	return s.studentDAO.GetStudentByID(ctx, stuID)
+}
+
+// 校验学号和密码，成功时返回学生信息；不向调用方区分学号不存在和密码错误
+func (s *StudentService) Authenticate(ctx context.Context, stuID, password string) (_ *do.Student, err error) {
+	ctx, span := tracing.Start(ctx, "StudentService.Authenticate", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+
+	student, err := s.studentDAO.GetStudentByID(ctx, stuID)
+	if err != nil && !apperr.Is(err, apperr.CodeStudentNotFound) {
+		return nil, err
+	}
+	if err != nil || student.Password != password {
+		return nil, apperr.NewMessage(apperr.CodeInvalidCredentials, "error.student_credentials")
+	}
+	return student, nil
 }
 
 // 检查学生是否可以借书
-func (s *StudentService) CanStudentBorrow(stuID string) (bool, string, error) {
-	student, err := s.studentDAO.GetStudentByID(stuID)
+func (s *StudentService) CanStudentBorrow(ctx context.Context, stuID string) (_ bool, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "StudentService.CanStudentBorrow", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+
+	blocked, err := borrowBlock(ctx, s.studentDAO, stuID)
 	if err != nil {
 		return false, "", err
 	}
-	
+	if blocked != nil {
+		return false, blocked.Localize(i18n.Locale(ctx)), nil
+	}
+	return true, "", nil
+}
+
+// 检查学生是否可以借书，不能借时返回说明原因的错误；在事务中调用时传入事务中的仓储
+func borrowBlock(ctx context.Context, studentDAO repository.StudentRepository, stuID string) (*apperr.Error, error) {
+	_, blocks, err := borrowBlocks(ctx, studentDAO, stuID)
+	if err != nil || len(blocks) == 0 {
+		return nil, err
+	}
+	return blocks[0], nil
+}
+
+// 获取学生及其不能借书的全部原因，流通台需要逐条展示和越过
+func borrowBlocks(ctx context.Context, studentDAO repository.StudentRepository, stuID string) (_ *do.Student, _ []*apperr.Error, err error) {
+	ctx, span := tracing.Start(ctx, "borrow.eligibility", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+
+	student, err := studentDAO.GetStudentByID(ctx, stuID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var blocks []*apperr.Error
 	if !student.CanBorrow {
-		return false, "学生借阅权限已被禁用", nil
+		blocks = append(blocks, apperr.New(apperr.CodeBorrowDisabled))
 	}
-	
+
 	// 检查是否有未支付的罚款
-	hasUnpaidFine, err := s.studentDAO.HasUnpaidFine(stuID)
+	hasUnpaidFine, err := studentDAO.HasUnpaidFine(ctx, stuID)
 	if err != nil {
-		return false, "", err
+		return nil, nil, err
 	}
-	
 	if hasUnpaidFine {
-		return false, "有未支付的罚款，请先支付罚款", nil
+		blocks = append(blocks, apperr.New(apperr.CodeUnpaidFine))
 	}
-	
-	return true, "", nil
+
+	return student, blocks, nil
 }
 
 // 禁用学生借阅权限
-func (s *StudentService) DisableBorrowPermission(stuID string) error {
-	return s.studentDAO.UpdateStudentBorrowStatus(stuID, false)
+func (s *StudentService) DisableBorrowPermission(ctx context.Context, stuID string) (err error) {
+	ctx, span := tracing.Start(ctx, "StudentService.DisableBorrowPermission", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+
+	return s.store.Transaction(ctx, func(tx repository.Repositories) error {
+		return setBorrowPermission(ctx, tx, stuID, false)
+	})
 }
 
 // 启用学生借阅权限
-func (s *StudentService) EnableBorrowPermission(stuID string) error {
-	return s.studentDAO.UpdateStudentBorrowStatus(stuID, true)
+func (s *StudentService) EnableBorrowPermission(ctx context.Context, stuID string) (err error) {
+	ctx, span := tracing.Start(ctx, "StudentService.EnableBorrowPermission", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+
+	return s.store.Transaction(ctx, func(tx repository.Repositories) error {
+		return setBorrowPermission(ctx, tx, stuID, true)
+	})
 }
 
 // 获取学生的借阅记录
-func (s *StudentService) GetStudentBorrowRecords(stuID string) ([]do.BorrowRecord, error) {
-	return s.borrowDAO.GetStudentBorrowRecords(stuID)
+func (s *StudentService) GetStudentBorrowRecords(ctx context.Context, stuID string) (_ []do.BorrowRecord, err error) {
+	ctx, span := tracing.Start(ctx, "StudentService.GetStudentBorrowRecords", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+	return s.borrowDAO.GetStudentBorrowRecords(ctx, stuID)
 }
 
 // 检查学生是否有逾期记录
-func (s *StudentService) HasOverdueRecords(stuID string) (bool, error) {
-	records, err := s.borrowDAO.GetStudentBorrowRecords(stuID)
+func (s *StudentService) HasOverdueRecords(ctx context.Context, stuID string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "StudentService.HasOverdueRecords", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+
+	records, err := s.borrowDAO.GetStudentBorrowRecords(ctx, stuID)
 	if err != nil {
 		return false, err
 	}
@@ -77,3 +156,72 @@ func (s *StudentService) HasOverdueRecords(stuID string) (bool, error) {
 	
 	return false, nil
 }
+
+// 设置自助借还PIN，需要验证登录密码
+func (s *StudentService) SetPIN(ctx context.Context, stuID, password, pin string) (err error) {
+	ctx, span := tracing.Start(ctx, "StudentService.SetPIN", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+
+	student, err := s.studentDAO.GetStudentByID(ctx, stuID)
+	if err != nil {
+		return err
+	}
+	if student.Password != password {
+		return apperr.NewMessage(apperr.CodeInvalidCredentials, "error.student_credentials")
+	}
+	if !validPIN(pin) {
+		return apperr.Invalid("invalid.pin")
+	}
+	pinHash, err := hashPIN(pin)
+	if err != nil {
+		return err
+	}
+	return s.studentDAO.UpdateStudentPINHash(ctx, stuID, pinHash)
+}
+
+// 校验自助借还PIN；锁定期间返回PIN_LOCKED，输错时计数，连续输错pinMaxAttempts次后锁定
+func (s *StudentService) VerifyPIN(ctx context.Context, stuID, pin string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "StudentService.VerifyPIN", studentAttribute(stuID))
+	defer tracing.End(span, &err)
+
+	stored, err := s.studentDAO.GetStudentPIN(ctx, stuID)
+	if err != nil {
+		return false, err
+	}
+	if stored.Hash == "" {
+		return false, nil
+	}
+	now := time.Now()
+	if stored.LockedUntil != nil && now.Before(*stored.LockedUntil) {
+		return false, apperr.New(apperr.CodePINLocked)
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(stored.Hash), []byte(pin)) != nil {
+		return false, s.studentDAO.RecordPINFailure(ctx, stuID, pinMaxAttempts, now.Add(pinLockout))
+	}
+	if stored.FailedAttempts > 0 || stored.LockedUntil != nil {
+		return true, s.studentDAO.ResetPINFailures(ctx, stuID)
+	}
+	return true, nil
+}
+
+func validPIN(pin string) bool {
+	if len(pin) < 4 || len(pin) > 6 {
+		return false
+	}
+	for _, c := range pin {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// PIN只有4到6位数字，用bcrypt哈希保存，防止拿到哈希后穷举
+func hashPIN(pin string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"backend/apperr"
+	"context"
+	"testing"
+)
+
+func TestStudentPIN(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLibrary()
+	s := NewStudentService(store)
+
+	if ok, err := s.VerifyPIN(ctx, "S1", "1234"); err != nil || ok {
+		t.Errorf("未设置PIN时校验结果 = %v, %v，期望 false", ok, err)
+	}
+	if err := s.SetPIN(ctx, "S1", "wrong", "1234"); err == nil {
+		t.Error("登录密码错误时不应设置PIN")
+	}
+	if err := s.SetPIN(ctx, "S1", "pass1", "12ab"); err == nil {
+		t.Error("非数字PIN不应设置成功")
+	}
+	if err := s.SetPIN(ctx, "S1", "pass1", "1234"); err != nil {
+		t.Fatalf("设置PIN失败: %v", err)
+	}
+
+	if ok, err := s.VerifyPIN(ctx, "S1", "1234"); err != nil || !ok {
+		t.Errorf("正确PIN校验结果 = %v, %v，期望 true", ok, err)
+	}
+	if ok, err := s.VerifyPIN(ctx, "S1", "4321"); err != nil || ok {
+		t.Errorf("错误PIN校验结果 = %v, %v，期望 false", ok, err)
+	}
+	// 其他学生没有设置PIN，使用相同PIN不能通过
+	if ok, _ := s.VerifyPIN(ctx, "S3", "1234"); ok {
+		t.Error("其他学生不应通过校验")
+	}
+}
+
+func TestStudentPINLockout(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLibrary()
+	s := NewStudentService(store)
+	if err := s.SetPIN(ctx, "S1", "pass1", "1234"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 校验通过后清零输错次数
+	for i := 0; i < pinMaxAttempts-1; i++ {
+		s.VerifyPIN(ctx, "S1", "0000")
+	}
+	if ok, err := s.VerifyPIN(ctx, "S1", "1234"); err != nil || !ok {
+		t.Fatalf("输错%d次后正确PIN校验结果 = %v, %v，期望 true", pinMaxAttempts-1, ok, err)
+	}
+
+	for i := 0; i < pinMaxAttempts; i++ {
+		if ok, err := s.VerifyPIN(ctx, "S1", "0000"); err != nil || ok {
+			t.Fatalf("第%d次输错 = %v, %v，期望 false", i+1, ok, err)
+		}
+	}
+	// 锁定期间正确的PIN也不能通过
+	if _, err := s.VerifyPIN(ctx, "S1", "1234"); !apperr.Is(err, apperr.CodePINLocked) {
+		t.Errorf("锁定后校验 = %v，期望 PIN_LOCKED", err)
+	}
+	// 重新设置PIN解除锁定
+	if err := s.SetPIN(ctx, "S1", "pass1", "5678"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.VerifyPIN(ctx, "S1", "5678"); err != nil || !ok {
+		t.Errorf("重新设置后校验结果 = %v, %v，期望 true", ok, err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	s := NewStudentService(newTestLibrary())
+
+	student, err := s.Authenticate(ctx, "S1", "pass1")
+	if err != nil || student.StuId != "S1" {
+		t.Fatalf("Authenticate(S1) = %v, %v", student, err)
+	}
+	// 学号不存在和密码错误返回相同的错误
+	for _, tc := range []struct{ stuID, password string }{{"S1", "wrong"}, {"NOBODY", "pass1"}} {
+		if _, err := s.Authenticate(ctx, tc.stuID, tc.password); !apperr.Is(err, apperr.CodeInvalidCredentials) {
+			t.Errorf("Authenticate(%s, %s) = %v，期望 INVALID_CREDENTIALS", tc.stuID, tc.password, err)
+		}
+	}
+}
+
+func TestCanStudentBorrow(t *testing.T) {
+	ctx := context.Background()
+	store := newTestLibrary()
+	s := NewStudentService(store)
+
+	if ok, _, err := s.CanStudentBorrow(ctx, "S1"); err != nil || !ok {
+		t.Errorf("S1 可借阅 = %v, %v，期望 true", ok, err)
+	}
+	if ok, reason, err := s.CanStudentBorrow(ctx, "S2"); err != nil || ok || reason == "" {
+		t.Errorf("S2 可借阅 = %v, %q, %v，期望 false 并说明原因", ok, reason, err)
+	}
+	if _, _, err := s.CanStudentBorrow(ctx, "NOBODY"); err == nil {
+		t.Error("不存在的学生应返回错误")
+	}
+}
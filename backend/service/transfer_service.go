@@ -0,0 +1,239 @@
+package service
+
+import (
+	"backend/apperr"
+	"backend/dao"
+	"backend/do"
+	"backend/events"
+	"backend/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type TransferService struct {
+	transferDAO *dao.TransferDAO
+	db          *sql.DB
+	bus         *events.Bus
+}
+
+func NewTransferService(db *sql.DB, bus *events.Bus) *TransferService {
+	return &TransferService{
+		transferDAO: dao.NewTransferDAO(db),
+		db:          db,
+		bus:         bus,
+	}
+}
+
+// 馆员手动申请调拨：把一册在架单册调往其他分馆
+func (s *TransferService) RequestTransfer(ctx context.Context, barcode, toBranchID string) (*do.Transfer, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := dao.NewBranchDAOTx(tx).GetBranchByID(ctx, toBranchID); err != nil {
+		return nil, err
+	}
+
+	itemDAOTx := dao.NewBookItemDAOTx(tx)
+	item, err := itemDAOTx.GetItemByBarcode(ctx, barcode)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status != do.ItemStatusAvailable {
+		return nil, apperr.New(apperr.CodeItemNotOnShelf)
+	}
+	if item.CurrentBranchID == toBranchID {
+		return nil, apperr.New(apperr.CodeItemAtBranch)
+	}
+
+	id, err := createTransfer(ctx, dao.NewRepositoriesTx(tx), item, toBranchID, do.TransferReasonManual, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.NewBookDAOTx(tx).SyncCopiesFromItems(ctx, item.BookID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.transferDAO.GetTransferByID(ctx, id)
+}
+
+// 调出分馆发出调拨
+func (s *TransferService) ShipTransfer(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	transferDAOTx := dao.NewTransferDAOTx(tx)
+	transfer, err := transferDAOTx.GetTransferByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if transfer.Status != do.TransferStatusRequested {
+		return apperr.NewMessage(apperr.CodeTransferStateInvalid, "error.transfer_not_requested")
+	}
+	if err := transferDAOTx.MarkShipped(ctx, id, time.Now()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// 调入分馆签收：为预约调拨的单册上预约架，其余单册在本馆上架（或满足新的排队预约）
+func (s *TransferService) ReceiveTransfer(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	transferDAOTx := dao.NewTransferDAOTx(tx)
+	transfer, err := transferDAOTx.GetTransferByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if transfer.Status != do.TransferStatusRequested && transfer.Status != do.TransferStatusInTransit {
+		return apperr.NewMessage(apperr.CodeTransferStateInvalid, "error.transfer_closed")
+	}
+	if err := transferDAOTx.MarkReceived(ctx, id, time.Now()); err != nil {
+		return err
+	}
+
+	item, err := dao.NewBookItemDAOTx(tx).GetItemByBarcode(ctx, transfer.Barcode)
+	if err != nil {
+		return err
+	}
+
+	var routing *ItemRouting
+	if transfer.HoldID != nil {
+		holdDAOTx := dao.NewHoldDAOTx(tx)
+		hold, err := holdDAOTx.GetHoldByID(ctx, *transfer.HoldID)
+		if err != nil {
+			return err
+		}
+		// 预约在运送途中被取消时，单册按普通归还处理
+		if hold.Status == do.HoldStatusInTransit {
+			if routing, err = assignItemToHold(ctx, dao.NewRepositoriesTx(tx), item, transfer.ToBranchID, hold); err != nil {
+				return err
+			}
+		}
+	}
+	if routing == nil {
+		sendHome := transfer.Reason != do.TransferReasonManual
+		if routing, err = routeItem(ctx, dao.NewRepositoriesTx(tx), item, transfer.ToBranchID, sendHome); err != nil {
+			return err
+		}
+	}
+
+	if err := dao.NewBookDAOTx(tx).SyncCopiesFromItems(ctx, item.BookID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	publishAvailability(ctx, s.bus, dao.NewBookDAO(s.db), item.BookID)
+	publishRouting(s.bus, item.BookID, routing)
+	return nil
+}
+
+// 查询调拨单
+func (s *TransferService) ListTransfers(ctx context.Context, status, branchID string) ([]do.Transfer, error) {
+	return s.transferDAO.ListTransfers(ctx, status, branchID)
+}
+
+// ItemRouting 单册归还或签收后的去向
+type ItemRouting struct {
+	Status     string `json:"status"`
+	BranchID   string `json:"branch_id"`
+	TransferID int    `json:"transfer_id,omitempty"`
+	TransferTo string `json:"transfer_to,omitempty"`
+	HoldID     int    `json:"hold_id,omitempty"`
+	HoldStuID  string `json:"hold_stu_id,omitempty"`
+}
+
+// 为单册寻找去向：优先满足最早的排队预约；sendHome为true且不在所属分馆时调回所属分馆；否则在当前分馆上架
+func routeItem(ctx context.Context, tx repository.Repositories, item *do.BookItem, atBranchID string, sendHome bool) (*ItemRouting, error) {
+	hold, err := tx.Holds().GetNextWaitingHold(ctx, item.BookID)
+	if err != nil {
+		return nil, err
+	}
+	if hold != nil {
+		return assignItemToHold(ctx, tx, item, atBranchID, hold)
+	}
+
+	item.CurrentBranchID = atBranchID
+	if sendHome && atBranchID != item.HomeBranchID {
+		id, err := createTransfer(ctx, tx, item, item.HomeBranchID, do.TransferReasonReturnHome, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &ItemRouting{Status: item.Status, BranchID: atBranchID, TransferID: id, TransferTo: item.HomeBranchID}, nil
+	}
+
+	item.Status = do.ItemStatusAvailable
+	if err := tx.BookItems().UpdateItemLocation(ctx, item.Barcode, atBranchID, do.ItemStatusAvailable); err != nil {
+		return nil, err
+	}
+	return &ItemRouting{Status: item.Status, BranchID: atBranchID}, nil
+}
+
+// 把单册分配给预约：已在取书分馆则上预约架，否则调拨到取书分馆
+func assignItemToHold(ctx context.Context, tx repository.Repositories, item *do.BookItem, atBranchID string, hold *do.Hold) (*ItemRouting, error) {
+	holds := tx.Holds()
+	item.CurrentBranchID = atBranchID
+	routing := &ItemRouting{BranchID: atBranchID, HoldID: hold.ID, HoldStuID: hold.StuID}
+
+	if hold.PickupBranchID == atBranchID {
+		item.Status = do.ItemStatusOnHold
+		if err := tx.BookItems().UpdateItemLocation(ctx, item.Barcode, atBranchID, do.ItemStatusOnHold); err != nil {
+			return nil, err
+		}
+		if err := holds.MarkHoldReady(ctx, hold.ID, item.Barcode, time.Now()); err != nil {
+			return nil, err
+		}
+		routing.Status = item.Status
+		return routing, nil
+	}
+
+	id, err := createTransfer(ctx, tx, item, hold.PickupBranchID, do.TransferReasonHold, &hold.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := holds.UpdateHoldStatus(ctx, hold.ID, do.HoldStatusInTransit, item.Barcode); err != nil {
+		return nil, err
+	}
+	routing.Status = item.Status
+	routing.TransferID = id
+	routing.TransferTo = hold.PickupBranchID
+	return routing, nil
+}
+
+// 为单册创建调拨单并将其标记为调拨中
+func createTransfer(ctx context.Context, tx repository.Repositories, item *do.BookItem, toBranchID, reason string, holdID *int) (int, error) {
+	id, err := tx.Transfers().CreateTransfer(ctx, &do.Transfer{
+		Barcode:      item.Barcode,
+		FromBranchID: item.CurrentBranchID,
+		ToBranchID:   toBranchID,
+		Reason:       reason,
+		HoldID:       holdID,
+		Status:       do.TransferStatusRequested,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("创建调拨单失败: %w", err)
+	}
+
+	item.Status = do.ItemStatusInTransit
+	if err := tx.BookItems().UpdateItemLocation(ctx, item.Barcode, item.CurrentBranchID, do.ItemStatusInTransit); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"backend/apperr"
+	"backend/dao"
+	"backend/do"
+	"backend/repository"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// 每次投递最多尝试次数，用尽后进入死信
+	maxWebhookAttempts = 8
+	// 每轮最多分发的事件数和投递数
+	webhookBatchSize = 100
+	// 单次推送的超时时间
+	webhookTimeout = 10 * time.Second
+	// 认领投递后的推送租期
+	webhookLease = 2 * time.Minute
+)
+
+// LoanEvent book.borrowed / book.returned 事件数据
+type LoanEvent struct {
+	RecordID   int        `json:"record_id"`
+	StuID      string     `json:"stu_id"`
+	BookID     string     `json:"book_id"`
+	Barcode    string     `json:"barcode,omitempty"`
+	BranchID   string     `json:"branch_id,omitempty"`
+	BorrowDate time.Time  `json:"borrow_date"`
+	DueDate    time.Time  `json:"due_date"`
+	ReturnDate *time.Time `json:"return_date,omitempty"`
+	IsOverdue  bool       `json:"is_overdue"`
+	FineAmount float64    `json:"fine_amount"`
+}
+
+// FineEvent fine.charged / fine.paid 事件数据
+type FineEvent struct {
+	StuID    string    `json:"stu_id"`
+	RecordID int       `json:"record_id,omitempty"`
+	BookID   string    `json:"book_id,omitempty"`
+	Amount   float64   `json:"amount,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// StudentBlockedEvent student.blocked 事件数据
+type StudentBlockedEvent struct {
+	StuID  string    `json:"stu_id"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+// 推送给对方的请求体
+type webhookEnvelope struct {
+	ID        int             `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// 在业务事务中写入发件箱，事务回滚时事件一并丢弃
+func recordEvent(ctx context.Context, tx repository.Repositories, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return tx.Outbox().CreateEvent(ctx, eventType, string(payload))
+}
+
+// WebhookService 推送地址管理，以及把发件箱中的事件签名后推送给各系统
+type WebhookService struct {
+	db         *sql.DB
+	webhookDAO *dao.WebhookDAO
+	client     *http.Client
+	heartbeat  Heartbeat
+}
+
+func NewWebhookService(db *sql.DB) *WebhookService {
+	return &WebhookService{
+		db:         db,
+		webhookDAO: dao.NewWebhookDAO(db),
+		client:     &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// 登记推送地址，返回的签名密钥只在此时出现一次
+func (s *WebhookService) RegisterEndpoint(ctx context.Context, endpointURL string, events []string) (*do.WebhookEndpoint, string, error) {
+	parsed, err := url.Parse(endpointURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, "", apperr.Invalid("invalid.webhook_url")
+	}
+
+	events, err = normalizeWebhookEvents(events)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+
+	endpoint := &do.WebhookEndpoint{
+		URL:     endpointURL,
+		Secret:  hex.EncodeToString(secret),
+		Events:  events,
+		Enabled: true,
+	}
+	id, err := s.webhookDAO.CreateEndpoint(ctx, endpoint)
+	if err != nil {
+		return nil, "", err
+	}
+
+	endpoint, err = s.webhookDAO.GetEndpointByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	return endpoint, endpoint.Secret, nil
+}
+
+// 校验并去重订阅的事件
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, apperr.Invalid("invalid.events_required")
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, event := range events {
+		if !webhookEventKnown(event) {
+			return nil, apperr.Invalid("invalid.unknown_event").With("event", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+func webhookEventKnown(event string) bool {
+	for _, known := range do.WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取所有推送地址
+func (s *WebhookService) GetEndpoints(ctx context.Context) ([]do.WebhookEndpoint, error) {
+	return s.webhookDAO.GetEndpoints(ctx, false)
+}
+
+// 启用或停用推送地址，停用期间的事件不会再分发给它
+func (s *WebhookService) SetEndpointEnabled(ctx context.Context, id int, enabled bool) error {
+	if _, err := s.webhookDAO.GetEndpointByID(ctx, id); err != nil {
+		return err
+	}
+	return s.webhookDAO.UpdateEndpointEnabled(ctx, id, enabled)
+}
+
+// 把发件箱中尚未分发的事件分发给订阅了该事件的推送地址，返回分发的事件数
+func (s *WebhookService) DispatchOutbox(ctx context.Context, now time.Time) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	webhookDAOTx := dao.NewWebhookDAOTx(tx)
+	events, err := webhookDAOTx.GetUndispatchedEvents(ctx, webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	endpoints, err := webhookDAOTx.GetEndpoints(ctx, true)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		for _, endpoint := range endpoints {
+			if !subscribes(&endpoint, event.EventType) {
+				continue
+			}
+			if err := webhookDAOTx.CreateDelivery(ctx, event.ID, endpoint.ID, now); err != nil {
+				return 0, err
+			}
+		}
+		if err := webhookDAOTx.MarkEventDispatched(ctx, event.ID, now); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
+
+func subscribes(endpoint *do.WebhookEndpoint, eventType string) bool {
+	for _, event := range endpoint.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// 推送到了推送时间的投递，失败的按指数退避重试，返回成功和失败的数量
+func (s *WebhookService) DeliverPending(ctx context.Context, now time.Time) (int, int, error) {
+	deliveries, err := s.webhookDAO.GetDueDeliveries(ctx, now, webhookBatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	delivered, failed := 0, 0
+	for _, delivery := range deliveries {
+		claimed, err := s.webhookDAO.ClaimDelivery(ctx, delivery.ID, now, now.Add(webhookLease))
+		if err != nil {
+			return delivered, failed, err
+		}
+		if !claimed {
+			continue
+		}
+
+		statusCode, sendErr := s.deliver(ctx, &delivery)
+		if sendErr == nil {
+			if err := s.webhookDAO.MarkDelivered(ctx, delivery.ID, statusCode, time.Now()); err != nil {
+				return delivered, failed, err
+			}
+			delivered++
+			continue
+		}
+
+		failed++
+		status, nextAttemptAt := do.WebhookDeliveryPending, now.Add(retryDelay(delivery.Attempts+1))
+		if delivery.Attempts+1 >= maxWebhookAttempts {
+			status = do.WebhookDeliveryDead
+		}
+		if err := s.webhookDAO.MarkDeliveryFailed(ctx, delivery.ID, status, statusCode, sendErr.Error(), nextAttemptAt); err != nil {
+			return delivered, failed, err
+		}
+	}
+	return delivered, failed, nil
+}
+
+// 签名并推送一条投递，对方返回2xx视为成功
+func (s *WebhookService) deliver(ctx context.Context, delivery *do.WebhookDelivery) (int, error) {
+	endpoint, err := s.webhookDAO.GetEndpointByID(ctx, delivery.EndpointID)
+	if err != nil {
+		return 0, err
+	}
+	if !endpoint.Enabled {
+		return 0, apperr.New(apperr.CodeWebhookDisabled)
+	}
+	event, err := s.webhookDAO.GetEventByID(ctx, delivery.EventID)
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := json.Marshal(webhookEnvelope{
+		ID:        event.ID,
+		Type:      event.EventType,
+		CreatedAt: event.CreatedAt,
+		Data:      json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "library-webhook/1.0")
+	req.Header.Set("X-Webhook-Event", event.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(delivery.ID))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhook(endpoint.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("推送地址返回 %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// 计算推送签名：HMAC-SHA256(secret, timestamp + "." + body) 的十六进制
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// 推送任务的心跳，供就绪检查使用
+func (s *WebhookService) Heartbeat() *Heartbeat {
+	return &s.heartbeat
+}
+
+// 定时分发发件箱并推送，直到stop被关闭；stop关闭时正在执行的查询随之中止，未完成的投递下次重试
+func (s *WebhookService) RunWorker(interval time.Duration, stop <-chan struct{}) {
+	ctx, cancel := stopContext(stop)
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.heartbeat.beat(interval)
+		s.runOnce(ctx, time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *WebhookService) runOnce(ctx context.Context, now time.Time) {
+	if _, err := s.DispatchOutbox(ctx, now); err != nil {
+		slog.ErrorContext(ctx, "分发推送事件失败", "error", err)
+	}
+	delivered, failed, err := s.DeliverPending(ctx, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "推送事件失败", "error", err)
+	}
+	if delivered > 0 || failed > 0 {
+		slog.InfoContext(ctx, "事件推送完成", "delivered", delivered, "failed", failed)
+	}
+}
+
+// 查询投递记录，status=dead即死信
+func (s *WebhookService) ListDeliveries(ctx context.Context, status string, endpointID, limit int) ([]do.WebhookDelivery, error) {
+	return s.webhookDAO.ListDeliveries(ctx, status, endpointID, limit)
+}
+
+// 立即重新推送一条未成功的投递（包括死信）
+func (s *WebhookService) RetryDelivery(ctx context.Context, id int) error {
+	ok, err := s.webhookDAO.ResetDelivery(ctx, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return apperr.New(apperr.CodeDeliveryNotFound)
+	}
+	return nil
+}
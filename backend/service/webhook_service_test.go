@@ -0,0 +1,46 @@
+package service
+
+import (
+	"backend/do"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSignWebhook(t *testing.T) {
+	// 接收方按 HMAC-SHA256(secret, timestamp + "." + body) 验签，期望值由独立实现算出
+	got := SignWebhook("whsec_test", "1700000000", []byte(`{"type":"book.borrowed"}`))
+	if want := "ba0338f1ea7323951023388ddb767063dc154333ffa575dd1dec1f8ae32ec965"; got != want {
+		t.Errorf("SignWebhook = %s，期望 %s", got, want)
+	}
+	// 时间戳参与签名，防止重放旧请求
+	if SignWebhook("whsec_test", "1700000001", []byte(`{"type":"book.borrowed"}`)) == got {
+		t.Error("时间戳不同时签名相同")
+	}
+}
+
+func TestNormalizeWebhookEvents(t *testing.T) {
+	events, err := normalizeWebhookEvents([]string{do.EventBookBorrowed, do.EventFinePaid, do.EventBookBorrowed})
+	if err != nil || !slices.Equal(events, []string{do.EventBookBorrowed, do.EventFinePaid}) {
+		t.Errorf("去重后的事件 = %v, %v", events, err)
+	}
+	for _, events := range [][]string{nil, {"book.lost"}} {
+		if _, err := normalizeWebhookEvents(events); err == nil {
+			t.Errorf("订阅 %v 通过了校验", events)
+		}
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	// 第n次失败后等待 2^(n-1) 分钟再重试
+	for attempts, want := range map[int]time.Duration{
+		1:                      time.Minute,
+		2:                      2 * time.Minute,
+		3:                      4 * time.Minute,
+		maxWebhookAttempts - 1: 64 * time.Minute,
+	} {
+		if got := retryDelay(attempts); got != want {
+			t.Errorf("第%d次失败后 retryDelay = %v，期望 %v", attempts, got, want)
+		}
+	}
+}
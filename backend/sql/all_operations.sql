@@ -3,7 +3,7 @@
 
 -- ==================== 表结构创建 ====================
 -- 用途：创建系统所需的数据库表结构
--- 文件：table_create.sql
+-- 文件：migrations/0001_init.up.sql（以迁移为准）
 
 -- 学生表
 CREATE TABLE IF NOT EXISTS students (
@@ -12,6 +12,9 @@ CREATE TABLE IF NOT EXISTS students (
     password VARCHAR(255) NOT NULL, -- 密码
     trust FLOAT DEFAULT 1, -- 信任度
     can_borrow BOOLEAN DEFAULT TRUE, -- 是否可以借阅
+    pin_hash VARCHAR(255) DEFAULT NULL, -- 自助借还机PIN哈希（bcrypt）
+    pin_failed_attempts INT NOT NULL DEFAULT 0, -- 连续输错PIN的次数
+    pin_locked_until DATETIME NULL, -- PIN锁定截止时间
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -24,6 +27,7 @@ CREATE TABLE IF NOT EXISTS books (
     total_copies INT DEFAULT 0, -- 总馆藏数量
     available_copies INT DEFAULT 0, -- 可借阅数量
     can_borrow BOOLEAN DEFAULT TRUE, -- 是否可以借阅
+    cover_key VARCHAR(255) DEFAULT NULL, -- 封面存储键（版本目录）
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -32,6 +36,7 @@ CREATE TABLE IF NOT EXISTS borrow_records (
     id INT AUTO_INCREMENT PRIMARY KEY,
     stu_id VARCHAR(255) NOT NULL, -- 学号
     book_id VARCHAR(255) NOT NULL, -- 图书编号
+    barcode VARCHAR(255) DEFAULT NULL, -- 借出的单册条码（未登记单册的书籍为空）
     borrow_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- 借书时间
     due_date TIMESTAMP, -- 预计还书时间
     return_date TIMESTAMP, -- 实际还书时间
@@ -42,6 +47,193 @@ CREATE TABLE IF NOT EXISTS borrow_records (
     FOREIGN KEY (book_id) REFERENCES books(book_id)
 );
 
+-- 馆员表
+CREATE TABLE IF NOT EXISTS librarians (
+    librarian_id VARCHAR(255) PRIMARY KEY, -- 工号
+    name VARCHAR(50) NOT NULL, -- 姓名
+    password VARCHAR(255) NOT NULL, -- 密码
+    role VARCHAR(20) NOT NULL DEFAULT 'librarian', -- 角色：librarian/admin
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 分馆表
+CREATE TABLE IF NOT EXISTS branches (
+    branch_id VARCHAR(255) PRIMARY KEY, -- 分馆编号
+    name VARCHAR(100) NOT NULL, -- 分馆名称
+    address VARCHAR(255) DEFAULT '', -- 地址
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 单册表
+CREATE TABLE IF NOT EXISTS book_items (
+    barcode VARCHAR(255) PRIMARY KEY, -- 单册条码
+    book_id VARCHAR(255) NOT NULL, -- 图书编号
+    home_branch_id VARCHAR(255) NOT NULL, -- 所属分馆
+    current_branch_id VARCHAR(255) NOT NULL, -- 当前所在分馆
+    status VARCHAR(20) NOT NULL DEFAULT 'available', -- available/on_loan/in_transit/on_hold_shelf
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (book_id) REFERENCES books(book_id),
+    FOREIGN KEY (home_branch_id) REFERENCES branches(branch_id),
+    FOREIGN KEY (current_branch_id) REFERENCES branches(branch_id),
+    INDEX idx_book_items_book (book_id, status)
+);
+
+-- 预约表
+CREATE TABLE IF NOT EXISTS holds (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    stu_id VARCHAR(255) NOT NULL, -- 学号
+    book_id VARCHAR(255) NOT NULL, -- 图书编号
+    pickup_branch_id VARCHAR(255) NOT NULL, -- 取书分馆
+    barcode VARCHAR(255) DEFAULT NULL, -- 分配给该预约的单册
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting', -- waiting/in_transit/ready/fulfilled/cancelled
+    ready_at TIMESTAMP NULL DEFAULT NULL, -- 上预约架时间
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (stu_id) REFERENCES students(stu_id),
+    FOREIGN KEY (book_id) REFERENCES books(book_id),
+    FOREIGN KEY (pickup_branch_id) REFERENCES branches(branch_id),
+    INDEX idx_holds_book (book_id, status, created_at)
+);
+
+-- 调拨表
+CREATE TABLE IF NOT EXISTS transfers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    barcode VARCHAR(255) NOT NULL, -- 单册条码
+    from_branch_id VARCHAR(255) NOT NULL, -- 调出分馆
+    to_branch_id VARCHAR(255) NOT NULL, -- 调入分馆
+    reason VARCHAR(20) NOT NULL, -- manual/return_home/hold
+    hold_id INT DEFAULT NULL, -- 为预约发起的调拨
+    status VARCHAR(20) NOT NULL DEFAULT 'requested', -- requested/in_transit/received/cancelled
+    shipped_at TIMESTAMP NULL DEFAULT NULL,
+    received_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (barcode) REFERENCES book_items(barcode),
+    FOREIGN KEY (from_branch_id) REFERENCES branches(branch_id),
+    FOREIGN KEY (to_branch_id) REFERENCES branches(branch_id),
+    FOREIGN KEY (hold_id) REFERENCES holds(id)
+);
+
+-- 强制借出记录表
+CREATE TABLE IF NOT EXISTS circulation_overrides (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    librarian_id VARCHAR(255) NOT NULL, -- 操作馆员
+    stu_id VARCHAR(255) NOT NULL, -- 学号
+    barcode VARCHAR(255) DEFAULT NULL, -- 针对单册的受阻原因对应的条码
+    block_code VARCHAR(50) NOT NULL, -- 被越过的受阻原因代码
+    reason VARCHAR(500) NOT NULL, -- 强制借出原因
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (librarian_id) REFERENCES librarians(librarian_id),
+    FOREIGN KEY (stu_id) REFERENCES students(stu_id)
+);
+
+-- 自助借还机设备表
+CREATE TABLE IF NOT EXISTS kiosk_devices (
+    device_id VARCHAR(255) PRIMARY KEY, -- 设备编号
+    name VARCHAR(100) NOT NULL, -- 设备名称
+    branch_id VARCHAR(255) NOT NULL, -- 设备所在分馆
+    api_key_hash VARCHAR(64) NOT NULL UNIQUE, -- 设备API密钥哈希
+    enabled BOOLEAN DEFAULT TRUE, -- 是否启用
+    rate_limit_per_min INT DEFAULT 30, -- 每分钟请求上限
+    last_seen_at DATETIME DEFAULT NULL, -- 最近使用时间
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (branch_id) REFERENCES branches(branch_id)
+);
+
+-- 每周开放时间表（未设置的星期视为正常开放）
+CREATE TABLE IF NOT EXISTS library_hours (
+    weekday TINYINT PRIMARY KEY, -- 星期，0为周日
+    open_time VARCHAR(5) NOT NULL DEFAULT '08:00', -- 开馆时间
+    close_time VARCHAR(5) NOT NULL DEFAULT '22:00', -- 闭馆时间
+    closed BOOLEAN DEFAULT FALSE -- 当天固定闭馆
+);
+
+-- 闭馆日期表（节假日、寒暑假）
+CREATE TABLE IF NOT EXISTS library_closures (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL, -- 名称，如国庆节、寒假
+    kind VARCHAR(20) NOT NULL DEFAULT 'holiday', -- holiday / break / other
+    start_date DATE NOT NULL, -- 开始日期（含）
+    end_date DATE NOT NULL, -- 结束日期（含）
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_library_closures_dates (start_date, end_date)
+);
+
+-- 通知设置表（没有设置的学生不接收任何通知）
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    stu_id VARCHAR(255) PRIMARY KEY, -- 学号
+    email VARCHAR(255) NOT NULL DEFAULT '', -- 接收通知的邮箱
+    language VARCHAR(10) NOT NULL DEFAULT 'zh-CN', -- 通知语言 zh-CN / en-US
+    due_soon BOOLEAN DEFAULT FALSE, -- 订阅到期提醒
+    overdue BOOLEAN DEFAULT FALSE, -- 订阅逾期提醒
+    hold_ready BOOLEAN DEFAULT FALSE, -- 订阅预约到馆提醒
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (stu_id) REFERENCES students(stu_id)
+);
+
+-- 通知投递记录表（同一类型对同一借阅记录或预约只发一次）
+CREATE TABLE IF NOT EXISTS notifications (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    stu_id VARCHAR(255) NOT NULL, -- 学号
+    kind VARCHAR(20) NOT NULL, -- due_soon / overdue / hold_ready
+    ref_id INT NOT NULL, -- 借阅记录ID或预约ID
+    email VARCHAR(255) NOT NULL, -- 收件邮箱
+    subject VARCHAR(255) NOT NULL, -- 邮件主题
+    body TEXT NOT NULL, -- 邮件正文
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending / sent / failed
+    attempts INT NOT NULL DEFAULT 0, -- 已发送次数
+    last_error VARCHAR(1000) DEFAULT NULL, -- 最近一次发送失败原因
+    next_attempt_at DATETIME NOT NULL, -- 下次发送时间
+    sent_at DATETIME DEFAULT NULL, -- 发送成功时间
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_notifications_kind_ref (kind, ref_id),
+    INDEX idx_notifications_status (status, next_attempt_at),
+    FOREIGN KEY (stu_id) REFERENCES students(stu_id)
+);
+
+-- 事件推送地址表
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    url VARCHAR(1000) NOT NULL, -- 推送地址
+    secret VARCHAR(64) NOT NULL, -- HMAC签名密钥
+    events VARCHAR(500) NOT NULL, -- 订阅的事件，逗号分隔
+    enabled BOOLEAN DEFAULT TRUE, -- 是否启用
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 事件发件箱表（与借还书等业务操作在同一事务中写入）
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL, -- 事件类型，如 book.borrowed
+    payload TEXT NOT NULL, -- 事件数据（JSON）
+    dispatched_at DATETIME DEFAULT NULL, -- 分发到各推送地址的时间
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_webhook_outbox_dispatched (dispatched_at)
+);
+
+-- 事件投递表（status为dead即死信）
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    event_id INT NOT NULL, -- 发件箱事件
+    endpoint_id INT NOT NULL, -- 推送地址
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending / delivered / dead
+    attempts INT NOT NULL DEFAULT 0, -- 已推送次数
+    last_status_code INT DEFAULT NULL, -- 最近一次响应状态码
+    last_error VARCHAR(1000) DEFAULT NULL, -- 最近一次失败原因
+    next_attempt_at DATETIME NOT NULL, -- 下次推送时间
+    delivered_at DATETIME DEFAULT NULL, -- 推送成功时间
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_webhook_deliveries_status (status, next_attempt_at),
+    FOREIGN KEY (event_id) REFERENCES webhook_outbox(id),
+    FOREIGN KEY (endpoint_id) REFERENCES webhook_endpoints(id)
+);
+
+-- 实时事件转发表（仅EVENT_BROKER=db时使用，各实例轮询读取，保留一小时）
+CREATE TABLE IF NOT EXISTS stream_events (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    payload TEXT NOT NULL, -- 实时事件（JSON）
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_stream_events_created (created_at)
+);
+
 -- ==================== 学生相关操作 ====================
 -- 用途：学生信息的查询和更新操作
 -- 文件：student_dao.go
@@ -54,6 +246,19 @@ WHERE stu_id = ?;
 -- 更新学生借阅状态
 UPDATE students SET can_borrow = ? WHERE stu_id = ?;
 
+-- 获取 / 设置自助借还机PIN哈希，设置新PIN时解除锁定
+SELECT pin_hash, pin_failed_attempts, pin_locked_until FROM students WHERE stu_id = ?;
+UPDATE students SET pin_hash = ?, pin_failed_attempts = 0, pin_locked_until = NULL WHERE stu_id = ?;
+
+-- 记录一次PIN输错，达到上限时锁定并清零计数（MySQL按顺序赋值，先用旧的计数计算锁定时间）
+UPDATE students
+SET pin_locked_until = CASE WHEN pin_failed_attempts + 1 >= ? THEN ? ELSE pin_locked_until END,
+    pin_failed_attempts = CASE WHEN pin_failed_attempts + 1 >= ? THEN 0 ELSE pin_failed_attempts + 1 END
+WHERE stu_id = ?;
+
+-- PIN校验通过后清零输错次数
+UPDATE students SET pin_failed_attempts = 0, pin_locked_until = NULL WHERE stu_id = ?;
+
 -- 检查学生是否有未支付的罚款
 SELECT COUNT(*) 
 FROM borrow_records 
@@ -64,44 +269,229 @@ WHERE stu_id = ? AND is_overdue = true AND fine_amount > 0 AND return_date IS NU
 -- 文件：book_dao.go
 
 -- 根据书名或作者查找书籍
-SELECT book_id, title, author, description, total_copies, available_copies, can_borrow, created_at
+SELECT book_id, title, author, description, total_copies, available_copies, can_borrow, cover_key, created_at
 FROM books 
 WHERE (title LIKE ? OR author LIKE ?) AND can_borrow = true;
 
 -- 根据图书ID获取书籍信息
-SELECT book_id, title, author, description, total_copies, available_copies, can_borrow, created_at
+SELECT book_id, title, author, description, total_copies, available_copies, can_borrow, cover_key, created_at
 FROM books 
 WHERE book_id = ?;
 
 -- 更新书籍可借阅数量
 UPDATE books SET available_copies = ? WHERE book_id = ?;
 
+-- 更新书籍封面存储键（NULL表示移除封面）
+UPDATE books SET cover_key = ? WHERE book_id = ?;
+
+-- ==================== 馆员相关操作 ====================
+-- 用途：馆员登录
+-- 文件：librarian_dao.go
+
+-- 根据工号获取馆员信息
+SELECT librarian_id, name, password, role, created_at
+FROM librarians
+WHERE librarian_id = ?;
+
 -- ==================== 借阅相关操作 ====================
 -- 用途：借阅记录的创建、查询和更新操作
 -- 文件：borrow_dao.go
 
 -- 创建借阅记录
-INSERT INTO borrow_records (stu_id, book_id, borrow_date, due_date, return_date, is_overdue, fine_amount)
-VALUES (?, ?, ?, ?, ?, ?, ?);
+INSERT INTO borrow_records (stu_id, book_id, barcode, borrow_date, due_date, return_date, is_overdue, fine_amount)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
 
 -- 根据学号和图书ID获取借阅记录
-SELECT id, stu_id, book_id, borrow_date, due_date, return_date, is_overdue, fine_amount, created_at
+SELECT id, stu_id, book_id, barcode, borrow_date, due_date, return_date, is_overdue, fine_amount, created_at
 FROM borrow_records 
 WHERE stu_id = ? AND book_id = ? AND return_date IS NULL;
 
--- 还书操作
-UPDATE borrow_records 
-SET return_date = ?, is_overdue = (due_date < ?)
-WHERE stu_id = ? AND book_id = ? AND return_date IS NULL;
+-- 根据单册条码获取未归还的借阅记录（流通台还书）
+SELECT id, stu_id, book_id, barcode, borrow_date, due_date, return_date, is_overdue, fine_amount, created_at
+FROM borrow_records
+WHERE barcode = ? AND return_date IS NULL FOR UPDATE;
 
--- 更新逾期罚款金额
-UPDATE borrow_records SET is_overdue = true, fine_amount = ? WHERE id = ?;
+-- 还书操作（逾期和罚款金额由服务层计算，每天0.5元）
+UPDATE borrow_records
+SET return_date = ?, is_overdue = ?, fine_amount = ?
+WHERE id = ? AND return_date IS NULL;
 
 -- 获取学生的所有借阅记录
-SELECT id, stu_id, book_id, borrow_date, due_date, return_date, is_overdue, fine_amount, created_at
+SELECT id, stu_id, book_id, barcode, borrow_date, due_date, return_date, is_overdue, fine_amount, created_at
 FROM borrow_records 
 WHERE stu_id = ? AND return_date IS NULL;
 
+-- ==================== 分馆相关操作 ====================
+-- 用途：分馆、单册、预约和调拨
+-- 文件：branch_dao.go / book_item_dao.go / hold_dao.go / transfer_dao.go
+
+-- 查找一册在架可借的单册（可按当前分馆过滤，事务中加行锁）
+SELECT barcode, book_id, home_branch_id, current_branch_id, status, created_at
+FROM book_items
+WHERE book_id = ? AND status = 'available' AND current_branch_id = ?
+ORDER BY (home_branch_id = current_branch_id) DESC, barcode LIMIT 1 FOR UPDATE;
+
+-- 更新单册所在分馆和状态
+UPDATE book_items SET current_branch_id = ?, status = ? WHERE barcode = ?;
+
+-- 根据单册重新计算书籍的总馆藏和可借数量
+UPDATE books
+SET total_copies = (SELECT COUNT(*) FROM book_items WHERE book_id = ?),
+    available_copies = (SELECT COUNT(*) FROM book_items WHERE book_id = ? AND status = 'available')
+WHERE book_id = ? AND EXISTS (SELECT 1 FROM book_items WHERE book_id = ?);
+
+-- 按分馆统计馆藏
+SELECT br.branch_id, br.name,
+       (SELECT COUNT(*) FROM book_items bi WHERE bi.book_id = ? AND bi.home_branch_id = br.branch_id),
+       (SELECT COUNT(*) FROM book_items bi WHERE bi.book_id = ? AND bi.current_branch_id = br.branch_id AND bi.status = 'available')
+FROM branches br
+ORDER BY br.branch_id;
+
+-- 获取某本书最早的排队预约
+SELECT id, stu_id, book_id, pickup_branch_id, barcode, status, ready_at, created_at
+FROM holds
+WHERE book_id = ? AND status = 'waiting'
+ORDER BY created_at, id
+LIMIT 1 FOR UPDATE;
+
+-- 创建调拨单
+INSERT INTO transfers (barcode, from_branch_id, to_branch_id, reason, hold_id, status)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- 记录强制借出
+INSERT INTO circulation_overrides (librarian_id, stu_id, barcode, block_code, reason)
+VALUES (?, ?, ?, ?, ?);
+
+-- 标记调拨单已发出 / 已签收
+UPDATE transfers SET status = 'in_transit', shipped_at = ? WHERE id = ?;
+UPDATE transfers SET status = 'received', received_at = ? WHERE id = ?;
+
+-- ==================== 自助借还机相关操作 ====================
+-- 用途：设备登记、认证和启停
+-- 文件：kiosk_device_dao.go
+
+-- 根据API密钥哈希认证设备
+SELECT device_id, name, branch_id, api_key_hash, enabled, rate_limit_per_min, last_seen_at, created_at
+FROM kiosk_devices
+WHERE api_key_hash = ?;
+
+-- 启用 / 停用设备
+UPDATE kiosk_devices SET enabled = ? WHERE device_id = ?;
+
+-- 重新生成设备密钥
+UPDATE kiosk_devices SET api_key_hash = ? WHERE device_id = ?;
+
+-- 记录设备最近使用时间
+UPDATE kiosk_devices SET last_seen_at = ? WHERE device_id = ?;
+
+-- ==================== 开馆日历相关操作 ====================
+-- 用途：开放时间、闭馆日期；借书时顺延应还日期，还书时闭馆日不计逾期天数
+-- 文件：calendar_dao.go
+
+-- 获取每周开放时间
+SELECT weekday, open_time, close_time, closed FROM library_hours ORDER BY weekday;
+
+-- 设置某个星期的开放时间
+INSERT INTO library_hours (weekday, open_time, close_time, closed)
+VALUES (?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE open_time = VALUES(open_time), close_time = VALUES(close_time), closed = VALUES(closed);
+
+-- 获取与某段日期有交集的闭馆日期
+SELECT id, name, kind, start_date, end_date, created_at
+FROM library_closures
+WHERE end_date >= ? AND start_date <= ?
+ORDER BY start_date, id;
+
+-- 添加 / 删除闭馆日期
+INSERT INTO library_closures (name, kind, start_date, end_date) VALUES (?, ?, ?, ?);
+DELETE FROM library_closures WHERE id = ?;
+
+-- ==================== 通知相关操作 ====================
+-- 用途：通知设置、生成到期/逾期/预约到馆通知、发送与重试
+-- 文件：notification_dao.go
+
+-- 保存通知设置
+INSERT INTO notification_preferences (stu_id, email, language, due_soon, overdue, hold_ready)
+VALUES (?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE email = VALUES(email), language = VALUES(language),
+    due_soon = VALUES(due_soon), overdue = VALUES(overdue), hold_ready = VALUES(hold_ready);
+
+-- 查找3天内到期且尚未提醒的借阅记录（逾期提醒把条件换成 due_date < ?）
+SELECT br.id, br.stu_id, s.name, p.email, p.language, b.title, COALESCE(br.barcode, ''), br.due_date
+FROM borrow_records br
+JOIN students s ON s.stu_id = br.stu_id
+JOIN books b ON b.book_id = br.book_id
+JOIN notification_preferences p ON p.stu_id = br.stu_id AND p.due_soon = true
+WHERE br.return_date IS NULL AND br.due_date >= ? AND br.due_date <= ?
+  AND NOT EXISTS (SELECT 1 FROM notifications n WHERE n.kind = 'due_soon' AND n.ref_id = br.id);
+
+-- 查找已上预约架且尚未提醒的预约
+SELECT h.id, h.stu_id, s.name, p.email, p.language, b.title, COALESCE(h.barcode, ''), br.name
+FROM holds h
+JOIN students s ON s.stu_id = h.stu_id
+JOIN books b ON b.book_id = h.book_id
+JOIN branches br ON br.branch_id = h.pickup_branch_id
+JOIN notification_preferences p ON p.stu_id = h.stu_id AND p.hold_ready = true
+WHERE h.status = 'ready'
+  AND NOT EXISTS (SELECT 1 FROM notifications n WHERE n.kind = 'hold_ready' AND n.ref_id = h.id);
+
+-- 生成通知（已存在同类型同记录的通知时忽略）
+INSERT IGNORE INTO notifications (stu_id, kind, ref_id, email, subject, body, status, attempts, next_attempt_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?);
+
+-- 获取到了发送时间的通知
+SELECT id, stu_id, kind, ref_id, email, subject, body, status, attempts, last_error, next_attempt_at, sent_at, created_at
+FROM notifications
+WHERE status = 'pending' AND next_attempt_at <= ?
+ORDER BY next_attempt_at, id
+LIMIT ?;
+
+-- 认领通知（推迟下次发送时间，避免多个实例重复发送）
+UPDATE notifications SET next_attempt_at = ? WHERE id = ? AND status = 'pending' AND next_attempt_at <= ?;
+
+-- 发送成功 / 发送失败（失败后按1、2、4、8分钟退避重试，共5次）
+UPDATE notifications SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = ? WHERE id = ?;
+UPDATE notifications SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?;
+
+-- ==================== 事件推送相关操作 ====================
+-- 用途：发件箱写入、分发、签名推送与重试
+-- 文件：webhook_dao.go
+
+-- 写入发件箱（在借书、还书、支付罚款的事务中）
+INSERT INTO webhook_outbox (event_type, payload) VALUES (?, ?);
+
+-- 获取尚未分发的事件（加行锁，分发后标记）
+SELECT id, event_type, payload, dispatched_at, created_at
+FROM webhook_outbox
+WHERE dispatched_at IS NULL
+ORDER BY id
+LIMIT ? FOR UPDATE;
+UPDATE webhook_outbox SET dispatched_at = ? WHERE id = ?;
+
+-- 为订阅了该事件的推送地址创建投递
+INSERT INTO webhook_deliveries (event_id, endpoint_id, status, attempts, next_attempt_at)
+VALUES (?, ?, 'pending', 0, ?);
+
+-- 认领投递（推迟下次推送时间，避免多个实例重复推送）
+UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ? AND status = 'pending' AND next_attempt_at <= ?;
+
+-- 推送成功 / 推送失败（按1、2、4……分钟退避重试，共8次，用尽后为dead）
+UPDATE webhook_deliveries
+SET status = 'delivered', attempts = attempts + 1, last_status_code = ?, last_error = NULL, delivered_at = ?
+WHERE id = ?;
+UPDATE webhook_deliveries
+SET status = ?, attempts = attempts + 1, last_status_code = ?, last_error = ?, next_attempt_at = ?
+WHERE id = ?;
+
+-- ==================== 实时事件相关操作 ====================
+-- 用途：多实例部署时在实例间转发实时事件
+-- 文件：stream_event_dao.go
+
+INSERT INTO stream_events (payload) VALUES (?);
+SELECT id, payload, created_at FROM stream_events WHERE id > ? ORDER BY id LIMIT ?;
+SELECT COALESCE(MAX(id), 0) FROM stream_events;
+DELETE FROM stream_events WHERE created_at < ?;
+
 -- ==================== 事务操作 ====================
 -- 用途：需要事务处理的复杂业务操作
 -- 文件：borrow_service.go
@@ -109,18 +499,22 @@ WHERE stu_id = ? AND return_date IS NULL;
 -- 借书事务操作（包含以下SQL组合）：
 -- 1. 检查学生是否可以借书
 -- 2. 检查书籍是否可以借阅
--- 3. 创建借阅记录
--- 4. 减少书籍可借阅数量
+-- 3. 登记了单册的书籍：优先取学生已到架的预约，否则锁定一册在架单册并标记为借出
+-- 4. 创建借阅记录（应还日期为两个月后，落在闭馆日时顺延到下一个开馆日）
+-- 5. 减少书籍可借阅数量（登记了单册的书籍按单册重新统计）
+-- 6. 写入 book.borrowed 事件到发件箱
 
 -- 还书事务操作（包含以下SQL组合）：
--- 1. 检查是否逾期并计算罚款
+-- 1. 检查是否逾期并计算罚款（闭馆日不计逾期天数）
 -- 2. 执行还书操作
--- 3. 增加书籍可借阅数量
--- 4. 如果有逾期罚款，禁用学生借阅权限
+-- 3. 增加书籍可借阅数量；按单册借出的书依次尝试：满足排队预约、调回所属分馆、在还书分馆上架
+-- 4. 写入 book.returned 事件到发件箱
+-- 5. 如果有逾期罚款，禁用学生借阅权限，并写入 fine.charged 和 student.blocked 事件
 
 -- 支付罚款事务操作（包含以下SQL组合）：
 -- 1. 检查是否还有未支付的罚款
 -- 2. 启用学生借阅权限
+-- 3. 写入 fine.paid 事件到发件箱
 
 -- ==================== 测试数据 ====================
 -- 用途：插入测试数据用于开发和测试
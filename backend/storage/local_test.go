@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewLocalStorage(filepath.Join(dir, "covers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "covers/B001/v1/thumb.jpg"
+	if err := s.Put(ctx, key, []byte("old"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	// 已存在时覆盖
+	if err := s.Put(ctx, key, []byte("new"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	data, contentType, err := s.Get(ctx, key)
+	if err != nil || string(data) != "new" || contentType != "image/jpeg" {
+		t.Fatalf("Get(%s) = %q, %s, %v", key, data, contentType, err)
+	}
+	// 临时文件写完后已重命名，目录中只有目标文件
+	entries, err := os.ReadDir(filepath.Join(dir, "covers", "covers", "B001", "v1"))
+	if err != nil || len(entries) != 1 || entries[0].Name() != "thumb.jpg" {
+		t.Errorf("目录内容 = %v, %v", entries, err)
+	}
+
+	if err := s.Put(ctx, "notes/readme", []byte("x"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, contentType, err := s.Get(ctx, "notes/readme"); err != nil || contentType != "application/octet-stream" {
+		t.Errorf("没有扩展名时 Content-Type = %s, %v", contentType, err)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后读取 = %v，期望 ErrNotFound", err)
+	}
+	// 删除不存在的对象不报错
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("重复删除 = %v", err)
+	}
+}
+
+func TestLocalStorageRejectsInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewLocalStorage(filepath.Join(dir, "root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", "/etc/passwd", "../escape.jpg", "covers/../../escape.jpg", "covers//a.jpg", "covers/./a.jpg", `covers\a.jpg`} {
+		if err := s.Put(ctx, key, []byte("x"), ""); err == nil {
+			t.Errorf("Put(%q) 成功，期望拒绝", key)
+		}
+		if _, _, err := s.Get(ctx, key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) = %v，期望拒绝", key, err)
+		}
+		if err := s.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) 成功，期望拒绝", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.jpg")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("存储目录之外出现了文件: %v", err)
+	}
+}
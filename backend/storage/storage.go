@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// Storage 文件存储接口，键使用"/"分隔的相对路径；ctx取消时中止正在进行的读写
+type Storage interface {
+	// 写入对象，已存在时覆盖
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// 读取对象内容及其Content-Type，不存在时返回ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, string, error)
+	// 删除对象，不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// 校验存储键，拒绝绝对路径和跳出根目录的写法
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return errors.New("非法的存储键: " + key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return errors.New("非法的存储键: " + key)
+		}
+	}
+	return nil
+}
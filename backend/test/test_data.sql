@@ -10,6 +10,19 @@ INSERT INTO books (book_id, title, author, description, total_copies, available_
 ('B003', '算法导论', '王五', '算法学习经典', 2, 2, true),
 ('B004', '计算机网络', '赵六', '网络技术指南', 4, 4, true);
 
+INSERT INTO librarians (librarian_id, name, password, role) VALUES
+('L001', '管理员', 'admin123', 'admin'),
+('L002', '馆员甲', 'librarian123', 'librarian');
+
+INSERT INTO branches (branch_id, name, address) VALUES
+('MAIN', '总馆', '主校区图书馆'),
+('EAST', '东区分馆', '东校区图书馆');
+
+-- B003按单册管理，两个分馆各一册
+INSERT INTO book_items (barcode, book_id, home_branch_id, current_branch_id, status) VALUES
+('B003-0001', 'B003', 'MAIN', 'MAIN', 'available'),
+('B003-0002', 'B003', 'EAST', 'EAST', 'available');
+
 -- 插入借阅记录
 INSERT INTO borrow_records (stu_id, book_id, borrow_date, due_date, return_date, is_overdue, fine_amount) VALUES
 ('20230001', 'B001', '2024-01-01 10:00:00', '2024-03-01 10:00:00', NULL, false, 0),
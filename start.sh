@@ -20,4 +20,8 @@ echo "编译应用..."
 go build -o library_manager .
 
 echo "启动服务..."
-./library_manager
+if [ -f config.yaml ]; then
+    ./library_manager -config config.yaml
+else
+    ./library_manager
+fi